@@ -0,0 +1,285 @@
+// Package client is a Go client for the routes served by the backend. There's a
+// method on Client for every route, named after the route and taking and
+// returning the route's request and response types from the routes package.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bitclout/backend/routes"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgrijalva/jwt-go/v4"
+)
+
+const (
+	// The default time allowed for a call, including reading the response.
+	DefaultTimeout = 30 * time.Second
+	// The default lifetime of the JWTs the client signs.
+	DefaultJWTExpiration = 10 * time.Minute
+
+	// Error responses are read up to this size.
+	maxErrorBodyBytes = 1 << 16
+
+	// The routes that are signed with GlobalStateSharedSecret.
+	globalStateRoutePrefix = "/api/v1/global-state/"
+)
+
+// APIError is returned when the node responds with a status other than 200.
+type APIError struct {
+	RoutePath  string
+	StatusCode int
+	// The error the node put in its response. Routes report errors as
+	// {"error": ..., "code": ...} and the /api/v1 routes as {"Error": ..., "Code": ...},
+	// and both end up here. If the body wasn't JSON it's kept as is.
+	Message string
+	// The machine-readable code for the error, e.g. routes.ErrorCodeProfileNotFound.
+	// Empty if the node didn't send one.
+	Code    routes.ErrorCode
+	Details map[string]interface{}
+}
+
+func (apiErr *APIError) Error() string {
+	return fmt.Sprintf("Node returned status %d for %v: %v", apiErr.StatusCode, apiErr.RoutePath, apiErr.Message)
+}
+
+// Client calls the routes of a single node.
+type Client struct {
+	// The scheme, host and port of the node, e.g. http://localhost:17001.
+	BaseURL string
+	// The time allowed for a single call. Zero means no timeout beyond the one
+	// on the context passed in.
+	Timeout time.Duration
+
+	// If set, a JWT signed with this key is put in the JWT field of every request
+	// that has one and leaves it empty. The node checks the JWT against the public
+	// key the request is made on behalf of, so this should be that key's private
+	// key.
+	JWTPrivateKey *btcec.PrivateKey
+	// The lifetime of the JWTs signed with JWTPrivateKey. Nodes reject JWTs that
+	// live longer than their --jwt-max-lifetime.
+	JWTExpiration time.Duration
+	// The aud claim of the JWTs signed with JWTPrivateKey. Defaults to BaseURL's
+	// host, which should be one of the node's --jwt-audiences.
+	JWTAudience string
+
+	// If set, calls to the /api/v1/global-state routes are signed with this secret,
+	// which must match the node's --global-state-shared-secret.
+	GlobalStateSharedSecret string
+
+	transport  *http.Transport
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for the node at baseURL. A baseURL without a scheme,
+// like localhost:17001, is assumed to be http.
+func NewClient(baseURL string) *Client {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 5 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	jwtAudience := ""
+	if parsedURL, err := url.Parse(baseURL); err == nil {
+		jwtAudience = parsedURL.Host
+	}
+
+	return &Client{
+		BaseURL:       strings.TrimSuffix(baseURL, "/"),
+		Timeout:       DefaultTimeout,
+		JWTExpiration: DefaultJWTExpiration,
+		JWTAudience:   jwtAudience,
+		transport:     transport,
+		httpClient:    &http.Client{Transport: transport},
+	}
+}
+
+// SetTLSConfig sets the config used to connect to a node served over https, e.g.
+// to present a client certificate to a node started with --tls-require-client-cert.
+// It must be called before any calls are made. See
+// routes.CertificateReloader.ClientTLSConfig.
+func (client *Client) SetTLSConfig(tlsConfig *tls.Config) {
+	client.transport.TLSClientConfig = tlsConfig
+}
+
+// SignJWT returns a JWT that the node will accept on behalf of privateKey's public
+// key until it expires. Each token has a random jti so it can be used for a
+// single admin request. audience may be left empty for nodes that don't check it.
+func SignJWT(privateKey *btcec.PrivateKey, expiration time.Duration, audience string) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("SignJWT: Problem generating jti: %v", err)
+	}
+	claims := &jwt.StandardClaims{
+		ExpiresAt: jwt.At(time.Now().Add(expiration)),
+		ID:        hex.EncodeToString(jti),
+	}
+	if audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	signedToken, err := token.SignedString(privateKey.ToECDSA())
+	if err != nil {
+		return "", fmt.Errorf("SignJWT: Problem signing token: %v", err)
+	}
+	return signedToken, nil
+}
+
+// attachJWT fills in the JWT field of request if it has an empty one.
+func (client *Client) attachJWT(request interface{}) error {
+	if client.JWTPrivateKey == nil || request == nil {
+		return nil
+	}
+	requestValue := reflect.ValueOf(request)
+	if requestValue.Kind() != reflect.Ptr || requestValue.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	jwtField := requestValue.Elem().FieldByName("JWT")
+	if !jwtField.IsValid() || jwtField.Kind() != reflect.String || !jwtField.CanSet() || jwtField.String() != "" {
+		return nil
+	}
+
+	signedToken, err := SignJWT(client.JWTPrivateKey, client.JWTExpiration, client.JWTAudience)
+	if err != nil {
+		return err
+	}
+	jwtField.SetString(signedToken)
+	return nil
+}
+
+// Call sends request as the JSON body of a request to routePath and decodes the
+// response into response. A nil request sends no body, and a nil response
+// discards the response.
+func (client *Client) Call(ctx context.Context, method string, routePath string,
+	request interface{}, response interface{}) error {
+
+	var body []byte
+	if request != nil {
+		if err := client.attachJWT(request); err != nil {
+			return fmt.Errorf("Call: Problem attaching JWT for %v: %v", routePath, err)
+		}
+		var err error
+		body, err = json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("Call: Problem encoding request for %v: %v", routePath, err)
+		}
+	}
+
+	return client.do(ctx, method, routePath, body, "application/json", func(responseBody io.Reader) error {
+		return decodeResponse(routePath, responseBody, response)
+	})
+}
+
+func decodeResponse(routePath string, responseBody io.Reader, response interface{}) error {
+	if response == nil {
+		return nil
+	}
+	if err := json.NewDecoder(responseBody).Decode(response); err != nil {
+		return fmt.Errorf("decodeResponse: Problem decoding response from %v: %v", routePath, err)
+	}
+	return nil
+}
+
+// callRaw is like Call but returns the response body as is, for the few routes
+// that don't respond with JSON.
+func (client *Client) callRaw(ctx context.Context, method string, routePath string) ([]byte, error) {
+	var responseBytes []byte
+	err := client.do(ctx, method, routePath, nil, "", func(responseBody io.Reader) error {
+		var err error
+		responseBytes, err = ioutil.ReadAll(responseBody)
+		if err != nil {
+			return fmt.Errorf("callRaw: Problem reading response from %v: %v", routePath, err)
+		}
+		return nil
+	})
+	return responseBytes, err
+}
+
+func (client *Client) do(ctx context.Context, method string, routePath string, body []byte,
+	contentType string, readResponse func(responseBody io.Reader) error) error {
+
+	if client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, client.BaseURL+routePath, bodyReader)
+	if err != nil {
+		return fmt.Errorf("do: Problem creating request for %v: %v", routePath, err)
+	}
+	if contentType != "" && body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if client.GlobalStateSharedSecret != "" && strings.HasPrefix(routePath, globalStateRoutePrefix) {
+		if err := routes.SignGlobalStateRequest(req, client.GlobalStateSharedSecret, body); err != nil {
+			return fmt.Errorf("do: Problem signing request for %v: %v", routePath, err)
+		}
+	}
+
+	res, err := client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do: Problem calling %v: %v", routePath, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return newAPIError(routePath, res)
+	}
+	if err := readResponse(res.Body); err != nil {
+		return err
+	}
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, res.Body)
+	return nil
+}
+
+func newAPIError(routePath string, res *http.Response) *APIError {
+	apiErr := &APIError{RoutePath: routePath, StatusCode: res.StatusCode}
+	errorBytes, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBodyBytes))
+
+	// Field names are matched case-insensitively, so this picks up both error
+	// and Error.
+	errorRes := struct {
+		Error   string
+		Code    routes.ErrorCode
+		Details map[string]interface{}
+	}{}
+	if err := json.Unmarshal(errorBytes, &errorRes); err == nil && errorRes.Error != "" {
+		apiErr.Message = errorRes.Error
+		apiErr.Code = errorRes.Code
+		apiErr.Details = errorRes.Details
+	} else {
+		apiErr.Message = strings.TrimSpace(string(errorBytes))
+	}
+	return apiErr
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitclout/backend/routes"
+	"github.com/bitclout/core/lib"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientCoversAllRoutes(t *testing.T) {
+	require := require.New(t)
+
+	// Routes that aren't under a RoutePath constant.
+	routesWithoutMethods := map[string]bool{
+		"Index": true,
+	}
+
+	clientType := reflect.TypeOf(&Client{})
+	fes := &routes.APIServer{Params: &lib.BitCloutTestnetParams}
+	for _, route := range fes.AllRoutes() {
+		if routesWithoutMethods[route.Name] {
+			continue
+		}
+		_, exists := clientType.MethodByName(route.Name)
+		require.True(exists, "Add a method for route %v to Client", route.Name)
+	}
+}
+
+func TestClientCall(t *testing.T) {
+	require := require.New(t)
+
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	require.NoError(err)
+	publicKeyBase58Check := lib.PkToString(privateKey.PubKey().SerializeCompressed(), &lib.BitCloutTestnetParams)
+
+	var lastRequest *http.Request
+	var lastBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		lastRequest = req
+		lastBody = nil
+		json.NewDecoder(req.Body).Decode(&lastBody)
+
+		switch req.URL.Path {
+		case routes.RoutePathGetExchangeRate:
+			ww.Write([]byte(`{"SatoshisPerBitCloutExchangeRate": 123}`))
+		case routes.RoutePathGetSinglePost:
+			ww.WriteHeader(http.StatusNotFound)
+			ww.Write([]byte(`{"error": "GetSinglePost: Post not found", "code": "POST_NOT_FOUND"}`))
+		case routes.RoutePathAPIBalance:
+			ww.WriteHeader(http.StatusBadRequest)
+			ww.Write([]byte(`{"Error": "APIBalance: Bad public key", "Code": "INVALID_PUBLIC_KEY"}`))
+		case routes.RoutePathHealthCheck:
+			time.Sleep(time.Second)
+		default:
+			ww.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(strings.TrimPrefix(server.URL, "http://") + "/")
+	require.Equal(server.URL, client.BaseURL)
+	ctx := context.Background()
+
+	exchangeRate, err := client.GetExchangeRate(ctx)
+	require.NoError(err)
+	require.Equal("GET", lastRequest.Method)
+	require.Equal(uint64(123), exchangeRate.SatoshisPerBitCloutExchangeRate)
+
+	// Both error envelopes are understood.
+	_, err = client.GetSinglePost(ctx, &routes.GetSinglePostRequest{PostHashHex: "00"})
+	require.Equal(&APIError{
+		RoutePath:  routes.RoutePathGetSinglePost,
+		StatusCode: http.StatusNotFound,
+		Message:    "GetSinglePost: Post not found",
+		Code:       routes.ErrorCodePostNotFound,
+	}, err)
+	require.Equal("POST", lastRequest.Method)
+	require.Equal("application/json", lastRequest.Header.Get("Content-Type"))
+	require.Equal("00", lastBody["PostHashHex"])
+	_, err = client.APIBalance(ctx, &routes.APIBalanceRequest{})
+	require.Equal("APIBalance: Bad public key", err.(*APIError).Message)
+	require.Equal(routes.ErrorCodeInvalidPublicKey, err.(*APIError).Code)
+
+	// JWTs are only attached when the request has an empty JWT field.
+	require.NoError(client.MarkAllMessagesRead(ctx, &routes.MarkAllMessagesReadRequest{}))
+	require.Equal("", lastBody["JWT"])
+	client.JWTPrivateKey = privateKey
+	require.NoError(client.MarkAllMessagesRead(ctx, &routes.MarkAllMessagesReadRequest{
+		UserPublicKeyBase58Check: publicKeyBase58Check,
+	}))
+	fes := &routes.APIServer{JWTAudiences: []string{strings.TrimPrefix(server.URL, "http://")}}
+	isValid, err := fes.ValidateJWT(publicKeyBase58Check, lastBody["JWT"].(string))
+	require.NoError(err)
+	require.True(isValid)
+	// Every call gets a new token, so they can be used for admin routes.
+	firstJWT := lastBody["JWT"].(string)
+	isValid, err = fes.ValidateAdminJWT(publicKeyBase58Check, firstJWT)
+	require.NoError(err)
+	require.True(isValid)
+	require.NoError(client.MarkAllMessagesRead(ctx, &routes.MarkAllMessagesReadRequest{
+		UserPublicKeyBase58Check: publicKeyBase58Check,
+	}))
+	require.NotEqual(firstJWT, lastBody["JWT"])
+	require.NoError(client.MarkAllMessagesRead(ctx, &routes.MarkAllMessagesReadRequest{JWT: "mine"}))
+	require.Equal("mine", lastBody["JWT"])
+
+	// Calls to the global state routes are signed when there's a secret.
+	_, err = client.GlobalStateGetRemote(ctx, &routes.GlobalStateGetRemoteRequest{})
+	require.NoError(err)
+	require.Empty(lastRequest.Header.Get(routes.GlobalStateSignatureHeader))
+	client.GlobalStateSharedSecret = "secret"
+	_, err = client.GlobalStateGetRemote(ctx, &routes.GlobalStateGetRemoteRequest{})
+	require.NoError(err)
+	require.NotEmpty(lastRequest.Header.Get(routes.GlobalStateSignatureHeader))
+	_, err = client.GetExchangeRate(ctx)
+	require.NoError(err)
+	require.Empty(lastRequest.Header.Get(routes.GlobalStateSignatureHeader))
+
+	// Calls give up when the context is cancelled or the client times out.
+	cancelledCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	err = client.HealthCheck(cancelledCtx)
+	require.Error(err)
+	require.Contains(err.Error(), "context deadline exceeded")
+	client.Timeout = 10 * time.Millisecond
+	require.Error(client.HealthCheck(ctx))
+}
@@ -0,0 +1,835 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+
+	"github.com/bitclout/backend/routes"
+)
+
+// Every route has a method here named after the route. Remember to add new routes
+// here along with their Route; TestClientCoversAllRoutes checks that none are
+// missing.
+
+func (client *Client) HealthCheck(ctx context.Context) error {
+	_, err := client.callRaw(ctx, "GET", routes.RoutePathHealthCheck)
+	return err
+}
+
+// Metrics returns the node's metrics in the Prometheus text format.
+func (client *Client) Metrics(ctx context.Context) ([]byte, error) {
+	return client.callRaw(ctx, "GET", routes.RoutePathMetrics)
+}
+
+func (client *Client) GetOpenAPISpec(ctx context.Context) (*routes.OpenAPISpec, error) {
+	response := &routes.OpenAPISpec{}
+	if err := client.Call(ctx, "GET", routes.RoutePathGetOpenAPISpec, nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) ReprocessBitcoinBlock(ctx context.Context, request *routes.ReprocessBitcoinBlockRequest) (
+	*routes.ReprocessBitcoinBlockResponse, error) {
+
+	response := &routes.ReprocessBitcoinBlockResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathReprocessBitcoinBlock, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UploadImage uploads the image read from image on behalf of
+// userPublicKeyBase58Check. jwt can be left empty if the client has a
+// JWTPrivateKey.
+func (client *Client) UploadImage(ctx context.Context, userPublicKeyBase58Check string, jwt string,
+	fileName string, image io.Reader) (*routes.UploadImageResponse, error) {
+
+	if jwt == "" && client.JWTPrivateKey != nil {
+		var err error
+		jwt, err = SignJWT(client.JWTPrivateKey, client.JWTExpiration, client.JWTAudience)
+		if err != nil {
+			return nil, fmt.Errorf("UploadImage: %v", err)
+		}
+	}
+
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	if err := form.WriteField("UserPublicKeyBase58Check", userPublicKeyBase58Check); err != nil {
+		return nil, fmt.Errorf("UploadImage: Problem writing form: %v", err)
+	}
+	if err := form.WriteField("JWT", jwt); err != nil {
+		return nil, fmt.Errorf("UploadImage: Problem writing form: %v", err)
+	}
+	fileWriter, err := form.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, fmt.Errorf("UploadImage: Problem writing form: %v", err)
+	}
+	if _, err := io.Copy(fileWriter, image); err != nil {
+		return nil, fmt.Errorf("UploadImage: Problem reading image: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("UploadImage: Problem writing form: %v", err)
+	}
+
+	response := &routes.UploadImageResponse{}
+	err = client.do(ctx, "POST", routes.RoutePathUploadImage, body.Bytes(), form.FormDataContentType(),
+		func(responseBody io.Reader) error {
+			return decodeResponse(routes.RoutePathUploadImage, responseBody, response)
+		})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetExchangeRate(ctx context.Context) (*routes.GetExchangeRateResponse, error) {
+	response := &routes.GetExchangeRateResponse{}
+	if err := client.Call(ctx, "GET", routes.RoutePathGetExchangeRate, nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SendBitClout(ctx context.Context, request *routes.SendBitCloutRequest) (
+	*routes.SendBitCloutResponse, error) {
+
+	response := &routes.SendBitCloutResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathSendBitClout, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) BurnBitcoin(ctx context.Context, request *routes.BurnBitcoinRequest) (
+	*routes.BurnBitcoinResponse, error) {
+
+	response := &routes.BurnBitcoinResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathBurnBitcoin, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SubmitTransaction(ctx context.Context, request *routes.SubmitTransactionRequest) (
+	*routes.SubmitTransactionResponse, error) {
+
+	response := &routes.SubmitTransactionResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathSubmitTransaction, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) DeleteIdentities(ctx context.Context, request *routes.DeleteIdentityRequest) (
+	*routes.DeleteIdentityResponse, error) {
+
+	response := &routes.DeleteIdentityResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathDeleteIdentities, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetUsersStateless(ctx context.Context, request *routes.GetUsersStatelessRequest) (
+	*routes.GetUsersResponse, error) {
+
+	response := &routes.GetUsersResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetUsersStateless, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SendPhoneNumberVerificationText(ctx context.Context, request *routes.SendPhoneNumberVerificationTextRequest) (
+	*routes.SendPhoneNumberVerificationTextResponse, error) {
+
+	response := &routes.SendPhoneNumberVerificationTextResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathSendPhoneNumberVerificationText, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SubmitPhoneNumberVerificationCode(ctx context.Context, request *routes.SubmitPhoneNumberVerificationCodeRequest) error {
+	return client.Call(ctx, "POST", routes.RoutePathSubmitPhoneNumberVerificationCode, request, nil)
+}
+
+func (client *Client) SubmitPost(ctx context.Context, request *routes.SubmitPostRequest) (
+	*routes.SubmitPostResponse, error) {
+
+	response := &routes.SubmitPostResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathSubmitPost, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetPostsStateless(ctx context.Context, request *routes.GetPostsStatelessRequest) (
+	*routes.GetPostsStatelessResponse, error) {
+
+	response := &routes.GetPostsStatelessResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetPostsStateless, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) UpdateProfile(ctx context.Context, request *routes.UpdateProfileRequest) (
+	*routes.UpdateProfileResponse, error) {
+
+	response := &routes.UpdateProfileResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathUpdateProfile, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetProfiles(ctx context.Context, request *routes.GetProfilesRequest) (
+	*routes.GetProfilesResponse, error) {
+
+	response := &routes.GetProfilesResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetProfiles, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetSingleProfile(ctx context.Context, request *routes.GetSingleProfileRequest) (
+	*routes.GetSingleProfileResponse, error) {
+
+	response := &routes.GetSingleProfileResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetSingleProfile, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetPostsForPublicKey(ctx context.Context, request *routes.GetPostsForPublicKeyRequest) (
+	*routes.GetPostsForPublicKeyResponse, error) {
+
+	response := &routes.GetPostsForPublicKeyResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetPostsForPublicKey, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetDiamondsForPublicKey(ctx context.Context, request *routes.GetDiamondsForPublicKeyRequest) (
+	*routes.GetDiamondsForPublicKeyResponse, error) {
+
+	response := &routes.GetDiamondsForPublicKeyResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetDiamondsForPublicKey, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetDiamondedPosts(ctx context.Context, request *routes.GetPostsDiamondedBySenderForReceiverRequest) (
+	*routes.GetPostsDiamondedBySenderForReceiverResponse, error) {
+
+	response := &routes.GetPostsDiamondedBySenderForReceiverResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetDiamondedPosts, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetHodlersForPublicKey(ctx context.Context, request *routes.GetHodlersForPublicKeyRequest) (
+	*routes.GetHodlersForPublicKeyResponse, error) {
+
+	response := &routes.GetHodlersForPublicKeyResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetHodlersForPublicKey, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetFollowsStateless(ctx context.Context, request *routes.GetFollowsStatelessRequest) (
+	*routes.GetFollowsResponse, error) {
+
+	response := &routes.GetFollowsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetFollowsStateless, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) CreateFollowTxnStateless(ctx context.Context, request *routes.CreateFollowTxnStatelessRequest) (
+	*routes.CreateFollowTxnStatelessResponse, error) {
+
+	response := &routes.CreateFollowTxnStatelessResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathCreateFollowTxnStateless, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) CreateLikeStateless(ctx context.Context, request *routes.CreateLikeStatelessRequest) (
+	*routes.CreateLikeStatelessResponse, error) {
+
+	response := &routes.CreateLikeStatelessResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathCreateLikeStateless, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) BuyOrSellCreatorCoin(ctx context.Context, request *routes.BuyOrSellCreatorCoinRequest) (
+	*routes.BuyOrSellCreatorCoinResponse, error) {
+
+	response := &routes.BuyOrSellCreatorCoinResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathBuyOrSellCreatorCoin, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) TransferCreatorCoin(ctx context.Context, request *routes.TransferCreatorCoinRequest) (
+	*routes.TransferCreatorCoinResponse, error) {
+
+	response := &routes.TransferCreatorCoinResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathTransferCreatorCoin, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SendDiamonds(ctx context.Context, request *routes.SendDiamondsRequest) (
+	*routes.SendDiamondsResponse, error) {
+
+	response := &routes.SendDiamondsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathSendDiamonds, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetNotifications(ctx context.Context, request *routes.GetNotificationsRequest) (
+	*routes.GetNotificationsResponse, error) {
+
+	response := &routes.GetNotificationsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetNotifications, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetAppState(ctx context.Context, request *routes.GetAppStateRequest) (
+	*routes.GetAppStateResponse, error) {
+
+	response := &routes.GetAppStateResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetAppState, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) UpdateUserGlobalMetadata(ctx context.Context, request *routes.UpdateUserGlobalMetadataRequest) (
+	*routes.UpdateUserGlobalMetadataResponse, error) {
+
+	response := &routes.UpdateUserGlobalMetadataResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathUpdateUserGlobalMetadata, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetUserGlobalMetadata(ctx context.Context, request *routes.GetUserGlobalMetadataRequest) (
+	*routes.GetUserGlobalMetadataResponse, error) {
+
+	response := &routes.GetUserGlobalMetadataResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetUserGlobalMetadata, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) NodeControl(ctx context.Context, request *routes.NodeControlRequest) (
+	*routes.NodeControlResponse, error) {
+
+	response := &routes.NodeControlResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathNodeControl, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminUpdateUserGlobalMetadata(ctx context.Context, request *routes.AdminUpdateUserGlobalMetadataRequest) (
+	*routes.AdminUpdateUserGlobalMetadataResponse, error) {
+
+	response := &routes.AdminUpdateUserGlobalMetadataResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminUpdateUserGlobalMetadata, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetVerifiedUsers(ctx context.Context, request *routes.AdminGetVerifiedUsersRequest) (
+	*routes.AdminGetVerifiedUsersResponse, error) {
+
+	response := &routes.AdminGetVerifiedUsersResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetVerifiedUsers, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetUsernameVerificationAuditLogs(ctx context.Context, request *routes.AdminGetUsernameVerificationAuditLogsRequest) (
+	*routes.AdminGetUsernameVerificationAuditLogsResponse, error) {
+
+	response := &routes.AdminGetUsernameVerificationAuditLogsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetUsernameVerificationAuditLogs, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGrantVerificationBadge(ctx context.Context, request *routes.AdminGrantVerificationBadgeRequest) (
+	*routes.AdminGrantVerificationBadgeResponse, error) {
+
+	response := &routes.AdminGrantVerificationBadgeResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGrantVerificationBadge, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminRemoveVerificationBadge(ctx context.Context, request *routes.AdminRemoveVerificationBadgeRequest) (
+	*routes.AdminRemoveVerificationBadgeResponse, error) {
+
+	response := &routes.AdminRemoveVerificationBadgeResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminRemoveVerificationBadge, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetAllUserGlobalMetadata(ctx context.Context, request *routes.AdminGetAllUserGlobalMetadataRequest) (
+	*routes.AdminGetAllUserGlobalMetadataResponse, error) {
+
+	response := &routes.AdminGetAllUserGlobalMetadataResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetAllUserGlobalMetadata, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetUserGlobalMetadata(ctx context.Context, request *routes.AdminGetUserGlobalMetadataRequest) (
+	*routes.AdminGetUserGlobalMetadataResponse, error) {
+
+	response := &routes.AdminGetUserGlobalMetadataResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetUserGlobalMetadata, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminUpdateGlobalFeed(ctx context.Context, request *routes.AdminUpdateGlobalFeedRequest) (
+	*routes.AdminUpdateGlobalFeedResponse, error) {
+
+	response := &routes.AdminUpdateGlobalFeedResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminUpdateGlobalFeed, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminPinPost(ctx context.Context, request *routes.AdminPinPostRequest) (
+	*routes.AdminPinPostResponse, error) {
+
+	response := &routes.AdminPinPostResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminPinPost, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminRemoveNilPosts(ctx context.Context, request *routes.AdminRemoveNilPostsRequest) (
+	*routes.AdminRemoveNilPostsResponse, error) {
+
+	response := &routes.AdminRemoveNilPostsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminRemoveNilPosts, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetGlobalStatePrefixes(ctx context.Context, request *routes.AdminGetGlobalStatePrefixesRequest) (
+	*routes.AdminGetGlobalStatePrefixesResponse, error) {
+
+	response := &routes.AdminGetGlobalStatePrefixesResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetGlobalStatePrefixes, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetGlobalStateEntries(ctx context.Context, request *routes.AdminGetGlobalStateEntriesRequest) (
+	*routes.AdminGetGlobalStateEntriesResponse, error) {
+
+	response := &routes.AdminGetGlobalStateEntriesResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetGlobalStateEntries, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminUpdateGlobalStateEntry(ctx context.Context, request *routes.AdminUpdateGlobalStateEntryRequest) (
+	*routes.AdminUpdateGlobalStateEntryResponse, error) {
+
+	response := &routes.AdminUpdateGlobalStateEntryResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminUpdateGlobalStateEntry, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetGlobalStateAuditLogs(ctx context.Context, request *routes.AdminGetGlobalStateAuditLogsRequest) (
+	*routes.AdminGetGlobalStateAuditLogsResponse, error) {
+
+	response := &routes.AdminGetGlobalStateAuditLogsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetGlobalStateAuditLogs, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGlobalStateFsck(ctx context.Context, request *routes.AdminGlobalStateFsckRequest) (
+	*routes.AdminGlobalStateFsckResponse, error) {
+
+	response := &routes.AdminGlobalStateFsckResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGlobalStateFsck, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetMempoolStats(ctx context.Context, request *routes.AdminGetMempoolStatsRequest) (
+	*routes.AdminGetMempoolStatsResponse, error) {
+
+	response := &routes.AdminGetMempoolStatsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetMempoolStats, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SwapIdentity(ctx context.Context, request *routes.SwapIdentityRequest) (
+	*routes.SwapIdentityResponse, error) {
+
+	response := &routes.SwapIdentityResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathSwapIdentity, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) UpdateGlobalParams(ctx context.Context, request *routes.UpdateGlobalParamsRequest) (
+	*routes.UpdateGlobalParamsResponse, error) {
+
+	response := &routes.UpdateGlobalParamsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathUpdateGlobalParams, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetGlobalParams(ctx context.Context, request *routes.GetGlobalParamsRequest) (
+	*routes.GetGlobalParamsResponse, error) {
+
+	response := &routes.GetGlobalParamsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetGlobalParams, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) EvictUnminedBitcoinTxns(ctx context.Context, request *routes.EvictUnminedBitcoinTxnsRequest) (
+	*routes.EvictUnminedBitcoinTxnsResponse, error) {
+
+	response := &routes.EvictUnminedBitcoinTxnsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathEvictUnminedBitcoinTxns, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminRebuildTxindex(ctx context.Context, request *routes.AdminRebuildTxindexRequest) (
+	*routes.AdminRebuildTxindexResponse, error) {
+
+	response := &routes.AdminRebuildTxindexResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminRebuildTxindex, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetTxindexRebuildStatus(ctx context.Context, request *routes.AdminGetTxindexRebuildStatusRequest) (
+	*routes.AdminGetTxindexRebuildStatusResponse, error) {
+
+	response := &routes.AdminGetTxindexRebuildStatusResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetTxindexRebuildStatus, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetAdminRoles(ctx context.Context, request *routes.AdminGetAdminRolesRequest) (
+	*routes.AdminGetAdminRolesResponse, error) {
+
+	response := &routes.AdminGetAdminRolesResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetAdminRoles, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminUpdateAdminRoles(ctx context.Context, request *routes.AdminUpdateAdminRolesRequest) (
+	*routes.AdminUpdateAdminRolesResponse, error) {
+
+	response := &routes.AdminUpdateAdminRolesResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminUpdateAdminRoles, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) AdminGetAdminAuditLogs(ctx context.Context, request *routes.AdminGetAdminAuditLogsRequest) (
+	*routes.AdminGetAdminAuditLogsResponse, error) {
+
+	response := &routes.AdminGetAdminAuditLogsResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAdminGetAdminAuditLogs, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetSinglePost(ctx context.Context, request *routes.GetSinglePostRequest) (
+	*routes.GetSinglePostResponse, error) {
+
+	response := &routes.GetSinglePostResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetSinglePost, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) BlockPublicKey(ctx context.Context, request *routes.BlockPublicKeyRequest) (
+	*routes.BlockPublicKeyResponse, error) {
+
+	response := &routes.BlockPublicKeyResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathBlockPublicKey, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) BlockGetTxn(ctx context.Context, request *routes.GetTxnRequest) (
+	*routes.GetTxnResponse, error) {
+
+	response := &routes.GetTxnResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetTxn, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SendMessageStateless(ctx context.Context, request *routes.SendMessageStatelessRequest) (
+	*routes.SendMessageStatelessResponse, error) {
+
+	response := &routes.SendMessageStatelessResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathSendMessageStateless, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetMessagesStateless(ctx context.Context, request *routes.GetMessagesStatelessRequest) (
+	*routes.GetMessagesResponse, error) {
+
+	response := &routes.GetMessagesResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetMessagesStateless, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) MarkContactMessagesRead(ctx context.Context, request *routes.MarkContactMessagesReadRequest) error {
+	return client.Call(ctx, "POST", routes.RoutePathMarkContactMessagesRead, request, nil)
+}
+
+func (client *Client) MarkAllMessagesRead(ctx context.Context, request *routes.MarkAllMessagesReadRequest) error {
+	return client.Call(ctx, "POST", routes.RoutePathMarkAllMessagesRead, request, nil)
+}
+
+func (client *Client) GetBlockTemplate(ctx context.Context, request *routes.GetBlockTemplateRequest) (
+	*routes.GetBlockTemplateResponse, error) {
+
+	response := &routes.GetBlockTemplateResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetBlockTemplate, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) SubmitBlock(ctx context.Context, request *routes.SubmitBlockRequest) (
+	*routes.SubmitBlockResponse, error) {
+
+	response := &routes.SubmitBlockResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathSubmitBlock, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GetFullTikTokURL(ctx context.Context, request *routes.GetFullTikTokURLRequest) (
+	*routes.GetFullTikTokURLResponse, error) {
+
+	response := &routes.GetFullTikTokURLResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGetFullTikTokURL, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) APIBase(ctx context.Context) (*routes.APIBaseResponse, error) {
+	response := &routes.APIBaseResponse{}
+	if err := client.Call(ctx, "GET", routes.RoutePathAPIBase, nil, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) APIKeyPair(ctx context.Context, request *routes.APIKeyPairRequest) (
+	*routes.APIKeyPairResponse, error) {
+
+	response := &routes.APIKeyPairResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAPIKeyPair, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) APIBalance(ctx context.Context, request *routes.APIBalanceRequest) (
+	*routes.APIBalanceResponse, error) {
+
+	response := &routes.APIBalanceResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAPIBalance, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) APITransferBitClout(ctx context.Context, request *routes.APITransferBitCloutRequest) (
+	*routes.APITransferBitCloutResponse, error) {
+
+	response := &routes.APITransferBitCloutResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAPITransferBitClout, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) APITransactionInfo(ctx context.Context, request *routes.APITransactionInfoRequest) (
+	*routes.APITransactionInfoResponse, error) {
+
+	response := &routes.APITransactionInfoResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAPITransactionInfo, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) APINodeInfo(ctx context.Context, request *routes.APINodeInfoRequest) (
+	*routes.APINodeInfoResponse, error) {
+
+	response := &routes.APINodeInfoResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAPINodeInfo, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) APIBlock(ctx context.Context, request *routes.APIBlockRequest) (
+	*routes.APIBlockResponse, error) {
+
+	response := &routes.APIBlockResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathAPIBlock, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GlobalStatePutRemote(ctx context.Context, request *routes.GlobalStatePutRemoteRequest) (
+	*routes.GlobalStatePutRemoteResponse, error) {
+
+	response := &routes.GlobalStatePutRemoteResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGlobalStatePutRemote, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GlobalStateGetRemote(ctx context.Context, request *routes.GlobalStateGetRemoteRequest) (
+	*routes.GlobalStateGetRemoteResponse, error) {
+
+	response := &routes.GlobalStateGetRemoteResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGlobalStateGetRemote, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GlobalStateBatchGetRemote(ctx context.Context, request *routes.GlobalStateBatchGetRemoteRequest) (
+	*routes.GlobalStateBatchGetRemoteResponse, error) {
+
+	response := &routes.GlobalStateBatchGetRemoteResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGlobalStateBatchGetRemote, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GlobalStateDeleteRemote(ctx context.Context, request *routes.GlobalStateDeleteRemoteRequest) (
+	*routes.GlobalStateDeleteRemoteResponse, error) {
+
+	response := &routes.GlobalStateDeleteRemoteResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGlobalStateDeleteRemote, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GlobalStateSeekRemote(ctx context.Context, request *routes.GlobalStateSeekRemoteRequest) (
+	*routes.GlobalStateSeekRemoteResponse, error) {
+
+	response := &routes.GlobalStateSeekRemoteResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGlobalStateSeekRemote, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GlobalStateCompareAndSwapRemote(ctx context.Context, request *routes.GlobalStateCompareAndSwapRemoteRequest) (
+	*routes.GlobalStateCompareAndSwapRemoteResponse, error) {
+
+	response := &routes.GlobalStateCompareAndSwapRemoteResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGlobalStateCompareAndSwapRemote, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *Client) GlobalStateChangesRemote(ctx context.Context, request *routes.GlobalStateChangesRemoteRequest) (
+	*routes.GlobalStateChangesRemoteResponse, error) {
+
+	response := &routes.GlobalStateChangesRemoteResponse{}
+	if err := client.Call(ctx, "POST", routes.RoutePathGlobalStateChangesRemote, request, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -6,12 +6,15 @@ import (
 	"github.com/spf13/viper"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
 	// Core
 	TXIndex                bool
 	APIPort                uint16
+	APIShutdownDelay       time.Duration
+	APIShutdownTimeout     time.Duration
 
 	// Onboarding
 	StarterBitcloutSeed    string
@@ -24,14 +27,36 @@ type Config struct {
 	MinSatoshisForProfile  uint64
 
 	// Global State
-	GlobalStateRemoteNode   string
-	GlobalStateRemoteSecret string
+	GlobalStateRemoteNode           string
+	GlobalStateRemoteSecret         string
+	GlobalStateRemotePreviousSecret string
+	GlobalStatePIIKey               string
+	GlobalStatePIIPreviousKeys      []string
+	GlobalStatePIIIndexKey          string
+	GlobalStateRemoteCertFile       string
+	GlobalStateRemoteKeyFile        string
+	GlobalStateRemoteCAFile         string
 
 	// Web Security
 	AccessControlAllowOrigins []string
 	SecureHeaderDevelopment   bool
 	SecureHeaderAllowHosts    []string
 	AdminPublicKeys           []string
+	AdminOpenAccess           bool
+	JWTMaxLifetime            time.Duration
+	JWTAudiences              []string
+	RateLimitStore            string
+	RateLimitsFile            string
+	RateLimitIPHeader         string
+	TLSCertFile               string
+	TLSKeyFile                string
+	TLSClientCAFile           string
+	TLSRequireClientCert      bool
+
+	// Logging
+	AccessLogPath              string
+	AccessLogSampleRate        float64
+	AccessLogRouteSampleRates  map[string]float64
 
 	// Analytics + Profiling
 	AmplitudeKey           string
@@ -57,6 +82,8 @@ func LoadConfig(coreConfig *coreCmd.Config) *Config {
 		// TODO: pull this out of core. we shouldn't need core's config here
 		config.APIPort = coreConfig.Params.DefaultJSONPort
 	}
+	config.APIShutdownDelay = viper.GetDuration("api-shutdown-delay")
+	config.APIShutdownTimeout = viper.GetDuration("api-shutdown-timeout")
 
 	// Onboarding
 	config.StarterBitcloutSeed = viper.GetString("starter-bitclout-seed")
@@ -82,12 +109,50 @@ func LoadConfig(coreConfig *coreCmd.Config) *Config {
 	// Global State
 	config.GlobalStateRemoteNode = viper.GetString("global-state-remote-node")
 	config.GlobalStateRemoteSecret = viper.GetString("global-state-remote-secret")
+	config.GlobalStateRemotePreviousSecret = viper.GetString("global-state-remote-previous-secret")
+	config.GlobalStatePIIKey = viper.GetString("global-state-pii-key")
+	config.GlobalStatePIIPreviousKeys = viper.GetStringSlice("global-state-pii-previous-keys")
+	config.GlobalStatePIIIndexKey = viper.GetString("global-state-pii-index-key")
+	config.GlobalStateRemoteCertFile = viper.GetString("global-state-remote-cert-file")
+	config.GlobalStateRemoteKeyFile = viper.GetString("global-state-remote-key-file")
+	config.GlobalStateRemoteCAFile = viper.GetString("global-state-remote-ca-file")
 
 	// Web Security
 	config.AccessControlAllowOrigins = viper.GetStringSlice("access-control-allow-origins")
 	config.SecureHeaderDevelopment = viper.GetBool("secure-header-development")
 	config.SecureHeaderAllowHosts =  viper.GetStringSlice("secure-header-allow-hosts")
 	config.AdminPublicKeys = viper.GetStringSlice("admin-public-keys")
+	config.AdminOpenAccess = viper.GetBool("admin-open-access")
+	config.JWTMaxLifetime = viper.GetDuration("jwt-max-lifetime")
+	config.JWTAudiences = viper.GetStringSlice("jwt-audiences")
+	config.RateLimitStore = viper.GetString("rate-limit-store")
+	config.RateLimitsFile = viper.GetString("rate-limits-file")
+	config.RateLimitIPHeader = viper.GetString("rate-limit-ip-header")
+	config.TLSCertFile = viper.GetString("tls-cert-file")
+	config.TLSKeyFile = viper.GetString("tls-key-file")
+	config.TLSClientCAFile = viper.GetString("tls-client-ca-file")
+	config.TLSRequireClientCert = viper.GetBool("tls-require-client-cert")
+
+	// Logging
+	config.AccessLogPath = viper.GetString("access-log")
+	config.AccessLogSampleRate = viper.GetFloat64("access-log-sample-rate")
+	accessLogRouteSampleRates := viper.GetString("access-log-route-sample-rates")
+	if len(accessLogRouteSampleRates) > 0 {
+		config.AccessLogRouteSampleRates = make(map[string]float64)
+		for _, pair := range strings.Split(accessLogRouteSampleRates, ",") {
+			entry := strings.Split(pair, "=")
+			if len(entry) != 2 {
+				fmt.Printf("invalid route sample rate: %s", pair)
+				continue
+			}
+			sampleRate, err := strconv.ParseFloat(entry[1], 64)
+			if err != nil {
+				fmt.Printf("invalid sample rate: %s", entry[1])
+				continue
+			}
+			config.AccessLogRouteSampleRates[entry[0]] = sampleRate
+		}
+	}
 
 	// Analytics + Profiling
 	config.AmplitudeKey = viper.GetString("amplitude-key")
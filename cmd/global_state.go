@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitclout/backend/routes"
+	"github.com/bitclout/core/lib"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/spf13/cobra"
+)
+
+// globalStateCmd groups the commands for managing a node's local global state db.
+// These commands open the db directly, so the node must not be running.
+var globalStateCmd = &cobra.Command{
+	Use:   "global-state",
+	Short: "Manage the global state db",
+	Long: `Commands for backing up, restoring and maintaining the global state db. The
+node that owns the db must be stopped before running any of these commands.`,
+}
+
+var globalStateExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export every global state entry to JSONL",
+	Long: `Writes one JSON object per line for every key in global state. Each line
+contains the raw key and value as hex along with a decoded, readable version of
+both. The raw hex is what gets restored by import.`,
+	RunE: GlobalStateExport,
+}
+
+var globalStateImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import global state entries from JSONL produced by export",
+	Long: `Restores entries written by export. Importing the same file twice is a
+no-op. With --dry-run, nothing is written and the entries that would be added or
+changed are printed instead.`,
+	RunE: GlobalStateImport,
+}
+
+var globalStateRotatePIICmd = &cobra.Command{
+	Use:   "rotate-pii",
+	Short: "Encrypt or re-encrypt the personal information in global state",
+	Long: `Brings every email address and phone number in global state up to date with
+the given keys. Plaintext values are encrypted with --pii-key, values encrypted
+with one of --pii-previous-keys are re-wrapped with --pii-key, and phone number
+metadata stored under a plaintext phone number is moved to its HMAC key. Run this
+after enabling encryption or after changing --global-state-pii-key. Running it
+again is a no-op.`,
+	RunE: GlobalStateRotatePII,
+}
+
+var globalStateFsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Check global state for entries that are inconsistent with the chain",
+	Long: `Walks every key in global state and reports entries that can't be decoded or
+that no longer agree with the chain, such as feed posts that were deleted and
+verified usernames whose profile changed its name. Each issue is printed as a line
+of JSON. With --repair, the issues that can be fixed safely are fixed. The chain
+db under --data-dir is only read.`,
+	RunE: GlobalStateFsck,
+}
+
+// openGlobalStateForCmd opens the global state db under --data-dir.
+func openGlobalStateForCmd(cmd *cobra.Command) (*badger.DB, error) {
+	dataDir, err := cmd.Flags().GetString("data-dir")
+	if err != nil {
+		return nil, err
+	}
+	if dataDir == "" {
+		return nil, fmt.Errorf("--data-dir is required")
+	}
+	return OpenGlobalStateDB(GetGlobalStateDir(dataDir))
+}
+
+// piiKeyringForCmd returns the keyring given by the --pii-* flags, or nil if they
+// weren't set.
+func piiKeyringForCmd(cmd *cobra.Command) (*routes.PIIKeyring, error) {
+	piiKey, err := cmd.Flags().GetString("pii-key")
+	if err != nil {
+		return nil, err
+	}
+	piiPreviousKeys, err := cmd.Flags().GetStringSlice("pii-previous-keys")
+	if err != nil {
+		return nil, err
+	}
+	piiIndexKey, err := cmd.Flags().GetString("pii-index-key")
+	if err != nil {
+		return nil, err
+	}
+	return routes.NewPIIKeyring(piiKey, piiPreviousKeys, piiIndexKey)
+}
+
+// paramsForCmd returns the params used to encode public keys in decoded entries.
+func paramsForCmd(cmd *cobra.Command) (*lib.BitCloutParams, error) {
+	testnet, err := cmd.Flags().GetBool("testnet")
+	if err != nil {
+		return nil, err
+	}
+	if testnet {
+		return &lib.BitCloutTestnetParams, nil
+	}
+	return &lib.BitCloutMainnetParams, nil
+}
+
+func GlobalStateExport(cmd *cobra.Command, args []string) error {
+	params, err := paramsForCmd(cmd)
+	if err != nil {
+		return err
+	}
+	outFile, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return err
+	}
+
+	db, err := openGlobalStateForCmd(cmd)
+	if err != nil {
+		return fmt.Errorf("GlobalStateExport: Problem opening global state: %v", err)
+	}
+	defer db.Close()
+
+	var out io.Writer = os.Stdout
+	if outFile != "" {
+		file, err := os.Create(outFile)
+		if err != nil {
+			return fmt.Errorf("GlobalStateExport: Problem creating %v: %v", outFile, err)
+		}
+		defer file.Close()
+		out = file
+	}
+	writer := bufio.NewWriter(out)
+	defer writer.Flush()
+
+	// Walk every key rather than every registered prefix so that entries under
+	// unknown prefixes are exported too.
+	numExported := 0
+	encoder := json.NewEncoder(writer)
+	err = routes.GlobalStateIteratePrefix(routes.NewBadgerGlobalStore(db), []byte{}, func(key []byte, value []byte) error {
+		numExported++
+		return encoder.Encode(routes.DecodeGlobalStateEntry(key, value, params))
+	})
+	if err != nil {
+		return fmt.Errorf("GlobalStateExport: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d entries\n", numExported)
+	return nil
+}
+
+func GlobalStateImport(cmd *cobra.Command, args []string) error {
+	inFile, err := cmd.Flags().GetString("in")
+	if err != nil {
+		return err
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return err
+	}
+
+	db, err := openGlobalStateForCmd(cmd)
+	if err != nil {
+		return fmt.Errorf("GlobalStateImport: Problem opening global state: %v", err)
+	}
+	defer db.Close()
+	store := routes.NewBadgerGlobalStore(db)
+
+	var in io.Reader = os.Stdin
+	if inFile != "" {
+		file, err := os.Open(inFile)
+		if err != nil {
+			return fmt.Errorf("GlobalStateImport: Problem opening %v: %v", inFile, err)
+		}
+		defer file.Close()
+		in = file
+	}
+
+	numAdded, numChanged, numUnchanged := 0, 0, 0
+	decoder := json.NewDecoder(bufio.NewReader(in))
+	for decoder.More() {
+		entry := routes.GlobalStateEntry{}
+		if err := decoder.Decode(&entry); err != nil {
+			return fmt.Errorf("GlobalStateImport: Problem decoding entry %d: %v",
+				numAdded+numChanged+numUnchanged, err)
+		}
+		key, value, err := entry.Bytes()
+		if err != nil {
+			return fmt.Errorf("GlobalStateImport: %v", err)
+		}
+
+		existingValue, err := store.Get(key)
+		if err != nil {
+			return fmt.Errorf("GlobalStateImport: Problem getting existing value for %v: %v", entry.KeyHex, err)
+		}
+		if existingValue != nil && bytes.Equal(existingValue, value) {
+			numUnchanged++
+			continue
+		}
+
+		if existingValue == nil {
+			numAdded++
+		} else {
+			numChanged++
+		}
+		if dryRun {
+			action := "ADD"
+			if existingValue != nil {
+				action = "CHANGE"
+			}
+			fmt.Printf("%s\t%s\t%s\n", action, entry.Prefix, entry.KeyHex)
+			continue
+		}
+		if err := store.Put(key, value); err != nil {
+			return fmt.Errorf("GlobalStateImport: Problem putting %v: %v", entry.KeyHex, err)
+		}
+	}
+
+	verb := "Imported"
+	if dryRun {
+		verb = "Would import"
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d added, %d changed, %d unchanged\n", verb, numAdded, numChanged, numUnchanged)
+	return nil
+}
+
+func GlobalStateRotatePII(cmd *cobra.Command, args []string) error {
+	keyring, err := piiKeyringForCmd(cmd)
+	if err != nil {
+		return fmt.Errorf("GlobalStateRotatePII: %v", err)
+	}
+
+	db, err := openGlobalStateForCmd(cmd)
+	if err != nil {
+		return fmt.Errorf("GlobalStateRotatePII: Problem opening global state: %v", err)
+	}
+	defer db.Close()
+
+	numUpdated, err := routes.RotateGlobalStatePII(routes.NewBadgerGlobalStore(db), keyring)
+	if err != nil {
+		return fmt.Errorf("GlobalStateRotatePII: Rotated %d entries before failing: %v", numUpdated, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Rotated %d entries\n", numUpdated)
+	return nil
+}
+
+func init() {
+	// Note that these flags are intentionally not bound to viper so they don't
+	// collide with the flags of the same name on the run command.
+	globalStateCmd.PersistentFlags().String("data-dir", "",
+		"The data directory of the node whose global state should be used.")
+	globalStateCmd.PersistentFlags().Bool("testnet", false,
+		"Encode public keys in decoded entries using testnet params.")
+
+	globalStateExportCmd.Flags().String("out", "",
+		"The file to write JSONL to. Defaults to stdout.")
+
+	globalStateImportCmd.Flags().String("in", "",
+		"The JSONL file to read. Defaults to stdin.")
+	globalStateImportCmd.Flags().Bool("dry-run", false,
+		"Print the entries that would be added or changed without writing anything.")
+
+	globalStateCmd.PersistentFlags().String("pii-key", "",
+		"The value of --global-state-pii-key. For rotate-pii, the key to encrypt with "+
+			"going forward.")
+	globalStateCmd.PersistentFlags().StringSlice("pii-previous-keys", []string{},
+		"The value of --global-state-pii-previous-keys. For rotate-pii, the keys that "+
+			"data may currently be encrypted with.")
+	globalStateCmd.PersistentFlags().String("pii-index-key", "",
+		"The value of --global-state-pii-index-key.")
+
+	globalStateFsckCmd.Flags().Bool("repair", false,
+		"Fix the issues that can be fixed safely.")
+
+	globalStateCmd.AddCommand(globalStateExportCmd)
+	globalStateCmd.AddCommand(globalStateRotatePIICmd)
+	globalStateCmd.AddCommand(globalStateImportCmd)
+	globalStateCmd.AddCommand(globalStateFsckCmd)
+	rootCmd.AddCommand(globalStateCmd)
+}
+
+// chainDBView reads posts and profiles straight from a stopped node's chain db for
+// GlobalStateFsck.
+type chainDBView struct {
+	db *badger.DB
+}
+
+func (view *chainDBView) GetPostEntryForPostHash(postHash *lib.BlockHash) *lib.PostEntry {
+	return lib.DBGetPostEntryByPostHash(view.db, postHash)
+}
+
+func (view *chainDBView) GetProfileEntryForPKID(pkid *lib.PKID) *lib.ProfileEntry {
+	return lib.DBGetProfileEntryForPKID(view.db, pkid)
+}
+
+func GlobalStateFsck(cmd *cobra.Command, args []string) error {
+	params, err := paramsForCmd(cmd)
+	if err != nil {
+		return err
+	}
+	keyring, err := piiKeyringForCmd(cmd)
+	if err != nil {
+		return fmt.Errorf("GlobalStateFsck: %v", err)
+	}
+	repair, err := cmd.Flags().GetBool("repair")
+	if err != nil {
+		return err
+	}
+
+	db, err := openGlobalStateForCmd(cmd)
+	if err != nil {
+		return fmt.Errorf("GlobalStateFsck: Problem opening global state: %v", err)
+	}
+	defer db.Close()
+
+	dataDir, err := cmd.Flags().GetString("data-dir")
+	if err != nil {
+		return err
+	}
+	chainDBDir := lib.GetBadgerDbPath(dataDir)
+	chainDBOpts := badger.DefaultOptions(chainDBDir)
+	chainDBOpts.ValueDir = chainDBDir
+	chainDBOpts.ReadOnly = true
+	chainDB, err := badger.Open(chainDBOpts)
+	if err != nil {
+		return fmt.Errorf("GlobalStateFsck: Problem opening chain db: %v", err)
+	}
+	defer chainDB.Close()
+
+	fes := &routes.APIServer{
+		GlobalStore: routes.NewBadgerGlobalStore(db),
+		Params:      params,
+		PIIKeyring:  keyring,
+	}
+	report, err := fes.RunGlobalStateFsck(&chainDBView{db: chainDB}, repair)
+	if err != nil {
+		return fmt.Errorf("GlobalStateFsck: %v", err)
+	}
+
+	numRepaired := 0
+	encoder := json.NewEncoder(os.Stdout)
+	for _, issue := range report.Issues {
+		if issue.Repaired {
+			numRepaired++
+		}
+		if err := encoder.Encode(issue); err != nil {
+			return fmt.Errorf("GlobalStateFsck: %v", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Checked %d entries: %d issues, %d repaired\n",
+		report.NumKeysChecked, len(report.Issues), numRepaired)
+	return nil
+}
@@ -1,12 +1,14 @@
 package cmd
 
 import (
+	"context"
 	"github.com/bitclout/backend/routes"
 	coreCmd "github.com/bitclout/core/cmd"
 	"github.com/bitclout/core/lib"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/golang/glog"
 	"github.com/kevinburke/twilio-go"
+	"os"
 	"path/filepath"
 )
 
@@ -17,6 +19,18 @@ type Node struct {
 	GlobalState *badger.DB
 	Config      *Config
 
+	// Only set when global state is proxied to a remote node.
+	GlobalStateCache *routes.CachingGlobalStore
+
+	// Only set when the access log is written to a file.
+	AccessLog *os.File
+
+	// Only set when the API is served over TLS.
+	TLSCertificates *routes.CertificateReloader
+	// Only set when calls to the global state remote node need a client
+	// certificate or a CA bundle of their own.
+	GlobalStateRemoteCertificates *routes.CertificateReloader
+
 	CoreNode    *coreCmd.Node
 }
 
@@ -34,13 +48,7 @@ func (node *Node) Start() {
 	// Create a transaction index as a separate db that's managed by the APIServer.
 	// This makes it easy to delete manually if it gets too large.
 	if node.Config.TXIndex {
-		txindexDir := filepath.Join(lib.GetBadgerDbPath(node.CoreNode.Config.DataDirectory), "txindex")
-		txindexOpts := badger.DefaultOptions(txindexDir)
-		txindexOpts.ValueDir = lib.GetBadgerDbPath(txindexDir)
-		txindexOpts.MemTableSize = 1024 << 20
-		glog.Infof("TxIndex BadgerDB Dir: %v", txindexOpts.Dir)
-		glog.Infof("TxIndex BadgerDB ValueDir: %v", txindexOpts.ValueDir)
-		node.TXIndex, err = badger.Open(txindexOpts)
+		node.TXIndex, err = OpenTxindexDB(GetTxindexDir(node.CoreNode.Config.DataDirectory))
 		if err != nil {
 			glog.Fatal(err)
 		}
@@ -49,16 +57,98 @@ func (node *Node) Start() {
 	// For the global state, we use a local db unless a remote node is set in
 	// which case all global state set/fetch calls will proxy to the remote.
 	if node.Config.GlobalStateRemoteNode == "" {
-		globalStateDir := filepath.Join(lib.GetBadgerDbPath(node.CoreNode.Config.DataDirectory), "global_state")
-		globalStateOpts := badger.DefaultOptions(globalStateDir)
-		globalStateOpts.MemTableSize = 1024 << 20
-		globalStateOpts.ValueDir = lib.GetBadgerDbPath(globalStateDir)
-		glog.Infof("GlobalState BadgerDB Dir: %v", globalStateOpts.Dir)
-		glog.Infof("GlobalState BadgerDB ValueDir: %v", globalStateOpts.ValueDir)
-		node.GlobalState, err = badger.Open(globalStateOpts)
+		node.GlobalState, err = OpenGlobalStateDB(GetGlobalStateDir(node.CoreNode.Config.DataDirectory))
+		if err != nil {
+			glog.Fatal(err)
+		}
+	}
+
+	// Both the local db and the remote node are hidden behind a GlobalStore so
+	// the APIServer doesn't need to care which one it's talking to.
+	var globalStore routes.GlobalStore
+	// The same store without the change log or cache. See GlobalStateRateLimitStore.
+	var uncachedGlobalStore routes.GlobalStore
+	if node.GlobalState != nil {
+		badgerGlobalStore := routes.NewBadgerGlobalStore(node.GlobalState)
+		uncachedGlobalStore = badgerGlobalStore
+
+		// Only the node that owns the global state db runs migrations.
+		if err = routes.RunGlobalStateMigrations(badgerGlobalStore); err != nil {
+			glog.Fatal(err)
+		}
+
+		// Record every write so that nodes proxying to us can keep their caches
+		// up to date.
+		globalStore, err = routes.NewChangeLogGlobalStore(badgerGlobalStore)
+		if err != nil {
+			glog.Fatal(err)
+		}
+	} else {
+		if node.Config.GlobalStateRemoteCertFile != "" || node.Config.GlobalStateRemoteCAFile != "" {
+			node.GlobalStateRemoteCertificates, err = routes.NewCertificateReloader(
+				node.Config.GlobalStateRemoteCertFile, node.Config.GlobalStateRemoteKeyFile,
+				node.Config.GlobalStateRemoteCAFile)
+			if err != nil {
+				glog.Fatal(err)
+			}
+		}
+
+		// Cache reads from the remote node locally and follow its change feed to
+		// keep the cache fresh.
+		node.GlobalStateCache = routes.NewCachingGlobalStore(node.newRemoteGlobalStore())
+		go node.GlobalStateCache.Start()
+		globalStore = node.GlobalStateCache
+		uncachedGlobalStore = node.newRemoteGlobalStore()
+	}
+
+	piiKeyring, err := routes.NewPIIKeyring(node.Config.GlobalStatePIIKey,
+		node.Config.GlobalStatePIIPreviousKeys, node.Config.GlobalStatePIIIndexKey)
+	if err != nil {
+		glog.Fatal(err)
+	}
+
+	var rateLimiter *routes.RateLimiter
+	if node.Config.RateLimitStore != "" {
+		routeRateLimits, err := routes.LoadRouteRateLimits(node.Config.RateLimitsFile)
+		if err != nil {
+			glog.Fatal(err)
+		}
+		var rateLimitStore routes.RateLimitStore
+		switch node.Config.RateLimitStore {
+		case "memory":
+			rateLimitStore = routes.NewMemoryRateLimitStore()
+		case "global-state":
+			rateLimitStore = routes.NewGlobalStateRateLimitStore(uncachedGlobalStore)
+		default:
+			glog.Fatalf("Unknown --rate-limit-store %q", node.Config.RateLimitStore)
+		}
+		rateLimiter = routes.NewRateLimiter(rateLimitStore, routeRateLimits, node.Config.RateLimitIPHeader)
+	}
+
+	var accessLogger *routes.AccessLogger
+	if node.Config.AccessLogPath == "-" {
+		accessLogger = routes.NewAccessLogger(os.Stdout, node.Config.AccessLogSampleRate,
+			node.Config.AccessLogRouteSampleRates)
+	} else if node.Config.AccessLogPath != "" {
+		node.AccessLog, err = os.OpenFile(node.Config.AccessLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			glog.Fatal(err)
+		}
+		accessLogger = routes.NewAccessLogger(node.AccessLog, node.Config.AccessLogSampleRate,
+			node.Config.AccessLogRouteSampleRates)
+	}
+
+	if node.Config.TLSCertFile != "" || node.Config.TLSKeyFile != "" {
+		node.TLSCertificates, err = routes.NewCertificateReloader(
+			node.Config.TLSCertFile, node.Config.TLSKeyFile, node.Config.TLSClientCAFile)
 		if err != nil {
 			glog.Fatal(err)
 		}
+	} else if node.Config.TLSClientCAFile != "" {
+		glog.Fatal("--tls-client-ca-file requires --tls-cert-file and --tls-key-file")
+	}
+	if node.Config.TLSRequireClientCert && node.Config.TLSClientCAFile == "" {
+		glog.Fatal("--tls-require-client-cert requires --tls-client-ca-file")
 	}
 
 	var twilioClient *twilio.Client
@@ -78,9 +168,11 @@ func (node *Node) Start() {
 		node.Config.StarterBitcloutSeed,
 		node.Config.StarterBitcloutNanos,
 		node.Config.StarterPrefixNanosMap,
-		node.GlobalState,
+		globalStore,
 		node.Config.GlobalStateRemoteNode,
 		node.Config.GlobalStateRemoteSecret,
+		node.Config.GlobalStateRemotePreviousSecret,
+		piiKeyring,
 		node.Config.AccessControlAllowOrigins,
 		node.Config.SecureHeaderDevelopment,
 		node.Config.SecureHeaderAllowHosts,
@@ -96,22 +188,134 @@ func (node *Node) Start() {
 		node.Config.GCPBucketName,
 		node.Config.CompProfileCreation,
 		node.Config.AdminPublicKeys,
+		rateLimiter,
+		accessLogger,
 	)
 	if err != nil {
 		glog.Fatal(err)
 	}
+	apiServer.AdminOpenAccess = node.Config.AdminOpenAccess
+	if node.Config.AdminOpenAccess {
+		glog.Warning("Anyone can call the admin routes because --admin-open-access is set")
+	} else if len(node.Config.AdminPublicKeys) == 0 {
+		glog.Info("No --admin-public-keys were passed, so only admins granted roles in global state " +
+			"can call the admin routes")
+	}
+	apiServer.JWTMaxLifetime = node.Config.JWTMaxLifetime
+	apiServer.JWTAudiences = node.Config.JWTAudiences
+	if len(apiServer.JWTAudiences) == 0 {
+		apiServer.JWTAudiences = node.Config.SecureHeaderAllowHosts
+	}
+	if len(apiServer.JWTAudiences) == 0 {
+		glog.Fatal("Either --jwt-audiences or --secure-header-allow-hosts must be set so that " +
+			"JWTs signed for another node can't be used on this one")
+	}
+	apiServer.TLSCertificates = node.TLSCertificates
+	apiServer.RequireClientCertificate = node.Config.TLSRequireClientCert
+	apiServer.ShutdownDelay = node.Config.APIShutdownDelay
+	node.APIServer = apiServer
+
+	// Update the txindex as soon as the main chain changes rather than waiting for
+	// the txindex loop's next tip check. The mempool events also keep the ETags
+	// and the feed cache current.
+	node.CoreNode.EventManager.OnBlockConnected(apiServer.HandleBlockConnected)
+	node.CoreNode.EventManager.OnBlockDisconnected(apiServer.HandleBlockDisconnected)
+	node.CoreNode.EventManager.OnTransactionConnected(apiServer.HandleTransactionConnected)
+
+	go node.APIServer.Start()
+}
+
+func (node *Node) newRemoteGlobalStore() *routes.RemoteGlobalStore {
+	remoteGlobalStore := routes.NewRemoteGlobalStore(
+		node.Config.GlobalStateRemoteNode, node.Config.GlobalStateRemoteSecret)
+	if node.GlobalStateRemoteCertificates != nil {
+		remoteGlobalStore.Client.SetTLSConfig(node.GlobalStateRemoteCertificates.ClientTLSConfig())
+	}
+	return remoteGlobalStore
+}
+
+// ReloadCertificates reads the TLS certificates and CA bundles from disk again.
+// New connections use the reloaded certificates. If a file can't be read, the
+// certificates that were loaded before are kept.
+func (node *Node) ReloadCertificates() {
+	for _, certificates := range []*routes.CertificateReloader{
+		node.TLSCertificates, node.GlobalStateRemoteCertificates} {
+
+		if certificates == nil {
+			continue
+		}
+		if err := certificates.Reload(); err != nil {
+			glog.Error(err)
+			continue
+		}
+		glog.Infof("Node.ReloadCertificates: Reloaded certificate %q and CA bundle %q",
+			certificates.CertFile, certificates.CAFile)
+	}
+}
+
+// GetGlobalStateDir returns the directory holding the global state db for a node
+// with the given data directory.
+func GetGlobalStateDir(dataDirectory string) string {
+	return filepath.Join(lib.GetBadgerDbPath(dataDirectory), "global_state")
+}
+
+// OpenGlobalStateDB opens the badger db used to store global state.
+func OpenGlobalStateDB(globalStateDir string) (*badger.DB, error) {
+	globalStateOpts := badger.DefaultOptions(globalStateDir)
+	globalStateOpts.MemTableSize = 1024 << 20
+	globalStateOpts.ValueDir = lib.GetBadgerDbPath(globalStateDir)
+	glog.Infof("GlobalState BadgerDB Dir: %v", globalStateOpts.Dir)
+	glog.Infof("GlobalState BadgerDB ValueDir: %v", globalStateOpts.ValueDir)
+	return badger.Open(globalStateOpts)
+}
+
+// GetTxindexDir returns the directory holding the txindex db for a node with the
+// given data directory.
+func GetTxindexDir(dataDirectory string) string {
+	return filepath.Join(lib.GetBadgerDbPath(dataDirectory), "txindex")
+}
 
-	go apiServer.Start()
+// OpenTxindexDB opens the badger db used to store the txindex.
+func OpenTxindexDB(txindexDir string) (*badger.DB, error) {
+	txindexOpts := badger.DefaultOptions(txindexDir)
+	txindexOpts.ValueDir = lib.GetBadgerDbPath(txindexDir)
+	txindexOpts.MemTableSize = 1024 << 20
+	glog.Infof("TxIndex BadgerDB Dir: %v", txindexOpts.Dir)
+	glog.Infof("TxIndex BadgerDB ValueDir: %v", txindexOpts.ValueDir)
+	return badger.Open(txindexOpts)
 }
 
 func (node *Node) Stop() {
-	node.APIServer.Stop()
+	// The txindex db is only safe to close once the APIServer has stopped updating
+	// it.
+	txindexIsSafeToClose := true
+	if node.APIServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			node.Config.APIShutdownDelay+node.Config.APIShutdownTimeout)
+		if err := node.APIServer.Stop(ctx); err != nil {
+			glog.Error(err)
+			txindexIsSafeToClose = false
+		}
+		cancel()
+	}
+
+	if node.GlobalStateCache != nil {
+		node.GlobalStateCache.Stop()
+	}
 
 	if node.GlobalState != nil {
 		_ = node.GlobalState.Close()
 	}
 
 	if node.TXIndex != nil {
-		_ = node.TXIndex.Close()
+		if txindexIsSafeToClose {
+			_ = node.TXIndex.Close()
+		} else {
+			glog.Error("Node.Stop: Not closing the txindex db because it may still be in use")
+		}
+	}
+
+	if node.AccessLog != nil {
+		_ = node.AccessLog.Close()
 	}
 }
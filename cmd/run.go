@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/golang/glog"
 )
@@ -31,14 +32,25 @@ func Run(cmd *cobra.Command, args []string) {
 	node := NewNode(config, coreNode)
 	node.Start()
 
-	shutdownListener := make(chan os.Signal)
+	shutdownListener := make(chan os.Signal, 1)
 	signal.Notify(shutdownListener, syscall.SIGINT, syscall.SIGTERM)
 	defer func() {
 		node.Stop()
 		glog.Info("Shutdown complete")
 	}()
 
-	<-shutdownListener
+	// SIGHUP reloads TLS certificates.
+	reloadListener := make(chan os.Signal, 1)
+	signal.Notify(reloadListener, syscall.SIGHUP)
+
+	for {
+		select {
+		case <-reloadListener:
+			node.ReloadCertificates()
+		case <-shutdownListener:
+			return
+		}
+	}
 }
 
 func init() {
@@ -54,6 +66,12 @@ func init() {
 	runCmd.PersistentFlags().Uint64("api-port", 0,
 		"When set, determines the port on which this node will listen for json "+
 			"requests. If unset, the port will default to what is present in the BitCloutParams set.")
+	runCmd.PersistentFlags().Duration("api-shutdown-delay", 0,
+		"How long to keep serving requests after a shutdown signal while the health check "+
+			"fails, so load balancers can stop sending requests to this node first.")
+	runCmd.PersistentFlags().Duration("api-shutdown-timeout", 30*time.Second,
+		"How long to wait for in-flight requests and txindex updates to finish on "+
+			"shutdown, after --api-shutdown-delay. Connections still open after this are closed.")
 
 	// Onboarding
 	runCmd.PersistentFlags().String("starter-bitclout-seed", "",
@@ -88,7 +106,36 @@ func init() {
 			"emails, that should not be duplicated across multiple nodes.")
 	runCmd.PersistentFlags().String("global-state-remote-secret", "",
 		"When a remote node is being used to set/fetch global state, a secret "+
-			"is also required to restrict access.")
+			"is also required to restrict access. Requests to the remote node are "+
+			"signed with this secret. The node serving global state must be started "+
+			"with the same secret, otherwise it rejects all global state requests.")
+	runCmd.PersistentFlags().String("global-state-remote-previous-secret", "",
+		"Optional. A second secret that this node accepts on incoming global state "+
+			"requests but never signs with. To rotate secrets, set this to the old "+
+			"secret and global-state-remote-secret to the new one on the node serving "+
+			"global state, roll the new secret out to the other nodes, then unset this.")
+	runCmd.PersistentFlags().String("global-state-pii-key", "",
+		"Optional. A hex-encoded 32-byte key used to encrypt email addresses and phone "+
+			"numbers before they're written to global state. Every node that shares "+
+			"global state must use the same key. Requires global-state-pii-index-key.")
+	runCmd.PersistentFlags().StringSlice("global-state-pii-previous-keys", []string{},
+		"Optional. A comma-separated list of hex-encoded keys that were previously used "+
+			"as global-state-pii-key. They are only used to decrypt. To rotate keys, move "+
+			"the old key here, set a new global-state-pii-key, run "+
+			"'global-state rotate-pii', then remove the old key.")
+	runCmd.PersistentFlags().String("global-state-pii-index-key", "",
+		"Optional. A hex-encoded 32-byte key used to HMAC phone numbers so that phone "+
+			"number metadata can be looked up without storing the number in the key. "+
+			"Unlike global-state-pii-key, this key can't be rotated.")
+	runCmd.PersistentFlags().String("global-state-remote-cert-file", "",
+		"Optional. A PEM certificate presented to global-state-remote-node when it's served "+
+			"over https and requires client certificates. Requires global-state-remote-key-file. "+
+			"Reloaded on SIGHUP.")
+	runCmd.PersistentFlags().String("global-state-remote-key-file", "",
+		"Optional. The PEM private key for global-state-remote-cert-file.")
+	runCmd.PersistentFlags().String("global-state-remote-ca-file", "",
+		"Optional. A PEM CA bundle used to verify global-state-remote-node's certificate instead "+
+			"of the system's CAs. Reloaded on SIGHUP.")
 
 	// Web Security
 	runCmd.PersistentFlags().StringSlice("access-control-allow-origins", []string{"*"},
@@ -101,6 +148,50 @@ func init() {
 		"If set, runs our secure header middleware in development mode, which disables some "+
 			"of the options. The default is true to make it easy to run a node locally. "+
 			"See https://github.com/unrolled/secure for more info. Note that")
+	runCmd.PersistentFlags().Bool("admin-open-access", false,
+		"If set, anyone can call the admin routes, e.g. /api/v0/admin/*. Otherwise they can only be called "+
+			"by admin-public-keys, which are superadmins, and by admins that have been granted "+
+			"the route's role with the admin update-admin-roles route. Only use this for local "+
+			"development.")
+	runCmd.PersistentFlags().Duration("jwt-max-lifetime", 10*time.Minute,
+		"JWTs must expire within this long of when they're used. Admin JWTs can also only be "+
+			"used once, so admin clients should sign a new short-lived JWT for every request.")
+	runCmd.PersistentFlags().StringSlice("jwt-audiences", []string{},
+		"Accepts a comma-separated list of hosts, e.g. node.example.com, that JWTs must name in "+
+			"their aud claim so that a JWT signed for another node can't be used on this one. "+
+			"Defaults to secure-header-allow-hosts. The node won't start if neither is set.")
+	runCmd.PersistentFlags().String("rate-limit-store", "",
+		"Where to keep rate limit counters. Either \"memory\", which limits each node on its own, or "+
+			"\"global-state\", which shares limits between every node using the same global state. "+
+			"Rate limiting is disabled if not set.")
+	runCmd.PersistentFlags().String("rate-limits-file", "",
+		"Optional. A JSON file mapping route names to rate limits, which override the built-in "+
+			"defaults. Map a route to null to stop limiting it.")
+	runCmd.PersistentFlags().String("rate-limit-ip-header", "",
+		"Optional. The header to read client IPs from when rate limiting, e.g. X-Forwarded-For. "+
+			"Only set this when the node is behind a proxy that always sets the header.")
+	runCmd.PersistentFlags().String("tls-cert-file", "",
+		"Optional. A PEM certificate to serve the API over https with. Requires tls-key-file. "+
+			"The certificate and key are reloaded on SIGHUP.")
+	runCmd.PersistentFlags().String("tls-key-file", "",
+		"Optional. The PEM private key for tls-cert-file.")
+	runCmd.PersistentFlags().String("tls-client-ca-file", "",
+		"Optional. A PEM CA bundle used to verify client certificates. Clients may present a "+
+			"certificate signed by one of these CAs. Reloaded on SIGHUP.")
+	runCmd.PersistentFlags().Bool("tls-require-client-cert", false,
+		"When set, the /api/v0/admin/* and /api/v1/global-state/* routes can only be called with "+
+			"a client certificate signed by tls-client-ca-file. Other routes don't need one.")
+
+	// Logging
+	runCmd.PersistentFlags().String("access-log", "",
+		"Optional. A file to append a JSON access log to, or - for stdout. Only request fields "+
+			"tagged with safeForLogging are logged.")
+	runCmd.PersistentFlags().Float64("access-log-sample-rate", 1,
+		"The fraction of successful requests written to the access log, from 0 to 1. Failed "+
+			"requests are always logged.")
+	runCmd.PersistentFlags().String("access-log-route-sample-rates", "",
+		"Optional. Overrides --access-log-sample-rate for specific routes, e.g. "+
+			"HealthCheck=0,GetPostsStateless=0.1")
 
 	// Analytics + Profiling
 	runCmd.PersistentFlags().String("amplitude-key", "", "Client-side amplitude key for instrumenting user behavior.")
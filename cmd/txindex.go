@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bitclout/backend/routes"
+	"github.com/bitclout/core/lib"
+	chainlib "github.com/btcsuite/btcd/blockchain"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/spf13/cobra"
+)
+
+// txindexCmd groups the commands for checking and repairing a node's txindex.
+// These commands open the chain db and the txindex db directly, so the node must
+// not be running.
+var txindexCmd = &cobra.Command{
+	Use:   "txindex",
+	Short: "Verify and rebuild the transaction index",
+	Long: `Commands for checking the txindex against the chain and rebuilding it. The
+node that owns the dbs must be stopped before running any of these commands.`,
+}
+
+var txindexVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the txindex against the blocks it was built from",
+	Long: `Derives the txindex metadata for every transaction in the main chain blocks
+from --start-height to --end-height and compares it with what's stored in the
+txindex. Each transaction that's missing or different is printed as a line of
+JSON. Deriving the metadata for a block needs the state before it, so every block
+from the genesis block on is replayed into a scratch db. Nothing is written to the
+txindex.`,
+	RunE: TxindexVerify,
+}
+
+var txindexRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Rebuild the txindex from a given height",
+	Long: `Removes the txindex entries for every block from --from-height on and indexes
+those blocks again from the main chain. With the default --from-height of zero the
+whole txindex is rebuilt. Progress is printed as the txindex is rewound and then
+built back up.`,
+	RunE: TxindexRebuild,
+}
+
+// openTxindexForCmd opens the chain db and the txindex db under --data-dir and
+// returns an APIServer for working on the txindex, along with a function that
+// closes both dbs.
+func openTxindexForCmd(cmd *cobra.Command) (*routes.APIServer, func(), error) {
+	params, err := paramsForCmd(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	dataDir, err := cmd.Flags().GetString("data-dir")
+	if err != nil {
+		return nil, nil, err
+	}
+	if dataDir == "" {
+		return nil, nil, fmt.Errorf("--data-dir is required")
+	}
+
+	chainDBDir := lib.GetBadgerDbPath(dataDir)
+	chainDBOpts := badger.DefaultOptions(chainDBDir)
+	chainDBOpts.ValueDir = chainDBDir
+	chainDB, err := badger.Open(chainDBOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Problem opening chain db: %v", err)
+	}
+	txindexDB, err := OpenTxindexDB(GetTxindexDir(dataDir))
+	if err != nil {
+		_ = chainDB.Close()
+		return nil, nil, fmt.Errorf("Problem opening txindex db: %v", err)
+	}
+	closeDBs := func() {
+		_ = txindexDB.Close()
+		_ = chainDB.Close()
+	}
+
+	blockchain, err := lib.NewBlockchain(
+		[]string{}, 0,
+		params, chainlib.NewMedianTime(), chainDB,
+		nil, nil)
+	if err != nil {
+		closeDBs()
+		return nil, nil, fmt.Errorf("Problem loading chain: %v", err)
+	}
+	fes, err := routes.NewTxindexAPIServer(blockchain, txindexDB, params)
+	if err != nil {
+		closeDBs()
+		return nil, nil, err
+	}
+	return fes, closeDBs, nil
+}
+
+func TxindexVerify(cmd *cobra.Command, args []string) error {
+	startHeight, err := cmd.Flags().GetUint32("start-height")
+	if err != nil {
+		return err
+	}
+	endHeight, err := cmd.Flags().GetUint32("end-height")
+	if err != nil {
+		return err
+	}
+	scratchDir, err := cmd.Flags().GetString("scratch-dir")
+	if err != nil {
+		return err
+	}
+
+	fes, closeDBs, err := openTxindexForCmd(cmd)
+	if err != nil {
+		return fmt.Errorf("TxindexVerify: %v", err)
+	}
+	defer closeDBs()
+
+	if scratchDir == "" {
+		scratchDir, err = ioutil.TempDir("", "txindex-verify")
+		if err != nil {
+			return fmt.Errorf("TxindexVerify: Problem creating scratch dir: %v", err)
+		}
+		defer os.RemoveAll(scratchDir)
+	}
+	scratchOpts := badger.DefaultOptions(scratchDir)
+	scratchOpts.ValueDir = scratchDir
+	scratchDB, err := badger.Open(scratchOpts)
+	if err != nil {
+		return fmt.Errorf("TxindexVerify: Problem opening scratch db: %v", err)
+	}
+	defer scratchDB.Close()
+
+	report, err := fes.VerifyTxindex(scratchDB, startHeight, endHeight, func(height uint32, endHeight uint32) {
+		if height%1000 == 0 || height == endHeight {
+			fmt.Fprintf(os.Stderr, "Replayed block %d / %d\n", height, endHeight)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("TxindexVerify: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, mismatch := range report.Mismatches {
+		if err := encoder.Encode(mismatch); err != nil {
+			return fmt.Errorf("TxindexVerify: %v", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Checked %d txns in blocks %d to %d: %d mismatches\n",
+		report.NumTxnsChecked, report.StartHeight, report.EndHeight, len(report.Mismatches))
+	return nil
+}
+
+func TxindexRebuild(cmd *cobra.Command, args []string) error {
+	fromHeight, err := cmd.Flags().GetUint32("from-height")
+	if err != nil {
+		return err
+	}
+
+	fes, closeDBs, err := openTxindexForCmd(cmd)
+	if err != nil {
+		return fmt.Errorf("TxindexRebuild: %v", err)
+	}
+	defer closeDBs()
+
+	err = fes.RebuildTxindex(fromHeight, func(status routes.TxindexRebuildStatus) {
+		if status.CurrentHeight%1000 == 0 || status.CurrentHeight == status.TargetHeight {
+			fmt.Fprintf(os.Stderr, "Txindex at block %d / %d\n", status.CurrentHeight, status.TargetHeight)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("TxindexRebuild: %v", err)
+	}
+	return nil
+}
+
+func init() {
+	// Note that these flags are intentionally not bound to viper so they don't
+	// collide with the flags of the same name on the run command.
+	txindexCmd.PersistentFlags().String("data-dir", "",
+		"The data directory of the node whose txindex should be used.")
+	txindexCmd.PersistentFlags().Bool("testnet", false,
+		"Use testnet params.")
+
+	txindexVerifyCmd.Flags().Uint32("start-height", 0,
+		"The first block to check.")
+	txindexVerifyCmd.Flags().Uint32("end-height", 0,
+		"The last block to check. Defaults to the txindex tip.")
+	txindexVerifyCmd.Flags().String("scratch-dir", "",
+		"An empty directory for the db blocks are replayed into. Defaults to a "+
+			"temporary directory that's removed afterwards.")
+
+	txindexRebuildCmd.Flags().Uint32("from-height", 0,
+		"The lowest block to index again. Defaults to the whole txindex.")
+
+	txindexCmd.AddCommand(txindexVerifyCmd)
+	txindexCmd.AddCommand(txindexRebuildCmd)
+	rootCmd.AddCommand(txindexCmd)
+}
@@ -13,6 +13,7 @@ replace github.com/sasha-s/go-deadlock => ../core/third_party/github.com/sasha-s
 require (
 	cloud.google.com/go/storage v1.15.0
 	github.com/DataDog/datadog-go v4.5.0+incompatible
+	github.com/andybalholm/brotli v1.0.3
 	github.com/bitclout/core v0.0.0-00010101000000-000000000000
 	github.com/btcsuite/btcd v0.21.0-beta
 	github.com/btcsuite/btcutil v1.0.2
@@ -27,6 +28,7 @@ require (
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/nyaruka/phonenumbers v1.0.69
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v0.9.3
 	github.com/rollbar/rollbar-go v1.4.0
 	github.com/sasha-s/go-deadlock v0.2.0
 	github.com/spf13/cobra v1.1.3
@@ -1,14 +1,12 @@
 package miner
 
 import (
-	"bytes"
+	"context"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
+	"github.com/bitclout/backend/client"
 	"github.com/bitclout/backend/routes"
 	"github.com/bitclout/core/lib"
-	"io/ioutil"
-	"net/http"
 	"runtime"
 	"time"
 
@@ -36,6 +34,8 @@ type RemoteMiner struct {
 	latestExtraDatas        []uint64
 	latestBlockID           string
 	currentDiffTarget       *lib.BlockHash
+
+	client *client.Client
 }
 
 func NewRemoteMiner(
@@ -54,6 +54,7 @@ func NewRemoteMiner(
 		NumThreads:                     numMiningThreads,
 		IterationsPerCycle:             iterationsPerCycle,
 		TemplateRefreshIntervalSeconds: templateRefreshIntervalSeconds,
+		client:                         client.NewClient(remoteNode),
 	}
 
 	// Initialize the first block templates
@@ -85,30 +86,16 @@ func (bb *RemoteMiner) SubmitWinningHeader(
 	}
 
 	// Send the winning header to the BlockProducer
-	submitBlockRequest := &routes.SubmitBlockRequest{
+	res, err := bb.client.SubmitBlock(context.Background(), &routes.SubmitBlockRequest{
 		PublicKeyBase58Check: bb.PublicKeyBase58Check,
 		Header:               headerBytes,
 		ExtraData:            extraData,
 		BlockID:              blockID,
-	}
-	jsonRequest, err := json.Marshal(submitBlockRequest)
-	if err != nil {
-		return fmt.Errorf("Error marshaling SubmitBlock request: %v", err)
-	}
-	resObj, err := http.Post(
-		fmt.Sprintf("%s%s", bb.RemoteNode, routes.RoutePathSubmitBlock),
-		"application/json", /*contentType*/
-		bytes.NewBuffer(jsonRequest))
+	})
 	if err != nil {
 		return fmt.Errorf("Error submitting block: %v", err)
 	}
 
-	res := &routes.SubmitBlockResponse{}
-	if err := json.NewDecoder(resObj.Body).Decode(&res); err != nil {
-		return fmt.Errorf("Error decoding response: %v", err)
-	}
-	resObj.Body.Close()
-
 	// Log
 	if !res.IsMainChain {
 		glog.Debugf("Submitted block, but it's not on the main chain: isMainChain: %v, isOrphan: %v", res.IsMainChain, res.IsOrphan)
@@ -128,32 +115,14 @@ func (bb *RemoteMiner) SubmitWinningHeader(
 
 func (bb *RemoteMiner) RefreshBlockTemplates() error {
 	// Get a bunch of block templates from the server
-	getBlockTemplateRequest := &routes.GetBlockTemplateRequest{
+	res, err := bb.client.GetBlockTemplate(context.Background(), &routes.GetBlockTemplateRequest{
 		PublicKeyBase58Check: bb.PublicKeyBase58Check,
 		NumHeaders:           bb.NumThreads,
-		HeaderVersion: 		  lib.CurrentHeaderVersion,
-	}
-	jsonRequest, err := json.Marshal(getBlockTemplateRequest)
-	if err != nil {
-		return fmt.Errorf("Error marshaling block template request: %v", err)
-	}
-	resObj, err := http.Post(
-		fmt.Sprintf("%s%s", bb.RemoteNode, routes.RoutePathGetBlockTemplate),
-		"application/json", /*contentType*/
-		bytes.NewBuffer(jsonRequest))
+		HeaderVersion:        lib.CurrentHeaderVersion,
+	})
 	if err != nil {
 		return fmt.Errorf("Error fetching block templates: %v", err)
 	}
-	if resObj.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resObj.Body)
-		return fmt.Errorf("Error fetching block templates; status code %v: %v", resObj.StatusCode, string(body))
-	}
-
-	res := &routes.GetBlockTemplateResponse{}
-	if err := json.NewDecoder(resObj.Body).Decode(&res); err != nil {
-		return fmt.Errorf("Error decoding response: %v", err)
-	}
-	resObj.Body.Close()
 
 	// Update the latest blocks we're mining on
 	bb.mtxLatestBLockTemplates.Lock()
@@ -0,0 +1,170 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/sasha-s/go-deadlock"
+)
+
+// AccessLogEntry is one line of the access log.
+type AccessLogEntry struct {
+	Time          time.Time
+	Method        string
+	Path          string
+	Route         string
+	Status        int
+	ResponseBytes int
+	LatencyMillis float64
+	// The first public key in the request body. Public keys are logged whether or
+	// not they're tagged since they're public anyway.
+	PublicKey string `json:",omitempty"`
+	// The fields of the request body tagged with safeForLogging. Nothing else from
+	// the body is ever logged.
+	Request map[string]interface{} `json:",omitempty"`
+}
+
+// AccessLogger writes an AccessLogEntry as a line of JSON for every request it
+// samples. Requests that fail are always logged.
+type AccessLogger struct {
+	mtx deadlock.Mutex
+	out io.Writer
+	// The fraction of successful requests that are logged, from 0 to 1.
+	sampleRate float64
+	// Overrides sampleRate for the routes it contains, e.g. to quiet HealthCheck.
+	routeSampleRates map[string]float64
+}
+
+func NewAccessLogger(out io.Writer, sampleRate float64, routeSampleRates map[string]float64) *AccessLogger {
+	return &AccessLogger{
+		out:              out,
+		sampleRate:       sampleRate,
+		routeSampleRates: routeSampleRates,
+	}
+}
+
+// statusRecordingResponseWriter records the status and size of a response so it
+// can be logged and measured.
+type statusRecordingResponseWriter struct {
+	http.ResponseWriter
+	status        int
+	responseBytes int
+}
+
+func (ww *statusRecordingResponseWriter) WriteHeader(status int) {
+	if ww.status == 0 {
+		ww.status = status
+	}
+	ww.ResponseWriter.WriteHeader(status)
+}
+
+func (ww *statusRecordingResponseWriter) Write(data []byte) (int, error) {
+	if ww.status == 0 {
+		ww.status = http.StatusOK
+	}
+	numBytes, err := ww.ResponseWriter.Write(data)
+	ww.responseBytes += numBytes
+	return numBytes, err
+}
+
+// safeRequestFields decodes the body into requestType and returns its fields
+// tagged with safeForLogging, along with the first public key field.
+func safeRequestFields(requestType reflect.Type, bodyBytes []byte) (
+	_fields map[string]interface{}, _publicKey string) {
+
+	requestValue := reflect.New(requestType)
+	if err := json.Unmarshal(bodyBytes, requestValue.Interface()); err != nil {
+		return nil, ""
+	}
+
+	fields := make(map[string]interface{})
+	publicKey := ""
+	for ii := 0; ii < requestType.NumField(); ii++ {
+		field := requestType.Field(ii)
+		fieldValue := requestValue.Elem().Field(ii)
+		if field.PkgPath != "" {
+			continue
+		}
+		if field.Tag.Get(SafeForLoggingKey) == SafeForLoggingValue {
+			fields[field.Name] = fieldValue.Interface()
+		}
+		if publicKey == "" && fieldValue.Kind() == reflect.String &&
+			(strings.HasSuffix(field.Name, "PublicKeyBase58Check") || strings.HasSuffix(field.Name, "PublicKey")) {
+			publicKey = fieldValue.String()
+		}
+	}
+	return fields, publicKey
+}
+
+// AccessLog wraps a route's handler so that its requests are logged.
+func (logger *AccessLogger) AccessLog(inner http.Handler, routeName string) http.Handler {
+	requestType := routeRequestTypes[routeName]
+	sampleRate, exists := logger.routeSampleRates[routeName]
+	if !exists {
+		sampleRate = logger.sampleRate
+	}
+
+	return http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		// Hold on to the body since the handler will consume it.
+		var bodyBytes []byte
+		if requestType != nil && req.Body != nil {
+			var err error
+			bodyBytes, err = ioutil.ReadAll(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+			req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+			if err != nil {
+				bodyBytes = nil
+			}
+		}
+
+		recorder := &statusRecordingResponseWriter{ResponseWriter: ww}
+		inner.ServeHTTP(recorder, req)
+		latency := time.Since(start)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status < 400 && rand.Float64() >= sampleRate {
+			return
+		}
+
+		entry := &AccessLogEntry{
+			Time:          start.UTC(),
+			Method:        req.Method,
+			Path:          req.URL.Path,
+			Route:         routeName,
+			Status:        status,
+			ResponseBytes: recorder.responseBytes,
+			LatencyMillis: float64(latency) / float64(time.Millisecond),
+		}
+		if len(bodyBytes) > 0 {
+			entry.Request, entry.PublicKey = safeRequestFields(requestType, bodyBytes)
+		}
+		logger.write(entry)
+	})
+}
+
+func (logger *AccessLogger) write(entry *AccessLogEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		glog.Errorf("AccessLogger: Problem encoding entry for %v: %v", entry.Route, err)
+		return
+	}
+	line = append(line, '\n')
+
+	logger.mtx.Lock()
+	defer logger.mtx.Unlock()
+	if _, err := logger.out.Write(line); err != nil {
+		glog.Errorf("AccessLogger: Problem writing entry: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitclout/core/lib"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog(t *testing.T) {
+	require := require.New(t)
+
+	out := &bytes.Buffer{}
+	logger := NewAccessLogger(out, 1, map[string]float64{"GetNotifications": 0})
+
+	inner := http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		// The handler still sees the whole body.
+		requestData := SendPhoneNumberVerificationTextRequest{}
+		require.NoError(json.NewDecoder(req.Body).Decode(&requestData))
+		if requestData.PhoneNumber == "" {
+			_AddCodedError(ww, ErrorCodeBadRequest, "missing phone number")
+			return
+		}
+		ww.Write([]byte("{}"))
+	})
+
+	makeRequest := func(routeName string, body string) *AccessLogEntry {
+		out.Reset()
+		request := httptest.NewRequest("POST", "/api/v0/some-route", strings.NewReader(body))
+		logger.AccessLog(inner, routeName).ServeHTTP(httptest.NewRecorder(), request)
+		if out.Len() == 0 {
+			return nil
+		}
+		require.True(strings.HasSuffix(out.String(), "\n"))
+		entry := &AccessLogEntry{}
+		require.NoError(json.Unmarshal(out.Bytes(), entry))
+		return entry
+	}
+
+	// Only tagged fields make it into the log.
+	entry := makeRequest("SendPhoneNumberVerificationText",
+		`{"PublicKeyBase58Check": "pk1", "PhoneNumber": "+14155552671"}`)
+	require.NotNil(entry)
+	require.Equal("POST", entry.Method)
+	require.Equal("/api/v0/some-route", entry.Path)
+	require.Equal("SendPhoneNumberVerificationText", entry.Route)
+	require.Equal(http.StatusOK, entry.Status)
+	require.Equal(2, entry.ResponseBytes)
+	require.True(entry.LatencyMillis >= 0)
+	require.Equal("pk1", entry.PublicKey)
+	require.Equal(map[string]interface{}{"PublicKeyBase58Check": "pk1"}, entry.Request)
+	require.NotContains(out.String(), "4155552671")
+
+	// Routes sampled at zero are still logged when they fail.
+	entry = makeRequest("GetNotifications", `{"PublicKeyBase58Check": "pk1", "PhoneNumber": "+14155552671"}`)
+	require.Nil(entry)
+	entry = makeRequest("GetNotifications", `{"PublicKeyBase58Check": "pk1"}`)
+	require.NotNil(entry)
+	require.Equal(http.StatusBadRequest, entry.Status)
+
+	// Routes without a known request type are logged without their body.
+	entry = makeRequest("UploadImage", `{"PublicKeyBase58Check": "pk1", "PhoneNumber": "+14155552671"}`)
+	require.NotNil(entry)
+	require.Empty(entry.PublicKey)
+	require.Nil(entry.Request)
+}
+
+func TestRouteRequestTypes(t *testing.T) {
+	require := require.New(t)
+
+	// Routes that take something other than a JSON body.
+	routesWithoutJSONBody := map[string]bool{
+		"UploadImage": true,
+	}
+
+	fes := &APIServer{Params: &lib.BitCloutTestnetParams}
+	routeNames := make(map[string]bool)
+	err := fes.NewRouter().Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		methods, _ := route.GetMethods()
+		routeNames[route.GetName()] = true
+		for _, method := range methods {
+			if method == "POST" && !routesWithoutJSONBody[route.GetName()] {
+				require.Contains(routeRequestTypes, route.GetName(),
+					"Add the request type of route %v to routeRequestTypes", route.GetName())
+			}
+		}
+		return nil
+	})
+	require.NoError(err)
+
+	for routeName, requestType := range routeRequestTypes {
+		require.True(routeNames[routeName], "routeRequestTypes has an unknown route %v", routeName)
+		require.NotEmpty(requestType.Name())
+	}
+}
@@ -0,0 +1,308 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	"github.com/golang/glog"
+	"github.com/sasha-s/go-deadlock"
+)
+
+const (
+	// The most audit log entries AdminGetAdminAuditLogs looks at in one call when
+	// filtering. If it runs out before finding NumToFetch matches, it returns what
+	// it found along with where to pick up from.
+	adminAuditLogMaxNumToScan = 10 * adminGlobalStateMaxNumToFetch
+	// Error responses longer than this aren't decoded, only their status is kept.
+	adminAuditLogMaxErrorBytes = 4096
+)
+
+// How many requests turned away by CheckAdminPublicKey are added to the audit log.
+// Anyone can send these, so past this they're only counted.
+var adminAuditLogRejectedQuota = RateLimitQuota{RequestsPerMinute: 60, Burst: 60}
+
+// AdminAuditLogEntry records a single request to an admin route. One is added for
+// every admin request that makes it past CheckAdminPublicKey, whether or not it
+// succeeds. Requests that CheckAdminPublicKey turns away are added too, up to
+// adminAuditLogRejectedQuota.
+type AdminAuditLogEntry struct {
+	TimestampNanos uint64
+	// The admin that made the request. When --admin-open-access is set, or when
+	// Rejected is set, this is whatever the request claimed.
+	AdminPublicKeyBase58Check string
+	// Set if CheckAdminPublicKey turned the request away, e.g. because of a bad
+	// JWT or a missing role, so it never reached the route.
+	Rejected bool `json:",omitempty"`
+	// The number of rejected requests that were left out of the log because of
+	// adminAuditLogRejectedQuota since the last one that was added.
+	NumRejectedDropped uint64 `json:",omitempty"`
+	// The name of the route, e.g. AdminUpdateUserGlobalMetadata.
+	Route string
+	// The fields of the request body tagged with safeForLogging, as a JSON object.
+	// Nothing else from the body is ever stored.
+	Request json.RawMessage `json:",omitempty"`
+	Status  int
+	// Set if the request failed.
+	ErrorCode    ErrorCode `json:",omitempty"`
+	ErrorMessage string    `json:",omitempty"`
+}
+
+// errorRecordingResponseWriter holds on to the body of error responses so that
+// their code can be added to the audit log.
+type errorRecordingResponseWriter struct {
+	statusRecordingResponseWriter
+	errorBody bytes.Buffer
+}
+
+// adminAuditLogRejections limits how many rejected requests are added to the
+// audit log. The zero value is ready to use.
+type adminAuditLogRejections struct {
+	mtx        deadlock.Mutex
+	fullAt     time.Time
+	numDropped uint64
+}
+
+// take returns whether a rejected request should be logged and, if so, how many
+// were dropped since the last one that was.
+func (rejections *adminAuditLogRejections) take(now time.Time) (_ok bool, _numDropped uint64) {
+	rejections.mtx.Lock()
+	defer rejections.mtx.Unlock()
+
+	newFullAt, retryAfter := takeRateLimitToken(rejections.fullAt, now, adminAuditLogRejectedQuota)
+	if retryAfter > 0 {
+		rejections.numDropped++
+		return false, 0
+	}
+	rejections.fullAt = newFullAt
+	numDropped := rejections.numDropped
+	rejections.numDropped = 0
+	return true, numDropped
+}
+
+type adminCheckPassedContextKey struct{}
+
+// markAdminCheckPassed tells the AdminAuditLog wrapping req that CheckAdminPublicKey
+// let it through.
+func markAdminCheckPassed(req *http.Request) {
+	if passed, ok := req.Context().Value(adminCheckPassedContextKey{}).(*bool); ok {
+		*passed = true
+	}
+}
+
+func (ww *errorRecordingResponseWriter) Write(data []byte) (int, error) {
+	numBytes, err := ww.statusRecordingResponseWriter.Write(data)
+	if ww.status >= 400 && ww.errorBody.Len() <= adminAuditLogMaxErrorBytes {
+		ww.errorBody.Write(data[:numBytes])
+	}
+	return numBytes, err
+}
+
+// AdminAuditLog wraps an admin route's handler so that each of its requests is
+// added to the admin audit log. It runs before CheckAdminPublicKey so that
+// rejected requests are seen too, but since those can come from anyone only
+// adminAuditLogRejectedQuota of them are written to global state.
+func (fes *APIServer) AdminAuditLog(inner http.Handler, routeName string) http.Handler {
+	requestType := routeRequestTypes[routeName]
+
+	return http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		// Hold on to the body since the handler will consume it.
+		var bodyBytes []byte
+		if req.Body != nil {
+			var err error
+			bodyBytes, err = ioutil.ReadAll(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+			req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+			if err != nil {
+				bodyBytes = nil
+			}
+		}
+
+		recorder := &errorRecordingResponseWriter{
+			statusRecordingResponseWriter: statusRecordingResponseWriter{ResponseWriter: ww},
+		}
+		// CheckAdminPublicKey isn't there with --admin-open-access, so nothing is
+		// ever rejected.
+		passedAdminCheck := fes.AdminOpenAccess
+		req = req.WithContext(context.WithValue(req.Context(), adminCheckPassedContextKey{}, &passedAdminCheck))
+		inner.ServeHTTP(recorder, req)
+
+		entry := &AdminAuditLogEntry{
+			TimestampNanos: uint64(start.UnixNano()),
+			Route:          routeName,
+			Status:         recorder.status,
+			Rejected:       !passedAdminCheck,
+		}
+		if entry.Rejected {
+			var ok bool
+			if ok, entry.NumRejectedDropped = fes.adminAuditLogRejections.take(start); !ok {
+				return
+			}
+		}
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		if len(bodyBytes) > 0 {
+			adminRequest := AdminRequest{}
+			if err := json.Unmarshal(bodyBytes, &adminRequest); err == nil {
+				entry.AdminPublicKeyBase58Check = adminRequest.AdminPublicKey
+			}
+			if requestType != nil {
+				if fields, _ := safeRequestFields(requestType, bodyBytes); fields != nil {
+					if fieldsJSON, err := json.Marshal(fields); err == nil {
+						entry.Request = fieldsJSON
+					}
+				}
+			}
+		}
+		if entry.Status >= 400 {
+			apiErr := &APIError{}
+			if err := json.Unmarshal(recorder.errorBody.Bytes(), apiErr); err == nil && apiErr.Code != "" {
+				entry.ErrorCode = apiErr.Code
+				entry.ErrorMessage = apiErr.Message
+			} else {
+				entry.ErrorCode = errorCodeForStatus(entry.Status)
+			}
+		}
+
+		// The request has already been served, so all we can do if this fails is
+		// make some noise.
+		if err := fes.putAdminAuditLog(entry); err != nil {
+			glog.Errorf("AdminAuditLog: Problem adding %v request by %v to the audit log: %v",
+				routeName, entry.AdminPublicKeyBase58Check, err)
+		}
+	})
+}
+
+func (fes *APIServer) putAdminAuditLog(entry *AdminAuditLogEntry) error {
+	randomBytes := make([]byte, globalStateAuditLogRandomBytes)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return fmt.Errorf("putAdminAuditLog: Problem generating key: %v", err)
+	}
+	entryBuf := bytes.NewBuffer([]byte{})
+	if err := gob.NewEncoder(entryBuf).Encode(entry); err != nil {
+		return fmt.Errorf("putAdminAuditLog: Problem encoding entry: %v", err)
+	}
+	return fes.GlobalStatePut(
+		GlobalStateKeyForAdminAuditLog(entry.TimestampNanos, randomBytes), entryBuf.Bytes())
+}
+
+// AdminGetAdminAuditLogsRequest ...
+type AdminGetAdminAuditLogsRequest struct {
+	AdminPublicKey string `safeForLogging:"true"`
+	JWT            string
+	// Only return requests made before this time. Leave as zero to start with the
+	// most recent request. Pass the NextBeforeTstampNanos from the previous
+	// response to get the next page.
+	BeforeTstampNanos uint64 `safeForLogging:"true"`
+	NumToFetch        int    `safeForLogging:"true"`
+
+	// Optional. Only return requests made by this admin.
+	FilterAdminPublicKeyBase58Check string `safeForLogging:"true"`
+	// Optional. Only return requests to this route, e.g. AdminUpdateGlobalFeed.
+	FilterRoute string `safeForLogging:"true"`
+	// If true, only return requests that failed.
+	FilterFailed bool `safeForLogging:"true"`
+}
+
+// AdminGetAdminAuditLogsResponse ...
+type AdminGetAdminAuditLogsResponse struct {
+	// The most recent requests first.
+	AuditLogs []*AdminAuditLogEntry
+	// Zero if there are no more requests. When filtering this may be set even if
+	// fewer than NumToFetch requests were returned, in which case there may be
+	// more matches further back.
+	NextBeforeTstampNanos uint64
+}
+
+// AdminGetAdminAuditLogs returns the requests made to admin routes, most recent
+// first.
+func (fes *APIServer) AdminGetAdminAuditLogs(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminGetAdminAuditLogsRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetAdminAuditLogs: Problem parsing request body: %v", err))
+		return
+	}
+
+	numToFetch := requestData.NumToFetch
+	if numToFetch <= 0 {
+		numToFetch = adminGlobalStateDefaultNumToFetch
+	}
+	if numToFetch > adminGlobalStateMaxNumToFetch {
+		numToFetch = adminGlobalStateMaxNumToFetch
+	}
+
+	res := AdminGetAdminAuditLogsResponse{
+		AuditLogs: []*AdminAuditLogEntry{},
+	}
+	// Seek backwards one batch at a time, starting just before beforeTstampNanos,
+	// until we've found numToFetch matches, run out of entries, or looked at
+	// adminAuditLogMaxNumToScan entries. See AdminGetGlobalStateAuditLogs for how
+	// the seek works.
+	maxKeyLen := len(_GlobalStatePrefixAdminAuditLog) + 8 + globalStateAuditLogRandomBytes
+	beforeTstampNanos := requestData.BeforeTstampNanos
+	for numScanned := 0; len(res.AuditLogs) < numToFetch && numScanned < adminAuditLogMaxNumToScan; {
+		seekStartKey := _GlobalStatePrefixAdminAuditLog
+		if beforeTstampNanos > 0 {
+			seekStartKey = append(append([]byte{}, _GlobalStatePrefixAdminAuditLog...),
+				lib.EncodeUint64(beforeTstampNanos-1)...)
+		}
+		keys, vals, err := fes.GlobalStateSeek(seekStartKey /*startPrefix*/, _GlobalStatePrefixAdminAuditLog, /*validForPrefix*/
+			maxKeyLen, adminGlobalStateMaxNumToFetch, true, /*reverse*/
+			true /*fetchValues*/)
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("AdminGetAdminAuditLogs: %v", err))
+			return
+		}
+		if len(keys) != len(vals) {
+			_AddInternalServerError(ww, "AdminGetAdminAuditLogs: GlobalState keys/vals length mismatch.")
+			return
+		}
+
+		ii := 0
+		for ; ii < len(keys) && len(res.AuditLogs) < numToFetch; ii++ {
+			entry := &AdminAuditLogEntry{}
+			if err = gob.NewDecoder(bytes.NewReader(vals[ii])).Decode(entry); err != nil {
+				_AddInternalServerError(ww, fmt.Sprintf("AdminGetAdminAuditLogs: Problem decoding entry %x: %v", keys[ii], err))
+				return
+			}
+			beforeTstampNanos = entry.TimestampNanos
+			numScanned++
+
+			if requestData.FilterAdminPublicKeyBase58Check != "" &&
+				entry.AdminPublicKeyBase58Check != requestData.FilterAdminPublicKeyBase58Check {
+				continue
+			}
+			if requestData.FilterRoute != "" && entry.Route != requestData.FilterRoute {
+				continue
+			}
+			if requestData.FilterFailed && entry.Status < 400 {
+				continue
+			}
+			res.AuditLogs = append(res.AuditLogs, entry)
+		}
+		// A short batch that we got all the way through means there's nothing left.
+		if len(keys) < adminGlobalStateMaxNumToFetch && ii == len(keys) {
+			beforeTstampNanos = 0
+			break
+		}
+	}
+	// Entries that share a nanosecond with the last one looked at are skipped on
+	// the next page, like in AdminGetGlobalStateAuditLogs.
+	res.NextBeforeTstampNanos = beforeTstampNanos
+
+	if err := json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetAdminAuditLogs: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
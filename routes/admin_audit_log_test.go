@@ -0,0 +1,137 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAuditLog(t *testing.T) {
+	require := require.New(t)
+
+	superadmin := newTestAdmin(t)
+	moderator := newTestAdmin(t)
+	fes := &APIServer{
+		Params:                              &lib.BitCloutTestnetParams,
+		GlobalStore:                         NewMemoryGlobalStore(),
+		AccessControlAllowOrigins:           []string{"*"},
+		SecureHeaderMiddlewareIsDevelopment: true,
+		JWTAudiences:                        []string{testJWTAudience},
+		AdminPublicKeys:                     []string{superadmin.publicKeyBase58Check},
+	}
+	router := fes.NewRouter()
+
+	call := func(routePath string, admin *testAdmin, request map[string]interface{}, response interface{}) int {
+		request["AdminPublicKey"] = admin.publicKeyBase58Check
+		request["JWT"] = admin.jwt()
+		body, err := json.Marshal(request)
+		require.NoError(err)
+		req := httptest.NewRequest("POST", routePath, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		if response != nil {
+			require.NoError(json.NewDecoder(recorder.Body).Decode(response))
+		}
+		return recorder.Code
+	}
+	getAuditLogs := func(request map[string]interface{}) *AdminGetAdminAuditLogsResponse {
+		res := &AdminGetAdminAuditLogsResponse{}
+		require.Equal(http.StatusOK, call(RoutePathAdminGetAdminAuditLogs, superadmin, request, res))
+		return res
+	}
+
+	// A request that succeeds, one that fails in the handler, and one that
+	// CheckAdminPublicKey turns away.
+	require.Equal(http.StatusOK, call(RoutePathAdminUpdateAdminRoles, superadmin, map[string]interface{}{
+		"PublicKeyBase58Check": moderator.publicKeyBase58Check,
+		"Roles":                []AdminRole{AdminRoleModerator},
+	}, nil))
+	require.Equal(http.StatusBadRequest, call(RoutePathAdminUpdateAdminRoles, superadmin, map[string]interface{}{
+		"PublicKeyBase58Check": moderator.publicKeyBase58Check,
+		"Roles":                []string{"owner"},
+	}, nil))
+	require.Equal(http.StatusForbidden, call(RoutePathAdminGetAdminAuditLogs, moderator,
+		map[string]interface{}{}, nil))
+
+	res := getAuditLogs(map[string]interface{}{})
+	require.Len(res.AuditLogs, 3)
+	require.Zero(res.NextBeforeTstampNanos)
+	rejectedLog, failedLog, succeededLog := res.AuditLogs[0], res.AuditLogs[1], res.AuditLogs[2]
+	require.Equal("AdminUpdateAdminRoles", succeededLog.Route)
+	require.Equal(superadmin.publicKeyBase58Check, succeededLog.AdminPublicKeyBase58Check)
+	require.Equal(http.StatusOK, succeededLog.Status)
+	require.Empty(succeededLog.ErrorCode)
+	require.Equal(http.StatusBadRequest, failedLog.Status)
+	require.Equal(ErrorCodeBadRequest, failedLog.ErrorCode)
+	require.Contains(failedLog.ErrorMessage, "owner")
+	require.False(failedLog.Rejected)
+	require.Greater(failedLog.TimestampNanos, succeededLog.TimestampNanos)
+	require.True(rejectedLog.Rejected)
+	require.Equal("AdminGetAdminAuditLogs", rejectedLog.Route)
+	require.Equal(moderator.publicKeyBase58Check, rejectedLog.AdminPublicKeyBase58Check)
+	require.Equal(http.StatusForbidden, rejectedLog.Status)
+	require.Equal(ErrorCodeForbidden, rejectedLog.ErrorCode)
+
+	// Only safe fields of the request are kept.
+	request := make(map[string]interface{})
+	require.NoError(json.Unmarshal(failedLog.Request, &request))
+	require.Equal(moderator.publicKeyBase58Check, request["PublicKeyBase58Check"])
+	require.Equal([]interface{}{"owner"}, request["Roles"])
+	require.NotContains(request, "JWT")
+
+	// The first query is now in the log too.
+	res = getAuditLogs(map[string]interface{}{"NumToFetch": 1})
+	require.Len(res.AuditLogs, 1)
+	require.Equal("AdminGetAdminAuditLogs", res.AuditLogs[0].Route)
+	require.NotZero(res.NextBeforeTstampNanos)
+	res = getAuditLogs(map[string]interface{}{"NumToFetch": 1, "BeforeTstampNanos": res.NextBeforeTstampNanos})
+	require.Len(res.AuditLogs, 1)
+	require.Equal(rejectedLog.TimestampNanos, res.AuditLogs[0].TimestampNanos)
+
+	// Filters.
+	res = getAuditLogs(map[string]interface{}{"FilterFailed": true})
+	require.Len(res.AuditLogs, 2)
+	require.Equal(rejectedLog.TimestampNanos, res.AuditLogs[0].TimestampNanos)
+	require.Equal(failedLog.TimestampNanos, res.AuditLogs[1].TimestampNanos)
+	res = getAuditLogs(map[string]interface{}{"FilterRoute": "AdminUpdateAdminRoles"})
+	require.Len(res.AuditLogs, 2)
+	res = getAuditLogs(map[string]interface{}{"FilterAdminPublicKeyBase58Check": moderator.publicKeyBase58Check})
+	require.Len(res.AuditLogs, 1)
+	require.Equal(rejectedLog.TimestampNanos, res.AuditLogs[0].TimestampNanos)
+	require.Zero(res.NextBeforeTstampNanos)
+
+	// Entries show up in the global state browser.
+	prefixRes := &AdminGetGlobalStateEntriesResponse{}
+	require.Equal(http.StatusOK, call(RoutePathAdminGetGlobalStateEntries, superadmin,
+		map[string]interface{}{"PrefixName": "AdminAuditLog"}, prefixRes))
+	require.NotEmpty(prefixRes.Entries)
+	require.Empty(prefixRes.Entries[0].DecodeError)
+}
+
+func TestAdminAuditLogRejections(t *testing.T) {
+	require := require.New(t)
+
+	rejections := &adminAuditLogRejections{}
+	now := time.Unix(1000, 0)
+	for ii := 0; ii < adminAuditLogRejectedQuota.Burst; ii++ {
+		ok, numDropped := rejections.take(now)
+		require.True(ok)
+		require.Zero(numDropped)
+	}
+	// Past the burst rejections are dropped, and the next one that's logged says
+	// how many.
+	ok, _ := rejections.take(now)
+	require.False(ok)
+	ok, _ = rejections.take(now)
+	require.False(ok)
+	ok, numDropped := rejections.take(now.Add(time.Minute))
+	require.True(ok)
+	require.Equal(uint64(2), numDropped)
+}
@@ -25,7 +25,7 @@ func (fes *APIServer) AdminPinPost(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := AdminPinPostRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminUpdateGlobalFeed: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -34,26 +34,26 @@ func (fes *APIServer) AdminPinPost(ww http.ResponseWriter, req *http.Request) {
 	if requestData.PostHashHex != "" {
 		postHashBytes, err := hex.DecodeString(requestData.PostHashHex)
 		if err != nil || len(postHashBytes) != lib.HashSizeBytes {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminPinPost: Error parsing post hash %v: %v",
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminPinPost: Error parsing post hash %v: %v",
 				requestData.PostHashHex, err))
 			return
 		}
 		copy(postHash[:], postHashBytes)
 	} else {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminPinPost: Request missing PostHashHex"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminPinPost: Request missing PostHashHex"))
 		return
 	}
 
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminPinPost: Problem fetching utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminPinPost: Problem fetching utxoView: %v", err))
 		return
 	}
 
 	// Get the post entry.
 	postEntry := utxoView.GetPostEntryForPostHash(postHash)
 	if postEntry == nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodePostNotFound, fmt.Sprintf(
 			"AdminPinPost: Problem getting postEntry for post hash: %v : %s", err, requestData.PostHashHex))
 		return
 	}
@@ -63,14 +63,14 @@ func (fes *APIServer) AdminPinPost(ww http.ResponseWriter, req *http.Request) {
 	if requestData.UnpinPost {
 		err = fes.GlobalStateDelete(dbKey)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminPinPost: Problem deleting post from global state: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminPinPost: Problem deleting post from global state: %v", err))
 			return
 		}
 	} else {
 		// Encode the post entry and stick it in the database.
 		err = fes.GlobalStatePut(dbKey, []byte{1})
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminPinPost: Problem putting updated user metadata: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminPinPost: Problem putting updated user metadata: %v", err))
 			return
 		}
 	}
@@ -78,7 +78,7 @@ func (fes *APIServer) AdminPinPost(ww http.ResponseWriter, req *http.Request) {
 	// If we made it this far we were successful, return without error.
 	res := AdminPinPostResponse{}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminPinPost: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminPinPost: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -99,7 +99,7 @@ func (fes *APIServer) AdminUpdateGlobalFeed(ww http.ResponseWriter, req *http.Re
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := AdminUpdateGlobalFeedRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminUpdateGlobalFeed: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -108,26 +108,26 @@ func (fes *APIServer) AdminUpdateGlobalFeed(ww http.ResponseWriter, req *http.Re
 	if requestData.PostHashHex != "" {
 		postHashBytes, err := hex.DecodeString(requestData.PostHashHex)
 		if err != nil || len(postHashBytes) != lib.HashSizeBytes {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Error parsing post hash %v: %v",
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminUpdateGlobalFeed: Error parsing post hash %v: %v",
 				requestData.PostHashHex, err))
 			return
 		}
 		copy(postHash[:], postHashBytes)
 	} else {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Request missing PostHashHex"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminUpdateGlobalFeed: Request missing PostHashHex"))
 		return
 	}
 
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem fetching utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem fetching utxoView: %v", err))
 		return
 	}
 
 	// Get the post entry.
 	postEntry := utxoView.GetPostEntryForPostHash(postHash)
 	if postEntry == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem getting postEntry for post hash: %v : %s", err, requestData.PostHashHex))
+		_AddCodedError(ww, ErrorCodePostNotFound, fmt.Sprintf("AdminUpdateGlobalFeed: Problem getting postEntry for post hash: %v : %s", err, requestData.PostHashHex))
 		return
 	}
 
@@ -136,14 +136,14 @@ func (fes *APIServer) AdminUpdateGlobalFeed(ww http.ResponseWriter, req *http.Re
 	if requestData.RemoveFromGlobalFeed {
 		err = fes.GlobalStateDelete(dbKey)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem deleting post from global state: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem deleting post from global state: %v", err))
 			return
 		}
 	} else {
 		// Encode the post entry and stick it in the database.
 		err = fes.GlobalStatePut(dbKey, []byte{1})
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem putting updated user metadata: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem putting updated user metadata: %v", err))
 			return
 		}
 	}
@@ -151,7 +151,7 @@ func (fes *APIServer) AdminUpdateGlobalFeed(ww http.ResponseWriter, req *http.Re
 	// If we made it this far we were successful, return without error.
 	res := AdminUpdateGlobalFeedResponse{}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -166,19 +166,22 @@ type AdminRemoveNilPostsRequest struct {
 // AdminUpdateGlobalFeedResponse ...
 type AdminRemoveNilPostsResponse struct{}
 
+// AdminRemoveNilPosts removes posts that no longer exist from the most recent part
+// of the global feed. AdminGlobalStateFsck does the same for the entire feed and
+// pinned posts, along with checking the rest of global state.
 func (fes *APIServer) AdminRemoveNilPosts(ww http.ResponseWriter, req *http.Request) {
 
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := AdminRemoveNilPostsRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateGlobalFeed: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminUpdateGlobalFeed: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Get a view with all the mempool transactions (used to get all posts / reader state).
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"AdminRemoveNilPosts: Error getting augmented universal view: #{err}"))
 		return
 	}
@@ -199,7 +202,7 @@ func (fes *APIServer) AdminRemoveNilPosts(ww http.ResponseWriter, req *http.Requ
 		true,                                  /*reverse*/
 		false)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"AdminRemoveNilPosts: Problem seeking through global state keys: #{err}"))
 		return
 	}
@@ -218,7 +221,7 @@ func (fes *APIServer) AdminRemoveNilPosts(ww http.ResponseWriter, req *http.Requ
 		if postEntry == nil {
 			err = fes.GlobalStateDelete(dbKeyBytes)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf(
+				_AddInternalServerError(ww, fmt.Sprintf(
 					"AdminRemoveNilPosts: Problem deleting missing key in GlobalState Key-value store for global feed: #{err}"))
 				return
 			}
@@ -227,7 +230,7 @@ func (fes *APIServer) AdminRemoveNilPosts(ww http.ResponseWriter, req *http.Requ
 
 	res := &AdminRemoveNilPostsResponse{}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"AdminRemoveNilPosts: Problem encoding response as JSON: #{err}"))
 		return
 	}
@@ -0,0 +1,398 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bitclout/core/lib"
+)
+
+const (
+	// The most entries returned by a single call to AdminGetGlobalStateEntries or
+	// AdminGetGlobalStateAuditLogs.
+	adminGlobalStateMaxNumToFetch = 1000
+	// Used when a request doesn't specify NumToFetch.
+	adminGlobalStateDefaultNumToFetch = 100
+	// The number of random bytes at the end of each audit log key.
+	globalStateAuditLogRandomBytes = 8
+)
+
+// GlobalStateAuditLog records a single change an admin made through
+// AdminUpdateGlobalStateEntry.
+type GlobalStateAuditLog struct {
+	TimestampNanos            uint64
+	AdminPublicKeyBase58Check string
+	Key                       []byte
+	// The value before the change. Nil if the key did not exist.
+	OldValue []byte
+	// The value after the change. Nil if the entry was deleted.
+	NewValue   []byte
+	IsDeletion bool
+}
+
+// AdminGetGlobalStatePrefixesRequest ...
+type AdminGetGlobalStatePrefixesRequest struct{}
+
+type GlobalStatePrefixResponse struct {
+	Name      string
+	PrefixHex string
+}
+
+// AdminGetGlobalStatePrefixesResponse ...
+type AdminGetGlobalStatePrefixesResponse struct {
+	Prefixes []*GlobalStatePrefixResponse
+}
+
+// AdminGetGlobalStatePrefixes lists every prefix in the global state registry.
+func (fes *APIServer) AdminGetGlobalStatePrefixes(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminGetGlobalStatePrefixesRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetGlobalStatePrefixes: Problem parsing request body: %v", err))
+		return
+	}
+
+	res := AdminGetGlobalStatePrefixesResponse{
+		Prefixes: []*GlobalStatePrefixResponse{},
+	}
+	for _, prefix := range GlobalStatePrefixes() {
+		res.Prefixes = append(res.Prefixes, &GlobalStatePrefixResponse{
+			Name:      prefix.Name,
+			PrefixHex: hex.EncodeToString(prefix.Prefix),
+		})
+	}
+	if err := json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetGlobalStatePrefixes: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// AdminGetGlobalStateEntriesRequest ...
+type AdminGetGlobalStateEntriesRequest struct {
+	// The name of the prefix to list, as returned by AdminGetGlobalStatePrefixes.
+	PrefixName string `safeForLogging:"true"`
+	// The key to start from, inclusive. Leave empty to start from the beginning of
+	// the prefix. Pass the NextStartKeyHex from the previous response to get the
+	// next page.
+	StartKeyHex string `safeForLogging:"true"`
+	NumToFetch  int    `safeForLogging:"true"`
+}
+
+// AdminGetGlobalStateEntriesResponse ...
+type AdminGetGlobalStateEntriesResponse struct {
+	Entries []*GlobalStateEntry
+	// Empty if there are no more entries under the prefix.
+	NextStartKeyHex string
+}
+
+// AdminGetGlobalStateEntries pages through the entries under a prefix and decodes
+// each one with the prefix's decoder.
+func (fes *APIServer) AdminGetGlobalStateEntries(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminGetGlobalStateEntriesRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetGlobalStateEntries: Problem parsing request body: %v", err))
+		return
+	}
+
+	var prefix *GlobalStatePrefix
+	for _, registeredPrefix := range GlobalStatePrefixes() {
+		if registeredPrefix.Name == requestData.PrefixName {
+			prefix = registeredPrefix
+			break
+		}
+	}
+	if prefix == nil {
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminGetGlobalStateEntries: Unknown prefix %v", requestData.PrefixName))
+		return
+	}
+
+	startKey := prefix.Prefix
+	if requestData.StartKeyHex != "" {
+		var err error
+		startKey, err = hex.DecodeString(requestData.StartKeyHex)
+		if err != nil || !bytes.HasPrefix(startKey, prefix.Prefix) {
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminGetGlobalStateEntries: StartKeyHex %v is not a key under %v",
+				requestData.StartKeyHex, requestData.PrefixName))
+			return
+		}
+	}
+
+	numToFetch := requestData.NumToFetch
+	if numToFetch <= 0 {
+		numToFetch = adminGlobalStateDefaultNumToFetch
+	}
+	if numToFetch > adminGlobalStateMaxNumToFetch {
+		numToFetch = adminGlobalStateMaxNumToFetch
+	}
+
+	// Fetch one extra entry so we know whether there's another page.
+	keys, vals, err := fes.GlobalStateSeek(startKey /*startPrefix*/, prefix.Prefix, /*validForPrefix*/
+		0 /*maxKeyLen -- ignored since reverse is false*/, numToFetch+1, false, /*reverse*/
+		true /*fetchValues*/)
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetGlobalStateEntries: %v", err))
+		return
+	}
+	if len(keys) != len(vals) {
+		_AddInternalServerError(ww, "AdminGetGlobalStateEntries: GlobalState keys/vals length mismatch.")
+		return
+	}
+
+	res := AdminGetGlobalStateEntriesResponse{
+		Entries: []*GlobalStateEntry{},
+	}
+	if len(keys) > numToFetch {
+		res.NextStartKeyHex = hex.EncodeToString(keys[numToFetch])
+		keys = keys[:numToFetch]
+	}
+	for ii, key := range keys {
+		res.Entries = append(res.Entries, DecodeGlobalStateEntry(key, vals[ii], fes.Params))
+	}
+	if err = json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetGlobalStateEntries: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// AdminUpdateGlobalStateEntryRequest ...
+type AdminUpdateGlobalStateEntryRequest struct {
+	AdminPublicKey string `safeForLogging:"true"`
+	KeyHex         string `safeForLogging:"true"`
+	// The value the admin expects the entry to have. This is normally the ValueHex
+	// the admin was looking at, and the update fails if it has changed since then.
+	// Leave empty if the entry is not expected to exist.
+	ExpectedValueHex string
+	NewValueHex      string
+	// If true, NewValueHex is ignored and the entry is deleted.
+	Delete bool `safeForLogging:"true"`
+}
+
+// AdminUpdateGlobalStateEntryResponse ...
+type AdminUpdateGlobalStateEntryResponse struct {
+	// The entry as it is now. Nil if it was deleted.
+	Entry *GlobalStateEntry
+}
+
+// AdminUpdateGlobalStateEntry sets or deletes a single raw global state entry and
+// records the change in the global state audit log.
+func (fes *APIServer) AdminUpdateGlobalStateEntry(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminUpdateGlobalStateEntryRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminUpdateGlobalStateEntry: Problem parsing request body: %v", err))
+		return
+	}
+
+	key, err := hex.DecodeString(requestData.KeyHex)
+	if err != nil {
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminUpdateGlobalStateEntry: Problem decoding KeyHex: %v", err))
+		return
+	}
+	prefix := GetGlobalStatePrefixForKey(key)
+	if prefix == nil {
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminUpdateGlobalStateEntry: Key %v is not under a known prefix",
+			requestData.KeyHex))
+		return
+	}
+	// The audit logs can't be used to cover their own tracks.
+	if bytes.Equal(prefix.Prefix, _GlobalStatePrefixGlobalStateAuditLog) ||
+		bytes.Equal(prefix.Prefix, _GlobalStatePrefixAdminAuditLog) {
+		_AddCodedError(ww, ErrorCodeBadRequest, "AdminUpdateGlobalStateEntry: The audit logs cannot be edited")
+		return
+	}
+
+	// An empty ExpectedValueHex means the entry should not exist yet.
+	var expectedValue []byte
+	if requestData.ExpectedValueHex != "" {
+		expectedValue, err = hex.DecodeString(requestData.ExpectedValueHex)
+		if err != nil {
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminUpdateGlobalStateEntry: Problem decoding ExpectedValueHex: %v", err))
+			return
+		}
+	}
+	var newValue []byte
+	if !requestData.Delete {
+		newValue, err = hex.DecodeString(requestData.NewValueHex)
+		if err != nil {
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminUpdateGlobalStateEntry: Problem decoding NewValueHex: %v", err))
+			return
+		}
+		// Make sure a non-nil value is written even if it's empty since a nil value
+		// means delete to CompareAndSwap.
+		if newValue == nil {
+			newValue = []byte{}
+		}
+	}
+
+	swapped, err := fes.GlobalStateCompareAndSwap(key, expectedValue, newValue)
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateGlobalStateEntry: Problem updating entry: %v", err))
+		return
+	}
+	if !swapped {
+		_AddCodedError(ww, ErrorCodeConflict, "AdminUpdateGlobalStateEntry: The entry has changed since ExpectedValueHex "+
+			"was fetched. Reload the entry and try again.")
+		return
+	}
+
+	auditLog := &GlobalStateAuditLog{
+		TimestampNanos:            uint64(time.Now().UnixNano()),
+		AdminPublicKeyBase58Check: requestData.AdminPublicKey,
+		Key:                       key,
+		OldValue:                  expectedValue,
+		NewValue:                  newValue,
+		IsDeletion:                requestData.Delete,
+	}
+	if err = fes.putGlobalStateAuditLog(auditLog); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateGlobalStateEntry: The entry was updated "+
+			"but the change could not be added to the audit log: %v", err))
+		return
+	}
+
+	res := AdminUpdateGlobalStateEntryResponse{}
+	if !requestData.Delete {
+		res.Entry = DecodeGlobalStateEntry(key, newValue, fes.Params)
+	}
+	if err = json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateGlobalStateEntry: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+func (fes *APIServer) putGlobalStateAuditLog(auditLog *GlobalStateAuditLog) error {
+	randomBytes := make([]byte, globalStateAuditLogRandomBytes)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return fmt.Errorf("putGlobalStateAuditLog: Problem generating key: %v", err)
+	}
+	auditLogBuf := bytes.NewBuffer([]byte{})
+	if err := gob.NewEncoder(auditLogBuf).Encode(auditLog); err != nil {
+		return fmt.Errorf("putGlobalStateAuditLog: Problem encoding audit log: %v", err)
+	}
+	return fes.GlobalStatePut(
+		GlobalStateKeyForGlobalStateAuditLog(auditLog.TimestampNanos, randomBytes), auditLogBuf.Bytes())
+}
+
+// AdminGetGlobalStateAuditLogsRequest ...
+type AdminGetGlobalStateAuditLogsRequest struct {
+	// Only return changes made before this time. Leave as zero to start with the
+	// most recent change. Pass the NextBeforeTstampNanos from the previous
+	// response to get the next page.
+	BeforeTstampNanos uint64 `safeForLogging:"true"`
+	NumToFetch        int    `safeForLogging:"true"`
+}
+
+// AdminGetGlobalStateAuditLogsResponse ...
+type AdminGetGlobalStateAuditLogsResponse struct {
+	// The most recent changes first.
+	AuditLogs []*GlobalStateEntry
+	// Zero if there are no more changes.
+	NextBeforeTstampNanos uint64
+}
+
+// AdminGetGlobalStateAuditLogs returns the changes made through
+// AdminUpdateGlobalStateEntry, most recent first.
+func (fes *APIServer) AdminGetGlobalStateAuditLogs(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminGetGlobalStateAuditLogsRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetGlobalStateAuditLogs: Problem parsing request body: %v", err))
+		return
+	}
+
+	numToFetch := requestData.NumToFetch
+	if numToFetch <= 0 {
+		numToFetch = adminGlobalStateDefaultNumToFetch
+	}
+	if numToFetch > adminGlobalStateMaxNumToFetch {
+		numToFetch = adminGlobalStateMaxNumToFetch
+	}
+
+	// Seek backwards from just before BeforeTstampNanos. The key is padded out with
+	// 0xFF to maxKeyLen so starting at [prefix][tstamp - 1] covers every entry in
+	// that nanosecond.
+	maxKeyLen := len(_GlobalStatePrefixGlobalStateAuditLog) + 8 + globalStateAuditLogRandomBytes
+	seekStartKey := _GlobalStatePrefixGlobalStateAuditLog
+	if requestData.BeforeTstampNanos > 0 {
+		seekStartKey = append(append([]byte{}, _GlobalStatePrefixGlobalStateAuditLog...),
+			lib.EncodeUint64(requestData.BeforeTstampNanos-1)...)
+	}
+	keys, vals, err := fes.GlobalStateSeek(seekStartKey /*startPrefix*/, _GlobalStatePrefixGlobalStateAuditLog, /*validForPrefix*/
+		maxKeyLen, numToFetch, true, /*reverse*/
+		true /*fetchValues*/)
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetGlobalStateAuditLogs: %v", err))
+		return
+	}
+	if len(keys) != len(vals) {
+		_AddInternalServerError(ww, "AdminGetGlobalStateAuditLogs: GlobalState keys/vals length mismatch.")
+		return
+	}
+
+	res := AdminGetGlobalStateAuditLogsResponse{
+		AuditLogs: []*GlobalStateEntry{},
+	}
+	for ii, key := range keys {
+		res.AuditLogs = append(res.AuditLogs, DecodeGlobalStateEntry(key, vals[ii], fes.Params))
+	}
+	// Entries that share a nanosecond with the last one returned are skipped on the
+	// next page. That's fine since admins can't make changes that quickly.
+	if len(keys) == numToFetch {
+		lastKey := keys[len(keys)-1]
+		res.NextBeforeTstampNanos = lib.DecodeUint64(
+			lastKey[len(_GlobalStatePrefixGlobalStateAuditLog) : len(_GlobalStatePrefixGlobalStateAuditLog)+8])
+	}
+	if err = json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetGlobalStateAuditLogs: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// AdminGlobalStateFsckRequest ...
+type AdminGlobalStateFsckRequest struct {
+	// If true, fix every issue that can be fixed safely. Otherwise only report them.
+	Repair bool `safeForLogging:"true"`
+}
+
+// AdminGlobalStateFsckResponse ...
+type AdminGlobalStateFsckResponse struct {
+	Report *GlobalStateFsckReport
+}
+
+// AdminGlobalStateFsck checks all of global state against the chain and the
+// mempool. It covers everything AdminRemoveNilPosts does and more.
+func (fes *APIServer) AdminGlobalStateFsck(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminGlobalStateFsckRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGlobalStateFsck: Problem parsing request body: %v", err))
+		return
+	}
+
+	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGlobalStateFsck: Error getting augmented universal view: %v", err))
+		return
+	}
+
+	report, err := fes.RunGlobalStateFsck(utxoView, requestData.Repair)
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGlobalStateFsck: %v", err))
+		return
+	}
+
+	res := AdminGlobalStateFsckResponse{
+		Report: report,
+	}
+	if err := json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGlobalStateFsck: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
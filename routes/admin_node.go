@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/btcsuite/btcd/wire"
-	"github.com/gorilla/mux"
 	"io"
 	"io/ioutil"
 	"math"
@@ -320,7 +319,7 @@ func (fes *APIServer) _handleNodeControlGetInfo(
 		MinerPublicKeys: minerPublicKeyStrs,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("NodeControl: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("NodeControl: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -331,7 +330,7 @@ func (fes *APIServer) _handleConnectBitCloutNode(
 	// Don't connect to the peer if we're already aware of them.
 	for _, bitcloutPeer := range fes.backendServer.GetConnectionManager().GetAllPeers() {
 		if strings.Contains(bitcloutPeer.Address(), ip+fmt.Sprintf(":%d", protocolPort)) {
-			_AddBadRequestError(ww, fmt.Sprintf("You are already connected to peer %s:%d", ip, protocolPort))
+			_AddCodedError(ww, ErrorCodeConflict, fmt.Sprintf("You are already connected to peer %s:%d", ip, protocolPort))
 			return
 		}
 	}
@@ -340,7 +339,7 @@ func (fes *APIServer) _handleConnectBitCloutNode(
 	conn, err := net.DialTimeout("tcp", ip+fmt.Sprintf(":%d", protocolPort), fes.Params.DialTimeout)
 	if err != nil {
 		// Give a clean error we can display in this case.
-		_AddBadRequestError(ww, fmt.Sprintf("Cannot connect to node %s:%d: %v", ip, protocolPort, err))
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf("Cannot connect to node %s:%d: %v", ip, protocolPort, err))
 		return
 	}
 	conn.Close()
@@ -354,7 +353,7 @@ func (fes *APIServer) _handleConnectBitCloutNode(
 	go func() {
 		netAddr, err := fes.backendServer.GetConnectionManager().GetAddrManager().HostToNetAddress(ip, protocolPort, 0)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("_handleConnectBitCloutNode: Cannot connect to node %s:%d: %v", ip, protocolPort, err))
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("_handleConnectBitCloutNode: Cannot connect to node %s:%d: %v", ip, protocolPort, err))
 			return
 		}
 		fes.backendServer.GetConnectionManager().ConnectPeer(nil, netAddr)
@@ -399,7 +398,7 @@ func (fes *APIServer) _handleConnectBitCloutNode(
 	select {
 	case <-connectPeerDone:
 	case <-time.After(5 * time.Second):
-		_AddBadRequestError(ww, fmt.Sprintf("Cannot connect to node %s:%d: %v", ip, protocolPort, err))
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf("Cannot connect to node %s:%d: %v", ip, protocolPort, err))
 		return
 	}
 
@@ -407,7 +406,7 @@ func (fes *APIServer) _handleConnectBitCloutNode(
 		// Return an empty response, which indicates we set the peer up to be connected.
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("NodeControl: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("NodeControl: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -425,7 +424,7 @@ func (fes *APIServer) _handleDisconnectBitCloutNode(
 		}
 	}
 	if peerFound == nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddNotFoundError(ww, fmt.Sprintf(
 			"Peer with IP %s not found in connected peer list. Are you sure "+
 				"you are connected to this peer?", ip))
 		return
@@ -442,7 +441,7 @@ func (fes *APIServer) _handleDisconnectBitCloutNode(
 		// Return an empty response, which indicates we set the peer up to be connected.
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("NodeControl: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("NodeControl: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -456,7 +455,7 @@ func (fes *APIServer) _handleChangeBitcoinSyncPeer(ww http.ResponseWriter, newPe
 	}
 	err := <-replyChan
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("Problem connecting to new peer %s: %v", newPeerAddr, err))
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf("Problem connecting to new peer %s: %v", newPeerAddr, err))
 		return
 	}
 
@@ -464,7 +463,7 @@ func (fes *APIServer) _handleChangeBitcoinSyncPeer(ww http.ResponseWriter, newPe
 		// Return an empty response, which indicates we set the peer up to be connected.
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("NodeControl: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("NodeControl: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -478,7 +477,7 @@ func (fes *APIServer) NodeControl(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := NodeControlRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("NodeControlRequest: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("NodeControlRequest: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -490,7 +489,7 @@ func (fes *APIServer) NodeControl(ww http.ResponseWriter, req *http.Request) {
 	allowedOperationTypes["update_miner"] = true
 
 	if _, isOperationTypeAllowed := allowedOperationTypes[requestData.OperationType]; !isOperationTypeAllowed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"NodeControlRequest: OperationType %s is not allowed. Allowed types are: %v",
 			requestData.OperationType, allowedOperationTypes))
 		return
@@ -522,12 +521,12 @@ func (fes *APIServer) NodeControl(ww http.ResponseWriter, req *http.Request) {
 			for _, pkStr := range pkStrings {
 				publicKeyBytes, _, err := lib.Base58CheckDecode(pkStr)
 				if err != nil {
-					_AddBadRequestError(ww, fmt.Sprintf("NodeControlRequest: Problem decoding miner public key from base58 %s: %v", pkStr, err))
+					_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("NodeControlRequest: Problem decoding miner public key from base58 %s: %v", pkStr, err))
 					return
 				}
 				pk, err := btcec.ParsePubKey(publicKeyBytes, btcec.S256())
 				if err != nil {
-					_AddBadRequestError(ww, fmt.Sprintf("NodeControlRequest: Problem parsing miner public key %s: %v", pkStr, err))
+					_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("NodeControlRequest: Problem parsing miner public key %s: %v", pkStr, err))
 					return
 				}
 
@@ -537,13 +536,21 @@ func (fes *APIServer) NodeControl(ww http.ResponseWriter, req *http.Request) {
 		fes.backendServer.GetMiner().PublicKeys = minerPublicKeys
 
 	} else {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"NodeControlRequest: OperationType %s is allowed but not implemented; "+
 				"this should never happen", requestData.OperationType))
 		return
 	}
 }
 
+type ReprocessBitcoinBlockRequest struct {
+	// The hash of the Bitcoin block to reprocess, as hex, or its height.
+	BlockHashHexOrBlockHeight string `safeForLogging:"true"`
+
+	JWT            string
+	AdminPublicKey string `safeForLogging:"true"`
+}
+
 type ReprocessBitcoinBlockResponse struct {
 	Message string
 }
@@ -590,12 +597,15 @@ func ReprocessBitcoinBlockUsingAPI(blockHeightOrHash string, bitcoinManager *lib
 
 // ReprocessBitcoinBlock ...
 func (fes *APIServer) ReprocessBitcoinBlock(ww http.ResponseWriter, req *http.Request) {
-	// Parse all the vars from the URL
-	vars := mux.Vars(req)
-	blockHashHexOrHeight, blockHashHexOrHeightExists := vars["blockHashHexOrblockHeight"]
-	if !blockHashHexOrHeightExists {
-		_AddBadRequestError(ww, fmt.Sprintf("ReprocessBitcoinBlock: Missing block hash hex or height parameter after the slash. "+
-			"Usage: curl localhost:8080/reprocess-bitcoin-block/<block hash or block height>"))
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := ReprocessBitcoinBlockRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("ReprocessBitcoinBlock: Problem parsing request body: %v", err))
+		return
+	}
+	blockHashHexOrHeight := requestData.BlockHashHexOrBlockHeight
+	if blockHashHexOrHeight == "" {
+		_AddCodedError(ww, ErrorCodeBadRequest, "ReprocessBitcoinBlock: Missing BlockHashHexOrBlockHeight")
 		return
 	}
 
@@ -604,7 +614,7 @@ func (fes *APIServer) ReprocessBitcoinBlock(ww http.ResponseWriter, req *http.Re
 	if len(blockHashHexOrHeight) == lib.HashSizeBytes*2 {
 		hash, err := chainhash.NewHashFromStr(blockHashHexOrHeight)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("ReprocessBitcoinBlock: Problem decoding block hash hex: %v", err))
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("ReprocessBitcoinBlock: Problem decoding block hash hex: %v", err))
 			return
 		}
 
@@ -617,7 +627,7 @@ func (fes *APIServer) ReprocessBitcoinBlock(ww http.ResponseWriter, req *http.Re
 		// height.
 		blockHeight, err := strconv.Atoi(blockHashHexOrHeight)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("ReprocessBitcoinBlock: Problem decoding block height: %v", err))
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("ReprocessBitcoinBlock: Problem decoding block height: %v", err))
 			return
 		}
 
@@ -625,7 +635,7 @@ func (fes *APIServer) ReprocessBitcoinBlock(ww http.ResponseWriter, req *http.Re
 		blockHeight -= int(fes.Params.BitcoinStartBlockNode.Height)
 
 		if blockHeight < 0 || int64(blockHeight) > int64(fes.backendServer.GetBitcoinManager().HeaderTip().Height) {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"ReprocessBitcoinBlock: Height provided is less than zero or exceeds "+
 					"maximum height known, which is %d", blockHeight))
 			return
@@ -633,7 +643,7 @@ func (fes *APIServer) ReprocessBitcoinBlock(ww http.ResponseWriter, req *http.Re
 
 		blockNode := fes.backendServer.GetBitcoinManager().HeaderAtHeight(uint32(blockHeight))
 		if blockNode == nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeNotFound, fmt.Sprintf(
 				"ReprocessBitcoinBlock: Did not find Bitcoin block with height: %d", blockHeight))
 			return
 		}
@@ -643,13 +653,13 @@ func (fes *APIServer) ReprocessBitcoinBlock(ww http.ResponseWriter, req *http.Re
 	}
 
 	if err := ReprocessBitcoinBlockUsingAPI(blockHashHexOrHeight, fes.backendServer.GetBitcoinManager(), fes.Params); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("ReprocessBitcoinBlock: Error processing Bitcoin block from API: %v", err))
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf("ReprocessBitcoinBlock: Error processing Bitcoin block from API: %v", err))
 		return
 	}
 
 	// Return the response.
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("ReprocessBitcoinBlock: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("ReprocessBitcoinBlock: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -671,7 +681,7 @@ func (fes *APIServer) AdminGetMempoolStats(ww http.ResponseWriter, req *http.Req
 		TransactionSummaryStats: transactionSummaryStats,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetMempoolStats: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetMempoolStats: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -694,7 +704,7 @@ func (fes *APIServer) EvictUnminedBitcoinTxns(ww http.ResponseWriter, req *http.
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := EvictUnminedBitcoinTxnsRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("EvictUnminedBitcoinTxns: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("EvictUnminedBitcoinTxns: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -712,7 +722,75 @@ func (fes *APIServer) EvictUnminedBitcoinTxns(ww http.ResponseWriter, req *http.
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("EvictUnminedBitcoinTxns: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("EvictUnminedBitcoinTxns: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// AdminRebuildTxindexRequest ...
+type AdminRebuildTxindexRequest struct {
+	// The lowest height to remove from the txindex and index again. Zero rebuilds
+	// the whole txindex.
+	FromHeight uint32 `safeForLogging:"true"`
+}
+
+// AdminRebuildTxindexResponse ...
+type AdminRebuildTxindexResponse struct {
+	Status TxindexRebuildStatus
+}
+
+// AdminRebuildTxindex starts rebuilding the txindex from the given height and
+// returns right away. Use AdminGetTxindexRebuildStatus to follow its progress.
+func (fes *APIServer) AdminRebuildTxindex(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminRebuildTxindexRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminRebuildTxindex: Problem parsing request body: %v", err))
+		return
+	}
+
+	if fes.TxIndexChain == nil {
+		_AddCodedError(ww, ErrorCodeTxindexDisabled, "AdminRebuildTxindex: Cannot be called when TxIndexChain "+
+			"is nil. This error occurs when --txindex was not passed to the program on startup")
+		return
+	}
+	// The only other way starting can fail is if a rebuild is already running.
+	if err := fes.startTxindexRebuild(requestData.FromHeight, nil); err != nil {
+		_AddCodedError(ww, ErrorCodeConflict, fmt.Sprintf("AdminRebuildTxindex: %v", err))
+		return
+	}
+	go func() {
+		if err := fes.runTxindexRebuild(requestData.FromHeight); err != nil {
+			glog.Errorf("AdminRebuildTxindex: %v", err)
+		}
+	}()
+
+	res := AdminRebuildTxindexResponse{
+		Status: fes.txindexRebuild.getStatus(),
+	}
+	if err := json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminRebuildTxindex: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// AdminGetTxindexRebuildStatusRequest ...
+type AdminGetTxindexRebuildStatusRequest struct{}
+
+// AdminGetTxindexRebuildStatusResponse ...
+type AdminGetTxindexRebuildStatusResponse struct {
+	// The status of the rebuild that's running, or of the last one if none is.
+	Status TxindexRebuildStatus
+}
+
+// AdminGetTxindexRebuildStatus reports the progress of a rebuild started with
+// AdminRebuildTxindex.
+func (fes *APIServer) AdminGetTxindexRebuildStatus(ww http.ResponseWriter, req *http.Request) {
+	res := AdminGetTxindexRebuildStatusResponse{
+		Status: fes.txindexRebuild.getStatus(),
+	}
+	if err := json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetTxindexRebuildStatus: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
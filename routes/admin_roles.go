@@ -0,0 +1,335 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/bitclout/core/lib"
+)
+
+// AdminRole is a set of admin routes that can be granted to an admin public key.
+// Every admin Route declares the role needed to call it in RequiredRole.
+type AdminRole string
+
+const (
+	// AdminRoleNone is the RequiredRole of routes that aren't admin routes.
+	AdminRoleNone AdminRole = ""
+	// Can view and edit user metadata, e.g. to blacklist or graylist a user.
+	AdminRoleModerator AdminRole = "moderator"
+	// Can grant and remove verification badges.
+	AdminRoleVerifier AdminRole = "verifier"
+	// Can add, pin, and remove posts on the global feed.
+	AdminRoleFeedCurator AdminRole = "feed-curator"
+	// Can inspect and control the node, e.g. its mempool and peers.
+	AdminRoleNodeOperator AdminRole = "node-operator"
+	// Has every other role, and can also edit raw global state, update global
+	// params, and grant roles.
+	AdminRoleSuperadmin AdminRole = "superadmin"
+)
+
+// AdminRoles lists every role that can be granted.
+var AdminRoles = []AdminRole{
+	AdminRoleModerator,
+	AdminRoleVerifier,
+	AdminRoleFeedCurator,
+	AdminRoleNodeOperator,
+	AdminRoleSuperadmin,
+}
+
+// IsValid returns true if role is one of AdminRoles.
+func (role AdminRole) IsValid() bool {
+	for _, validRole := range AdminRoles {
+		if role == validRole {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminRoleAssignment is stored in global state for each public key that has
+// been granted roles with AdminUpdateAdminRoles.
+type AdminRoleAssignment struct {
+	PublicKey []byte
+	Roles     []AdminRole
+	// The admin that last changed the roles and when.
+	UpdatedByPublicKeyBase58Check string
+	UpdatedTstampNanos            uint64
+}
+
+// hasAdminRole returns true if roles include requiredRole. Superadmins have every
+// role.
+func hasAdminRole(roles []AdminRole, requiredRole AdminRole) bool {
+	for _, role := range roles {
+		if role == requiredRole || role == AdminRoleSuperadmin {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAdminRoles returns the roles of the admin with the given public key. Keys
+// passed in with --admin-public-keys are superadmins so that a node always has
+// someone who can grant roles. Other keys get the roles assigned to them in
+// global state, if any.
+func (fes *APIServer) GetAdminRoles(publicKeyBase58Check string) ([]AdminRole, error) {
+	for _, adminPublicKey := range fes.AdminPublicKeys {
+		if adminPublicKey == publicKeyBase58Check {
+			return []AdminRole{AdminRoleSuperadmin}, nil
+		}
+	}
+
+	publicKeyBytes, _, err := lib.Base58CheckDecode(publicKeyBase58Check)
+	if err != nil {
+		return nil, fmt.Errorf("GetAdminRoles: Problem decoding public key %v: %v", publicKeyBase58Check, err)
+	}
+	assignment, err := fes.getAdminRoleAssignment(publicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	if assignment == nil {
+		return nil, nil
+	}
+	return assignment.Roles, nil
+}
+
+// BatchGetAdminRoles returns the roles of each of the given public keys, the same
+// as GetAdminRoles would, with a single global state read. Keys without roles
+// aren't in the map, and neither are keys that can't be decoded.
+func (fes *APIServer) BatchGetAdminRoles(publicKeysBase58Check []string) (map[string][]AdminRole, error) {
+	rolesByPublicKey := make(map[string][]AdminRole)
+	keyList := [][]byte{}
+	keyPublicKeys := []string{}
+	for _, publicKeyBase58Check := range publicKeysBase58Check {
+		if _, exists := rolesByPublicKey[publicKeyBase58Check]; exists {
+			continue
+		}
+		isSuperadmin := false
+		for _, adminPublicKey := range fes.AdminPublicKeys {
+			if adminPublicKey == publicKeyBase58Check {
+				isSuperadmin = true
+				break
+			}
+		}
+		if isSuperadmin {
+			rolesByPublicKey[publicKeyBase58Check] = []AdminRole{AdminRoleSuperadmin}
+			continue
+		}
+		publicKeyBytes, _, err := lib.Base58CheckDecode(publicKeyBase58Check)
+		if err != nil {
+			// A key that can't be decoded can't have been granted roles either.
+			continue
+		}
+		keyList = append(keyList, GlobalStateKeyForPublicKeyToAdminRoles(publicKeyBytes))
+		keyPublicKeys = append(keyPublicKeys, publicKeyBase58Check)
+	}
+	if len(keyList) == 0 {
+		return rolesByPublicKey, nil
+	}
+
+	values, err := fes.GlobalStateBatchGet(keyList)
+	if err != nil {
+		return nil, fmt.Errorf("BatchGetAdminRoles: Problem getting roles: %v", err)
+	}
+	if len(values) != len(keyList) {
+		return nil, fmt.Errorf("BatchGetAdminRoles: Expected %d values but got %d", len(keyList), len(values))
+	}
+	for ii, value := range values {
+		// BatchGet returns an empty value for keys that aren't present.
+		if len(value) == 0 {
+			continue
+		}
+		assignment, err := decodeAdminRoleAssignment(value)
+		if err != nil {
+			return nil, fmt.Errorf("BatchGetAdminRoles: %v", err)
+		}
+		if len(assignment.Roles) > 0 {
+			rolesByPublicKey[keyPublicKeys[ii]] = assignment.Roles
+		}
+	}
+	return rolesByPublicKey, nil
+}
+
+func (fes *APIServer) getAdminRoleAssignment(publicKeyBytes []byte) (*AdminRoleAssignment, error) {
+	value, err := fes.GlobalStateGet(GlobalStateKeyForPublicKeyToAdminRoles(publicKeyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("getAdminRoleAssignment: Problem getting roles: %v", err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	assignment, err := decodeAdminRoleAssignment(value)
+	if err != nil {
+		return nil, fmt.Errorf("getAdminRoleAssignment: %v", err)
+	}
+	return assignment, nil
+}
+
+func decodeAdminRoleAssignment(value []byte) (*AdminRoleAssignment, error) {
+	assignment := &AdminRoleAssignment{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(assignment); err != nil {
+		return nil, fmt.Errorf("Problem decoding roles: %v", err)
+	}
+	return assignment, nil
+}
+
+// AdminRoleAssignmentResponse ...
+type AdminRoleAssignmentResponse struct {
+	PublicKeyBase58Check          string
+	Roles                         []AdminRole
+	UpdatedByPublicKeyBase58Check string
+	UpdatedTstampNanos            uint64
+}
+
+func newAdminRoleAssignmentResponse(assignment *AdminRoleAssignment, params *lib.BitCloutParams) *AdminRoleAssignmentResponse {
+	return &AdminRoleAssignmentResponse{
+		PublicKeyBase58Check:          lib.PkToString(assignment.PublicKey, params),
+		Roles:                         assignment.Roles,
+		UpdatedByPublicKeyBase58Check: assignment.UpdatedByPublicKeyBase58Check,
+		UpdatedTstampNanos:            assignment.UpdatedTstampNanos,
+	}
+}
+
+// AdminGetAdminRolesRequest ...
+type AdminGetAdminRolesRequest struct {
+	AdminPublicKey string `safeForLogging:"true"`
+	JWT            string
+}
+
+// AdminGetAdminRolesResponse ...
+type AdminGetAdminRolesResponse struct {
+	// The keys passed in with --admin-public-keys. They are always superadmins.
+	SuperadminPublicKeys []string
+	// Every role assignment in global state, sorted by public key.
+	RoleAssignments []*AdminRoleAssignmentResponse
+}
+
+// AdminGetAdminRoles lists every admin and their roles.
+func (fes *APIServer) AdminGetAdminRoles(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminGetAdminRolesRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetAdminRoles: Problem parsing request body: %v", err))
+		return
+	}
+
+	res := AdminGetAdminRolesResponse{
+		SuperadminPublicKeys: append([]string{}, fes.AdminPublicKeys...),
+		RoleAssignments:      []*AdminRoleAssignmentResponse{},
+	}
+	err := GlobalStateIteratePrefix(fes.GlobalStore, _GlobalStatePrefixPublicKeyToAdminRoles,
+		func(key []byte, value []byte) error {
+			assignment := &AdminRoleAssignment{}
+			if err := gob.NewDecoder(bytes.NewReader(value)).Decode(assignment); err != nil {
+				return fmt.Errorf("Problem decoding roles for key %x: %v", key, err)
+			}
+			res.RoleAssignments = append(res.RoleAssignments, newAdminRoleAssignmentResponse(assignment, fes.Params))
+			return nil
+		})
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetAdminRoles: Problem listing roles: %v", err))
+		return
+	}
+	sort.Slice(res.RoleAssignments, func(ii, jj int) bool {
+		return res.RoleAssignments[ii].PublicKeyBase58Check < res.RoleAssignments[jj].PublicKeyBase58Check
+	})
+
+	if err = json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetAdminRoles: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// AdminUpdateAdminRolesRequest ...
+type AdminUpdateAdminRolesRequest struct {
+	AdminPublicKey       string `safeForLogging:"true"`
+	JWT                  string
+	PublicKeyBase58Check string `safeForLogging:"true"`
+	// The full set of roles the key should have. Leave empty to remove all of
+	// the key's roles.
+	Roles []AdminRole `safeForLogging:"true"`
+}
+
+// AdminUpdateAdminRolesResponse ...
+type AdminUpdateAdminRolesResponse struct {
+	// Nil if all of the key's roles were removed.
+	RoleAssignment *AdminRoleAssignmentResponse
+}
+
+// AdminUpdateAdminRoles replaces the roles assigned to a public key in global
+// state. Keys passed in with --admin-public-keys can't be changed this way.
+func (fes *APIServer) AdminUpdateAdminRoles(ww http.ResponseWriter, req *http.Request) {
+	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	requestData := AdminUpdateAdminRolesRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminUpdateAdminRoles: Problem parsing request body: %v", err))
+		return
+	}
+
+	publicKeyBytes, _, err := lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
+	if err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
+			"AdminUpdateAdminRoles: Problem decoding public key %v: %v", requestData.PublicKeyBase58Check, err))
+		return
+	}
+	for _, adminPublicKey := range fes.AdminPublicKeys {
+		if adminPublicKey == requestData.PublicKeyBase58Check {
+			_AddCodedError(ww, ErrorCodeBadRequest, "AdminUpdateAdminRoles: Keys passed in with --admin-public-keys are "+
+				"always superadmins and can't be changed")
+			return
+		}
+	}
+
+	// Dedupe the roles so the stored assignment is the same however it was requested.
+	roleSet := make(map[AdminRole]bool)
+	roles := []AdminRole{}
+	for _, role := range requestData.Roles {
+		if !role.IsValid() {
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminUpdateAdminRoles: Unknown role %q", role))
+			return
+		}
+		if !roleSet[role] {
+			roleSet[role] = true
+			roles = append(roles, role)
+		}
+	}
+	sort.Slice(roles, func(ii, jj int) bool {
+		return roles[ii] < roles[jj]
+	})
+
+	key := GlobalStateKeyForPublicKeyToAdminRoles(publicKeyBytes)
+	res := AdminUpdateAdminRolesResponse{}
+	if len(roles) == 0 {
+		if err = fes.GlobalStateDelete(key); err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateAdminRoles: Problem removing roles: %v", err))
+			return
+		}
+	} else {
+		assignment := &AdminRoleAssignment{
+			PublicKey:                     publicKeyBytes,
+			Roles:                         roles,
+			UpdatedByPublicKeyBase58Check: requestData.AdminPublicKey,
+			UpdatedTstampNanos:            uint64(time.Now().UnixNano()),
+		}
+		assignmentBuf := bytes.NewBuffer([]byte{})
+		if err = gob.NewEncoder(assignmentBuf).Encode(assignment); err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateAdminRoles: Problem encoding roles: %v", err))
+			return
+		}
+		if err = fes.GlobalStatePut(key, assignmentBuf.Bytes()); err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateAdminRoles: Problem saving roles: %v", err))
+			return
+		}
+		res.RoleAssignment = newAdminRoleAssignmentResponse(assignment, fes.Params)
+	}
+
+	if err = json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateAdminRoles: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
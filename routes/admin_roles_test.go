@@ -0,0 +1,171 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+type testAdmin struct {
+	t                    *testing.T
+	privateKey           *btcec.PrivateKey
+	publicKeyBase58Check string
+}
+
+func newTestAdmin(t *testing.T) *testAdmin {
+	privateKey, err := btcec.NewPrivateKey(btcec.S256())
+	require.NoError(t, err)
+	return &testAdmin{
+		t:                    t,
+		privateKey:           privateKey,
+		publicKeyBase58Check: lib.PkToString(privateKey.PubKey().SerializeCompressed(), &lib.BitCloutTestnetParams),
+	}
+}
+
+// signJWT returns a new token with the given claims.
+func (admin *testAdmin) signJWT(claims *jwt.StandardClaims) string {
+	signedToken, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(admin.privateKey.ToECDSA())
+	require.NoError(admin.t, err)
+	return signedToken
+}
+
+// testJWTAudience is the aud the tokens from jwt are for, so test servers that
+// check admin tokens need it in their JWTAudiences.
+const testJWTAudience = "localhost:17001"
+
+// jwt returns a new token that's good for one admin request.
+func (admin *testAdmin) jwt() string {
+	return admin.signJWT(&jwt.StandardClaims{
+		ExpiresAt: jwt.At(time.Now().Add(time.Minute)),
+		Audience:  jwt.ClaimStrings{testJWTAudience},
+		ID:        fmt.Sprint(time.Now().UnixNano()),
+	})
+}
+
+func TestAdminRoles(t *testing.T) {
+	require := require.New(t)
+
+	superadmin := newTestAdmin(t)
+	moderator := newTestAdmin(t)
+	fes := &APIServer{
+		Params:                              &lib.BitCloutTestnetParams,
+		GlobalStore:                         NewMemoryGlobalStore(),
+		AccessControlAllowOrigins:           []string{"*"},
+		SecureHeaderMiddlewareIsDevelopment: true,
+		JWTAudiences:                        []string{testJWTAudience},
+		AdminPublicKeys:                     []string{superadmin.publicKeyBase58Check},
+	}
+	router := fes.NewRouter()
+
+	call := func(handler http.Handler, routePath string, admin *testAdmin, request map[string]interface{},
+		response interface{}) int {
+
+		if admin != nil {
+			request["AdminPublicKey"] = admin.publicKeyBase58Check
+			request["JWT"] = admin.jwt()
+		}
+		body, err := json.Marshal(request)
+		require.NoError(err)
+		req := httptest.NewRequest("POST", routePath, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		if response != nil {
+			require.NoError(json.NewDecoder(recorder.Body).Decode(response))
+		}
+		return recorder.Code
+	}
+	moderatorRoute := fes.CheckAdminPublicKey(http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		ww.Write([]byte("{}"))
+	}), AdminRoleModerator)
+
+	// Admin routes are closed to keys without a role, and to requests without a
+	// valid JWT.
+	apiErr := &APIError{}
+	require.Equal(http.StatusForbidden, call(moderatorRoute, "/", moderator, map[string]interface{}{}, apiErr))
+	require.Equal(ErrorCodeForbidden, apiErr.Code)
+	require.Equal(string(AdminRoleModerator), apiErr.Details["RequiredRole"])
+	require.Equal(http.StatusForbidden, call(router, RoutePathAdminGetAdminRoles, moderator,
+		map[string]interface{}{}, nil))
+	require.Equal(http.StatusUnauthorized, call(router, RoutePathAdminGetAdminRoles, nil,
+		map[string]interface{}{"AdminPublicKey": superadmin.publicKeyBase58Check, "JWT": moderator.jwt()}, nil))
+
+	// Superadmins can grant roles and have every role themselves.
+	updateRes := &AdminUpdateAdminRolesResponse{}
+	require.Equal(http.StatusOK, call(router, RoutePathAdminUpdateAdminRoles, superadmin, map[string]interface{}{
+		"PublicKeyBase58Check": moderator.publicKeyBase58Check,
+		"Roles":                []AdminRole{AdminRoleModerator, AdminRoleVerifier, AdminRoleModerator},
+	}, updateRes))
+	require.Equal([]AdminRole{AdminRoleModerator, AdminRoleVerifier}, updateRes.RoleAssignment.Roles)
+	require.Equal(superadmin.publicKeyBase58Check, updateRes.RoleAssignment.UpdatedByPublicKeyBase58Check)
+	require.Equal(http.StatusOK, call(moderatorRoute, "/", moderator, map[string]interface{}{}, nil))
+	require.Equal(http.StatusOK, call(moderatorRoute, "/", superadmin, map[string]interface{}{}, nil))
+	// Other roles don't imply superadmin.
+	require.Equal(http.StatusForbidden, call(router, RoutePathAdminGetAdminRoles, moderator,
+		map[string]interface{}{}, nil))
+
+	getRes := &AdminGetAdminRolesResponse{}
+	require.Equal(http.StatusOK, call(router, RoutePathAdminGetAdminRoles, superadmin,
+		map[string]interface{}{}, getRes))
+	require.Equal([]string{superadmin.publicKeyBase58Check}, getRes.SuperadminPublicKeys)
+	require.Len(getRes.RoleAssignments, 1)
+	require.Equal(moderator.publicKeyBase58Check, getRes.RoleAssignments[0].PublicKeyBase58Check)
+
+	// Roles for a list of users are looked up together.
+	nonAdmin := newTestAdmin(t)
+	rolesByPublicKey, err := fes.BatchGetAdminRoles([]string{superadmin.publicKeyBase58Check,
+		moderator.publicKeyBase58Check, nonAdmin.publicKeyBase58Check, "not a public key"})
+	require.NoError(err)
+	require.Equal(map[string][]AdminRole{
+		superadmin.publicKeyBase58Check: {AdminRoleSuperadmin},
+		moderator.publicKeyBase58Check:  {AdminRoleModerator, AdminRoleVerifier},
+	}, rolesByPublicKey)
+
+	// Unknown roles and keys passed in with --admin-public-keys are rejected.
+	require.Equal(http.StatusBadRequest, call(router, RoutePathAdminUpdateAdminRoles, superadmin,
+		map[string]interface{}{"PublicKeyBase58Check": moderator.publicKeyBase58Check, "Roles": []string{"owner"}}, nil))
+	require.Equal(http.StatusBadRequest, call(router, RoutePathAdminUpdateAdminRoles, superadmin,
+		map[string]interface{}{"PublicKeyBase58Check": superadmin.publicKeyBase58Check}, nil))
+
+	// Removing every role closes the routes again.
+	require.Equal(http.StatusOK, call(router, RoutePathAdminUpdateAdminRoles, superadmin, map[string]interface{}{
+		"PublicKeyBase58Check": moderator.publicKeyBase58Check,
+	}, updateRes))
+	require.Nil(updateRes.RoleAssignment)
+	require.Equal(http.StatusForbidden, call(moderatorRoute, "/", moderator, map[string]interface{}{}, nil))
+
+	// Admin routes are only open to everyone when the operator opts in.
+	openFes := &APIServer{
+		Params:                              &lib.BitCloutTestnetParams,
+		GlobalStore:                         NewMemoryGlobalStore(),
+		AccessControlAllowOrigins:           []string{"*"},
+		SecureHeaderMiddlewareIsDevelopment: true,
+		JWTAudiences:                        []string{testJWTAudience},
+	}
+	require.Equal(http.StatusForbidden, call(openFes.NewRouter(), RoutePathAdminGetAdminRoles, moderator,
+		map[string]interface{}{}, nil))
+	openFes.AdminOpenAccess = true
+	require.Equal(http.StatusOK, call(openFes.NewRouter(), RoutePathAdminGetAdminRoles, nil,
+		map[string]interface{}{}, nil))
+}
+
+func TestAdminRoutesRequireRole(t *testing.T) {
+	require := require.New(t)
+
+	fes := &APIServer{Params: &lib.BitCloutTestnetParams}
+	for _, route := range fes.AllRoutes() {
+		if strings.HasPrefix(route.Pattern, "/api/v0/admin/") {
+			require.NotEqual(AdminRoleNone, route.RequiredRole, route.Name)
+		}
+	}
+}
@@ -30,14 +30,14 @@ func (fes *APIServer) GetGlobalParams(ww http.ResponseWriter, req *http.Request)
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetGlobalParamsRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetGlobalParams: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetGlobalParams: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Get a view
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetGlobalParams: Error getting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetGlobalParams: Error getting utxoView: %v", err))
 		return
 	}
 	// Return all the data associated with the transaction in the response
@@ -47,7 +47,7 @@ func (fes *APIServer) GetGlobalParams(ww http.ResponseWriter, req *http.Request)
 		MinimumNetworkFeeNanosPerKB: utxoView.GlobalParamsEntry.MinimumNetworkFeeNanosPerKB,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetGlobalParams: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetGlobalParams: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -94,14 +94,14 @@ func (fes *APIServer) UpdateGlobalParams(ww http.ResponseWriter, req *http.Reque
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := UpdateGlobalParamsRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateGlobalParams: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("UpdateGlobalParams: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the updater public key.
 	updaterPkBytes, _, err := lib.Base58CheckDecode(requestData.UpdaterPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateGlobalParams: Problem decoding updater "+
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("UpdateGlobalParams: Problem decoding updater "+
 			"base58 public key %s: %v", requestData.UpdaterPublicKeyBase58Check, err))
 		return
 	}
@@ -109,7 +109,7 @@ func (fes *APIServer) UpdateGlobalParams(ww http.ResponseWriter, req *http.Reque
 	// Get a utxoView.
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateGlobalParams: Error constucting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("UpdateGlobalParams: Error constucting utxoView: %v", err))
 		return
 	}
 
@@ -137,13 +137,13 @@ func (fes *APIServer) UpdateGlobalParams(ww http.ResponseWriter, req *http.Reque
 		requestData.MinFeeRateNanosPerKB,
 		fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateGlobalParams: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("UpdateGlobalParams: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateGlobalParams: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("UpdateGlobalParams: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -156,7 +156,7 @@ func (fes *APIServer) UpdateGlobalParams(ww http.ResponseWriter, req *http.Reque
 		TransactionHex:    hex.EncodeToString(txnBytes),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateGlobalParams: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("UpdateGlobalParams: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -194,7 +194,8 @@ func (fes *APIServer) getPublicKeyFromUsernameOrPublicKeyString(usernameOrPublic
 		var err error
 		fromPublicKey, _, err := lib.Base58CheckDecode(usernameOrPublicKey)
 		if err != nil {
-			return nil, errors.Wrap(err, "getPublicKeyFromUsernameOrPublicKeyString: ")
+			return nil, NewAPIError(ErrorCodeInvalidPublicKey, fmt.Sprintf(
+				"getPublicKeyFromUsernameOrPublicKeyString: %v", err))
 		}
 		return fromPublicKey, nil
 	}
@@ -207,9 +208,9 @@ func (fes *APIServer) getPublicKeyFromUsernameOrPublicKeyString(usernameOrPublic
 	}
 	profileEntry := utxoView.GetProfileEntryForUsername([]byte(usernameOrPublicKey))
 	if profileEntry == nil {
-		return nil, errors.Wrap(
-			fmt.Errorf("getPublicKeyFromUsernameOrPublicKeyString: Profile with username %v does not exist",
-				usernameOrPublicKey), "")
+		return nil, NewAPIError(ErrorCodeProfileNotFound, fmt.Sprintf(
+			"getPublicKeyFromUsernameOrPublicKeyString: Profile with username %v does not exist",
+			usernameOrPublicKey))
 	}
 
 	return profileEntry.PublicKey, nil
@@ -221,14 +222,14 @@ func (fes *APIServer) SwapIdentity(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SwapIdentityRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SwapIdentity: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SwapIdentity: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the updater public key.
 	updaterPkBytes, _, err := lib.Base58CheckDecode(requestData.UpdaterPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SwapIdentity: Problem decoding updater "+
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("SwapIdentity: Problem decoding updater "+
 			"base58 public key %s: %v", requestData.UpdaterPublicKeyBase58Check, err))
 		return
 	}
@@ -236,13 +237,13 @@ func (fes *APIServer) SwapIdentity(ww http.ResponseWriter, req *http.Request) {
 	fromPublicKey, err := fes.getPublicKeyFromUsernameOrPublicKeyString(
 		requestData.FromUsernameOrPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, err.Error())
+		_AddCodedError(ww, errorCodeOf(err, ErrorCodeInternal), err.Error())
 		return
 	}
 	toPublicKey, err := fes.getPublicKeyFromUsernameOrPublicKeyString(
 		requestData.ToUsernameOrPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, err.Error())
+		_AddCodedError(ww, errorCodeOf(err, ErrorCodeInternal), err.Error())
 		return
 	}
 
@@ -255,13 +256,13 @@ func (fes *APIServer) SwapIdentity(ww http.ResponseWriter, req *http.Request) {
 		requestData.MinFeeRateNanosPerKB,
 		fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SwapIdentity: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("SwapIdentity: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SwapIdentity: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SwapIdentity: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -274,7 +275,7 @@ func (fes *APIServer) SwapIdentity(ww http.ResponseWriter, req *http.Request) {
 		TransactionHex:    hex.EncodeToString(txnBytes),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SwapIdentity: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SwapIdentity: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
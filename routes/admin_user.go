@@ -50,12 +50,12 @@ func (fes *APIServer) AdminUpdateUserGlobalMetadata(ww http.ResponseWriter, req
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := AdminUpdateUserGlobalMetadataRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem parsing request body: %v", err))
 		return
 	}
 
 	if requestData.UserPublicKeyBase58Check == "" && requestData.Username == "" {
-		_AddBadRequestError(ww,
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey,
 			fmt.Sprintf("AdminUpdateUserGlobalMetadataRequest: Must provide a valid username or public key."))
 		return
 	}
@@ -66,7 +66,7 @@ func (fes *APIServer) AdminUpdateUserGlobalMetadata(ww http.ResponseWriter, req
 	if requestData.UserPublicKeyBase58Check != "" {
 		userPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.UserPublicKeyBase58Check)
 		if err != nil || len(userPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem decoding updater public key %s: %v",
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem decoding updater public key %s: %v",
 				requestData.UserPublicKeyBase58Check, err))
 			return
 		}
@@ -76,13 +76,13 @@ func (fes *APIServer) AdminUpdateUserGlobalMetadata(ww http.ResponseWriter, req
 	if userPublicKeyBytes == nil && requestData.Username != "" {
 		utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem fetching utxoView: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem fetching utxoView: %v", err))
 			return
 		}
 
 		profile := utxoView.GetProfileEntryForUsername([]byte(requestData.Username))
 		if profile == nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem getting profile for username: %v : %s", err, requestData.Username))
+			_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem getting profile for username: %v : %s", err, requestData.Username))
 			return
 		}
 		userPublicKeyBytes = profile.PublicKey
@@ -91,86 +91,88 @@ func (fes *APIServer) AdminUpdateUserGlobalMetadata(ww http.ResponseWriter, req
 	// Now that we have a public key, set up the global state UserMetadata object.
 	userMetadata, err := fes.getUserMetadataFromGlobalState(lib.PkToString(userPublicKeyBytes, fes.Params))
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem getting metadata from global state: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem getting metadata from global state: %v", err))
 		return
 	}
 
 	// If this request is to remove phone number metadata, do it and then return early.
 	if requestData.RemovePhoneNumberMetadata {
 		if len(userMetadata.PhoneNumber) == 0 {
-			_AddBadRequestError(ww, "AdminUpdateUserGlobalMetadata: User does not have a phone number")
+			_AddCodedError(ww, ErrorCodeBadRequest, "AdminUpdateUserGlobalMetadata: User does not have a phone number")
 			return
 		}
-		phoneNumberMetadata, err := fes.getPhoneNumberMetadataFromGlobalState(userMetadata.PhoneNumber)
+		_, err = fes.updatePhoneNumberMetadataInGlobalState(userMetadata.PhoneNumber, func(phoneNumberMetadata *PhoneNumberMetadata) error {
+			phoneNumberMetadata.PublicKey = nil
+			return nil
+		})
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Error getting phone number metadata: %v", err))
-			return
-		}
-		phoneNumberMetadata.PublicKey = nil
-		err = fes.putPhoneNumberMetadataInGlobalState(phoneNumberMetadata)
-		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Error saving phone number metadata: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Error saving phone number metadata: %v", err))
 			return
 		}
 
 		// If we made it this far we were successful at removing phone metadata, return without error.
 		res := AdminUpdateUserGlobalMetadataResponse{}
 		if err := json.NewEncoder(ww).Encode(res); err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem encoding response as JSON: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem encoding response as JSON: %v", err))
 			return
 		}
 		return
 	}
 
-	// Now that we have a userMetadata object, update it based on the request.
+	// Update the blacklist and graylist indexes based on the request.
 	if requestData.IsBlacklistUpdate {
-		userMetadata.RemoveEverywhere = requestData.RemoveEverywhere
 		blacklistKey := GlobalStateKeyForBlacklistedProfile(userPublicKeyBytes)
-		if userMetadata.RemoveEverywhere {
+		if requestData.RemoveEverywhere {
 			err = fes.GlobalStatePut(blacklistKey, lib.IsBlacklisted)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem updating blacklist: %v", err))
+				_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem updating blacklist: %v", err))
 			}
 		} else {
 			err = fes.GlobalStateDelete(blacklistKey)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem deleting from blacklist: %v", err))
+				_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem deleting from blacklist: %v", err))
 				return
 			}
 		}
 		// We need to update global state's list of blacklisted users.
 
-		userMetadata.RemoveFromLeaderboard = requestData.RemoveFromLeaderboard
 		graylistkey := GlobalStateKeyForGraylistedProfile(userPublicKeyBytes)
-		if userMetadata.RemoveFromLeaderboard {
+		if requestData.RemoveFromLeaderboard {
 			// We need to update global state's list of graylisted users.
 			err = fes.GlobalStatePut(graylistkey, lib.IsGraylisted)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem updating graylist: %v", err))
+				_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem updating graylist: %v", err))
 				return
 			}
 		} else {
 			err = fes.GlobalStateDelete(graylistkey)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem deleting from graylist: %v", err))
+				_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem deleting from graylist: %v", err))
 				return
 			}
 		}
-	} else if requestData.IsWhitelistUpdate {
-		userMetadata.WhitelistPosts = requestData.WhitelistPosts
 	}
 
-	// Encode the updated entry and stick it in the database.
-	err = fes.putUserMetadataInGlobalState(userMetadata)
+	// Atomically apply the update to the user's metadata so that we don't clobber
+	// concurrent changes made by the user themselves.
+	_, err = fes.updateUserMetadataInGlobalState(userPublicKeyBytes, func(userMetadata *UserMetadata) error {
+		if requestData.IsBlacklistUpdate {
+			userMetadata.RemoveEverywhere = requestData.RemoveEverywhere
+			userMetadata.RemoveFromLeaderboard = requestData.RemoveFromLeaderboard
+		} else if requestData.IsWhitelistUpdate {
+			userMetadata.WhitelistPosts = requestData.WhitelistPosts
+		}
+		return nil
+	})
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem putting updated user metadata: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem putting updated user metadata: %v", err))
 		return
 	}
 
 	// If we made it this far we were successful, return without error.
 	res := AdminUpdateUserGlobalMetadataResponse{}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -192,7 +194,7 @@ func (fes *APIServer) AdminGetAllUserGlobalMetadata(ww http.ResponseWriter, req
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := AdminGetAllUserGlobalMetadataRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetAllUserGlobalMetadata: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -202,23 +204,20 @@ func (fes *APIServer) AdminGetAllUserGlobalMetadata(ww http.ResponseWriter, req
 		0 /*maxKeyLen -- ignored since reverse is false*/, requestData.NumToFetch, false, /*reverse*/
 		true /*fetchValues*/)
 	if err != nil {
-		_AddInternalServerError(
-			ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: %v", err))
 		return
 	}
 
 	// Sanity check that we got an appropriate number of keys and values.
 	if len(keys) != len(vals) {
-		_AddInternalServerError(
-			ww, "AdminGetAllUserGlobalMetadata: GlobalState keys/vals length mismatch.")
+		_AddInternalServerError(ww, "AdminGetAllUserGlobalMetadata: GlobalState keys/vals length mismatch.")
 		return
 	}
 
 	// Get a view that includes the transaction we just processed.
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddInternalServerError(
-			ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: problem with GetAugmentedUniversalView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: problem with GetAugmentedUniversalView: %v", err))
 		return
 	}
 
@@ -231,14 +230,13 @@ func (fes *APIServer) AdminGetAllUserGlobalMetadata(ww http.ResponseWriter, req
 		pubKeyString := lib.PkToString(pkBytes, fes.Params)
 
 		// Decode the user metadata associated with this key.
-		userMetadata := UserMetadata{}
-		err = gob.NewDecoder(bytes.NewReader(vals[ii])).Decode(&userMetadata)
+		userMetadata, err := fes.PIIKeyring.decodeUserMetadata(vals[ii])
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: Problem getting metadata from global state: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: Problem getting metadata from global state: %v", err))
 			return
 		}
 
-		publicKeyToUserMetadata[pubKeyString] = &userMetadata
+		publicKeyToUserMetadata[pubKeyString] = userMetadata
 
 		profileEntry := utxoView.GetProfileEntryForPublicKey(pkBytes)
 		if profileEntry != nil {
@@ -252,7 +250,7 @@ func (fes *APIServer) AdminGetAllUserGlobalMetadata(ww http.ResponseWriter, req
 		PubKeyToUsername:           publicKeyToUsername,
 	}
 	if err = json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -276,20 +274,20 @@ func (fes *APIServer) AdminGetUserGlobalMetadata(ww http.ResponseWriter, req *ht
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := AdminGetUserGlobalMetadataRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetAllUserGlobalMetadata: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetAllUserGlobalMetadata: Problem parsing request body: %v", err))
 		return
 	}
 	// Decode the user public key provided.
 	userPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.UserPublicKeyBase58Check)
 	if err != nil || len(userPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetUserGlobalMetadata: Problem userPublicKeyBase58Check: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("AdminGetUserGlobalMetadata: Problem userPublicKeyBase58Check: %v", err))
 		return
 	}
 
 	// Grab user's metadata from global state
 	userMetadata, err := fes.getUserMetadataFromGlobalState(lib.PkToString(userPublicKeyBytes, fes.Params))
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetUserGlobalMetadata: Problem obtaining userMetadata from global state: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetUserGlobalMetadata: Problem obtaining userMetadata from global state: %v", err))
 	}
 
 	// Get a view that includes the transaction we just processed.
@@ -307,7 +305,7 @@ func (fes *APIServer) AdminGetUserGlobalMetadata(ww http.ResponseWriter, req *ht
 		UserProfileEntryResponse: _profileEntryToResponse(profileEntry, fes.Params, nil, utxoView),
 	}
 	if err = json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetUserGlobalMetadata: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetUserGlobalMetadata: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -398,21 +396,21 @@ func (fes *APIServer) AdminGrantVerificationBadge(ww http.ResponseWriter, req *h
 	requestData := AdminGrantVerificationBadgeRequest{}
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGrantVerificationBadge: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGrantVerificationBadge: Problem parsing request body: %v", err))
 		return
 	}
 	usernameToVerify := requestData.UsernameToVerify
 
 	// Verify the username adheres to the consensus username criteria
 	if len(usernameToVerify) == 0 || len(usernameToVerify) > lib.MaxUsernameLengthBytes || !lib.UsernameRegex.Match([]byte(usernameToVerify)) {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGrantVerificationBadge: Must provide a valid username"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminGrantVerificationBadge: Must provide a valid username"))
 		return
 	}
 
 	// Verify the username has an underlying profile
 	pubKey, err := fes.getPublicKeyFromUsernameOrPublicKeyString(usernameToVerify)
 	if err != nil {
-		_AddBadRequestError(ww,
+		_AddCodedError(ww, errorCodeOf(err, ErrorCodeInternal),
 			fmt.Sprintf("AdminGrantVerificationBadge: Username %s has no associated underlying publickey.", usernameToVerify))
 		return
 	}
@@ -420,12 +418,12 @@ func (fes *APIServer) AdminGrantVerificationBadge(ww http.ResponseWriter, req *h
 	// Use a utxoView to get the pkid for this pub key.
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGrantVerificationBadge: Problem getting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGrantVerificationBadge: Problem getting utxoView: %v", err))
 		return
 	}
 	pkidEntryToVerify := utxoView.GetPKIDForPublicKey(pubKey)
 	if pkidEntryToVerify == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGrantVerificationBadge: PKID not found for username: %s", usernameToVerify))
+		_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("AdminGrantVerificationBadge: PKID not found for username: %s", usernameToVerify))
 		return
 	}
 
@@ -445,7 +443,7 @@ func (fes *APIServer) AdminGrantVerificationBadge(ww http.ResponseWriter, req *h
 	// Add a new audit log record for this verification request.
 	err = fes.UpdateUsernameVerificationAuditLog(usernameToVerify, pkidEntryToVerify, false, requestData.AdminPublicKey, utxoView)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGrantVerificationBadge: error updating audit log of username verification: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGrantVerificationBadge: error updating audit log of username verification: %v", err))
 		return
 	}
 	// Add username -> PKID mapping
@@ -461,7 +459,7 @@ func (fes *APIServer) AdminGrantVerificationBadge(ww http.ResponseWriter, req *h
 	gob.NewEncoder(metadataDataBuf).Encode(verifiedMapStruct)
 	err = fes.GlobalStatePut(_GlobalStatePrefixForVerifiedMap, metadataDataBuf.Bytes())
 	if err != nil {
-		_AddBadRequestError(ww, "AdminGrantVerificationBadge: Failed placing new verification map into the database.")
+		_AddInternalServerError(ww, "AdminGrantVerificationBadge: Failed placing new verification map into the database.")
 		return
 	}
 
@@ -470,7 +468,7 @@ func (fes *APIServer) AdminGrantVerificationBadge(ww http.ResponseWriter, req *h
 		Message: "Successfully added verification badge for: " + usernameToVerify,
 	}
 	if err = json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"AdminGrantVerificationBadge: Problem encoding response as JSON: %v", err))
 		return
 	}
@@ -497,47 +495,47 @@ func (fes *APIServer) AdminRemoveVerificationBadge(ww http.ResponseWriter, req *
 	requestData := AdminRemoveVerificationBadgeRequest{}
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminRemoveVerificationBadge: Problem parsing request body: %v", err))
 		return
 	}
 	usernameToRemove := requestData.UsernameForWhomToRemoveVerification
 
 	// Verify the username adheres to the consensus username criteria
 	if len(usernameToRemove) == 0 || len(usernameToRemove) > lib.MaxUsernameLengthBytes || !lib.UsernameRegex.Match([]byte(usernameToRemove)) {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Must provide a valid username"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("AdminRemoveVerificationBadge: Must provide a valid username"))
 		return
 	}
 
 	// Verify the username has an underlying profile
 	pubKey, err := fes.getPublicKeyFromUsernameOrPublicKeyString(usernameToRemove)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Username has no associated underlying publickey"))
+		_AddCodedError(ww, errorCodeOf(err, ErrorCodeInternal), fmt.Sprintf("AdminRemoveVerificationBadge: Username has no associated underlying publickey"))
 		return
 	}
 
 	// Use a utxoView to get the pkid for this pub key.
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem getting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem getting utxoView: %v", err))
 		return
 	}
 	pkidEntryToUnverify := utxoView.GetPKIDForPublicKey(pubKey)
 	if pkidEntryToUnverify == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: PKID not found for username: %s", usernameToRemove))
+		_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("AdminRemoveVerificationBadge: PKID not found for username: %s", usernameToRemove))
 		return
 	}
 
 	// Pull the verified map from global state
 	verifiedMapBytes, err := fes.GlobalStateGet(_GlobalStatePrefixForVerifiedMap)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Failed fetching verified map from database."))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Failed fetching verified map from database."))
 		return
 	}
 	verifiedMapStruct := VerifiedUsernameToPKID{}
 	if verifiedMapBytes != nil {
 		err = gob.NewDecoder(bytes.NewReader(verifiedMapBytes)).Decode(&verifiedMapStruct)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Failed decoding verified map from database."))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Failed decoding verified map from database."))
 			return
 		}
 	} else {
@@ -546,7 +544,7 @@ func (fes *APIServer) AdminRemoveVerificationBadge(ww http.ResponseWriter, req *
 			Message: "Couldn't find a verified username map in global state.  Nothing to delete.",
 		}
 		if err := json.NewEncoder(ww).Encode(res); err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem encoding response as "+
+			_AddInternalServerError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem encoding response as "+
 				"JSON: %v", err))
 			return
 		}
@@ -556,7 +554,7 @@ func (fes *APIServer) AdminRemoveVerificationBadge(ww http.ResponseWriter, req *
 	// Add a new audit log for this verification removal request.
 	err = fes.UpdateUsernameVerificationAuditLog(usernameToRemove, pkidEntryToUnverify, true, requestData.AdminPublicKey, utxoView)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: error updating audit log of username verification: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: error updating audit log of username verification: %v", err))
 		return
 	}
 
@@ -568,7 +566,7 @@ func (fes *APIServer) AdminRemoveVerificationBadge(ww http.ResponseWriter, req *
 	gob.NewEncoder(metadataDataBuf).Encode(verifiedMapStruct)
 	err = fes.GlobalStatePut(_GlobalStatePrefixForVerifiedMap, metadataDataBuf.Bytes())
 	if err != nil {
-		_AddBadRequestError(ww, "AdminRemoveVerificationBadge: Failed placing new verification map into the database.")
+		_AddInternalServerError(ww, "AdminRemoveVerificationBadge: Failed placing new verification map into the database.")
 		return
 	}
 
@@ -577,7 +575,7 @@ func (fes *APIServer) AdminRemoveVerificationBadge(ww http.ResponseWriter, req *
 		Message: "Successfully removed verification badge for: " + usernameToRemove,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -597,7 +595,7 @@ func (fes *APIServer) AdminGetVerifiedUsers(ww http.ResponseWriter, req *http.Re
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := AdminGetVerifiedUsersRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetVerifiedUsers: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetVerifiedUsers: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -608,7 +606,7 @@ func (fes *APIServer) AdminGetVerifiedUsers(ww http.ResponseWriter, req *http.Re
 		return
 	}
 	if verifiedMap == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetVerifiedUsers: No verified user map in global state."))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetVerifiedUsers: No verified user map in global state."))
 		return
 	}
 
@@ -620,7 +618,7 @@ func (fes *APIServer) AdminGetVerifiedUsers(ww http.ResponseWriter, req *http.Re
 	// Return a success message
 	res := AdminGetVerifiedUsersResponse{VerifiedUsers: verifiedUsers}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminRemoveVerificationBadge: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -650,14 +648,14 @@ func (fes *APIServer) AdminGetUsernameVerificationAuditLogs(ww http.ResponseWrit
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := AdminGetUsernameVerificationAuditLogsRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetVerifiedUsers: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("AdminGetVerifiedUsers: Problem parsing request body: %v", err))
 		return
 	}
 	// Get the verification audit logs from global state.
 	key := GlobalStateKeyForUsernameVerificationAuditLogs(requestData.Username)
 	verificationUsernameAuditLogBytes, err := fes.GlobalStateGet(key)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetUsernameVerificationAuditLogs: Problem getting audit logs for this username: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetUsernameVerificationAuditLogs: Problem getting audit logs for this username: %v", err))
 		return
 	}
 
@@ -665,7 +663,7 @@ func (fes *APIServer) AdminGetUsernameVerificationAuditLogs(ww http.ResponseWrit
 	if verificationUsernameAuditLogBytes != nil {
 		err = gob.NewDecoder(bytes.NewReader(verificationUsernameAuditLogBytes)).Decode(&verificationAuditLogs)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("AdminGetUsernameVerificationAuditLogs: Problem decoding username verification logs for this user: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("AdminGetUsernameVerificationAuditLogs: Problem decoding username verification logs for this user: %v", err))
 			return
 		}
 	}
@@ -687,7 +685,7 @@ func (fes *APIServer) AdminGetUsernameVerificationAuditLogs(ww http.ResponseWrit
 		VerificationAuditLogs: verificationAuditLogsResponse,
 	}
 	if err = json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetUsernameVerificationAuditLogs: Problem encoding response as JSON: #{err}"))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetUsernameVerificationAuditLogs: Problem encoding response as JSON: #{err}"))
 		return
 	}
 }
@@ -0,0 +1,210 @@
+package routes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// ErrorCode is a stable, machine-readable identifier for the kind of error a
+// route failed with. Clients should branch on the code rather than on the
+// message, which is meant for humans and may change at any time.
+//
+// Codes are part of the API. Don't rename or remove them, and add a status for
+// new ones to errorCodeStatuses.
+type ErrorCode string
+
+const (
+	// The request was malformed in a way not covered by a more specific code.
+	ErrorCodeBadRequest ErrorCode = "BAD_REQUEST"
+	// The request body couldn't be parsed.
+	ErrorCodeInvalidRequestBody ErrorCode = "INVALID_REQUEST_BODY"
+	// A public key in the request couldn't be decoded.
+	ErrorCodeInvalidPublicKey ErrorCode = "INVALID_PUBLIC_KEY"
+	// The JWT was missing or didn't check out against the public key.
+	ErrorCodeInvalidJWT ErrorCode = "INVALID_JWT"
+	// The request wasn't signed, or wasn't signed correctly.
+	ErrorCodeUnauthorized ErrorCode = "UNAUTHORIZED"
+	// The caller isn't allowed to do this, e.g. they aren't an admin.
+	ErrorCodeForbidden       ErrorCode = "FORBIDDEN"
+	ErrorCodeNotFound        ErrorCode = "NOT_FOUND"
+	ErrorCodeProfileNotFound ErrorCode = "PROFILE_NOT_FOUND"
+	ErrorCodePostNotFound    ErrorCode = "POST_NOT_FOUND"
+	// The request conflicts with the current state, e.g. the entry being updated
+	// has changed since it was read.
+	ErrorCodeConflict ErrorCode = "CONFLICT"
+	// The transaction can't be paid for.
+	ErrorCodeInsufficientBalance ErrorCode = "INSUFFICIENT_BALANCE"
+	// The transaction couldn't be created or was rejected by the node.
+	ErrorCodeTransactionRejected ErrorCode = "TRANSACTION_REJECTED"
+	ErrorCodeRateLimited         ErrorCode = "RATE_LIMITED"
+	// The route needs --txindex, which this node wasn't started with.
+	ErrorCodeTxindexDisabled ErrorCode = "TXINDEX_DISABLED"
+	// The route needs something this node isn't configured with, like Twilio
+	// keys or a block producer.
+	ErrorCodeFeatureDisabled ErrorCode = "FEATURE_DISABLED"
+	// The node is still syncing the blockchain or the mempool.
+	ErrorCodeNodeNotSynced ErrorCode = "NODE_NOT_SYNCED"
+	// The node is shutting down. Try another node.
+	ErrorCodeShuttingDown ErrorCode = "SHUTTING_DOWN"
+	// A service the node relies on, like Twilio, Blockonomics or the image
+	// store, failed or couldn't be reached.
+	ErrorCodeUpstream ErrorCode = "UPSTREAM_ERROR"
+	// Something went wrong on the node. Retrying may help.
+	ErrorCodeInternal ErrorCode = "INTERNAL"
+)
+
+// errorCodeStatuses is the HTTP status each code is returned with.
+var errorCodeStatuses = map[ErrorCode]int{
+	ErrorCodeBadRequest:          http.StatusBadRequest,
+	ErrorCodeInvalidRequestBody:  http.StatusBadRequest,
+	ErrorCodeInvalidPublicKey:    http.StatusBadRequest,
+	ErrorCodeInvalidJWT:          http.StatusUnauthorized,
+	ErrorCodeUnauthorized:        http.StatusUnauthorized,
+	ErrorCodeForbidden:           http.StatusForbidden,
+	ErrorCodeNotFound:            http.StatusNotFound,
+	ErrorCodeProfileNotFound:     http.StatusNotFound,
+	ErrorCodePostNotFound:        http.StatusNotFound,
+	ErrorCodeConflict:            http.StatusConflict,
+	ErrorCodeInsufficientBalance: http.StatusBadRequest,
+	ErrorCodeTransactionRejected: http.StatusBadRequest,
+	ErrorCodeRateLimited:         http.StatusTooManyRequests,
+	ErrorCodeTxindexDisabled:     http.StatusServiceUnavailable,
+	ErrorCodeFeatureDisabled:     http.StatusServiceUnavailable,
+	ErrorCodeNodeNotSynced:       http.StatusServiceUnavailable,
+	ErrorCodeShuttingDown:        http.StatusServiceUnavailable,
+	ErrorCodeUpstream:            http.StatusBadGateway,
+	ErrorCodeInternal:            http.StatusInternalServerError,
+}
+
+// Status returns the HTTP status the code is returned with.
+func (code ErrorCode) Status() int {
+	if status, exists := errorCodeStatuses[code]; exists {
+		return status
+	}
+	return http.StatusBadRequest
+}
+
+// errorCodeForStatus picks a code for errors that are only known by their status.
+func errorCodeForStatus(statusCode int) ErrorCode {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return ErrorCodeForbidden
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusConflict:
+		return ErrorCodeConflict
+	case http.StatusTooManyRequests:
+		return ErrorCodeRateLimited
+	case http.StatusBadGateway:
+		return ErrorCodeUpstream
+	case http.StatusServiceUnavailable:
+		return ErrorCodeFeatureDisabled
+	}
+	if statusCode >= http.StatusInternalServerError {
+		return ErrorCodeInternal
+	}
+	return ErrorCodeBadRequest
+}
+
+// insufficientBalanceErrors are the bits of text, lowercased, that the core
+// library's errors use when the transactor can't cover a transaction. Besides
+// the RuleErrors named Insufficient..., picking inputs fails with "not
+// sufficient" and some creator coin checks say there's "not enough".
+var insufficientBalanceErrors = []string{
+	"insufficient",
+	"not sufficient",
+	"not enough",
+}
+
+// transactionErrorCode picks the code for an error creating, connecting or
+// processing a transaction. The core library reports why a transaction was
+// rejected with a RuleError in the error's text, so that's what we look at.
+func transactionErrorCode(err error) ErrorCode {
+	if err == nil {
+		return ErrorCodeTransactionRejected
+	}
+	errorString := strings.ToLower(err.Error())
+	for _, insufficientBalanceError := range insufficientBalanceErrors {
+		if strings.Contains(errorString, insufficientBalanceError) {
+			return ErrorCodeInsufficientBalance
+		}
+	}
+	return ErrorCodeTransactionRejected
+}
+
+// errorCodeOf returns err's code if err is or wraps an *APIError, or defaultCode
+// if it doesn't. Helpers that can fail because of the request as well as because
+// of the node return an *APIError for the former, so that handlers can pass the
+// code on.
+func errorCodeOf(err error, defaultCode ErrorCode) ErrorCode {
+	apiErr := &APIError{}
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return defaultCode
+}
+
+// APIError is the body of every error response. Routes under /api/v0 encode it
+// with lowercase keys, {"error": ..., "code": ..., "details": ...}, which keeps
+// the "error" field the frontend has always read. The exchange API at /api/v1
+// encodes it with Go field names like the rest of its responses, see
+// APIAddCodedError.
+type APIError struct {
+	// A human-readable description of the error.
+	Message string    `json:"error"`
+	Code    ErrorCode `json:"code"`
+	// Anything a client may want to act on besides the code, e.g. how long to
+	// wait before retrying.
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func NewAPIError(code ErrorCode, message string) *APIError {
+	return &APIError{Message: message, Code: code}
+}
+
+func (apiErr *APIError) Error() string {
+	return fmt.Sprintf("%v: %v", apiErr.Code, apiErr.Message)
+}
+
+// WithDetail adds a detail to the error and returns it.
+func (apiErr *APIError) WithDetail(key string, value interface{}) *APIError {
+	if apiErr.Details == nil {
+		apiErr.Details = make(map[string]interface{})
+	}
+	apiErr.Details[key] = value
+	return apiErr
+}
+
+func _AddAPIError(ww http.ResponseWriter, apiErr *APIError) {
+	glog.Error(apiErr.Message)
+	ww.WriteHeader(apiErr.Code.Status())
+	json.NewEncoder(ww).Encode(apiErr)
+}
+
+func _AddCodedError(ww http.ResponseWriter, code ErrorCode, errorString string) {
+	_AddAPIError(ww, NewAPIError(code, errorString))
+}
+
+// exchangeAPIError is how the exchange API encodes an APIError.
+type exchangeAPIError struct {
+	Error   string
+	Code    ErrorCode
+	Details map[string]interface{} `json:",omitempty"`
+}
+
+// APIAddCodedError sets an error response for the exchange API on the
+// ResponseWriter passed in.
+func APIAddCodedError(ww http.ResponseWriter, code ErrorCode, errorString string) {
+	ww.WriteHeader(code.Status())
+	json.NewEncoder(ww).Encode(exchangeAPIError{
+		Error: errorString,
+		Code:  code,
+	})
+}
@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIErrorEnvelopes(t *testing.T) {
+	require := require.New(t)
+
+	decode := func(response *httptest.ResponseRecorder) map[string]interface{} {
+		body := make(map[string]interface{})
+		require.NoError(json.Unmarshal(response.Body.Bytes(), &body))
+		return body
+	}
+
+	// The frontend routes keep their lowercase error field and gain a code.
+	response := httptest.NewRecorder()
+	_AddCodedError(response, ErrorCodeProfileNotFound, "GetSingleProfile: Could not find profile")
+	require.Equal(http.StatusNotFound, response.Code)
+	require.Equal(map[string]interface{}{
+		"error": "GetSingleProfile: Could not find profile",
+		"code":  "PROFILE_NOT_FOUND",
+	}, decode(response))
+
+	// The untyped helpers pick the code from their status.
+	for _, testCase := range []struct {
+		addError func(ww http.ResponseWriter, errorString string)
+		status   int
+		code     ErrorCode
+	}{
+		{_AddNotFoundError, http.StatusNotFound, ErrorCodeNotFound},
+		{_AddInternalServerError, http.StatusInternalServerError, ErrorCodeInternal},
+	} {
+		response = httptest.NewRecorder()
+		testCase.addError(response, "oops")
+		require.Equal(testCase.status, response.Code)
+		require.Equal(string(testCase.code), decode(response)["code"])
+	}
+
+	// Details are only sent when there are some.
+	response = httptest.NewRecorder()
+	_AddAPIError(response, NewAPIError(ErrorCodeRateLimited, "Slow down").WithDetail("RetryAfterSeconds", 3))
+	require.Equal(http.StatusTooManyRequests, response.Code)
+	require.Equal(map[string]interface{}{
+		"error":   "Slow down",
+		"code":    "RATE_LIMITED",
+		"details": map[string]interface{}{"RetryAfterSeconds": float64(3)},
+	}, decode(response))
+
+	// The exchange API uses Go field names.
+	response = httptest.NewRecorder()
+	APIAddCodedError(response, ErrorCodeTxindexDisabled, "APIBase: No txindex")
+	require.Equal(http.StatusServiceUnavailable, response.Code)
+	require.Equal(map[string]interface{}{
+		"Error": "APIBase: No txindex",
+		"Code":  "TXINDEX_DISABLED",
+	}, decode(response))
+	response = httptest.NewRecorder()
+	APIAddError(response, "APIBalance: Bad request")
+	require.Equal(http.StatusBadRequest, response.Code)
+	require.Equal("BAD_REQUEST", decode(response)["Code"])
+}
+
+func TestErrorCodes(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(http.StatusServiceUnavailable, ErrorCodeNodeNotSynced.Status())
+	require.Equal(http.StatusBadRequest, ErrorCode("SOMETHING_NEW").Status())
+	for code, status := range errorCodeStatuses {
+		require.NotEmpty(code)
+		require.True(status >= http.StatusBadRequest, "%v should be an error status", code)
+	}
+
+	require.Equal(ErrorCodeInsufficientBalance, transactionErrorCode(fmt.Errorf(
+		"AddInputsAndChangeToTransaction: RuleErrorInsufficientBalance")))
+	require.Equal(ErrorCodeInsufficientBalance, transactionErrorCode(fmt.Errorf(
+		"AddInputsAndChangeToTransaction: Total input 5 is not sufficient to cover the spend amount")))
+	require.Equal(ErrorCodeTransactionRejected, transactionErrorCode(fmt.Errorf(
+		"ConnectTransaction: RuleErrorPostEntryNotFound")))
+
+	// Helpers that return an *APIError keep its code, however it's wrapped.
+	apiErr := NewAPIError(ErrorCodeProfileNotFound, "No such profile")
+	require.Equal(ErrorCodeProfileNotFound, errorCodeOf(apiErr, ErrorCodeInternal))
+	require.Equal(ErrorCodeProfileNotFound, errorCodeOf(fmt.Errorf("Lookup: %w", apiErr), ErrorCodeInternal))
+	require.Equal(ErrorCodeInternal, errorCodeOf(fmt.Errorf("Lookup: No view"), ErrorCodeInternal))
+}
@@ -15,17 +15,23 @@ func (fes *APIServer) Index(w http.ResponseWriter, r *http.Request) {
 
 // NOTE: This is a readiness check not a health check
 func (fes *APIServer) HealthCheck(ww http.ResponseWriter, rr *http.Request) {
+	// Fail while shutting down so load balancers stop sending us requests.
+	if fes.IsStopping() {
+		_AddCodedError(ww, ErrorCodeShuttingDown, "HealthCheck: Node is shutting down")
+		return
+	}
+
 	// Check that the blockchain is fully current.
 	blockchainHeight := fes.blockchain.BlockTip().Height
 	if fes.blockchain.ChainState() != lib.SyncStateFullyCurrent {
-		_AddBadRequestError(ww, fmt.Sprintf("Waiting for blockchain to sync. "+
+		_AddCodedError(ww, ErrorCodeNodeNotSynced, fmt.Sprintf("Waiting for blockchain to sync. "+
 			"Height: %v, SyncState: %v", blockchainHeight, fes.blockchain.ChainState()))
 		return
 	}
 
 	// Check that we've received our first transaction bundle.
 	if !fes.backendServer.HasProcessedFirstTransactionBundle() {
-		_AddBadRequestError(ww, "Waiting on mempool to sync")
+		_AddCodedError(ww, ErrorCodeNodeNotSynced, "Waiting on mempool to sync")
 		return
 	}
 
@@ -55,7 +61,7 @@ func (fes *APIServer) GetExchangeRate(ww http.ResponseWriter, rr *http.Request)
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetExchangeRate: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetExchangeRate: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -86,7 +92,7 @@ func (fes *APIServer) GetAppState(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetAppStateRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 			"GetAppState: Problem parsing request body: %v", err))
 		return
 	}
@@ -104,7 +110,7 @@ func (fes *APIServer) GetAppState(ww http.ResponseWriter, req *http.Request) {
 	// Get a view with all the mempool transactions (used to get all posts / reader state).
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetAppState: Error getting augmented universal view: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetAppState: Error getting augmented universal view: %v", err))
 		return
 	}
 
@@ -123,7 +129,7 @@ func (fes *APIServer) GetAppState(ww http.ResponseWriter, req *http.Request) {
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetNotifications: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetNotifications: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
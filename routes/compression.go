@@ -0,0 +1,196 @@
+package routes
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/golang/glog"
+)
+
+const (
+	// Responses smaller than this are sent as is since compressing them saves
+	// little and costs a round of encoder setup.
+	compressionMinBytes = 1024
+	// Brotli's default level is too slow for responses that are built on every
+	// request. Level 4 still compresses JSON better than gzip.
+	brotliCompressionLevel = 4
+
+	contentEncodingBrotli = "br"
+	contentEncodingGzip   = "gzip"
+)
+
+// contentEncodings are the encodings Compress supports, most preferred first.
+var contentEncodings = []string{contentEncodingBrotli, contentEncodingGzip}
+
+// resettableWriteCloser is implemented by both gzip.Writer and brotli.Writer, which
+// lets us pool them.
+type resettableWriteCloser interface {
+	io.WriteCloser
+	Reset(ww io.Writer)
+}
+
+var encoderPools = map[string]*sync.Pool{
+	contentEncodingBrotli: {New: func() interface{} {
+		return brotli.NewWriterLevel(nil, brotliCompressionLevel)
+	}},
+	contentEncodingGzip: {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+}
+
+// negotiateContentEncoding returns the encoding from contentEncodings that the
+// Accept-Encoding header prefers, or "" if it doesn't accept any of them. Ties go
+// to the encoding we prefer.
+func negotiateContentEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	wildcardQuality := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		encoding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if encoding == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				parsedQuality, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil {
+					parsedQuality = 0
+				}
+				quality = parsedQuality
+			}
+		}
+		if encoding == "*" {
+			wildcardQuality = quality
+		} else {
+			qualities[encoding] = quality
+		}
+	}
+
+	bestEncoding := ""
+	bestQuality := 0.0
+	for _, encoding := range contentEncodings {
+		quality, exists := qualities[encoding]
+		if !exists {
+			quality = wildcardQuality
+		}
+		if quality > bestQuality {
+			bestEncoding, bestQuality = encoding, quality
+		}
+	}
+	return bestEncoding
+}
+
+// compressingResponseWriter holds on to the start of a response until it knows
+// whether the response is big enough to compress.
+type compressingResponseWriter struct {
+	http.ResponseWriter
+	encoding string
+	// Zero until the handler writes a header or body.
+	status int
+	buf    []byte
+	// Set once the header has been sent, after which writes go straight through,
+	// to encoder if the response is being compressed.
+	flushed bool
+	encoder resettableWriteCloser
+}
+
+func (ww *compressingResponseWriter) WriteHeader(status int) {
+	if ww.status == 0 {
+		ww.status = status
+	}
+}
+
+func (ww *compressingResponseWriter) Write(data []byte) (int, error) {
+	if ww.status == 0 {
+		ww.status = http.StatusOK
+	}
+	if ww.flushed {
+		if ww.encoder != nil {
+			return ww.encoder.Write(data)
+		}
+		return ww.ResponseWriter.Write(data)
+	}
+
+	ww.buf = append(ww.buf, data...)
+	if len(ww.buf) >= compressionMinBytes {
+		if err := ww.flush(true /*compress*/); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+// flush sends the header and whatever has been buffered so far. If compress is
+// true, the body is compressed unless the handler already encoded it.
+func (ww *compressingResponseWriter) flush(compress bool) error {
+	ww.flushed = true
+	if compress && ww.Header().Get("Content-Encoding") == "" {
+		ww.Header().Set("Content-Encoding", ww.encoding)
+		ww.Header().Del("Content-Length")
+		ww.encoder = encoderPools[ww.encoding].Get().(resettableWriteCloser)
+		ww.encoder.Reset(ww.ResponseWriter)
+	}
+	ww.ResponseWriter.WriteHeader(ww.status)
+
+	var err error
+	if len(ww.buf) > 0 {
+		if ww.encoder != nil {
+			_, err = ww.encoder.Write(ww.buf)
+		} else {
+			_, err = ww.ResponseWriter.Write(ww.buf)
+		}
+	}
+	ww.buf = nil
+	return err
+}
+
+// Close sends anything still buffered and finishes the compressed stream.
+func (ww *compressingResponseWriter) Close() error {
+	if ww.status == 0 {
+		return nil
+	}
+	if !ww.flushed {
+		if err := ww.flush(false /*compress*/); err != nil {
+			return err
+		}
+	}
+	if ww.encoder == nil {
+		return nil
+	}
+	err := ww.encoder.Close()
+	encoderPools[ww.encoding].Put(ww.encoder)
+	ww.encoder = nil
+	return err
+}
+
+// Compress compresses responses with brotli or gzip, whichever the client
+// prefers, as long as they're at least compressionMinBytes long.
+func Compress(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		// Caches need to know the response depends on Accept-Encoding even when it
+		// wasn't compressed.
+		ww.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateContentEncoding(req.Header.Get("Accept-Encoding"))
+		if encoding == "" || req.Method == "HEAD" {
+			inner.ServeHTTP(ww, req)
+			return
+		}
+
+		compressor := &compressingResponseWriter{
+			ResponseWriter: ww,
+			encoding:       encoding,
+		}
+		inner.ServeHTTP(compressor, req)
+		if err := compressor.Close(); err != nil {
+			glog.Errorf("Compress: Problem finishing %v response to %v: %v", encoding, req.URL.Path, err)
+		}
+	})
+}
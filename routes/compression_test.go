@@ -0,0 +1,85 @@
+package routes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateContentEncoding(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("", negotiateContentEncoding(""))
+	require.Equal("", negotiateContentEncoding("identity"))
+	require.Equal("gzip", negotiateContentEncoding("gzip, deflate"))
+	require.Equal("br", negotiateContentEncoding("gzip, deflate, br"))
+	require.Equal("gzip", negotiateContentEncoding("br;q=0.5, GZIP"))
+	require.Equal("gzip", negotiateContentEncoding("br;q=0, gzip;q=0.1"))
+	require.Equal("", negotiateContentEncoding("br;q=0, gzip;q=0"))
+	require.Equal("br", negotiateContentEncoding("*"))
+	require.Equal("gzip", negotiateContentEncoding("br;q=0, *"))
+}
+
+func TestCompress(t *testing.T) {
+	require := require.New(t)
+
+	bigBody := strings.Repeat(`{"PostHashHex":"abc"},`, 200)
+	handler := Compress(http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/small" {
+			ww.Write([]byte("{}"))
+			return
+		}
+		if req.URL.Path == "/error" {
+			ww.WriteHeader(http.StatusBadRequest)
+		}
+		// Write the body in pieces to check that buffering doesn't lose any.
+		for ii := 0; ii < len(bigBody); ii += 100 {
+			end := ii + 100
+			if end > len(bigBody) {
+				end = len(bigBody)
+			}
+			ww.Write([]byte(bigBody[ii:end]))
+		}
+	}))
+	call := func(path string, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal("Accept-Encoding", recorder.Header().Get("Vary"))
+		return recorder
+	}
+
+	res := call("/big", "gzip")
+	require.Equal(http.StatusOK, res.Code)
+	require.Equal("gzip", res.Header().Get("Content-Encoding"))
+	require.Less(res.Body.Len(), len(bigBody))
+	gzipReader, err := gzip.NewReader(res.Body)
+	require.NoError(err)
+	decompressed, err := ioutil.ReadAll(gzipReader)
+	require.NoError(err)
+	require.Equal(bigBody, string(decompressed))
+
+	res = call("/error", "br")
+	require.Equal(http.StatusBadRequest, res.Code)
+	require.Equal("br", res.Header().Get("Content-Encoding"))
+	decompressed, err = ioutil.ReadAll(brotli.NewReader(bytes.NewReader(res.Body.Bytes())))
+	require.NoError(err)
+	require.Equal(bigBody, string(decompressed))
+
+	// Small responses and clients that don't accept an encoding we support get
+	// the response as is.
+	res = call("/small", "gzip, br")
+	require.Empty(res.Header().Get("Content-Encoding"))
+	require.Equal("{}", res.Body.String())
+	res = call("/big", "deflate")
+	require.Empty(res.Header().Get("Content-Encoding"))
+	require.Equal(bigBody, res.Body.String())
+}
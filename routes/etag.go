@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// ETagRoutes are the read-only routes that CheckETag serves. Their responses only
+// depend on the request and on the state of the blockchain, the mempool and global
+// state, so the ETag is computed from those without running the handler, and
+// clients polling for data that hasn't changed get a 304 instead.
+//
+// A route can only be listed here if its handler has no side effects, since the
+// handler doesn't run at all for a 304. GetNotifications isn't listed for that
+// reason: it records the newest notification the user has seen.
+var ETagRoutes = map[string]bool{
+	"GetPostsStateless":       true,
+	"GetSinglePost":           true,
+	"GetPostsForPublicKey":    true,
+	"GetProfiles":             true,
+	"GetSingleProfile":        true,
+	"GetDiamondsForPublicKey": true,
+	"GetDiamondedPosts":       true,
+	"GetHodlersForPublicKey":  true,
+	"GetFollowsStateless":     true,
+}
+
+// chainStateVersion returns bytes that change whenever the block tip, the mempool
+// or global state does. It returns nil if any of them can't be versioned, e.g.
+// the node doesn't have a blockchain and mempool, or it proxies global state to
+// a remote node and has fallen behind its change feed.
+func (fes *APIServer) chainStateVersion() []byte {
+	if fes.blockchain == nil || fes.mempool == nil {
+		return nil
+	}
+	versioner, ok := fes.GlobalStore.(globalStateVersioner)
+	if !ok {
+		return nil
+	}
+	globalStateVersion, ok := versioner.globalStateVersion()
+	if !ok {
+		return nil
+	}
+	tipHash := fes.blockchain.BlockTip().Hash
+	version := append([]byte{}, tipHash[:]...)
+	version = append(version, make([]byte, 8)...)
+	binary.BigEndian.PutUint64(version[len(version)-8:], atomic.LoadUint64(&fes.mempoolVersion))
+	return append(version, globalStateVersion...)
+}
+
+// bumpMempoolVersion records that transactions may have entered or left the
+// mempool.
+func (fes *APIServer) bumpMempoolVersion() {
+	atomic.AddUint64(&fes.mempoolVersion, 1)
+}
+
+// computeETag returns a weak ETag for the response to a request with the given
+// query and body made while the chain was at chainStateVersion. It's weak since
+// the same response may be sent compressed or not.
+func computeETag(routeName string, chainStateVersion []byte, rawQuery string, body []byte) string {
+	hasher := sha256.New()
+	for _, part := range [][]byte{[]byte(routeName), chainStateVersion, []byte(rawQuery), body} {
+		// Prefix each part with its length so that parts can't run together.
+		lengthBytes := make([]byte, 8)
+		binary.BigEndian.PutUint64(lengthBytes, uint64(len(part)))
+		hasher.Write(lengthBytes)
+		hasher.Write(part)
+	}
+	return `W/"` + hex.EncodeToString(hasher.Sum(nil)[:16]) + `"`
+}
+
+// etagMatches returns true if the If-None-Match header includes etag. ETags are
+// compared weakly, as RFC 7232 requires for If-None-Match.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// etagResponseWriter drops the ETag from responses that aren't 200s so that
+// errors, which may be temporary, are never revalidated.
+type etagResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (ww *etagResponseWriter) WriteHeader(status int) {
+	if !ww.wroteHeader {
+		ww.wroteHeader = true
+		if status != http.StatusOK {
+			ww.Header().Del("ETag")
+			ww.Header().Del("Cache-Control")
+		}
+	}
+	ww.ResponseWriter.WriteHeader(status)
+}
+
+func (ww *etagResponseWriter) Write(data []byte) (int, error) {
+	if !ww.wroteHeader {
+		ww.WriteHeader(http.StatusOK)
+	}
+	return ww.ResponseWriter.Write(data)
+}
+
+// CheckETag sets an ETag on responses from one of ETagRoutes, and responds with
+// 304 Not Modified without calling the handler if the request's If-None-Match
+// header already has it.
+func (fes *APIServer) CheckETag(inner http.Handler, routeName string) http.Handler {
+	return http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		chainStateVersion := fes.chainStateVersion()
+		if chainStateVersion == nil {
+			inner.ServeHTTP(ww, req)
+			return
+		}
+
+		// Hold on to the body since the handler will consume it.
+		var bodyBytes []byte
+		if req.Body != nil {
+			var err error
+			bodyBytes, err = ioutil.ReadAll(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+			if err != nil {
+				_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("CheckETag: Problem reading request body: %v", err))
+				return
+			}
+			req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+		}
+
+		etag := computeETag(routeName, chainStateVersion, req.URL.RawQuery, bodyBytes)
+		ww.Header().Set("ETag", etag)
+		// Clients may keep the response but have to check that it's still current
+		// before using it again.
+		ww.Header().Set("Cache-Control", "no-cache")
+		if etagMatches(req.Header.Get("If-None-Match"), etag) {
+			ww.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		inner.ServeHTTP(&etagResponseWriter{ResponseWriter: ww}, req)
+	})
+}
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitclout/core/lib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckETag(t *testing.T) {
+	require := require.New(t)
+
+	fes, _, miner := newTestAPIServer(t, "" /*globalStateRemoteNode*/)
+	numCalls := 0
+	handler := fes.CheckETag(http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		numCalls++
+		if strings.Contains(req.URL.RawQuery, "fail") {
+			_AddCodedError(ww, ErrorCodeBadRequest, "failed")
+			return
+		}
+		ww.Write([]byte("{}"))
+	}), "GetPostsStateless")
+	call := func(query string, body string, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/?"+query, strings.NewReader(body))
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	res := call("", `{"NumToFetch":10}`, "")
+	require.Equal(http.StatusOK, res.Code)
+	etag := res.Header().Get("ETag")
+	require.True(strings.HasPrefix(etag, `W/"`))
+	require.Equal(1, numCalls)
+
+	// Asking again with the ETag skips the handler.
+	res = call("", `{"NumToFetch":10}`, `"other", `+etag)
+	require.Equal(http.StatusNotModified, res.Code)
+	require.Empty(res.Body.String())
+	require.Equal(etag, res.Header().Get("ETag"))
+	require.Equal(1, numCalls)
+
+	// Other requests have other ETags.
+	res = call("", `{"NumToFetch":20}`, etag)
+	require.Equal(http.StatusOK, res.Code)
+	require.NotEqual(etag, res.Header().Get("ETag"))
+
+	// Errors don't get an ETag.
+	res = call("fail", `{"NumToFetch":10}`, "")
+	require.Equal(http.StatusBadRequest, res.Code)
+	require.Empty(res.Header().Get("ETag"))
+
+	// A new block changes the ETag.
+	_, err := miner.MineAndProcessSingleBlock(0 /*threadIndex*/, fes.mempool)
+	require.NoError(err)
+	res = call("", `{"NumToFetch":10}`, etag)
+	require.Equal(http.StatusOK, res.Code)
+	require.NotEqual(etag, res.Header().Get("ETag"))
+	etag = res.Header().Get("ETag")
+
+	// So does a transaction entering the mempool.
+	fes.HandleTransactionConnected(nil)
+	res = call("", `{"NumToFetch":10}`, etag)
+	require.Equal(http.StatusOK, res.Code)
+	require.NotEqual(etag, res.Header().Get("ETag"))
+	etag = res.Header().Get("ETag")
+
+	// And so does any change to global state, like a profile being blacklisted.
+	require.NoError(fes.GlobalStatePut(GlobalStateKeyForBlacklistedProfile(
+		lib.MustBase58CheckDecode(senderPkString)), lib.IsBlacklisted))
+	res = call("", `{"NumToFetch":10}`, etag)
+	require.Equal(http.StatusOK, res.Code)
+	require.NotEqual(etag, res.Header().Get("ETag"))
+	etag = res.Header().Get("ETag")
+
+	// Without a global state version there's no ETag at all.
+	fes.GlobalStore = NewMemoryGlobalStore()
+	res = call("", `{"NumToFetch":10}`, etag)
+	require.Equal(http.StatusOK, res.Code)
+	require.Empty(res.Header().Get("ETag"))
+}
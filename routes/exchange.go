@@ -15,7 +15,6 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/btcsuite/btcd/btcec"
-	"github.com/dgraph-io/badger/v3"
 	"github.com/golang/glog"
 	bip39 "github.com/tyler-smith/go-bip39"
 )
@@ -51,62 +50,60 @@ func (fes *APIServer) APIRoutes() []Route {
 			[]string{"GET"},
 			RoutePathAPIBase,
 			fes.APIBase,
-			false, // CheckSecret
+			AdminRoleNone,
 		},
 		Route{
 			"APIKeyPair",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAPIKeyPair,
 			fes.APIKeyPair,
-			false, // CheckSecret
+			AdminRoleNone,
 		},
 		Route{
 			"APIBalance",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAPIBalance,
 			fes.APIBalance,
-			false, // CheckSecret
+			AdminRoleNone,
 		},
 		Route{
 			"APITransferBitClout",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAPITransferBitClout,
 			fes.APITransferBitClout,
-			false, // CheckSecret
+			AdminRoleNone,
 		},
 		Route{
 			"APITransactionInfo",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAPITransactionInfo,
 			fes.APITransactionInfo,
-			false, // CheckSecret
+			AdminRoleNone,
 		},
 		Route{
 			"APINodeInfo",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAPINodeInfo,
 			fes.APINodeInfo,
-			true, // CheckSecret
+			AdminRoleNodeOperator,
 		},
 		Route{
 			"APIBlock",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAPIBlock,
 			fes.APIBlock,
-			false, // CheckSecret
+			AdminRoleNone,
 		},
 	}
 
 	return APIRoutes
 }
 
-// APIAddError sets an error response on the ResponseWriter passed in.
+// APIAddError sets a BAD_REQUEST error response on the ResponseWriter passed in.
+// It's only for requests that are malformed. Anything else, including failures
+// on the node's side, should use APIAddCodedError with a code of its own.
 func APIAddError(ww http.ResponseWriter, errorString string) {
-	//glog.Error(errorString)
-	ww.WriteHeader(http.StatusBadRequest)
-	json.NewEncoder(ww).Encode(struct {
-		Error string
-	}{Error: errorString})
+	APIAddCodedError(ww, ErrorCodeBadRequest, errorString)
 }
 
 // APIBaseResponse ...
@@ -137,7 +134,7 @@ func _headerToResponse(header *lib.MsgBitCloutHeader, hash string) *HeaderRespon
 // APIBase is an endpoint that simply confirms that the API is up and running.
 func (fes *APIServer) APIBase(ww http.ResponseWriter, rr *http.Request) {
 	if fes.TxIndexChain == nil {
-		APIAddError(ww, fmt.Sprintf("APIBase: Cannot be called when TxIndexChain "+
+		APIAddCodedError(ww, ErrorCodeTxindexDisabled, fmt.Sprintf("APIBase: Cannot be called when TxIndexChain "+
 			"is nil. This error occurs when --txindex was not passed to the program on startup"))
 		return
 	}
@@ -147,11 +144,11 @@ func (fes *APIServer) APIBase(ww http.ResponseWriter, rr *http.Request) {
 	// Take the hash computed from above and find the corresponding block.
 	blockMsg, err := lib.GetBlock(blockNode.Hash, fes.blockchain.DB())
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBase: Problem fetching block: %v", err))
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APIBase: Problem fetching block: %v", err))
 		return
 	}
 	if blockMsg == nil {
-		APIAddError(ww, fmt.Sprintf("APIBase: Block with hash %v not found", blockNode.Hash))
+		APIAddCodedError(ww, ErrorCodeNotFound, fmt.Sprintf("APIBase: Block with hash %v not found", blockNode.Hash))
 		return
 	}
 
@@ -168,7 +165,7 @@ func (fes *APIServer) APIBase(ww http.ResponseWriter, rr *http.Request) {
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBaseResponse: Problem encoding response "+
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APIBaseResponse: Problem encoding response "+
 			"as JSON: %v", err))
 		return
 	}
@@ -245,7 +242,7 @@ func (fes *APIServer) APIKeyPair(ww http.ResponseWriter, rr *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	apiKeyPairRequest := APIKeyPairRequest{}
 	if err := decoder.Decode(&apiKeyPairRequest); err != nil {
-		APIAddError(ww, fmt.Sprintf("APIKeyPair: Problem parsing request body: %v", err))
+		APIAddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("APIKeyPair: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -269,7 +266,7 @@ func (fes *APIServer) APIKeyPair(ww http.ResponseWriter, rr *http.Request) {
 		PrivateKeyHex:         hex.EncodeToString(privKey.Serialize()),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		APIAddError(ww, fmt.Sprintf("APIKeyPair: Problem encoding response as JSON: %v", err))
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APIKeyPair: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -336,7 +333,7 @@ func (fes *APIServer) APIBalance(ww http.ResponseWriter, rr *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	balanceRequest := APIBalanceRequest{}
 	if err := decoder.Decode(&balanceRequest); err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBalanceRequest: Problem parsing request body: %v", err))
+		APIAddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("APIBalanceRequest: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -349,19 +346,19 @@ func (fes *APIServer) APIBalance(ww http.ResponseWriter, rr *http.Request) {
 	// Parse the public key into bytes.
 	publicKeyBytes, _, err := lib.Base58CheckDecode(balanceRequest.PublicKeyBase58Check)
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBalanceRequest: Problem parsing request body: %v", err))
+		APIAddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("APIBalanceRequest: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Get all the UTXOs for the public key.
 	utxoView, err := fes.mempool.GetAugmentedUtxoViewForPublicKey(publicKeyBytes, nil)
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBalanceRequest: Problem getting UTXOs for public key: %v", err))
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APIBalanceRequest: Problem getting UTXOs for public key: %v", err))
 		return
 	}
 	utxoEntries, err := utxoView.GetUnspentUtxoEntrysForPublicKey(publicKeyBytes)
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBalanceRequest: Problem getting UTXO entries for public key: %v", err))
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APIBalanceRequest: Problem getting UTXO entries for public key: %v", err))
 		return
 	}
 
@@ -399,7 +396,7 @@ func (fes *APIServer) APIBalance(ww http.ResponseWriter, rr *http.Request) {
 		})
 	}
 	if err := json.NewEncoder(ww).Encode(balanceResponse); err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBalance: Problem encoding response as JSON: %v", err))
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APIBalance: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -563,7 +560,7 @@ func (fes *APIServer) APITransferBitClout(ww http.ResponseWriter, rr *http.Reque
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	transferBitCloutRequest := APITransferBitCloutRequest{}
 	if err := decoder.Decode(&transferBitCloutRequest); err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransferBitClout: Problem parsing request body: %v", err))
+		APIAddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("APITransferBitClout: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -591,13 +588,13 @@ func (fes *APIServer) APITransferBitClout(ww http.ResponseWriter, rr *http.Reque
 	recipientPubBytes, _, err := lib.Base58CheckDecode(
 		transferBitCloutRequest.RecipientPublicKeyBase58Check)
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransferBitClout: Problem decoding recipient "+
+		APIAddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("APITransferBitClout: Problem decoding recipient "+
 			"base58 public key %s: %v", transferBitCloutRequest.RecipientPublicKeyBase58Check, err))
 		return
 	}
 	recipientPub, err := btcec.ParsePubKey(recipientPubBytes, btcec.S256())
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransferBitClout: Problem encoding recipient "+
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransferBitClout: Problem encoding recipient "+
 			"base58 public key %s: %v", transferBitCloutRequest.RecipientPublicKeyBase58Check, err))
 		return
 	}
@@ -626,14 +623,14 @@ func (fes *APIServer) APITransferBitClout(ww http.ResponseWriter, rr *http.Reque
 			uint64(minFeeRateNanosPerKB),
 			fes.backendServer.GetMempool())
 		if err != nil {
-			APIAddError(ww, fmt.Sprintf("APITransferBitClout: Error processing MAX transaction: %v", err))
+			APIAddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("APITransferBitClout: Error processing MAX transaction: %v", err))
 			return
 		}
 
 		// Sanity check that the input is equal to:
 		//   (spend amount + change amount + fees)
 		if totalInputt != (spendAmountt + changeAmountt + feeNanoss) {
-			APIAddError(ww, fmt.Sprintf("APITransferBitClout: totalInput=%d is not equal "+
+			APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransferBitClout: totalInput=%d is not equal "+
 				"to the sum of the (spend amount=%d, change=%d, and fees=%d) which sums "+
 				"to %d. This means there was likely a problem with CreateMaxSpend",
 				totalInputt, spendAmountt, changeAmountt, feeNanoss, (spendAmountt+changeAmountt+feeNanoss)))
@@ -644,7 +641,7 @@ func (fes *APIServer) APITransferBitClout(ww http.ResponseWriter, rr *http.Reque
 		// sign/validate/broadcast it.
 		err = fes._processTransactionWithKey(txnn, senderPriv, shouldBroadcast)
 		if err != nil {
-			APIAddError(ww, fmt.Sprintf("APITransferBitClout: Problem processing transaction: %v", err))
+			APIAddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("APITransferBitClout: Problem processing transaction: %v", err))
 			return
 		}
 
@@ -681,7 +678,7 @@ func (fes *APIServer) APITransferBitClout(ww http.ResponseWriter, rr *http.Reque
 			0, /*inputSubsidy*/
 			shouldBroadcast)
 		if err != nil {
-			APIAddError(ww, fmt.Sprintf("APITransferBitClout: Error processing regular transaction: %v", err))
+			APIAddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("APITransferBitClout: Error processing regular transaction: %v", err))
 			return
 		}
 	}
@@ -703,7 +700,7 @@ func (fes *APIServer) APITransferBitClout(ww http.ResponseWriter, rr *http.Reque
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransferBitClout: Problem encoding response as JSON: %v", err))
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransferBitClout: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -723,12 +720,22 @@ func (fes *APIServer) GetTxindexUpdateBlockNodes() (
 			"happen and it means the transaction index is broken.")
 		return nil, nil, nil, nil, nil
 	}
-	// If the tip of the txindex is no longer stored in the block index, it
-	// means the txindex hit a fork that we are no longer keeping track of.
-	// The only thing we can really do in this case is rebuild the entire index
-	// from scratch. To do that, we return all the blocks in the index to detach
-	// and all the blocks in the real chain to attach.
-	txindexTipNode := fes.blockchain.CopyBlockIndex()[*txindexTipHash.Hash]
+	// The txindex tip is almost always on the main chain, so look for it there
+	// before copying the whole block index.
+	var txindexTipNode *lib.BlockNode
+	bestChain := fes.blockchain.BestChain()
+	if int(txindexTipHash.Height) < len(bestChain) &&
+		*bestChain[txindexTipHash.Height].Hash == *txindexTipHash.Hash {
+
+		txindexTipNode = bestChain[txindexTipHash.Height]
+	} else {
+		// If the tip of the txindex is no longer stored in the block index, it
+		// means the txindex hit a fork that we are no longer keeping track of.
+		// The only thing we can really do in this case is rebuild the entire index
+		// from scratch. To do that, we return all the blocks in the index to detach
+		// and all the blocks in the real chain to attach.
+		txindexTipNode = fes.blockchain.CopyBlockIndex()[*txindexTipHash.Hash]
+	}
 
 	if txindexTipNode == nil {
 		glog.Info("GetTxindexUpdateBlockNodes: Txindex tip was not found in block " +
@@ -758,6 +765,13 @@ func (fes *APIServer) GetTxindexUpdateBlockNodes() (
 // cleaner, but this code works and passes all the tests so I'm leaving it as-is for now.
 // If we ever have issues with the block explorer we should kill this code probably.
 func (fes *APIServer) UpdateTxindex() error {
+	return fes.updateTxindex(0)
+}
+
+// updateTxindex is UpdateTxindex but attaches at most maxBlocksToAttach blocks,
+// or all of them if maxBlocksToAttach is zero. Blocks to detach are always all
+// detached.
+func (fes *APIServer) updateTxindex(maxBlocksToAttach int) error {
 	// If we don't have a chain set, return an error.
 	if fes.TxIndexChain == nil {
 		return fmt.Errorf("UpdateTxindex: Cannot be called when TxIndexChain " +
@@ -769,6 +783,12 @@ func (fes *APIServer) UpdateTxindex() error {
 	// done with the rest of the function.
 	fes.TxIndexLock.Lock()
 	defer fes.TxIndexLock.Unlock()
+
+	// Finish off a block that was being attached when the last update failed or
+	// the node went down, before looking at where the txindex tip is.
+	if err := fes.recoverTxindexPendingBlock(); err != nil {
+		return fmt.Errorf("UpdateTxindex: %v", err)
+	}
 	txindexTipNode, blockTipNode, commonAncestor, detachBlocks, attachBlocks := fes.GetTxindexUpdateBlockNodes()
 
 	// Note that the blockchain's ChainLock does not need to be held at this
@@ -789,6 +809,10 @@ func (fes *APIServer) UpdateTxindex() error {
 		return nil
 	}
 
+	if maxBlocksToAttach > 0 && len(attachBlocks) > maxBlocksToAttach {
+		attachBlocks = attachBlocks[:maxBlocksToAttach]
+	}
+
 	// When the txindex tip does not match the block tip then there's work
 	// to do. Log at the info level.
 	glog.Infof("UpdateTxindex: Updating txindex tip (height: %d, hash: %v) "+
@@ -798,99 +822,25 @@ func (fes *APIServer) UpdateTxindex() error {
 
 	// For each of the blocks we're removing, delete the transactions from
 	// the transaction index.
-	for _, blockToDetach := range detachBlocks {
-		// Go through each txn in the block and delete its mappings from our
-		// txindex.
-		glog.Debugf("UpdateTxindex: Detaching block (height: %d, hash: %v)",
-			blockToDetach.Height, blockToDetach.Hash)
-		blockMsg, err := lib.GetBlock(blockToDetach.Hash, fes.TxIndexChain.DB())
-		if err != nil {
-			return fmt.Errorf("UpdateTxindex: Problem fetching detach block "+
-				"with hash %v: %v", blockToDetach.Hash, err)
-		}
-		// Iterate through each transaction in the block and delete all its
-		// mappings from the db. Note the txindex has its own db that is
-		// distinct and isolated from our core blockchain db.
-		for _, txn := range blockMsg.Txns {
-			if err := lib.DbDeleteTxindexTransactionMappings(
-				fes.TxIndexChain.DB(), txn, fes.Params); err != nil {
-
-				return fmt.Errorf("UpdateTxindex: Problem deleting "+
-					"transaction mappings for transaction %v: %v", txn.Hash(), err)
-			}
-		}
-
-		// Now that all the transactions have been deleted from our txindex,
-		// it's safe to disconnect the block from our txindex chain.
-		//
-		// Only set a BitcoinManager if we have one. This prevents some tests from erroring out.
-		var bitcoinManager *lib.BitcoinManager
-		if fes.backendServer != nil && fes.backendServer.GetBitcoinManager() != nil {
-			bitcoinManager = fes.backendServer.GetBitcoinManager()
-		}
-		utxoView, err := lib.NewUtxoView(
-			fes.TxIndexChain.DB(), fes.Params, bitcoinManager)
-		if err != nil {
-			return fmt.Errorf(
-				"UpdateTxindex: Error initializing UtxoView: %v", err)
-		}
-		utxoOps, err := lib.GetUtxoOperationsForBlock(
-			fes.TxIndexChain.DB(), blockToDetach.Hash)
-		if err != nil {
-			return fmt.Errorf(
-				"UpdateTxindex: Error getting UtxoOps for block %v: %v", blockToDetach, err)
-		}
-		// Compute the hashes for all the transactions.
-		txHashes, err := lib.ComputeTransactionHashes(blockMsg.Txns)
-		if err != nil {
-			return fmt.Errorf(
-				"UpdateTxindex: Error computing tx hashes for block %v: %v",
-				blockToDetach, err)
-		}
-		if err := utxoView.DisconnectBlock(blockMsg, txHashes, utxoOps); err != nil {
-			return fmt.Errorf("UpdateTxindex: Error detaching block "+
-				"%v from UtxoView: %v", blockToDetach, err)
-		}
-		if err := utxoView.FlushToDb(); err != nil {
-			return fmt.Errorf("UpdateTxindex: Error flushing view to db for block "+
-				"%v: %v", blockToDetach, err)
-		}
-		// We have to flush a couple of extra things that the view doesn't flush...
-		if err := lib.PutBestHash(utxoView.TipHash, fes.TxIndexChain.DB(), lib.ChainTypeBitCloutBlock); err != nil {
-			return fmt.Errorf("UpdateTxindex: Error putting best hash for block "+
-				"%v: %v", blockToDetach, err)
-		}
-		err = fes.TxIndexChain.DB().Update(func(txn *badger.Txn) error {
-			if err := lib.DeleteUtxoOperationsForBlockWithTxn(txn, blockToDetach.Hash); err != nil {
-				return fmt.Errorf("UpdateTxindex: Error deleting UtxoOperations 1 for block %v, %v", blockToDetach.Hash, err)
-			}
-			if err := txn.Delete(lib.BlockHashToBlockKey(blockToDetach.Hash)); err != nil {
-				return fmt.Errorf("UpdateTxindex: Error deleting UtxoOperations 2 for block %v %v", blockToDetach.Hash, err)
-			}
-			return nil
-		})
-		if err != nil {
-			return fmt.Errorf("UpdateTxindex: Error updating badgger: %v", err)
-		}
-		// Delete this block from the chain db so we don't get duplicate block errors.
-
-		// Remove this block from our bestChain data structures.
-		newBlockIndex := fes.TxIndexChain.CopyBlockIndex()
-		newBestChain, newBestChainMap := fes.TxIndexChain.CopyBestChain()
-		newBestChain = newBestChain[:len(newBestChain)-1]
-		delete(newBestChainMap, *(blockToDetach.Hash))
-		delete(newBlockIndex, *(blockToDetach.Hash))
-
-		fes.TxIndexChain.SetBestChainMap(newBestChain, newBestChainMap, newBlockIndex)
-
-		// At this point the entries for the block should have been removed
-		// from both our Txindex chain and our transaction index mappings.
+	// The txindex tip is saved with every block, so it's safe to stop partway and
+	// pick up where we left off on the next start.
+	numDetached, err := fes.detachTxindexBlocks(detachBlocks, nil /*progress*/)
+	if err != nil {
+		return fmt.Errorf("UpdateTxindex: %v", err)
+	}
+	if numDetached < len(detachBlocks) {
+		return nil
 	}
 
 	// For each of the blocks we're adding, process them on our txindex chain
 	// and add their mappings to our txn index. Compute any metadata that might
 	// be useful.
 	for _, blockToAttach := range attachBlocks {
+		if fes.IsStopping() {
+			glog.Infof("UpdateTxindex: Stopping before attaching block %d because the "+
+				"APIServer is stopping", blockToAttach.Height)
+			return nil
+		}
 		if blockToAttach.Height%100 == 0 {
 			glog.Infof("UpdateTxindex: Txindex progress: block %d / %d",
 				blockToAttach.Height, blockTipNode.Height)
@@ -898,59 +848,10 @@ func (fes *APIServer) UpdateTxindex() error {
 		glog.Tracef("UpdateTxindex: Attaching block (height: %d, hash: %v)",
 			blockToAttach.Height, blockToAttach.Hash)
 
-		blockMsg, err := lib.GetBlock(blockToAttach.Hash, fes.blockchain.DB())
-		if err != nil {
-			return fmt.Errorf("UpdateTxindex: Problem fetching attach block "+
-				"with hash %v: %v", blockToAttach.Hash, err)
-		}
-
-		// We use a view to simulate adding transactions to our chain. This allows
-		// us to extract custom metadata fields that we can show in our block explorer.
-		//
-		// Only set a BitcoinManager if we have one. This makes some tests pass.
-		var bitcoinManager *lib.BitcoinManager
-		if fes.backendServer != nil && fes.backendServer.GetBitcoinManager() != nil {
-			bitcoinManager = fes.backendServer.GetBitcoinManager()
-		}
-		utxoView, err := lib.NewUtxoView(fes.TxIndexChain.DB(), fes.Params, bitcoinManager)
-		if err != nil {
-			return fmt.Errorf(
-				"UpdateTxindex: Error initializing UtxoView: %v", err)
-		}
-
-		// Do each block update in a single transaction so we're safe in case the node
-		// restarts.
-		fes.TxIndexChain.DB().Update(func(dbTxn *badger.Txn) error {
-
-			// Iterate through each transaction in the block and do the following:
-			// - Connect it to the view
-			// - Compute its mapping values, which may include custom metadata fields
-			// - add all its mappings to the db.
-			for txnIndexInBlock, txn := range blockMsg.Txns {
-				txnMeta, err := lib.ConnectTxnAndComputeTransactionMetadata(
-					txn, utxoView, blockToAttach.Hash, blockToAttach.Height, uint64(txnIndexInBlock))
-				if err != nil {
-					return fmt.Errorf("UpdateTxindex: Problem connecting txn %v to txindex: %v",
-						txn, err)
-				}
-
-				err = lib.DbPutTxindexTransactionMappingsWithTxn(dbTxn, txn, fes.Params, txnMeta)
-				if err != nil {
-					return fmt.Errorf("UpdateTxindex: Problem adding txn %v to txindex: %v",
-						txn, err)
-				}
-			}
-
-			return nil
-		})
-
-		// Now that we have added all the txns to our TxIndex db, attach the block
-		// to update our chain.
-		_, _, err = fes.TxIndexChain.ProcessBlock(blockMsg, false /*verifySignatures*/)
-		if err != nil {
-			return fmt.Errorf("UpdateTxindex: Problem attaching block %v: %v",
-				blockToAttach, err)
+		if err := fes.attachTxindexBlock(blockToAttach); err != nil {
+			return fmt.Errorf("UpdateTxindex: %v", err)
 		}
+		fes.txindexRebuild.recordHeight(blockToAttach.Height, blockTipNode.Height)
 	}
 
 	glog.Infof("UpdateTxindex: Txindex update complete. New tip: (height: %d, hash: %v)",
@@ -1009,7 +910,7 @@ type APITransactionInfoResponse struct {
 func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Request) {
 	// If the --txindex flag hasn't been passed to the node, return an error outright.
 	if fes.TxIndexChain == nil {
-		APIAddError(ww, fmt.Sprintf("APITransactionInfo: This function cannot be "+
+		APIAddCodedError(ww, ErrorCodeTxindexDisabled, fmt.Sprintf("APITransactionInfo: This function cannot be "+
 			"called without passing --txindex to the node on startup."))
 		return
 	}
@@ -1018,7 +919,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	transactionInfoRequest := APITransactionInfoRequest{}
 	if err := decoder.Decode(&transactionInfoRequest); err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransactionInfo: Problem parsing request body: %v", err))
+		APIAddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("APITransactionInfo: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -1029,7 +930,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 		// Get all the txns from the mempool.
 		poolTxns, _, err := fes.mempool.GetTransactionsOrderedByTimeAdded()
 		if err != nil {
-			APIAddError(ww, fmt.Sprintf("APITransactionInfo: Error getting txns from mempool "+
+			APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransactionInfo: Error getting txns from mempool "+
 				"for mempool request: %v", err))
 			return
 		}
@@ -1043,7 +944,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 		utxoView, err := lib.NewUtxoView(
 			fes.TxIndexChain.DB(), fes.Params, bitcoinManager)
 		if err != nil {
-			APIAddError(ww, fmt.Sprintf("UpdateTxindex: Error initializing UtxoView "+
+			APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("UpdateTxindex: Error initializing UtxoView "+
 				"for mempool request: %v", err))
 			return
 		}
@@ -1056,7 +957,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 				poolTx.Tx, utxoView, &lib.BlockHash{} /*Block hash*/, nextBlockHeight,
 				uint64(0) /*txnIndexInBlock*/)
 			if err != nil {
-				APIAddError(ww, fmt.Sprintf("UpdateTxindex: Error connecting "+
+				APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("UpdateTxindex: Error connecting "+
 					"txn for mempool request: %v: %v", poolTx.Tx, err))
 				return
 			}
@@ -1066,7 +967,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 
 		// At this point, all the transactions should have been added to the request.
 		if err := json.NewEncoder(ww).Encode(res); err != nil {
-			APIAddError(ww, fmt.Sprintf("APITransactionInfo: Problem encoding response "+
+			APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransactionInfo: Problem encoding response "+
 				"as JSON: %v", err))
 			return
 		}
@@ -1102,7 +1003,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 			// Try to look the transaction up in the mempool before giving up.
 			txnInPool := fes.mempool.GetTransaction(txID)
 			if txnInPool == nil {
-				APIAddError(ww, fmt.Sprintf("APITransactionInfo: Could not find "+
+				APIAddCodedError(ww, ErrorCodeNotFound, fmt.Sprintf("APITransactionInfo: Could not find "+
 					"transaction with TransactionIDBase58Check = %s",
 					transactionInfoRequest.TransactionIDBase58Check))
 				return
@@ -1112,7 +1013,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 			// Get all the txns from the mempool.
 			poolTxns, _, err := fes.mempool.GetTransactionsOrderedByTimeAdded()
 			if err != nil {
-				APIAddError(ww, fmt.Sprintf("APITransactionInfo: Error getting txns from mempool: %v", err))
+				APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransactionInfo: Error getting txns from mempool: %v", err))
 				return
 			}
 			// Set up a view to apply txns to.
@@ -1123,7 +1024,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 			}
 			utxoView, err := lib.NewUtxoView(fes.TxIndexChain.DB(), fes.Params, bitcoinManager)
 			if err != nil {
-				APIAddError(ww, fmt.Sprintf("UpdateTxindex: Error initializing UtxoView: %v", err))
+				APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("UpdateTxindex: Error initializing UtxoView: %v", err))
 				return
 			}
 			// Connect all txns in the mempool up to the current one we want info on.
@@ -1135,7 +1036,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 				_, err := lib.ConnectTxnAndComputeTransactionMetadata(
 					poolTx.Tx, utxoView, &lib.BlockHash{} /*Block hash*/, nextBlockHeight, uint64(0) /*txnIndexInBlock*/)
 				if err != nil {
-					APIAddError(ww, fmt.Sprintf("UpdateTxindex: Error connecting txn: %v: %v", txn, err))
+					APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("UpdateTxindex: Error connecting txn: %v: %v", txn, err))
 					return
 				}
 			}
@@ -1144,7 +1045,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 			txnMeta, err = lib.ConnectTxnAndComputeTransactionMetadata(
 				txn, utxoView, &lib.BlockHash{} /*Block hash*/, nextBlockHeight, uint64(0) /*txnIndexInBlock*/)
 			if err != nil {
-				APIAddError(ww, fmt.Sprintf("UpdateTxindex: Error connecting MAIN txn: %v: %v", txn, err))
+				APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("UpdateTxindex: Error connecting MAIN txn: %v: %v", txn, err))
 				return
 			}
 		}
@@ -1155,7 +1056,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 		}
 
 		if err := json.NewEncoder(ww).Encode(res); err != nil {
-			APIAddError(ww, fmt.Sprintf("APITransactionInfo: Problem encoding response "+
+			APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransactionInfo: Problem encoding response "+
 				"as JSON: %v", err))
 			return
 		}
@@ -1170,7 +1071,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 	publicKeyBytes, _, err := lib.Base58CheckDecode(
 		transactionInfoRequest.PublicKeyBase58Check)
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransactionInfo: Problem parsing "+
+		APIAddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("APITransactionInfo: Problem parsing "+
 			"PublicKeyBase58Check: %v", err))
 		return
 	}
@@ -1180,7 +1081,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 	if fes.blockchain != nil && fes.backendServer != nil {
 		utxoEntries, err := fes.blockchain.GetSpendableUtxosForPublicKey(publicKeyBytes, fes.backendServer.GetMempool(), nil)
 		if err != nil {
-			APIAddError(ww, fmt.Sprintf(
+			APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf(
 				"APITransactionInfo: Problem getting utxos from view: %v", err))
 			return
 		}
@@ -1204,7 +1105,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 		fullTxn, txnMeta := lib.DbGetTxindexFullTransactionByTxID(
 			fes.TxIndexChain.DB(), fes.blockchain.DB(), txHash)
 		if fullTxn == nil || txnMeta == nil {
-			APIAddError(ww, fmt.Sprintf("APITransactionInfo: Problem looking up "+
+			APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransactionInfo: Problem looking up "+
 				"transaction with TxID: %v; this should never happen", txIDString))
 			return
 		}
@@ -1215,7 +1116,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 	// Get all the txns from the mempool.
 	poolTxns, _, err := fes.mempool.GetTransactionsOrderedByTimeAdded()
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransactionInfo: Error getting txns from mempool: %v", err))
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransactionInfo: Error getting txns from mempool: %v", err))
 		return
 	}
 	// Set up a view to apply txns to.
@@ -1228,7 +1129,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 	utxoView, err := lib.NewUtxoView(
 		fes.TxIndexChain.DB(), fes.Params, bitcoinManager)
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("UpdateTxindex: Error initializing UtxoView: %v", err))
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("UpdateTxindex: Error initializing UtxoView: %v", err))
 		return
 	}
 	// Look up all the transactions for the public key from the mempool.
@@ -1240,7 +1141,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 			poolTx.Tx, utxoView, &lib.BlockHash{} /*Block hash*/, nextBlockHeight,
 			uint64(0) /*txnIndexInBlock*/)
 		if err != nil {
-			APIAddError(ww, fmt.Sprintf("UpdateTxindex: Error connecting "+
+			APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("UpdateTxindex: Error connecting "+
 				"txn: %v: %v", poolTx.Tx, err))
 			return
 		}
@@ -1266,7 +1167,7 @@ func (fes *APIServer) APITransactionInfo(ww http.ResponseWriter, rr *http.Reques
 
 	// At this point, all the transactions should have been added to the request.
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransactionInfo: Problem encoding response "+
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransactionInfo: Problem encoding response "+
 			"as JSON: %v", err))
 		return
 	}
@@ -1304,7 +1205,7 @@ func (fes *APIServer) APINodeInfo(ww http.ResponseWriter, rr *http.Request) {
 	}
 	bb, err := json.Marshal(reqBodyObj)
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APINodeInfo: Problem serializing request "+
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APINodeInfo: Problem serializing request "+
 			"to node-control endpoint: %v", err))
 		return
 	}
@@ -1312,7 +1213,7 @@ func (fes *APIServer) APINodeInfo(ww http.ResponseWriter, rr *http.Request) {
 		bytes.NewBuffer(bb))
 	request.Header.Set("Content-Type", "application/json")
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APINodeInfo: Problem creating request "+
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APINodeInfo: Problem creating request "+
 			"to node-control endpoint: %v", err))
 		return
 	}
@@ -1378,7 +1279,7 @@ func (fes *APIServer) APIBlock(ww http.ResponseWriter, rr *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	blockRequest := APIBlockRequest{}
 	if err := decoder.Decode(&blockRequest); err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBlockRequest: Problem parsing request body: %v", err))
+		APIAddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("APIBlockRequest: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -1413,11 +1314,11 @@ func (fes *APIServer) APIBlock(ww http.ResponseWriter, rr *http.Request) {
 	// Take the hash computed from above and find the corresponding block.
 	blockMsg, err := lib.GetBlock(blockHash, fes.blockchain.DB())
 	if err != nil {
-		APIAddError(ww, fmt.Sprintf("APIBlockRequest: Problem fetching block: %v", err))
+		APIAddCodedError(ww, ErrorCodeNotFound, fmt.Sprintf("APIBlockRequest: Problem fetching block: %v", err))
 		return
 	}
 	if blockMsg == nil {
-		APIAddError(ww, fmt.Sprintf("APIBlockRequest: Block with hash %v not found", blockHash))
+		APIAddCodedError(ww, ErrorCodeNotFound, fmt.Sprintf("APIBlockRequest: Block with hash %v not found", blockHash))
 		return
 	}
 
@@ -1436,7 +1337,7 @@ func (fes *APIServer) APIBlock(ww http.ResponseWriter, rr *http.Request) {
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		APIAddError(ww, fmt.Sprintf("APITransactionInfo: Problem encoding response "+
+		APIAddCodedError(ww, ErrorCodeInternal, fmt.Sprintf("APITransactionInfo: Problem encoding response "+
 			"as JSON: %v", err))
 		return
 	}
@@ -172,19 +172,23 @@ func newTestAPIServer(t *testing.T, globalStateRemoteNode string) (*APIServer, *
 	_, _, _ = block1, block2, mempool
 
 	// Create a global state db only if a remote node was not provided
-	var globalStateDB *badger.DB
+	var globalStore GlobalStore
 	if globalStateRemoteNode == "" {
-		globalStateDB, _ = GetTestBadgerDb()
+		globalStateDB, _ := GetTestBadgerDb()
+		globalStore, err = NewChangeLogGlobalStore(NewBadgerGlobalStore(globalStateDB))
+		require.NoError(err)
+	} else {
+		globalStore = NewRemoteGlobalStore(globalStateRemoteNode, globalStateSharedSecret)
 	}
 	publicApiServer, err := NewAPIServer(
 		nil, mempool,
 		chain, miner.BlockProducer, txDB, params, testJSONPort,
 		testMinFeeRateNanosPerKB, "", 20000,
 		nil,
-		globalStateDB, globalStateRemoteNode, globalStateSharedSecret,
+		globalStore, globalStateRemoteNode, globalStateSharedSecret, "", nil,
 		[]string{}, false, []string{},
 		"", "", false, nil, "", 0,
-		"", "", "", "", false, []string{})
+		"", "", "", "", false, []string{}, nil, nil)
 	require.NoError(err)
 
 	// Calling initState() initializes the state of the APIServer and the router as well.
@@ -195,10 +199,10 @@ func newTestAPIServer(t *testing.T, globalStateRemoteNode string) (*APIServer, *
 		chain, miner.BlockProducer, txDB, params, testJSONPort,
 		testMinFeeRateNanosPerKB, "", 20000,
 		nil,
-		globalStateDB, globalStateRemoteNode, "",
+		globalStore, globalStateRemoteNode, "", "", nil,
 		[]string{}, false, []string{},
 		"", "", false, nil, "", 0,
-		"", "", "", "", false, []string{"adminpublickey"})
+		"", "", "", "", false, []string{"adminpublickey"}, nil, nil)
 	require.NoError(err)
 
 	// Calling initState() initializes the state of the APIServer and the router as well.
@@ -505,7 +509,7 @@ func TestAPI(t *testing.T) {
 		request.Header.Set("Content-Type", "application/json")
 		response := httptest.NewRecorder()
 		apiServer.router.ServeHTTP(response, request)
-		assert.Equal(400, response.Code, "400 response expected")
+		assert.Equal(404, response.Code, "404 response expected")
 		assert.Contains(string(response.Body.Bytes()), "Could not find transaction")
 	}
 	// Getting info on a nonexistent public key should fail gracefully.
@@ -0,0 +1,457 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	"github.com/sasha-s/go-deadlock"
+)
+
+const (
+	// The longest a page stays in the feed cache. Pages are dropped as soon as the
+	// block tip or the mempool changes, or this node changes the global state they
+	// were built from, so this only bounds how stale a page gets when global state
+	// is changed through another node or the by-clout feed's window moves on.
+	feedCacheTTL = 15 * time.Second
+	// Once this many pages are cached the cache starts over. Only the first few
+	// pages of each feed are hot, so this is rarely hit.
+	feedCacheMaxPages = 1000
+	// The most pages of the global feed one GetPostsStateless request goes through
+	// looking for NumToFetch posts the reader hasn't blocked.
+	feedCacheMaxPagesPerRequest = 20
+)
+
+// feedCache holds pages of the hot feeds, built without a reader, so that requests
+// for the same page don't each build an augmented UtxoView and walk the db and
+// global state again. The zero value is ready to use.
+type feedCache struct {
+	mtx deadlock.Mutex
+	// The chainStateVersion the cached pages were built at.
+	chainStateVersion []byte
+	pagesByKey        map[string]*feedCachePage
+}
+
+type feedCachePage struct {
+	value  interface{}
+	err    error
+	expiry time.Time
+	// Closed once value and err are set. Requests for a page that's still being
+	// built wait for it rather than building it again.
+	built chan struct{}
+}
+
+// get returns the page cached under key, calling build to build it if it isn't
+// cached at chainStateVersion or has expired. Errors aren't cached. If
+// chainStateVersion is nil, nothing is cached.
+func (cache *feedCache) get(key string, chainStateVersion []byte, now time.Time,
+	build func() (interface{}, error)) (interface{}, error) {
+
+	if chainStateVersion == nil {
+		return build()
+	}
+
+	cache.mtx.Lock()
+	if !bytes.Equal(cache.chainStateVersion, chainStateVersion) || len(cache.pagesByKey) >= feedCacheMaxPages {
+		cache.chainStateVersion = chainStateVersion
+		cache.pagesByKey = nil
+	}
+	if cache.pagesByKey == nil {
+		cache.pagesByKey = make(map[string]*feedCachePage)
+	}
+	if page, exists := cache.pagesByKey[key]; exists && now.Before(page.expiry) {
+		cache.mtx.Unlock()
+		<-page.built
+		return page.value, page.err
+	}
+	page := &feedCachePage{
+		expiry: now.Add(feedCacheTTL),
+		built:  make(chan struct{}),
+	}
+	cache.pagesByKey[key] = page
+	cache.mtx.Unlock()
+
+	// The page is dropped if build fails or panics, before the requests waiting on
+	// it are let go so that they don't find it again.
+	finished := false
+	defer func() {
+		if !finished || page.err != nil {
+			cache.mtx.Lock()
+			if cache.pagesByKey[key] == page {
+				delete(cache.pagesByKey, key)
+			}
+			cache.mtx.Unlock()
+		}
+		close(page.built)
+	}()
+	// Requests waiting on the page get this if build panics.
+	page.err = fmt.Errorf("feedCache.get: Problem building page %v", key)
+	page.value, page.err = build()
+	finished = true
+	return page.value, page.err
+}
+
+// invalidate drops every cached page. Pages that are being built are still
+// returned to the requests waiting on them but aren't kept.
+func (cache *feedCache) invalidate() {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	cache.pagesByKey = nil
+}
+
+// invalidateFeedCacheForGlobalStateKey drops the cached feeds if key is in one of
+// the parts of global state they're built from: the global feed, pinned posts,
+// verified usernames, and the graylist and blacklist.
+func (fes *APIServer) invalidateFeedCacheForGlobalStateKey(key []byte) {
+	for _, prefix := range [][]byte{
+		_GlobalStatePrefixTstampNanosPostHash,
+		_GlobalStatePrefixTstampNanosPinnedPostHash,
+		_GlobalStatePrefixForVerifiedMap,
+		_GlobalStatePrefixPublicKeyToGraylistState,
+		_GlobalStatePrefixPublicKeyToBlacklistState,
+	} {
+		if bytes.HasPrefix(key, prefix) {
+			fes.feedCache.invalidate()
+			return
+		}
+	}
+}
+
+// cachedFeedPost is a post in a cached feed along with its response as seen by
+// a reader who isn't logged in. The response is shared, so it's copied before
+// anything reader specific is added to it.
+type cachedFeedPost struct {
+	postEntry *lib.PostEntry
+	response  *PostEntryResponse
+}
+
+// cachedPostFeed is a page of the global feed or the by-clout feed.
+type cachedPostFeed struct {
+	// Only set for the first page of the global feed.
+	pinnedPosts []*cachedFeedPost
+	posts       []*cachedFeedPost
+	// Where the next page of the global feed starts. Nil if this is the last page
+	// or the page is of the by-clout feed.
+	nextStartPostHash *lib.BlockHash
+}
+
+// canServePostsStatelessFromFeedCache returns true if the request is for the global
+// feed or the by-clout feed and the cached page can be made into what the reader
+// would otherwise get. The by-clout feed leaves out restricted posters except for
+// the reader, so a restricted reader has to have their page built from scratch.
+func (fes *APIServer) canServePostsStatelessFromFeedCache(
+	requestData *GetPostsStatelessRequest, readerPK []byte) (bool, error) {
+
+	if requestData.GetPostsForFollowFeed {
+		return false, nil
+	}
+	if requestData.GetPostsForGlobalWhitelist {
+		return true, nil
+	}
+	if !requestData.GetPostsByClout {
+		return false, nil
+	}
+	if readerPK == nil {
+		return true, nil
+	}
+	unrestrictedPubKeys, err := fes.FilterOutRestrictedPubKeysFromList(
+		[][]byte{readerPK}, nil /*readerPK*/, "leaderboard")
+	if err != nil {
+		return false, fmt.Errorf("canServePostsStatelessFromFeedCache: Problem checking reader: %v", err)
+	}
+	return len(unrestrictedPubKeys) != 0, nil
+}
+
+// getPostsStatelessFromFeedCache returns the posts GetPostsStateless would for a
+// request that canServePostsStatelessFromFeedCache accepts, before the PostContent
+// filter and OrderBy are applied.
+//
+// The reader's own posts and the posts of users they've blocked are taken off the
+// global feed after the cached page is fetched, so the pages after it are fetched
+// too until the reader has NumToFetch posts from other users, like they would
+// from GetPostEntriesForGlobalWhitelist.
+func (fes *APIServer) getPostsStatelessFromFeedCache(requestData *GetPostsStatelessRequest,
+	startPostHash *lib.BlockHash, readerPK []byte, numToFetch int) (
+	_postEntryResponses []*PostEntryResponse, _err error) {
+
+	chainStateVersion := fes.chainStateVersion()
+	getPage := func(startPostHash *lib.BlockHash) (*cachedPostFeed, error) {
+		var cacheKey string
+		if requestData.GetPostsForGlobalWhitelist {
+			startPostHashHex := ""
+			if startPostHash != nil {
+				startPostHashHex = hex.EncodeToString(startPostHash[:])
+			}
+			cacheKey = fmt.Sprintf("GetPostsStateless/GlobalWhitelist/%v/%d/%v",
+				startPostHashHex, numToFetch, requestData.AddGlobalFeedBool)
+		} else {
+			cacheKey = fmt.Sprintf("GetPostsStateless/ByClout/%d/%d/%v",
+				requestData.PostsByCloutMinutesLookback, numToFetch, requestData.AddGlobalFeedBool)
+		}
+		cachedValue, err := fes.feedCache.get(cacheKey, chainStateVersion, time.Now(), func() (interface{}, error) {
+			return fes.buildCachedPostFeed(requestData, startPostHash, numToFetch)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return cachedValue.(*cachedPostFeed), nil
+	}
+	feed, err := getPage(startPostHash)
+	if err != nil {
+		return nil, err
+	}
+
+	postEntryResponses := []*PostEntryResponse{}
+	if readerPK == nil {
+		for _, post := range feed.pinnedPosts {
+			postEntryResponses = append(postEntryResponses, post.response)
+		}
+		for _, post := range feed.posts {
+			postEntryResponses = append(postEntryResponses, post.response)
+		}
+		return postEntryResponses, nil
+	}
+
+	blockedPubKeys, err := fes.GetBlockedPubKeysForUser(readerPK)
+	if err != nil {
+		return nil, fmt.Errorf("getPostsStatelessFromFeedCache: Error fetching blocked pub keys for user: %v", err)
+	}
+	isBlocked := func(publicKey []byte) bool {
+		_, blocked := blockedPubKeys[lib.PkToString(publicKey, fes.Params)]
+		return blocked
+	}
+	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
+	if err != nil {
+		return nil, fmt.Errorf("getPostsStatelessFromFeedCache: Error fetching mempool view: %v", err)
+	}
+
+	// The by-clout feed has never had reader state on its posts, only on the posts
+	// they reclout.
+	if !requestData.GetPostsForGlobalWhitelist {
+		for _, post := range feed.posts {
+			if isBlocked(post.postEntry.PosterPublicKey) {
+				continue
+			}
+			postEntryResponses = append(postEntryResponses,
+				withRecloutReaderState(post.response, post.postEntry, readerPK, utxoView))
+		}
+		return postEntryResponses, nil
+	}
+
+	for _, post := range feed.pinnedPosts {
+		if isBlocked(post.postEntry.PosterPublicKey) {
+			continue
+		}
+		postEntryResponse := withRecloutReaderState(post.response, post.postEntry, readerPK, utxoView)
+		postEntryResponse.PostEntryReaderState = utxoView.GetPostEntryReaderState(readerPK, post.postEntry)
+		postEntryResponses = append(postEntryResponses, postEntryResponse)
+	}
+
+	// Swap the reader's posts on the cached page for all of their posts in the
+	// time range the page covers, like GetPostEntriesForGlobalWhitelist does.
+	var otherPostEntries []*lib.PostEntry
+	var feedPostEntryResponses []*PostEntryResponse
+	for numPages := 1; ; numPages++ {
+		for _, post := range feed.posts {
+			if len(feedPostEntryResponses) >= numToFetch {
+				break
+			}
+			if reflect.DeepEqual(post.postEntry.PosterPublicKey, readerPK) {
+				continue
+			}
+			otherPostEntries = append(otherPostEntries, post.postEntry)
+			if isBlocked(post.postEntry.PosterPublicKey) {
+				continue
+			}
+			postEntryResponse := withRecloutReaderState(post.response, post.postEntry, readerPK, utxoView)
+			postEntryResponse.PostEntryReaderState = utxoView.GetPostEntryReaderState(readerPK, post.postEntry)
+			feedPostEntryResponses = append(feedPostEntryResponses, postEntryResponse)
+		}
+		if len(feedPostEntryResponses) >= numToFetch || feed.nextStartPostHash == nil ||
+			numPages >= feedCacheMaxPagesPerRequest {
+			break
+		}
+		feed, err = getPage(feed.nextStartPostHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+	minTimestampNanos, maxTimestampNanos := globalWhitelistTimestampRange(startPostHash, otherPostEntries, utxoView)
+	readerPostEntries, err := fes.getReaderPostEntriesForGlobalWhitelist(
+		readerPK, minTimestampNanos, maxTimestampNanos, utxoView)
+	if err != nil {
+		return nil, err
+	}
+	if len(readerPostEntries) > 0 && !isBlocked(readerPK) {
+		verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
+		if err != nil {
+			return nil, fmt.Errorf("getPostsStatelessFromFeedCache: Error fetching verifiedMap: %v", err)
+		}
+		readerProfileEntryResponse := _profileEntryToResponse(
+			utxoView.GetProfileEntryForPublicKey(readerPK), fes.Params, verifiedMap, utxoView)
+		for _, postEntry := range readerPostEntries {
+			postEntryResponse, err := fes._postEntryToResponse(
+				postEntry, requestData.AddGlobalFeedBool, fes.Params, utxoView, readerPK, 2)
+			if err != nil {
+				// Just ignore posts that fail to convert for whatever reason.
+				continue
+			}
+			postEntryResponse.ProfileEntryResponse = readerProfileEntryResponse
+			postEntryResponse.PostEntryReaderState = utxoView.GetPostEntryReaderState(readerPK, postEntry)
+			feedPostEntryResponses = append(feedPostEntryResponses, postEntryResponse)
+		}
+	}
+	sort.SliceStable(feedPostEntryResponses, func(ii, jj int) bool {
+		return feedPostEntryResponses[ii].TimestampNanos > feedPostEntryResponses[jj].TimestampNanos
+	})
+
+	return append(postEntryResponses, feedPostEntryResponses...), nil
+}
+
+// buildCachedPostFeed builds the page of the global feed or the by-clout feed that
+// a reader who isn't logged in would get.
+func (fes *APIServer) buildCachedPostFeed(requestData *GetPostsStatelessRequest,
+	startPostHash *lib.BlockHash, numToFetch int) (*cachedPostFeed, error) {
+
+	var postEntries []*lib.PostEntry
+	var pinnedPostEntries []*lib.PostEntry
+	var err error
+	if !requestData.GetPostsForGlobalWhitelist {
+		postEntries, _, err = fes.GetPostEntriesByCloutAfterTimePaginated(
+			nil /*readerPK*/, requestData.PostsByCloutMinutesLookback, numToFetch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Get a view with all the mempool transactions (used to get all posts / reader state).
+	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
+	if err != nil {
+		return nil, fmt.Errorf("buildCachedPostFeed: Error fetching mempool view: %v", err)
+	}
+
+	if requestData.GetPostsForGlobalWhitelist {
+		postEntries, err = fes.getGlobalWhitelistPostEntries(startPostHash, nil /*skipPublicKey*/, numToFetch, utxoView)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(postEntries, func(ii, jj int) bool {
+			return postEntries[ii].TimestampNanos > postEntries[jj].TimestampNanos
+		})
+		if startPostHash == nil {
+			pinnedPostEntries, err = fes.getPinnedPostEntries(utxoView)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
+	if err != nil {
+		return nil, fmt.Errorf("buildCachedPostFeed: Error fetching verifiedMap: %v", err)
+	}
+	toCachedFeedPosts := func(postEntries []*lib.PostEntry) []*cachedFeedPost {
+		posts := []*cachedFeedPost{}
+		for _, postEntry := range postEntries {
+			postEntryResponse, err := fes._postEntryToResponse(
+				postEntry, requestData.AddGlobalFeedBool, fes.Params, utxoView, nil /*readerPK*/, 2)
+			if err != nil {
+				// Just ignore posts that fail to convert for whatever reason.
+				continue
+			}
+			postEntryResponse.ProfileEntryResponse = _profileEntryToResponse(
+				utxoView.GetProfileEntryForPublicKey(postEntry.PosterPublicKey), fes.Params, verifiedMap, utxoView)
+			posts = append(posts, &cachedFeedPost{
+				postEntry: postEntry,
+				response:  postEntryResponse,
+			})
+		}
+		return posts
+	}
+
+	feed := &cachedPostFeed{
+		pinnedPosts: toCachedFeedPosts(pinnedPostEntries),
+		posts:       toCachedFeedPosts(postEntries),
+	}
+	// A full page means there may be more after it.
+	if requestData.GetPostsForGlobalWhitelist && numToFetch > 0 && len(postEntries) >= numToFetch {
+		feed.nextStartPostHash = postEntries[len(postEntries)-1].PostHash
+	}
+	return feed, nil
+}
+
+// withRecloutReaderState returns a copy of a cached postEntryResponse in which the
+// posts it reclouts have readerPK's reader state, as they would have had the
+// response been built for readerPK. The cached response is left alone.
+func withRecloutReaderState(postEntryResponse *PostEntryResponse, postEntry *lib.PostEntry,
+	readerPK []byte, utxoView *lib.UtxoView) *PostEntryResponse {
+
+	readerPostEntryResponse := *postEntryResponse
+	if postEntryResponse.RecloutedPostEntryResponse != nil && postEntry.RecloutedPostHash != nil {
+		recloutedPostEntry := utxoView.GetPostEntryForPostHash(postEntry.RecloutedPostHash)
+		if recloutedPostEntry != nil {
+			recloutedPostEntryResponse := withRecloutReaderState(
+				postEntryResponse.RecloutedPostEntryResponse, recloutedPostEntry, readerPK, utxoView)
+			recloutedPostEntryResponse.PostEntryReaderState = utxoView.GetPostEntryReaderState(
+				readerPK, recloutedPostEntry)
+			readerPostEntryResponse.RecloutedPostEntryResponse = recloutedPostEntryResponse
+		}
+	}
+	return &readerPostEntryResponse
+}
+
+// canServeProfilesFromFeedCache returns true if GetProfilesByCoinValue would return
+// the same profiles for readerPK as for a reader who isn't logged in, which is
+// the case unless the reader is restricted under moderationType.
+func (fes *APIServer) canServeProfilesFromFeedCache(readerPK []byte, moderationType string) (bool, error) {
+	if readerPK == nil {
+		return true, nil
+	}
+	unrestrictedPubKeys, err := fes.FilterOutRestrictedPubKeysFromList(
+		[][]byte{readerPK}, nil /*readerPK*/, moderationType)
+	if err != nil {
+		return false, fmt.Errorf("canServeProfilesFromFeedCache: Problem checking reader: %v", err)
+	}
+	return len(unrestrictedPubKeys) != 0, nil
+}
+
+// getProfilesByCoinValueFromFeedCache returns responses for the profiles
+// GetProfilesByCoinValue finds, without their posts, in no particular order. The
+// slice returned belongs to the caller but the responses in it are shared.
+func (fes *APIServer) getProfilesByCoinValueFromFeedCache(
+	startProfilePubKey []byte, numToFetch int, moderationType string) (
+	_profileEntryResponses []*ProfileEntryResponse, _err error) {
+
+	cacheKey := fmt.Sprintf("GetProfilesByCoinValue/%x/%d/%v", startProfilePubKey, numToFetch, moderationType)
+	cachedValue, err := fes.feedCache.get(cacheKey, fes.chainStateVersion(), time.Now(), func() (interface{}, error) {
+		utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
+		if err != nil {
+			return nil, fmt.Errorf("getProfilesByCoinValueFromFeedCache: Error fetching mempool view: %v", err)
+		}
+		profileEntriesByPublicKey, _, _, err := fes.GetProfilesByCoinValue(
+			utxoView, nil /*readerPK*/, startProfilePubKey, numToFetch, false /*getPosts*/, moderationType)
+		if err != nil {
+			return nil, err
+		}
+		verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
+		if err != nil {
+			return nil, fmt.Errorf("getProfilesByCoinValueFromFeedCache: Error fetching verifiedMap: %v", err)
+		}
+		profileEntryResponses := []*ProfileEntryResponse{}
+		for _, profileEntry := range profileEntriesByPublicKey {
+			profileEntryResponses = append(profileEntryResponses,
+				_profileEntryToResponse(profileEntry, fes.Params, verifiedMap, utxoView))
+		}
+		return profileEntryResponses, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers sort the slice, so they each get their own.
+	return append([]*ProfileEntryResponse{}, cachedValue.([]*ProfileEntryResponse)...), nil
+}
@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedCache(t *testing.T) {
+	require := require.New(t)
+
+	fes := &APIServer{GlobalStore: NewMemoryGlobalStore()}
+	cache := &fes.feedCache
+	now := time.Now()
+	numBuilds := 0
+	build := func() (interface{}, error) {
+		numBuilds++
+		return numBuilds, nil
+	}
+	get := func(key string, chainStateVersion string, now time.Time) interface{} {
+		value, err := cache.get(key, []byte(chainStateVersion), now, build)
+		require.NoError(err)
+		return value
+	}
+
+	// Pages are built once per chain state.
+	require.Equal(1, get("a", "v1", now))
+	require.Equal(1, get("a", "v1", now))
+	require.Equal(2, get("b", "v1", now))
+	require.Equal(3, get("a", "v2", now))
+	require.Equal(4, get("b", "v2", now))
+	// And at most once per TTL.
+	require.Equal(5, get("a", "v2", now.Add(feedCacheTTL)))
+	require.Equal(5, get("a", "v2", now.Add(feedCacheTTL)))
+
+	// Without a chain state nothing is cached.
+	value, err := cache.get("a", nil, now, build)
+	require.NoError(err)
+	require.Equal(6, value)
+
+	// Errors aren't cached.
+	_, err = cache.get("c", []byte("v2"), now, func() (interface{}, error) {
+		return nil, fmt.Errorf("failed")
+	})
+	require.Error(err)
+	require.Equal(7, get("c", "v2", now))
+
+	// Nor are pages whose build panicked.
+	require.Panics(func() {
+		cache.get("e", []byte("v2"), now, func() (interface{}, error) {
+			panic("failed")
+		})
+	})
+	require.Equal(8, get("e", "v2", now))
+
+	// Changes to global state the feeds are built from drop the cache, other
+	// changes don't.
+	require.NoError(fes.GlobalStatePut(GlobalStateKeyForPublicKeyToUserMetadata([]byte("reader")), []byte{1}))
+	require.Equal(7, get("c", "v2", now))
+	require.NoError(fes.GlobalStatePut(GlobalStateKeyForBlacklistedProfile([]byte("poster")), []byte{1}))
+	require.Equal(9, get("c", "v2", now))
+	require.NoError(fes.GlobalStateDelete(GlobalStateKeyForBlacklistedProfile([]byte("poster"))))
+	require.Equal(10, get("c", "v2", now))
+
+	// Requests for a page that's being built wait for it.
+	numBuilds = 0
+	building := make(chan struct{})
+	finishBuild := make(chan struct{})
+	go cache.get("d", []byte("v2"), now, func() (interface{}, error) {
+		close(building)
+		<-finishBuild
+		return build()
+	})
+	<-building
+	var wg sync.WaitGroup
+	for ii := 0; ii < 10; ii++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := cache.get("d", []byte("v2"), now, build)
+			require.NoError(err)
+			require.Equal(1, value)
+		}()
+	}
+	close(finishBuild)
+	wg.Wait()
+	require.Equal(1, numBuilds)
+}
@@ -1,7 +1,6 @@
 package routes
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/bitclout/core/lib"
@@ -9,59 +8,78 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/dgraph-io/badger/v3"
 	"github.com/nyaruka/phonenumbers"
 	"github.com/pkg/errors"
 )
 
 const (
-	GlobalStateSharedSecretParam = "shared_secret"
-
 	RoutePathGlobalStatePutRemote      = "/api/v1/global-state/put"
 	RoutePathGlobalStateGetRemote      = "/api/v1/global-state/get"
 	RoutePathGlobalStateBatchGetRemote = "/api/v1/global-state/batch-get"
 	RoutePathGlobalStateDeleteRemote   = "/api/v1/global-state/delete"
 	RoutePathGlobalStateSeekRemote     = "/api/v1/global-state/seek"
+
+	RoutePathGlobalStateCompareAndSwapRemote = "/api/v1/global-state/compare-and-swap"
+	RoutePathGlobalStateChangesRemote        = "/api/v1/global-state/changes"
+
+	// The number of times GlobalStateUpdate will re-read a key and retry when
+	// another writer beats it to the punch.
+	GlobalStateMaxUpdateAttempts = 10
 )
 
 // GlobalStateRoutes returns the routes for managing global state.
-// Note that these routes are generally protected by a shared_secret
+// Note that these routes are protected by a signature computed with a shared
+// secret rather than by CheckAdminPublicKey. See CheckGlobalStateSignature.
 func (fes *APIServer) GlobalStateRoutes() []Route {
 	var GlobalStateRoutes = []Route{
 		{
 			"GlobalStatePutRemote",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGlobalStatePutRemote,
-			fes.GlobalStatePutRemote,
-			true, // CheckSecret
+			fes.CheckGlobalStateSignature(fes.GlobalStatePutRemote),
+			AdminRoleNone,
 		},
 		{
 			"GlobalStateGetRemote",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGlobalStateGetRemote,
-			fes.GlobalStateGetRemote,
-			true, // CheckSecret
+			fes.CheckGlobalStateSignature(fes.GlobalStateGetRemote),
+			AdminRoleNone,
 		},
 		{
 			"GlobalStateBatchGetRemote",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGlobalStateBatchGetRemote,
-			fes.GlobalStateBatchGetRemote,
-			true, // CheckSecret
+			fes.CheckGlobalStateSignature(fes.GlobalStateBatchGetRemote),
+			AdminRoleNone,
 		},
 		{
 			"GlobalStateDeleteRemote",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGlobalStateDeleteRemote,
-			fes.GlobalStateDeleteRemote,
-			true, // CheckSecret
+			fes.CheckGlobalStateSignature(fes.GlobalStateDeleteRemote),
+			AdminRoleNone,
 		},
 		{
 			"GlobalStateSeekRemote",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGlobalStateSeekRemote,
-			fes.GlobalStateSeekRemote,
-			true, // CheckSecret
+			fes.CheckGlobalStateSignature(fes.GlobalStateSeekRemote),
+			AdminRoleNone,
+		},
+		{
+			"GlobalStateCompareAndSwapRemote",
+			[]string{"POST", "OPTIONS"},
+			RoutePathGlobalStateCompareAndSwapRemote,
+			fes.CheckGlobalStateSignature(fes.GlobalStateCompareAndSwapRemote),
+			AdminRoleNone,
+		},
+		{
+			"GlobalStateChangesRemote",
+			[]string{"POST", "OPTIONS"},
+			RoutePathGlobalStateChangesRemote,
+			fes.CheckGlobalStateSignature(fes.GlobalStateChangesRemote),
+			AdminRoleNone,
 		},
 	}
 
@@ -69,54 +87,90 @@ func (fes *APIServer) GlobalStateRoutes() []Route {
 }
 
 var (
-	// The key prefixes for the  global state key-value database.
+	// The key prefixes for the  global state key-value database. Every prefix must
+	// be registered with registerGlobalStatePrefix so that ValidateGlobalStatePrefixes
+	// can catch two prefixes sharing the same ID at startup.
 
 	// The prefix for accessing a user's metadata (e.g. email, blacklist status, etc.):
 	// <prefix,  ProfilePubKey [33]byte> -> <UserMetadata>
-	_GlobalStatePrefixPublicKeyToUserMetadata = []byte{0}
+	_GlobalStatePrefixPublicKeyToUserMetadata = registerGlobalStatePrefix(
+		[]byte{0}, "PublicKeyToUserMetadata", decodeUserMetadataEntry)
 
 	// The prefix for accessing whitelisted posts for the global feed:
 	// Unlike in db_utils here, we use a single byte as a value placeholder.  This is a
 	// result of the way global state handles when a key is not present.
 	// <prefix, tstampNanos uint64, PostHash> -> <[]byte{1}>
-	_GlobalStatePrefixTstampNanosPostHash = []byte{1}
+	_GlobalStatePrefixTstampNanosPostHash = registerGlobalStatePrefix(
+		[]byte{1}, "TstampNanosPostHash", decodeTstampPostHashEntry)
 
 	// The prefix for accessing a phone number's metadata
+	// When a PII index key is configured, the key holds HMAC(PhoneNumber) instead.
 	// <prefix,  PhoneNumber [variableLength]byte> -> <PhoneNumberMetadata>
-	_GlobalStatePrefixPhoneNumberToPhoneNumberMetadata = []byte{2}
+	_GlobalStatePrefixPhoneNumberToPhoneNumberMetadata = registerGlobalStatePrefix(
+		[]byte{2}, "PhoneNumberToPhoneNumberMetadata", decodePhoneNumberMetadataEntry)
 
 	// The prefix for accessing the verified users map.
 	// The resulting map takes a username and returns a PKID.
 	// <prefix> -> <map[string]*PKID>
-	_GlobalStatePrefixForVerifiedMap = []byte{3}
+	_GlobalStatePrefixForVerifiedMap = registerGlobalStatePrefix(
+		[]byte{3}, "VerifiedMap", decodeVerifiedMapEntry)
 
 	// The prefix for accessing the pinned posts on the global feed:
 	// <prefix, tstampNanos uint64, PostHash> -> <[]byte{4}>
-	_GlobalStatePrefixTstampNanosPinnedPostHash = []byte{4}
+	_GlobalStatePrefixTstampNanosPinnedPostHash = registerGlobalStatePrefix(
+		[]byte{4}, "TstampNanosPinnedPostHash", decodeTstampPostHashEntry)
 
 	// The prefix for accessing the audit log of verification badges
 	// <prefix, username string> -> <VerificationAuditLog>
-	_GlobalStatePrefixUsernameVerificationAuditLog = []byte{5}
-
+	_GlobalStatePrefixUsernameVerificationAuditLog = registerGlobalStatePrefix(
+		[]byte{5}, "UsernameVerificationAuditLog", decodeUsernameVerificationAuditLogEntry)
 
 	// The prefix for accessing the graylisted users.
 	// <prefix, public key> -> <IsGraylisted>
-	_GlobalStatePrefixPublicKeyToGraylistState = []byte{6}
+	_GlobalStatePrefixPublicKeyToGraylistState = registerGlobalStatePrefix(
+		[]byte{6}, "PublicKeyToGraylistState", decodePublicKeyFlagEntry)
 
 	// The prefix for accesing the blacklisted users.
 	// <prefix, public key> -> <IsBlacklisted>
-	_GlobalStatePrefixPublicKeyToBlacklistState = []byte{7}
+	_GlobalStatePrefixPublicKeyToBlacklistState = registerGlobalStatePrefix(
+		[]byte{7}, "PublicKeyToBlacklistState", decodePublicKeyFlagEntry)
 
 	// The prefix for checking the most recent read time stamp for a user reading
 	// a contact's private message.
 	// <prefix, user public key, contact's public key> -> <tStampNanos>
-	_GlobalStatePrefixUserPublicKeyContactPublicKeyToMostRecentReadTstampNanos = []byte{8}
-
-	// TODO: This process is a bit error-prone. We should come up with a test or
-	// something to at least catch cases where people have two prefixes with the
-	// same ID.
-	//
-	// NEXT_TAG: 3
+	_GlobalStatePrefixUserPublicKeyContactPublicKeyToMostRecentReadTstampNanos = registerGlobalStatePrefix(
+		[]byte{8}, "UserPublicKeyContactPublicKeyToMostRecentReadTstampNanos", decodeMostRecentReadTstampNanosEntry)
+
+	// The key storing the schema version of global state. Migrations in
+	// globalStateMigrations are run in order until this reaches the latest version.
+	// <prefix> -> <version uint64>
+	_GlobalStatePrefixSchemaVersion = registerGlobalStatePrefix(
+		[]byte{9}, "SchemaVersion", decodeSchemaVersionEntry)
+
+	// The prefix for the audit log of changes admins make through the global state
+	// browser. The random bytes keep two changes in the same nanosecond apart.
+	// <prefix, tstampNanos uint64, random [8]byte> -> <GlobalStateAuditLog>
+	_GlobalStatePrefixGlobalStateAuditLog = registerGlobalStatePrefix(
+		[]byte{10}, "GlobalStateAuditLog", decodeGlobalStateAuditLogEntry)
+
+	// The prefix for the token buckets used by GlobalStateRateLimitStore. The key is
+	// hashed so that IP addresses aren't stored in the clear.
+	// <prefix, sha256(bucket key)> -> <tstampNanos uint64 the bucket is full again>
+	_GlobalStatePrefixRateLimitBucket = registerGlobalStatePrefix(
+		[]byte{11}, "RateLimitBucket", decodeRateLimitBucketEntry)
+
+	// The prefix for the roles granted to admins with AdminUpdateAdminRoles.
+	// <prefix, public key> -> <AdminRoleAssignment>
+	_GlobalStatePrefixPublicKeyToAdminRoles = registerGlobalStatePrefix(
+		[]byte{12}, "PublicKeyToAdminRoles", decodeAdminRolesEntry)
+
+	// The prefix for the audit log of requests to admin routes, see AdminAuditLog.
+	// The random bytes keep two requests in the same nanosecond apart.
+	// <prefix, tstampNanos uint64, random [8]byte> -> <AdminAuditLogEntry>
+	_GlobalStatePrefixAdminAuditLog = registerGlobalStatePrefix(
+		[]byte{13}, "AdminAuditLog", decodeAdminAuditLogEntry)
+
+	// NEXT_TAG: 14
 )
 
 // This struct contains all the metadata associated with a user's public key.
@@ -136,6 +190,12 @@ type UserMetadata struct {
 	// E.164 format phone number for a user to receive text notifications at.
 	PhoneNumber string
 
+	// When the node is configured with a PII key, Email and PhoneNumber are
+	// encrypted into these fields before the metadata is written to global state
+	// and decrypted back out when it's read. See PIIKeyring.
+	EncryptedEmail       *EncryptedPII `json:",omitempty"`
+	EncryptedPhoneNumber *EncryptedPII `json:",omitempty"`
+
 	// Country code associated with the user's phone number. This is a string like "US"
 	PhoneNumberCountryCode string
 
@@ -182,6 +242,9 @@ type PhoneNumberMetadata struct {
 	// E.164 format phone number for a user to receive text notifications at.
 	PhoneNumber string
 
+	// The encrypted PhoneNumber when the node is configured with a PII key.
+	EncryptedPhoneNumber *EncryptedPII `json:",omitempty"`
+
 	// Country code associated with the user's phone number.
 	PhoneNumberCountryCode string
 
@@ -190,7 +253,11 @@ type PhoneNumberMetadata struct {
 }
 
 // countryCode is a string like 'US' (Note: the phonenumbers lib calls this a "region code")
-func GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber string) (_key []byte, _err error) {
+//
+// If keyring is non-nil, the key holds an HMAC of the phone number rather than the
+// number itself. Passing a nil keyring gives the key used before blind indexing
+// was enabled.
+func GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber string, keyring *PIIKeyring) (_key []byte, _err error) {
 	parsedNumber, err := phonenumbers.Parse(phoneNumber, "")
 	if err != nil {
 		return nil, errors.Wrap(fmt.Errorf(
@@ -198,6 +265,10 @@ func GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber string)
 	}
 	formattedNumber := phonenumbers.Format(parsedNumber, phonenumbers.E164)
 
+	if keyring != nil {
+		return globalStateKeyForPhoneNumberBytesToPhoneNumberMetadata(keyring.phoneNumberIndex(formattedNumber)), nil
+	}
+
 	// Get the key for the formatted number
 	return globalStateKeyForPhoneNumberBytesToPhoneNumberMetadata([]byte(formattedNumber)), nil
 }
@@ -257,6 +328,29 @@ func GlobalStateKeyForBlacklistedProfile(profilePubKey []byte) []byte {
 	return key
 }
 
+// Key for an entry in the global state audit log.
+func GlobalStateKeyForGlobalStateAuditLog(tstampNanos uint64, randomBytes []byte) []byte {
+	key := append([]byte{}, _GlobalStatePrefixGlobalStateAuditLog...)
+	key = append(key, lib.EncodeUint64(tstampNanos)...)
+	key = append(key, randomBytes...)
+	return key
+}
+
+// Key for an entry in the admin audit log.
+func GlobalStateKeyForAdminAuditLog(tstampNanos uint64, randomBytes []byte) []byte {
+	key := append([]byte{}, _GlobalStatePrefixAdminAuditLog...)
+	key = append(key, lib.EncodeUint64(tstampNanos)...)
+	key = append(key, randomBytes...)
+	return key
+}
+
+// Key for accessing the roles granted to an admin.
+func GlobalStateKeyForPublicKeyToAdminRoles(adminPubKey []byte) []byte {
+	key := append([]byte{}, _GlobalStatePrefixPublicKeyToAdminRoles...)
+	key = append(key, adminPubKey...)
+	return key
+}
+
 // Key for accessing a user's global metadata.
 func GlobalStateKeyForUserPkContactPkToMostRecentReadTstampNanos(userPubKey []byte, contactPubKey []byte) []byte {
 	prefixCopy := append([]byte{}, _GlobalStatePrefixUserPublicKeyContactPublicKeyToMostRecentReadTstampNanos...)
@@ -279,13 +373,13 @@ func (fes *APIServer) GlobalStatePutRemote(ww http.ResponseWriter, rr *http.Requ
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	requestData := GlobalStatePutRemoteRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStatePutRemote: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GlobalStatePutRemote: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Call the put function. Note that this may also proxy to another node.
 	if err := fes.GlobalStatePut(requestData.Key, requestData.Value); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GlobalStatePutRemote: Error processing GlobalStatePut: %v", err))
 		return
 	}
@@ -293,60 +387,35 @@ func (fes *APIServer) GlobalStatePutRemote(ww http.ResponseWriter, rr *http.Requ
 	// Return
 	res := GlobalStatePutRemoteResponse{}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStatePutRemote: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GlobalStatePutRemote: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
 
 func (fes *APIServer) CreateGlobalStatePutRequest(key []byte, value []byte) (
-	_url string, _json_data []byte, _err error) {
+	*http.Request, error) {
 
 	req := GlobalStatePutRemoteRequest{
 		Key:   key,
 		Value: value,
 	}
-	json_data, err := json.Marshal(req)
+	request, err := createGlobalStateRemoteRequest(fes.GlobalStateRemoteNode,
+		fes.GlobalStateRemoteNodeSharedSecret, RoutePathGlobalStatePutRemote, &req)
 	if err != nil {
-		return "", nil, fmt.Errorf("GlobalStatePut: Could not marshal JSON: %v", err)
+		return nil, fmt.Errorf("GlobalStatePut: %v", err)
 	}
 
-	url := fmt.Sprintf("%s%s?%s=%s",
-		fes.GlobalStateRemoteNode, RoutePathGlobalStatePutRemote,
-		GlobalStateSharedSecretParam, fes.GlobalStateRemoteNodeSharedSecret)
-
-	return url, json_data, nil
+	return request, nil
 }
 
+// GlobalStatePut sets a value in global state using whichever GlobalStore the
+// APIServer was created with.
 func (fes *APIServer) GlobalStatePut(key []byte, value []byte) error {
-	// If we have a remote node then use that node to fulfill this request.
-	if fes.GlobalStateRemoteNode != "" {
-		// TODO: This codepath is hard to exercise in a test.
-
-		url, json_data, err := fes.CreateGlobalStatePutRequest(key, value)
-		if err != nil {
-			return fmt.Errorf("GlobalStatePut: Error constructing request: %v", err)
-		}
-		res, err := http.Post(
-			url,
-			"application/json", /*contentType*/
-			bytes.NewBuffer(json_data))
-		if err != nil {
-			return fmt.Errorf("GlobalStatePut: Error processing remote request")
-		}
-		res.Body.Close()
-
-		//res := GlobalStatePutRemoteResponse{}
-		//json.NewDecoder(resReturned.Body).Decode(&res)
-
-		// No error means nothing to return.
-		return nil
+	if err := fes.GlobalStore.Put(key, value); err != nil {
+		return err
 	}
-
-	// If we get here, it means we don't have a remote node so store the
-	// data in our local db.
-	return fes.GlobalStateDB.Update(func(txn *badger.Txn) error {
-		return txn.Set(key, value)
-	})
+	fes.invalidateFeedCacheForGlobalStateKey(key)
+	return nil
 }
 
 type GlobalStateGetRemoteRequest struct {
@@ -362,14 +431,14 @@ func (fes *APIServer) GlobalStateGetRemote(ww http.ResponseWriter, rr *http.Requ
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	requestData := GlobalStateGetRemoteRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateGetRemote: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GlobalStateGetRemote: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Call the get function. Note that this may also proxy to another node.
 	val, err := fes.GlobalStateGet(requestData.Key)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GlobalStateGetRemote: Error processing GlobalStateGet: %v", err))
 		return
 	}
@@ -379,75 +448,30 @@ func (fes *APIServer) GlobalStateGetRemote(ww http.ResponseWriter, rr *http.Requ
 		Value: val,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateGetRemote: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GlobalStateGetRemote: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
 
 func (fes *APIServer) CreateGlobalStateGetRequest(key []byte) (
-	_url string, _json_data []byte, _err error) {
+	*http.Request, error) {
 
 	req := GlobalStateGetRemoteRequest{
 		Key: key,
 	}
-	json_data, err := json.Marshal(req)
+	request, err := createGlobalStateRemoteRequest(fes.GlobalStateRemoteNode,
+		fes.GlobalStateRemoteNodeSharedSecret, RoutePathGlobalStateGetRemote, &req)
 	if err != nil {
-		return "", nil, fmt.Errorf("GlobalStateGet: Could not marshal JSON: %v", err)
+		return nil, fmt.Errorf("GlobalStateGet: %v", err)
 	}
 
-	url := fmt.Sprintf("%s%s?%s=%s",
-		fes.GlobalStateRemoteNode, RoutePathGlobalStateGetRemote,
-		GlobalStateSharedSecretParam, fes.GlobalStateRemoteNodeSharedSecret)
-
-	return url, json_data, nil
+	return request, nil
 }
 
+// GlobalStateGet fetches a value from global state. A nil value with no error
+// means the key is not present.
 func (fes *APIServer) GlobalStateGet(key []byte) (value []byte, _err error) {
-	// If we have a remote node then use that node to fulfill this request.
-	if fes.GlobalStateRemoteNode != "" {
-		// TODO: This codepath is currently annoying to test.
-
-		url, json_data, err := fes.CreateGlobalStateGetRequest(key)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"GlobalStateGet: Error constructing request: %v", err)
-		}
-
-		resReturned, err := http.Post(
-			url,
-			"application/json", /*contentType*/
-			bytes.NewBuffer(json_data))
-		if err != nil {
-			return nil, fmt.Errorf("GlobalStateGet: Error processing remote request")
-		}
-
-		res := GlobalStateGetRemoteResponse{}
-		json.NewDecoder(resReturned.Body).Decode(&res)
-		resReturned.Body.Close()
-
-		return res.Value, nil
-	}
-
-	// If we get here, it means we don't have a remote node so get the
-	// data from our local db.
-	var retValue []byte
-	err := fes.GlobalStateDB.View(func(txn *badger.Txn) error {
-		item, err := txn.Get(key)
-		if err != nil {
-			return nil
-		}
-		retValue, err = item.ValueCopy(nil)
-		if err != nil {
-			return err
-		}
-
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("GlobalStateGet: Error copying value into new slice: %v", err)
-	}
-
-	return retValue, nil
+	return fes.GlobalStore.Get(key)
 }
 
 type GlobalStateBatchGetRemoteRequest struct {
@@ -463,14 +487,14 @@ func (fes *APIServer) GlobalStateBatchGetRemote(ww http.ResponseWriter, rr *http
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	requestData := GlobalStateBatchGetRemoteRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateBatchGetRemote: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GlobalStateBatchGetRemote: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Call the get function. Note that this may also proxy to another node.
 	values, err := fes.GlobalStateBatchGet(requestData.KeyList)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GlobalStateBatchGetRemote: Error processing GlobalStateBatchGet: %v", err))
 		return
 	}
@@ -480,80 +504,30 @@ func (fes *APIServer) GlobalStateBatchGetRemote(ww http.ResponseWriter, rr *http
 		ValueList: values,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateBatchGetRemote: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GlobalStateBatchGetRemote: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
 
 func (fes *APIServer) CreateGlobalStateBatchGetRequest(keyList [][]byte) (
-	_url string, _json_data []byte, _err error) {
+	*http.Request, error) {
 
 	req := GlobalStateBatchGetRemoteRequest{
 		KeyList: keyList,
 	}
-	json_data, err := json.Marshal(req)
+	request, err := createGlobalStateRemoteRequest(fes.GlobalStateRemoteNode,
+		fes.GlobalStateRemoteNodeSharedSecret, RoutePathGlobalStateBatchGetRemote, &req)
 	if err != nil {
-		return "", nil, fmt.Errorf("GlobalStateBatchGet: Could not marshal JSON: %v", err)
+		return nil, fmt.Errorf("GlobalStateBatchGet: %v", err)
 	}
 
-	url := fmt.Sprintf("%s%s?%s=%s",
-		fes.GlobalStateRemoteNode, RoutePathGlobalStateBatchGetRemote,
-		GlobalStateSharedSecretParam, fes.GlobalStateRemoteNodeSharedSecret)
-
-	return url, json_data, nil
+	return request, nil
 }
 
+// GlobalStateBatchGet fetches a list of values from global state. Keys that are
+// not present map to an empty byte slice.
 func (fes *APIServer) GlobalStateBatchGet(keyList [][]byte) (value [][]byte, _err error) {
-	// If we have a remote node then use that node to fulfill this request.
-	if fes.GlobalStateRemoteNode != "" {
-		// TODO: This codepath is currently annoying to test.
-
-		url, json_data, err := fes.CreateGlobalStateBatchGetRequest(keyList)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"GlobalStateBatchGet: Error constructing request: %v", err)
-		}
-
-		resReturned, err := http.Post(
-			url,
-			"application/json", /*contentType*/
-			bytes.NewBuffer(json_data))
-		if err != nil {
-			return nil, fmt.Errorf("GlobalStateBatchGet: Error processing remote request")
-		}
-
-		res := GlobalStateBatchGetRemoteResponse{}
-		json.NewDecoder(resReturned.Body).Decode(&res)
-		resReturned.Body.Close()
-
-		return res.ValueList, nil
-	}
-
-	// If we get here, it means we don't have a remote node so get the
-	// data from our local db.
-	var retValueList [][]byte
-	err := fes.GlobalStateDB.View(func(txn *badger.Txn) error {
-		for _, key := range keyList {
-			item, err := txn.Get(key)
-			if err != nil {
-				retValueList = append(retValueList, []byte{})
-				continue
-			}
-			value, err := item.ValueCopy(nil)
-			if err != nil {
-				return err
-			} else {
-				retValueList = append(retValueList, value)
-			}
-		}
-
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("GlobalStateBatchGet: Error copying value into new slice: %v", err)
-	}
-
-	return retValueList, nil
+	return fes.GlobalStore.BatchGet(keyList)
 }
 
 type GlobalStateDeleteRemoteRequest struct {
@@ -564,21 +538,18 @@ type GlobalStateDeleteRemoteResponse struct {
 }
 
 func (fes *APIServer) CreateGlobalStateDeleteRequest(key []byte) (
-	_url string, _json_data []byte, _err error) {
+	*http.Request, error) {
 
 	req := GlobalStateDeleteRemoteRequest{
 		Key: key,
 	}
-	json_data, err := json.Marshal(req)
+	request, err := createGlobalStateRemoteRequest(fes.GlobalStateRemoteNode,
+		fes.GlobalStateRemoteNodeSharedSecret, RoutePathGlobalStateDeleteRemote, &req)
 	if err != nil {
-		return "", nil, fmt.Errorf("GlobalStateDelete: Could not marshal JSON: %v", err)
+		return nil, fmt.Errorf("GlobalStateDelete: %v", err)
 	}
 
-	url := fmt.Sprintf("%s%s?%s=%s",
-		fes.GlobalStateRemoteNode, RoutePathGlobalStateDeleteRemote,
-		GlobalStateSharedSecretParam, fes.GlobalStateRemoteNodeSharedSecret)
-
-	return url, json_data, nil
+	return request, nil
 }
 
 func (fes *APIServer) GlobalStateDeleteRemote(ww http.ResponseWriter, rr *http.Request) {
@@ -586,13 +557,13 @@ func (fes *APIServer) GlobalStateDeleteRemote(ww http.ResponseWriter, rr *http.R
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	requestData := GlobalStateDeleteRemoteRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateDeleteRemote: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GlobalStateDeleteRemote: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Call the Delete function. Note that this may also proxy to another node.
 	if err := fes.GlobalStateDelete(requestData.Key); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GlobalStateDeleteRemote: Error processing GlobalStateDelete: %v", err))
 		return
 	}
@@ -600,42 +571,18 @@ func (fes *APIServer) GlobalStateDeleteRemote(ww http.ResponseWriter, rr *http.R
 	// Return
 	res := GlobalStateDeleteRemoteResponse{}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateDeleteRemote: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GlobalStateDeleteRemote: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
 
+// GlobalStateDelete removes a key from global state.
 func (fes *APIServer) GlobalStateDelete(key []byte) error {
-	// If we have a remote node then use that node to fulfill this request.
-	if fes.GlobalStateRemoteNode != "" {
-		// TODO: This codepath is currently annoying to test.
-
-		url, json_data, err := fes.CreateGlobalStateDeleteRequest(key)
-		if err != nil {
-			return fmt.Errorf("GlobalStateDelete: Could not construct request: %v", err)
-		}
-
-		res, err := http.Post(
-			url,
-			"application/json", /*contentType*/
-			bytes.NewBuffer(json_data))
-		if err != nil {
-			return fmt.Errorf("GlobalStateDelete: Error processing remote request")
-		}
-
-		res.Body.Close()
-		//res := GlobalStateDeleteRemoteResponse{}
-		//json.NewDecoder(resReturned.Body).Decode(&res)
-
-		// No error means nothing to return.
-		return nil
+	if err := fes.GlobalStore.Delete(key); err != nil {
+		return err
 	}
-
-	// If we get here, it means we don't have a remote node so store the
-	// data in our local db.
-	return fes.GlobalStateDB.Update(func(txn *badger.Txn) error {
-		return txn.Delete(key)
-	})
+	fes.invalidateFeedCacheForGlobalStateKey(key)
+	return nil
 }
 
 type GlobalStateSeekRemoteRequest struct {
@@ -653,7 +600,7 @@ type GlobalStateSeekRemoteResponse struct {
 
 func (fes *APIServer) CreateGlobalStateSeekRequest(startPrefix []byte, validForPrefix []byte,
 	maxKeyLen int, numToFetch int, reverse bool, fetchValues bool) (
-	_url string, _json_data []byte, _err error) {
+	*http.Request, error) {
 
 	req := GlobalStateSeekRemoteRequest{
 		StartPrefix:    startPrefix,
@@ -663,23 +610,20 @@ func (fes *APIServer) CreateGlobalStateSeekRequest(startPrefix []byte, validForP
 		Reverse:        reverse,
 		FetchValues:    fetchValues,
 	}
-	json_data, err := json.Marshal(req)
+	request, err := createGlobalStateRemoteRequest(fes.GlobalStateRemoteNode,
+		fes.GlobalStateRemoteNodeSharedSecret, RoutePathGlobalStateSeekRemote, &req)
 	if err != nil {
-		return "", nil, fmt.Errorf("GlobalStateSeek: Could not marshal JSON: %v", err)
+		return nil, fmt.Errorf("GlobalStateSeek: %v", err)
 	}
 
-	url := fmt.Sprintf("%s%s?%s=%s",
-		fes.GlobalStateRemoteNode, RoutePathGlobalStateSeekRemote,
-		GlobalStateSharedSecretParam, fes.GlobalStateRemoteNodeSharedSecret)
-
-	return url, json_data, nil
+	return request, nil
 }
 func (fes *APIServer) GlobalStateSeekRemote(ww http.ResponseWriter, rr *http.Request) {
 	// Parse the request.
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	requestData := GlobalStateSeekRemoteRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateSeekRemote: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GlobalStateSeekRemote: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -693,7 +637,7 @@ func (fes *APIServer) GlobalStateSeekRemote(ww http.ResponseWriter, rr *http.Req
 		requestData.FetchValues,
 	)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GlobalStateSeekRemote: Error processing GlobalStateSeek: %v", err))
 		return
 	}
@@ -704,53 +648,113 @@ func (fes *APIServer) GlobalStateSeekRemote(ww http.ResponseWriter, rr *http.Req
 		ValsFound: values,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateSeekRemote: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GlobalStateSeekRemote: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
 
+// GlobalStateSeek returns a page of keys (and optionally values) from global state.
+// See lib.DBGetPaginatedKeysAndValuesForPrefix for the semantics of the arguments.
 func (fes *APIServer) GlobalStateSeek(startPrefix []byte, validForPrefix []byte,
 	maxKeyLen int, numToFetch int, reverse bool, fetchValues bool) (
 	_keysFound [][]byte, _valsFound [][]byte, _err error) {
 
-	// If we have a remote node then use that node to fulfill this request.
-	if fes.GlobalStateRemoteNode != "" {
-		// TODO: This codepath is currently annoying to test.
-
-		url, json_data, err := fes.CreateGlobalStateSeekRequest(
-			startPrefix,
-			validForPrefix,
-			maxKeyLen,
-			numToFetch,
-			reverse,
-			fetchValues)
-		if err != nil {
-			return nil, nil, fmt.Errorf(
-				"GlobalStateSeek: Error constructing request: %v", err)
-		}
+	return fes.GlobalStore.Seek(startPrefix, validForPrefix, maxKeyLen, numToFetch, reverse, fetchValues)
+}
 
-		resReturned, err := http.Post(
-			url,
-			"application/json", /*contentType*/
-			bytes.NewBuffer(json_data))
-		if err != nil {
-			return nil, nil, fmt.Errorf("GlobalStateSeek: Error processing remote request")
-		}
+type GlobalStateCompareAndSwapRemoteRequest struct {
+	Key           []byte
+	ExpectedValue []byte
+	NewValue      []byte
+}
+
+type GlobalStateCompareAndSwapRemoteResponse struct {
+	Swapped bool
+}
+
+func (fes *APIServer) CreateGlobalStateCompareAndSwapRequest(key []byte, expectedValue []byte, newValue []byte) (
+	*http.Request, error) {
+
+	req := GlobalStateCompareAndSwapRemoteRequest{
+		Key:           key,
+		ExpectedValue: expectedValue,
+		NewValue:      newValue,
+	}
+	request, err := createGlobalStateRemoteRequest(fes.GlobalStateRemoteNode,
+		fes.GlobalStateRemoteNodeSharedSecret, RoutePathGlobalStateCompareAndSwapRemote, &req)
+	if err != nil {
+		return nil, fmt.Errorf("GlobalStateCompareAndSwap: %v", err)
+	}
 
-		res := GlobalStateSeekRemoteResponse{}
-		json.NewDecoder(resReturned.Body).Decode(&res)
-		resReturned.Body.Close()
+	return request, nil
+}
 
-		return res.KeysFound, res.ValsFound, nil
+func (fes *APIServer) GlobalStateCompareAndSwapRemote(ww http.ResponseWriter, rr *http.Request) {
+	// Parse the request.
+	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
+	requestData := GlobalStateCompareAndSwapRemoteRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GlobalStateCompareAndSwapRemote: Problem parsing request body: %v", err))
+		return
 	}
 
-	// If we get here, it means we don't have a remote node so get the
-	// data from our local db.
-	retKeys, retVals, err := lib.DBGetPaginatedKeysAndValuesForPrefix(fes.GlobalStateDB, startPrefix,
-		validForPrefix, maxKeyLen, numToFetch, reverse, fetchValues)
+	// Call the compare-and-swap function. Note that this may also proxy to another node.
+	swapped, err := fes.GlobalStateCompareAndSwap(requestData.Key, requestData.ExpectedValue, requestData.NewValue)
 	if err != nil {
-		return nil, nil, fmt.Errorf("GlobalStateSeek: Error getting paginated keys and values: %v", err)
+		_AddInternalServerError(ww, fmt.Sprintf(
+			"GlobalStateCompareAndSwapRemote: Error processing GlobalStateCompareAndSwap: %v", err))
+		return
+	}
+
+	// Return
+	res := GlobalStateCompareAndSwapRemoteResponse{
+		Swapped: swapped,
+	}
+	if err := json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("GlobalStateCompareAndSwapRemote: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// GlobalStateCompareAndSwap sets key to newValue only if its current value is
+// expectedValue. See GlobalStore.CompareAndSwap.
+func (fes *APIServer) GlobalStateCompareAndSwap(key []byte, expectedValue []byte, newValue []byte) (
+	_swapped bool, _err error) {
+
+	swapped, err := fes.GlobalStore.CompareAndSwap(key, expectedValue, newValue)
+	if swapped {
+		fes.invalidateFeedCacheForGlobalStateKey(key)
+	}
+	return swapped, err
+}
+
+// GlobalStateUpdate performs an atomic read-modify-write on a single key. It reads
+// the current value (nil if the key is not present), passes it to updateFunc, and
+// writes the result back with a compare-and-swap. If another writer changed the key
+// in the meantime, the whole cycle is retried so no update is silently dropped.
+// Returning a nil value from updateFunc deletes the key, and returning an error
+// aborts the update without writing anything.
+func (fes *APIServer) GlobalStateUpdate(key []byte, updateFunc func(oldValue []byte) (_newValue []byte, _err error)) error {
+	for attempt := 0; attempt < GlobalStateMaxUpdateAttempts; attempt++ {
+		oldValue, err := fes.GlobalStateGet(key)
+		if err != nil {
+			return fmt.Errorf("GlobalStateUpdate: Problem getting value: %v", err)
+		}
+
+		newValue, err := updateFunc(oldValue)
+		if err != nil {
+			return err
+		}
+
+		swapped, err := fes.GlobalStateCompareAndSwap(key, oldValue, newValue)
+		if err != nil {
+			return fmt.Errorf("GlobalStateUpdate: Problem swapping value: %v", err)
+		}
+		if swapped {
+			return nil
+		}
 	}
 
-	return retKeys, retVals, nil
+	return fmt.Errorf("GlobalStateUpdate: Gave up after %d attempts due to concurrent updates",
+		GlobalStateMaxUpdateAttempts)
 }
@@ -0,0 +1,206 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sasha-s/go-deadlock"
+)
+
+const (
+	// Headers used to sign requests to the /api/v1/global-state/* routes. The
+	// signature is an HMAC-SHA256 over the method, path, timestamp, nonce and a
+	// hash of the body, keyed by the shared secret.
+	GlobalStateTimestampHeader = "X-Global-State-Timestamp"
+	GlobalStateNonceHeader     = "X-Global-State-Nonce"
+	GlobalStateSignatureHeader = "X-Global-State-Signature"
+
+	// Requests whose timestamp is further than this from our clock are rejected.
+	// Nonces only need to be remembered for this long since anything older fails
+	// the timestamp check anyway.
+	GlobalStateMaxClockSkew = 60 * time.Second
+
+	// The number of random bytes in a request nonce.
+	globalStateNonceBytes = 16
+)
+
+// computeGlobalStateSignature returns the hex-encoded HMAC for a request.
+func computeGlobalStateSignature(secret string, method string, path string,
+	timestamp string, nonce string, body []byte) string {
+
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + path + "\n" + timestamp + "\n" + nonce + "\n"))
+	mac.Write([]byte(hex.EncodeToString(bodyHash[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSignedGlobalStateRequest builds a POST request to routePath on remoteNode with
+// the given JSON body and signs it with secret.
+func newSignedGlobalStateRequest(remoteNode string, secret string, routePath string,
+	jsonData []byte) (*http.Request, error) {
+
+	req, err := http.NewRequest("POST", remoteNode+routePath, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("newSignedGlobalStateRequest: Problem creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := SignGlobalStateRequest(req, secret, jsonData); err != nil {
+		return nil, fmt.Errorf("newSignedGlobalStateRequest: %v", err)
+	}
+	return req, nil
+}
+
+// SignGlobalStateRequest adds the headers CheckGlobalStateSignature expects to req,
+// whose body must be jsonData. Every call uses a fresh nonce, so a request that's
+// retried has to be signed again.
+func SignGlobalStateRequest(req *http.Request, secret string, jsonData []byte) error {
+	nonceBytes := make([]byte, globalStateNonceBytes)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return fmt.Errorf("SignGlobalStateRequest: Problem generating nonce: %v", err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	req.Header.Set(GlobalStateTimestampHeader, timestamp)
+	req.Header.Set(GlobalStateNonceHeader, nonce)
+	req.Header.Set(GlobalStateSignatureHeader,
+		computeGlobalStateSignature(secret, req.Method, req.URL.Path, timestamp, nonce, jsonData))
+	return nil
+}
+
+// nonceCache remembers the nonces we've seen recently so that signed requests and
+// tokens can't be replayed while they're still valid. The zero value is ready to
+// use.
+type nonceCache struct {
+	mtx           deadlock.Mutex
+	expiryByNonce map[string]time.Time
+	lastPruneTime time.Time
+}
+
+// checkAndAdd returns false if the nonce has already been used. Otherwise it records
+// the nonce until expiry and returns true.
+func (cache *nonceCache) checkAndAdd(nonce string, now time.Time, expiry time.Time) bool {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	if cache.expiryByNonce == nil {
+		cache.expiryByNonce = make(map[string]time.Time)
+	}
+	// Prune expired nonces at most once per skew window to keep this cheap.
+	if now.Sub(cache.lastPruneTime) > GlobalStateMaxClockSkew {
+		for existingNonce, existingExpiry := range cache.expiryByNonce {
+			if now.After(existingExpiry) {
+				delete(cache.expiryByNonce, existingNonce)
+			}
+		}
+		cache.lastPruneTime = now
+	}
+
+	if existingExpiry, exists := cache.expiryByNonce[nonce]; exists && !now.After(existingExpiry) {
+		return false
+	}
+	cache.expiryByNonce[nonce] = expiry
+	return true
+}
+
+// globalStateSecrets returns the secrets that are currently accepted on incoming
+// global state requests. Two secrets can be active at once so that secrets can be
+// rotated across a fleet of nodes without downtime.
+func (fes *APIServer) globalStateSecrets() []string {
+	secrets := []string{}
+	for _, secret := range []string{
+		fes.GlobalStateRemoteNodeSharedSecret, fes.GlobalStatePreviousSharedSecret} {
+
+		if secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// verifyGlobalStateRequest checks the signature, timestamp and nonce on an incoming
+// global state request.
+func (fes *APIServer) verifyGlobalStateRequest(req *http.Request, body []byte) error {
+	secrets := fes.globalStateSecrets()
+	if len(secrets) == 0 {
+		return fmt.Errorf("This node is not configured to accept global state requests")
+	}
+
+	timestamp := req.Header.Get(GlobalStateTimestampHeader)
+	nonce := req.Header.Get(GlobalStateNonceHeader)
+	signature := req.Header.Get(GlobalStateSignatureHeader)
+	if timestamp == "" || nonce == "" || signature == "" {
+		return fmt.Errorf("Missing signature headers")
+	}
+
+	timestampNanos, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid timestamp: %v", err)
+	}
+	now := time.Now()
+	skew := now.Sub(time.Unix(0, timestampNanos))
+	if skew > GlobalStateMaxClockSkew || skew < -GlobalStateMaxClockSkew {
+		return fmt.Errorf("Timestamp is outside of the allowed window")
+	}
+
+	signatureMatches := false
+	for _, secret := range secrets {
+		expectedSignature := computeGlobalStateSignature(
+			secret, req.Method, req.URL.Path, timestamp, nonce, body)
+		if hmac.Equal([]byte(expectedSignature), []byte(signature)) {
+			signatureMatches = true
+			break
+		}
+	}
+	if !signatureMatches {
+		return fmt.Errorf("Invalid signature")
+	}
+
+	// Only record the nonce once we know the request is authentic so that an
+	// attacker can't fill up the cache.
+	// A request's timestamp may be up to GlobalStateMaxClockSkew in the future, so
+	// we need to keep the nonce around for twice the skew.
+	if !fes.globalStateNonceCache.checkAndAdd(nonce, now, now.Add(2*GlobalStateMaxClockSkew)) {
+		return fmt.Errorf("Nonce has already been used")
+	}
+
+	return nil
+}
+
+// CheckGlobalStateSignature wraps the /api/v1/global-state/* handlers and rejects
+// any request that isn't signed with one of our active shared secrets.
+func (fes *APIServer) CheckGlobalStateSignature(inner http.HandlerFunc) http.HandlerFunc {
+	return func(ww http.ResponseWriter, req *http.Request) {
+		if req.Body == nil {
+			_AddCodedError(ww, ErrorCodeInvalidRequestBody, "CheckGlobalStateSignature: Request has no Body attribute")
+			return
+		}
+
+		// We read the entire body and then create a new ReadCloser Body object
+		// from the bytes we read because you can only read the body once
+		bodyBytes, err := ioutil.ReadAll(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+		if err != nil {
+			_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("CheckGlobalStateSignature: %v", err))
+			return
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+
+		if err := fes.verifyGlobalStateRequest(req, bodyBytes); err != nil {
+			_AddCodedError(ww, ErrorCodeUnauthorized, fmt.Sprintf("CheckGlobalStateSignature: %v", err))
+			return
+		}
+
+		inner(ww, req)
+	}
+}
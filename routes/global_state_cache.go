@@ -0,0 +1,287 @@
+package routes
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// How long a replica asks the primary to hold a changes request open.
+	globalStateCachePollWait = 20 * time.Second
+	// If the change feed hasn't been caught up for this long, cached values are no
+	// longer trusted and reads go to the primary, falling back to the cache only if
+	// the primary can't be reached.
+	globalStateCacheMaxStaleness = 45 * time.Second
+	// Backoff bounds used when the primary can't be reached.
+	globalStateCacheMinBackoff = 1 * time.Second
+	globalStateCacheMaxBackoff = 30 * time.Second
+	// The most keys kept in a replica's cache.
+	globalStateCacheMaxEntries = 100000
+)
+
+// CachingGlobalStore is used by nodes that proxy global state to a remote node. It
+// keeps a local read-through cache of values and follows the remote node's change
+// feed to drop keys as soon as they change. This keeps hot paths like
+// FilterOutRestrictedPubKeysFromList from making a round trip on every call and
+// lets the node keep serving reads if the remote node is briefly unreachable.
+//
+// Writes always go straight to the remote node. Seek is never cached.
+type CachingGlobalStore struct {
+	remote *RemoteGlobalStore
+
+	mtx sync.RWMutex
+	// Cached values keyed by string(key). A nil value means the key is known to be
+	// absent.
+	cache map[string][]byte
+	// Bumped every time anything is removed from the cache. A read that started
+	// before a bump may have fetched a value that is already stale, so it must not
+	// be added to the cache.
+	generation uint64
+	// The position in the remote node's change feed.
+	epoch        string
+	sequence     uint64
+	lastCaughtUp time.Time
+
+	quit chan struct{}
+}
+
+func NewCachingGlobalStore(remote *RemoteGlobalStore) *CachingGlobalStore {
+	return &CachingGlobalStore{
+		remote: remote,
+		cache:  make(map[string][]byte),
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start follows the remote node's change feed until Stop is called.
+func (cgs *CachingGlobalStore) Start() {
+	backoff := globalStateCacheMinBackoff
+	for {
+		select {
+		case <-cgs.quit:
+			return
+		default:
+		}
+
+		cgs.mtx.RLock()
+		epoch, sequence := cgs.epoch, cgs.sequence
+		cgs.mtx.RUnlock()
+
+		res, err := cgs.remote.Changes(epoch, sequence, globalStateCachePollWait)
+		if err != nil {
+			glog.Errorf("CachingGlobalStore.Start: Problem fetching changes, retrying in %v: %v", backoff, err)
+			select {
+			case <-cgs.quit:
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > globalStateCacheMaxBackoff {
+				backoff = globalStateCacheMaxBackoff
+			}
+			continue
+		}
+		backoff = globalStateCacheMinBackoff
+
+		cgs.applyChanges(res)
+	}
+}
+
+// Stop stops following the change feed. Reads and writes continue to work but
+// stop using the cache shortly after.
+func (cgs *CachingGlobalStore) Stop() {
+	close(cgs.quit)
+}
+
+func (cgs *CachingGlobalStore) applyChanges(res *GlobalStateChangesRemoteResponse) {
+	cgs.mtx.Lock()
+	defer cgs.mtx.Unlock()
+
+	if res.Reset {
+		glog.Infof("CachingGlobalStore.applyChanges: Resetting cache at epoch %v sequence %v",
+			res.Epoch, res.LatestSequence)
+		cgs.cache = make(map[string][]byte)
+		cgs.generation++
+		cgs.epoch = res.Epoch
+		cgs.sequence = res.LatestSequence
+		cgs.lastCaughtUp = time.Now()
+		return
+	}
+
+	for _, change := range res.Changes {
+		delete(cgs.cache, string(change.Key))
+		cgs.sequence = change.Sequence
+	}
+	if len(res.Changes) > 0 {
+		cgs.generation++
+	}
+	// Changes are returned in batches so we're only caught up once we've seen the
+	// latest one.
+	if cgs.sequence == res.LatestSequence {
+		cgs.lastCaughtUp = time.Now()
+	}
+}
+
+// isFreshLocked returns true if the change feed is caught up recently enough for
+// the cache to be trusted. Must be called with mtx held.
+func (cgs *CachingGlobalStore) isFreshLocked() bool {
+	return !cgs.lastCaughtUp.IsZero() && time.Since(cgs.lastCaughtUp) < globalStateCacheMaxStaleness
+}
+
+// globalStateVersion is our position in the remote node's change feed, along
+// with the generation so that our own writes count before they come back through
+// the feed. If the feed hasn't caught up recently, changes may have been missed,
+// so there's no version.
+func (cgs *CachingGlobalStore) globalStateVersion() (_version []byte, _ok bool) {
+	cgs.mtx.RLock()
+	defer cgs.mtx.RUnlock()
+
+	if !cgs.isFreshLocked() {
+		return nil, false
+	}
+	return []byte(fmt.Sprintf("%v:%d:%d", cgs.epoch, cgs.sequence, cgs.generation)), true
+}
+
+// invalidate drops a key that we just wrote. We don't cache the value we wrote
+// since another node's write to the same key may land right after ours.
+func (cgs *CachingGlobalStore) invalidate(key []byte) {
+	cgs.mtx.Lock()
+	defer cgs.mtx.Unlock()
+
+	delete(cgs.cache, string(key))
+	cgs.generation++
+}
+
+// maybeAdd caches a value fetched from the remote node if nothing was invalidated
+// since the fetch started.
+func (cgs *CachingGlobalStore) maybeAdd(generation uint64, keyList [][]byte, valueList [][]byte) {
+	cgs.mtx.Lock()
+	defer cgs.mtx.Unlock()
+
+	if generation != cgs.generation || !cgs.isFreshLocked() {
+		return
+	}
+	for ii, key := range keyList {
+		// Evict arbitrary entries to stay under the limit.
+		for existingKey := range cgs.cache {
+			if len(cgs.cache) < globalStateCacheMaxEntries {
+				break
+			}
+			delete(cgs.cache, existingKey)
+		}
+		cgs.cache[string(key)] = valueList[ii]
+	}
+}
+
+func (cgs *CachingGlobalStore) Put(key []byte, value []byte) error {
+	defer cgs.invalidate(key)
+	return cgs.remote.Put(key, value)
+}
+
+func (cgs *CachingGlobalStore) Get(key []byte) (_value []byte, _err error) {
+	cgs.mtx.RLock()
+	cachedValue, isCached := cgs.cache[string(key)]
+	isFresh := cgs.isFreshLocked()
+	generation := cgs.generation
+	cgs.mtx.RUnlock()
+
+	if isCached && isFresh {
+		return cachedValue, nil
+	}
+
+	value, err := cgs.remote.Get(key)
+	if err != nil {
+		if isCached {
+			glog.Warningf("CachingGlobalStore.Get: Serving possibly stale value because "+
+				"remote node is unreachable: %v", err)
+			return cachedValue, nil
+		}
+		return nil, err
+	}
+	cgs.maybeAdd(generation, [][]byte{key}, [][]byte{value})
+
+	return value, nil
+}
+
+func (cgs *CachingGlobalStore) BatchGet(keyList [][]byte) (_valueList [][]byte, _err error) {
+	valueList := make([][]byte, len(keyList))
+	missingIndexes := []int{}
+	missingKeys := [][]byte{}
+	staleValues := make(map[int][]byte)
+
+	cgs.mtx.RLock()
+	isFresh := cgs.isFreshLocked()
+	generation := cgs.generation
+	for ii, key := range keyList {
+		cachedValue, isCached := cgs.cache[string(key)]
+		if isCached && isFresh {
+			valueList[ii] = cachedValue
+			continue
+		}
+		if isCached {
+			staleValues[ii] = cachedValue
+		}
+		missingIndexes = append(missingIndexes, ii)
+		missingKeys = append(missingKeys, key)
+	}
+	cgs.mtx.RUnlock()
+
+	if len(missingKeys) > 0 {
+		fetchedValues, err := cgs.remote.BatchGet(missingKeys)
+		if err == nil && len(fetchedValues) != len(missingKeys) {
+			err = fmt.Errorf("Expected %d values but got %d", len(missingKeys), len(fetchedValues))
+		}
+		if err != nil {
+			// Only fall back to the cache if every missing key has a cached value.
+			if len(staleValues) != len(missingKeys) {
+				return nil, fmt.Errorf("CachingGlobalStore.BatchGet: %v", err)
+			}
+			glog.Warningf("CachingGlobalStore.BatchGet: Serving possibly stale values because "+
+				"remote node is unreachable: %v", err)
+			for ii, value := range staleValues {
+				valueList[ii] = value
+			}
+		} else {
+			// BatchGet maps missing keys to an empty value. Store them as nil in the
+			// cache so a later Get still returns nil for them.
+			cachedValues := make([][]byte, len(fetchedValues))
+			for jj, value := range fetchedValues {
+				valueList[missingIndexes[jj]] = value
+				if len(value) > 0 {
+					cachedValues[jj] = value
+				}
+			}
+			cgs.maybeAdd(generation, missingKeys, cachedValues)
+		}
+	}
+
+	// Keep BatchGet's convention of returning an empty value for missing keys.
+	for ii, value := range valueList {
+		if value == nil {
+			valueList[ii] = []byte{}
+		}
+	}
+	return valueList, nil
+}
+
+func (cgs *CachingGlobalStore) Delete(key []byte) error {
+	defer cgs.invalidate(key)
+	return cgs.remote.Delete(key)
+}
+
+func (cgs *CachingGlobalStore) Seek(startPrefix []byte, validForPrefix []byte, maxKeyLen int,
+	numToFetch int, reverse bool, fetchValues bool) (_keysFound [][]byte, _valsFound [][]byte, _err error) {
+
+	return cgs.remote.Seek(startPrefix, validForPrefix, maxKeyLen, numToFetch, reverse, fetchValues)
+}
+
+func (cgs *CachingGlobalStore) CompareAndSwap(key []byte, expectedValue []byte, newValue []byte) (
+	_swapped bool, _err error) {
+
+	// Whether or not the swap succeeds, our cached value may be out of date.
+	defer cgs.invalidate(key)
+	return cgs.remote.CompareAndSwap(key, expectedValue, newValue)
+}
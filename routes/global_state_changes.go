@@ -0,0 +1,266 @@
+package routes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// The number of most recent changes the primary keeps around. A replica that
+	// falls further behind than this is told to reset its cache.
+	globalStateChangeLogSize = 10000
+
+	// The most changes returned in a single response to the changes route.
+	globalStateChangesMaxBatchSize = 1000
+
+	// The longest the changes route will block waiting for a new change.
+	GlobalStateChangesMaxWait = 30 * time.Second
+)
+
+// GlobalStateChange records that a key was written or deleted. Only the key is
+// included since replicas simply drop the key from their cache and re-fetch it
+// the next time it's needed.
+type GlobalStateChange struct {
+	Sequence uint64
+	Key      []byte
+}
+
+// ChangeLogGlobalStore wraps the GlobalStore on the node that owns global state
+// and remembers which keys were recently changed. Replicas follow these changes
+// via the /api/v1/global-state/changes route to keep their caches up to date.
+//
+// The log lives in memory only. Each ChangeLogGlobalStore has a random epoch so
+// that a replica can tell when the primary restarted and its log was lost.
+type ChangeLogGlobalStore struct {
+	store GlobalStore
+
+	mtx   sync.Mutex
+	epoch string
+	// The sequence number of the most recent change, or zero if there are none.
+	latestSequence uint64
+	// The most recent changes in order of Sequence. Holds at most
+	// globalStateChangeLogSize entries.
+	changes []*GlobalStateChange
+	// Closed and replaced every time a change is recorded so that long-polling
+	// callers can wait on it.
+	newChange chan struct{}
+}
+
+func NewChangeLogGlobalStore(store GlobalStore) (*ChangeLogGlobalStore, error) {
+	epochBytes := make([]byte, 16)
+	if _, err := rand.Read(epochBytes); err != nil {
+		return nil, fmt.Errorf("NewChangeLogGlobalStore: Problem generating epoch: %v", err)
+	}
+	return &ChangeLogGlobalStore{
+		store:     store,
+		epoch:     hex.EncodeToString(epochBytes),
+		newChange: make(chan struct{}),
+	}, nil
+}
+
+func (clgs *ChangeLogGlobalStore) recordChange(key []byte) {
+	clgs.mtx.Lock()
+	defer clgs.mtx.Unlock()
+
+	clgs.latestSequence++
+	clgs.changes = append(clgs.changes, &GlobalStateChange{
+		Sequence: clgs.latestSequence,
+		Key:      append([]byte{}, key...),
+	})
+	if len(clgs.changes) > globalStateChangeLogSize {
+		clgs.changes = append([]*GlobalStateChange{},
+			clgs.changes[len(clgs.changes)-globalStateChangeLogSize:]...)
+	}
+
+	close(clgs.newChange)
+	clgs.newChange = make(chan struct{})
+}
+
+// globalStateVersion is the epoch and the latest sequence. Every write through
+// the store is recorded, so these change whenever global state does.
+func (clgs *ChangeLogGlobalStore) globalStateVersion() (_version []byte, _ok bool) {
+	clgs.mtx.Lock()
+	defer clgs.mtx.Unlock()
+
+	return []byte(fmt.Sprintf("%v:%d", clgs.epoch, clgs.latestSequence)), true
+}
+
+// changesSinceLocked returns the changes after afterSequence. It returns reset=true
+// if the caller has missed changes that are no longer in the log. Must be called
+// with mtx held.
+func (clgs *ChangeLogGlobalStore) changesSinceLocked(epoch string, afterSequence uint64) (
+	_changes []*GlobalStateChange, _reset bool) {
+
+	if epoch != clgs.epoch || afterSequence > clgs.latestSequence {
+		return nil, true
+	}
+	if afterSequence == clgs.latestSequence {
+		return nil, false
+	}
+	// The log is contiguous so we can index straight into it.
+	oldestSequence := clgs.changes[0].Sequence
+	if afterSequence+1 < oldestSequence {
+		return nil, true
+	}
+	changes := clgs.changes[afterSequence+1-oldestSequence:]
+	if len(changes) > globalStateChangesMaxBatchSize {
+		changes = changes[:globalStateChangesMaxBatchSize]
+	}
+	return append([]*GlobalStateChange{}, changes...), false
+}
+
+// ChangesSince returns the changes recorded after afterSequence, waiting up to
+// maxWait for one to arrive if there are none yet. If the epoch doesn't match or
+// the caller has fallen too far behind, reset is true and the caller should drop
+// everything it has cached and continue from the returned epoch and sequence.
+func (clgs *ChangeLogGlobalStore) ChangesSince(epoch string, afterSequence uint64, maxWait time.Duration) (
+	_epoch string, _changes []*GlobalStateChange, _latestSequence uint64, _reset bool) {
+
+	clgs.mtx.Lock()
+	changes, reset := clgs.changesSinceLocked(epoch, afterSequence)
+	if reset || len(changes) > 0 || maxWait <= 0 {
+		defer clgs.mtx.Unlock()
+		return clgs.epoch, changes, clgs.latestSequence, reset
+	}
+	newChange := clgs.newChange
+	clgs.mtx.Unlock()
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+	select {
+	case <-newChange:
+	case <-timer.C:
+	}
+
+	clgs.mtx.Lock()
+	defer clgs.mtx.Unlock()
+	changes, reset = clgs.changesSinceLocked(epoch, afterSequence)
+	return clgs.epoch, changes, clgs.latestSequence, reset
+}
+
+func (clgs *ChangeLogGlobalStore) Put(key []byte, value []byte) error {
+	if err := clgs.store.Put(key, value); err != nil {
+		return err
+	}
+	clgs.recordChange(key)
+	return nil
+}
+
+func (clgs *ChangeLogGlobalStore) Get(key []byte) (_value []byte, _err error) {
+	return clgs.store.Get(key)
+}
+
+func (clgs *ChangeLogGlobalStore) BatchGet(keyList [][]byte) (_valueList [][]byte, _err error) {
+	return clgs.store.BatchGet(keyList)
+}
+
+func (clgs *ChangeLogGlobalStore) Delete(key []byte) error {
+	if err := clgs.store.Delete(key); err != nil {
+		return err
+	}
+	clgs.recordChange(key)
+	return nil
+}
+
+func (clgs *ChangeLogGlobalStore) Seek(startPrefix []byte, validForPrefix []byte, maxKeyLen int,
+	numToFetch int, reverse bool, fetchValues bool) (_keysFound [][]byte, _valsFound [][]byte, _err error) {
+
+	return clgs.store.Seek(startPrefix, validForPrefix, maxKeyLen, numToFetch, reverse, fetchValues)
+}
+
+func (clgs *ChangeLogGlobalStore) CompareAndSwap(key []byte, expectedValue []byte, newValue []byte) (
+	_swapped bool, _err error) {
+
+	swapped, err := clgs.store.CompareAndSwap(key, expectedValue, newValue)
+	if err != nil {
+		return false, err
+	}
+	if swapped {
+		clgs.recordChange(key)
+	}
+	return swapped, nil
+}
+
+type GlobalStateChangesRemoteRequest struct {
+	// The epoch and sequence returned by the previous call. Leave both empty on the
+	// first call.
+	Epoch         string
+	AfterSequence uint64
+	// If there are no changes yet, wait up to this long for one before returning.
+	MaxWaitMillis uint64
+}
+
+type GlobalStateChangesRemoteResponse struct {
+	Epoch          string
+	Changes        []*GlobalStateChange
+	LatestSequence uint64
+	// If true, the caller has missed changes and must drop its entire cache.
+	Reset bool
+}
+
+func (fes *APIServer) GlobalStateChangesRemote(ww http.ResponseWriter, rr *http.Request) {
+	// Parse the request.
+	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
+	requestData := GlobalStateChangesRemoteRequest{}
+	if err := decoder.Decode(&requestData); err != nil {
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GlobalStateChangesRemote: Problem parsing request body: %v", err))
+		return
+	}
+
+	// Only the node that owns global state keeps a change log.
+	changeLog, ok := fes.GlobalStore.(*ChangeLogGlobalStore)
+	if !ok {
+		_AddCodedError(ww, ErrorCodeFeatureDisabled, "GlobalStateChangesRemote: This node does not serve global state changes")
+		return
+	}
+
+	maxWait := time.Duration(requestData.MaxWaitMillis) * time.Millisecond
+	if maxWait > GlobalStateChangesMaxWait {
+		maxWait = GlobalStateChangesMaxWait
+	}
+	epoch, changes, latestSequence, reset := changeLog.ChangesSince(
+		requestData.Epoch, requestData.AfterSequence, maxWait)
+
+	// Return
+	res := GlobalStateChangesRemoteResponse{
+		Epoch:          epoch,
+		Changes:        changes,
+		LatestSequence: latestSequence,
+		Reset:          reset,
+	}
+	if err := json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("GlobalStateChangesRemote: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// Changes fetches the changes made on the remote node after afterSequence. See
+// ChangeLogGlobalStore.ChangesSince.
+func (rgs *RemoteGlobalStore) Changes(epoch string, afterSequence uint64, maxWait time.Duration) (
+	*GlobalStateChangesRemoteResponse, error) {
+
+	req := GlobalStateChangesRemoteRequest{
+		Epoch:         epoch,
+		AfterSequence: afterSequence,
+		MaxWaitMillis: uint64(maxWait / time.Millisecond),
+	}
+	res := GlobalStateChangesRemoteResponse{}
+	// The remote node holds the request open for up to maxWait so allow for that
+	// on top of the usual timeout.
+	if err := rgs.Client.Call(RoutePathGlobalStateChangesRemote, &req, &res, maxWait, true /*idempotent*/); err != nil {
+		return nil, errors.Wrapf(err, "RemoteGlobalStore.Changes: ")
+	}
+	if res.Epoch == "" {
+		return nil, fmt.Errorf("RemoteGlobalStore.Changes: Remote node did not return an epoch")
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,195 @@
+package routes
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// The default time allowed for a single call to a remote node, including
+	// reading the response.
+	GlobalStateRemoteTimeout = 10 * time.Second
+	// The default number of times an idempotent call is attempted before giving up.
+	GlobalStateRemoteMaxAttempts = 3
+	// The delay before the first retry. It doubles after every attempt.
+	globalStateRemoteInitialBackoff = 100 * time.Millisecond
+)
+
+// GlobalStateRemoteError is returned by GlobalStateClient whenever a call to the
+// remote node fails. Callers must treat it as "unknown" rather than "not found"
+// since the remote node may well have a value for the key.
+type GlobalStateRemoteError struct {
+	RoutePath string
+	// The HTTP status code returned by the remote node, or zero if no response
+	// was received.
+	StatusCode int
+	// The error message the remote node put in its response, if any.
+	RemoteError string
+	// The underlying transport or decoding error, if any.
+	Err error
+}
+
+func (remoteErr *GlobalStateRemoteError) Error() string {
+	if remoteErr.RemoteError != "" {
+		return fmt.Sprintf("Remote node returned status %d for %v: %v",
+			remoteErr.StatusCode, remoteErr.RoutePath, remoteErr.RemoteError)
+	}
+	if remoteErr.StatusCode != 0 && remoteErr.StatusCode != http.StatusOK {
+		return fmt.Sprintf("Remote node returned status %d for %v", remoteErr.StatusCode, remoteErr.RoutePath)
+	}
+	return fmt.Sprintf("Problem calling %v on remote node: %v", remoteErr.RoutePath, remoteErr.Err)
+}
+
+func (remoteErr *GlobalStateRemoteError) Unwrap() error {
+	return remoteErr.Err
+}
+
+// IsRetryable returns true if the call may succeed if it is tried again. Errors
+// the remote node reports with a 4xx status are not retried, with the exception
+// of 429.
+func (remoteErr *GlobalStateRemoteError) IsRetryable() bool {
+	return remoteErr.StatusCode == 0 ||
+		remoteErr.StatusCode >= http.StatusInternalServerError ||
+		remoteErr.StatusCode == http.StatusTooManyRequests
+}
+
+// GlobalStateClient makes signed calls to the /api/v1/global-state/* routes on a
+// remote node. It reuses connections across calls, bounds every call with a
+// timeout, and retries idempotent calls that fail for transient reasons.
+type GlobalStateClient struct {
+	RemoteNode   string
+	SharedSecret string
+	// The time allowed for a single attempt.
+	Timeout time.Duration
+	// The number of attempts made for idempotent calls.
+	MaxAttempts int
+
+	transport  *http.Transport
+	httpClient *http.Client
+}
+
+func NewGlobalStateClient(remoteNode string, sharedSecret string) *GlobalStateClient {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:   true,
+		TLSHandshakeTimeout: 5 * time.Second,
+		// The default of two idle connections per host causes a new connection to
+		// be opened for almost every call when we're under load since every call
+		// goes to the same host.
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &GlobalStateClient{
+		RemoteNode:   remoteNode,
+		SharedSecret: sharedSecret,
+		Timeout:      GlobalStateRemoteTimeout,
+		MaxAttempts:  GlobalStateRemoteMaxAttempts,
+		transport:    transport,
+		httpClient:   &http.Client{Transport: transport},
+	}
+}
+
+// SetTLSConfig sets the config used to connect to a RemoteNode served over https,
+// e.g. to present a client certificate. It must be called before any calls are
+// made. See CertificateReloader.ClientTLSConfig.
+func (client *GlobalStateClient) SetTLSConfig(tlsConfig *tls.Config) {
+	client.transport.TLSClientConfig = tlsConfig
+}
+
+// Call posts req to routePath and decodes the response into res if res is non-nil.
+// extraTimeout is added to the client's Timeout and is useful for calls that are
+// expected to block on the remote node. Only idempotent calls are retried since a
+// call that timed out may still have been applied by the remote node.
+func (client *GlobalStateClient) Call(routePath string, req interface{}, res interface{},
+	extraTimeout time.Duration, idempotent bool) error {
+
+	start := time.Now()
+	defer func() {
+		metricGlobalStateRemoteCallDuration.WithLabelValues(routePath).Observe(time.Since(start).Seconds())
+	}()
+
+	maxAttempts := 1
+	if idempotent && client.MaxAttempts > 1 {
+		maxAttempts = client.MaxAttempts
+	}
+
+	backoff := globalStateRemoteInitialBackoff
+	var remoteErr *GlobalStateRemoteError
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		remoteErr = client.callOnce(routePath, req, res, client.Timeout+extraTimeout)
+		if remoteErr == nil {
+			return nil
+		}
+		if !remoteErr.IsRetryable() || attempt == maxAttempts {
+			break
+		}
+
+		glog.V(1).Infof("GlobalStateClient.Call: Attempt %d of %d failed, retrying in %v: %v",
+			attempt, maxAttempts, backoff, remoteErr)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	metricGlobalStateRemoteCallFailures.WithLabelValues(routePath).Inc()
+	return remoteErr
+}
+
+func (client *GlobalStateClient) callOnce(routePath string, req interface{}, res interface{},
+	timeout time.Duration) *GlobalStateRemoteError {
+
+	// Every attempt gets a fresh nonce so retries aren't rejected as replays.
+	request, err := createGlobalStateRemoteRequest(client.RemoteNode, client.SharedSecret, routePath, req)
+	if err != nil {
+		// Nothing was sent so there's no point in retrying.
+		return &GlobalStateRemoteError{RoutePath: routePath, StatusCode: http.StatusBadRequest, Err: err}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resReturned, err := client.httpClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return &GlobalStateRemoteError{RoutePath: routePath, Err: err}
+	}
+	defer resReturned.Body.Close()
+
+	if resReturned.StatusCode != http.StatusOK {
+		remoteErr := &GlobalStateRemoteError{RoutePath: routePath, StatusCode: resReturned.StatusCode}
+		errorRes := struct {
+			Error string `json:"error"`
+		}{}
+		if err := json.NewDecoder(io.LimitReader(resReturned.Body, MaxRequestBodySizeBytes)).Decode(&errorRes); err == nil {
+			remoteErr.RemoteError = errorRes.Error
+		}
+		return remoteErr
+	}
+
+	if res == nil {
+		// Drain the body so the connection can be reused.
+		_, _ = io.Copy(ioutil.Discard, resReturned.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resReturned.Body).Decode(res); err != nil {
+		// A truncated body usually means the connection dropped, so this is
+		// treated like a transport error and retried.
+		return &GlobalStateRemoteError{
+			RoutePath: routePath,
+			Err:       fmt.Errorf("Problem decoding response: %v", err),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/bitclout/core/lib"
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// GlobalStateDecodeFunc turns the portion of a key after its prefix, along with the
+// raw value, into objects that can be marshaled to readable JSON. Either return
+// value may be nil if there is nothing interesting to show.
+type GlobalStateDecodeFunc func(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	_decodedKey interface{}, _decodedValue interface{}, _err error)
+
+// GlobalStateEntry is a single key/value pair from global state along with its
+// decoded form. KeyHex and ValueHex are authoritative; Key and Value are only
+// there to make the entry readable.
+type GlobalStateEntry struct {
+	Prefix      string
+	KeyHex      string
+	ValueHex    string
+	Key         interface{} `json:",omitempty"`
+	Value       interface{} `json:",omitempty"`
+	DecodeError string      `json:",omitempty"`
+}
+
+// DecodeGlobalStateEntry looks up the prefix for key in the registry and uses its
+// decoder to produce a GlobalStateEntry. Decoding problems are reported in the
+// DecodeError field rather than as an error so a single bad entry doesn't stop a
+// caller that is walking all of global state.
+func DecodeGlobalStateEntry(key []byte, value []byte, params *lib.BitCloutParams) *GlobalStateEntry {
+	entry := &GlobalStateEntry{
+		KeyHex:   hex.EncodeToString(key),
+		ValueHex: hex.EncodeToString(value),
+	}
+
+	prefix := GetGlobalStatePrefixForKey(key)
+	if prefix == nil {
+		entry.DecodeError = "Unknown prefix"
+		return entry
+	}
+	entry.Prefix = prefix.Name
+	if prefix.Decode == nil {
+		return entry
+	}
+
+	decodedKey, decodedValue, err := prefix.Decode(key[len(prefix.Prefix):], value, params)
+	if err != nil {
+		entry.DecodeError = err.Error()
+		return entry
+	}
+	entry.Key = decodedKey
+	entry.Value = decodedValue
+	return entry
+}
+
+// Bytes returns the raw key and value for the entry.
+func (entry *GlobalStateEntry) Bytes() (_key []byte, _value []byte, _err error) {
+	key, err := hex.DecodeString(entry.KeyHex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GlobalStateEntry.Bytes: Problem decoding KeyHex: %v", err)
+	}
+	value, err := hex.DecodeString(entry.ValueHex)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GlobalStateEntry.Bytes: Problem decoding ValueHex: %v", err)
+	}
+	return key, value, nil
+}
+
+type decodedPublicKey struct {
+	PublicKeyBase58Check string
+}
+
+type decodedTstampPostHash struct {
+	TstampNanos uint64
+	PostHashHex string
+}
+
+type decodedUserMetadata struct {
+	PublicKeyBase58Check string
+	*UserMetadata
+}
+
+type decodedPhoneNumberMetadata struct {
+	PublicKeyBase58Check string
+	*PhoneNumberMetadata
+}
+
+func decodePublicKeySuffix(keySuffix []byte, params *lib.BitCloutParams) (*decodedPublicKey, error) {
+	if len(keySuffix) != btcec.PubKeyBytesLenCompressed {
+		return nil, fmt.Errorf("Key has invalid public key length %d", len(keySuffix))
+	}
+	return &decodedPublicKey{
+		PublicKeyBase58Check: lib.PkToString(keySuffix, params),
+	}, nil
+}
+
+func decodeUserMetadataEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	decodedKey, err := decodePublicKeySuffix(keySuffix, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	userMetadata := &UserMetadata{}
+	if err = gob.NewDecoder(bytes.NewReader(value)).Decode(userMetadata); err != nil {
+		return nil, nil, fmt.Errorf("Problem decoding UserMetadata: %v", err)
+	}
+	decodedValue := &decodedUserMetadata{
+		UserMetadata: userMetadata,
+	}
+	if len(userMetadata.PublicKey) > 0 {
+		decodedValue.PublicKeyBase58Check = lib.PkToString(userMetadata.PublicKey, params)
+	}
+	return decodedKey, decodedValue, nil
+}
+
+func decodeTstampPostHashEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	if len(keySuffix) != 8+lib.HashSizeBytes {
+		return nil, nil, fmt.Errorf("Key has invalid length %d", len(keySuffix))
+	}
+	return &decodedTstampPostHash{
+		TstampNanos: lib.DecodeUint64(keySuffix[:8]),
+		PostHashHex: hex.EncodeToString(keySuffix[8:]),
+	}, nil, nil
+}
+
+func decodePhoneNumberMetadataEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	phoneNumberMetadata := &PhoneNumberMetadata{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(phoneNumberMetadata); err != nil {
+		return nil, nil, fmt.Errorf("Problem decoding PhoneNumberMetadata: %v", err)
+	}
+	decodedValue := &decodedPhoneNumberMetadata{
+		PhoneNumberMetadata: phoneNumberMetadata,
+	}
+	if len(phoneNumberMetadata.PublicKey) > 0 {
+		decodedValue.PublicKeyBase58Check = lib.PkToString(phoneNumberMetadata.PublicKey, params)
+	}
+	// With blind indexing enabled the key holds an HMAC of the number, not the
+	// E.164 number itself.
+	if !isPlaintextPhoneNumberKeySuffix(keySuffix) {
+		return map[string]string{"PhoneNumberIndexHex": hex.EncodeToString(keySuffix)}, decodedValue, nil
+	}
+	return map[string]string{"PhoneNumber": string(keySuffix)}, decodedValue, nil
+}
+
+func decodeVerifiedMapEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	verifiedMapStruct := VerifiedUsernameToPKID{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&verifiedMapStruct); err != nil {
+		return nil, nil, fmt.Errorf("Problem decoding VerifiedUsernameToPKID: %v", err)
+	}
+	usernameToPKIDBase58Check := make(map[string]string)
+	for username, pkid := range verifiedMapStruct.VerifiedUsernameToPKID {
+		usernameToPKIDBase58Check[username] = lib.PkToString(lib.PKIDToPublicKey(pkid), params)
+	}
+	return nil, usernameToPKIDBase58Check, nil
+}
+
+func decodeUsernameVerificationAuditLogEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	verificationAuditLogs := []VerificationUsernameAuditLog{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&verificationAuditLogs); err != nil {
+		return nil, nil, fmt.Errorf("Problem decoding VerificationUsernameAuditLog: %v", err)
+	}
+	verificationAuditLogsResponse := []VerificationUsernameAuditLogResponse{}
+	for _, verificationAuditLog := range verificationAuditLogs {
+		verificationAuditLogsResponse = append(verificationAuditLogsResponse,
+			VerificationUsernameAuditLogResponse{
+				TimestampNanos:               verificationAuditLog.TimestampNanos,
+				VerifierUsername:             verificationAuditLog.VerifierUsername,
+				VerifierPublicKeyBase58Check: lib.PkToString(lib.PKIDToPublicKey(verificationAuditLog.VerifierPKID), params),
+				VerifiedUsername:             verificationAuditLog.VerifiedUsername,
+				VerifiedPublicKeyBase58Check: lib.PkToString(lib.PKIDToPublicKey(verificationAuditLog.VerifiedPKID), params),
+				IsRemoval:                    verificationAuditLog.IsRemoval,
+			})
+	}
+	return map[string]string{"Username": string(keySuffix)}, verificationAuditLogsResponse, nil
+}
+
+func decodePublicKeyFlagEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	decodedKey, err := decodePublicKeySuffix(keySuffix, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodedKey, nil, nil
+}
+
+func decodeMostRecentReadTstampNanosEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	if len(keySuffix) != 2*btcec.PubKeyBytesLenCompressed {
+		return nil, nil, fmt.Errorf("Key has invalid length %d", len(keySuffix))
+	}
+	if len(value) != 8 {
+		return nil, nil, fmt.Errorf("Value has invalid length %d", len(value))
+	}
+	return map[string]string{
+		"UserPublicKeyBase58Check":    lib.PkToString(keySuffix[:btcec.PubKeyBytesLenCompressed], params),
+		"ContactPublicKeyBase58Check": lib.PkToString(keySuffix[btcec.PubKeyBytesLenCompressed:], params),
+	}, map[string]uint64{
+		"TstampNanos": binary.LittleEndian.Uint64(value),
+	}, nil
+}
+
+func decodeSchemaVersionEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	if len(value) != 8 {
+		return nil, nil, fmt.Errorf("Value has invalid length %d", len(value))
+	}
+	return nil, map[string]uint64{"Version": lib.DecodeUint64(value)}, nil
+}
+
+type decodedGlobalStateAuditLog struct {
+	TimestampNanos            uint64
+	AdminPublicKeyBase58Check string
+	IsDeletion                bool
+	// OldEntry is omitted if the key didn't exist and NewEntry is omitted if it was
+	// deleted.
+	OldEntry *GlobalStateEntry `json:",omitempty"`
+	NewEntry *GlobalStateEntry `json:",omitempty"`
+}
+
+func decodeGlobalStateAuditLogEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	if len(keySuffix) != 8+globalStateAuditLogRandomBytes {
+		return nil, nil, fmt.Errorf("Key has invalid length %d", len(keySuffix))
+	}
+	auditLog := GlobalStateAuditLog{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&auditLog); err != nil {
+		return nil, nil, fmt.Errorf("Problem decoding GlobalStateAuditLog: %v", err)
+	}
+	decodedValue := &decodedGlobalStateAuditLog{
+		TimestampNanos:            auditLog.TimestampNanos,
+		AdminPublicKeyBase58Check: auditLog.AdminPublicKeyBase58Check,
+		IsDeletion:                auditLog.IsDeletion,
+	}
+	if auditLog.OldValue != nil {
+		decodedValue.OldEntry = DecodeGlobalStateEntry(auditLog.Key, auditLog.OldValue, params)
+	}
+	if !auditLog.IsDeletion {
+		decodedValue.NewEntry = DecodeGlobalStateEntry(auditLog.Key, auditLog.NewValue, params)
+	}
+	return map[string]uint64{"TstampNanos": lib.DecodeUint64(keySuffix[:8])}, decodedValue, nil
+}
+
+func decodeRateLimitBucketEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	if len(keySuffix) != sha256.Size {
+		return nil, nil, fmt.Errorf("Key has invalid length %d", len(keySuffix))
+	}
+	if len(value) != 8 {
+		return nil, nil, fmt.Errorf("Value has invalid length %d", len(value))
+	}
+	return map[string]string{"BucketKeyHashHex": hex.EncodeToString(keySuffix)},
+		map[string]uint64{"FullAtTstampNanos": lib.DecodeUint64(value)}, nil
+}
+
+func decodeAdminRolesEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	decodedKey, err := decodePublicKeySuffix(keySuffix, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	assignment := &AdminRoleAssignment{}
+	if err = gob.NewDecoder(bytes.NewReader(value)).Decode(assignment); err != nil {
+		return nil, nil, fmt.Errorf("Problem decoding AdminRoleAssignment: %v", err)
+	}
+	return decodedKey, newAdminRoleAssignmentResponse(assignment, params), nil
+}
+
+func decodeAdminAuditLogEntry(keySuffix []byte, value []byte, params *lib.BitCloutParams) (
+	interface{}, interface{}, error) {
+
+	if len(keySuffix) != 8+globalStateAuditLogRandomBytes {
+		return nil, nil, fmt.Errorf("Key has invalid length %d", len(keySuffix))
+	}
+	entry := &AdminAuditLogEntry{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(entry); err != nil {
+		return nil, nil, fmt.Errorf("Problem decoding AdminAuditLogEntry: %v", err)
+	}
+	return map[string]uint64{"TstampNanos": lib.DecodeUint64(keySuffix[:8])}, entry, nil
+}
@@ -0,0 +1,281 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/bitclout/core/lib"
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// The kinds of problems RunGlobalStateFsck looks for.
+const (
+	// An entry that can't be decoded, or that sits under an unknown prefix.
+	GlobalStateFsckUndecodableEntry = "UndecodableEntry"
+	// A post on the global feed that was deleted, hidden or never existed.
+	GlobalStateFsckWhitelistedPostNotVisible = "WhitelistedPostNotVisible"
+	// A pinned post that was deleted, hidden or never existed.
+	GlobalStateFsckPinnedPostNotVisible = "PinnedPostNotVisible"
+	// Phone number metadata whose public key's UserMetadata has a different phone
+	// number, or none at all.
+	GlobalStateFsckPhoneNumberMetadataMismatch = "PhoneNumberMetadataMismatch"
+	// A verified username whose PKID has no profile or whose profile has since
+	// changed its username.
+	GlobalStateFsckVerifiedUsernameNotResolved = "VerifiedUsernameNotResolved"
+	// A blocked public key that isn't a valid public key for this network, or is
+	// the user's own key.
+	GlobalStateFsckStaleBlockedPublicKey = "StaleBlockedPublicKey"
+)
+
+// GlobalStateFsckChainView is the on-chain state RunGlobalStateFsck checks global
+// state against. *lib.UtxoView satisfies it.
+type GlobalStateFsckChainView interface {
+	GetPostEntryForPostHash(postHash *lib.BlockHash) *lib.PostEntry
+	GetProfileEntryForPKID(pkid *lib.PKID) *lib.ProfileEntry
+}
+
+// GlobalStateFsckIssue is a single problem found by RunGlobalStateFsck.
+type GlobalStateFsckIssue struct {
+	Check       string
+	KeyHex      string
+	Description string
+	// False if the issue needs a human to decide what to do about it.
+	Repairable  bool
+	Repaired    bool
+	RepairError string `json:",omitempty"`
+}
+
+// GlobalStateFsckReport is the result of a RunGlobalStateFsck.
+type GlobalStateFsckReport struct {
+	NumKeysChecked int
+	Issues         []*GlobalStateFsckIssue
+}
+
+func (report *GlobalStateFsckReport) addIssue(check string, key []byte, repairable bool,
+	format string, args ...interface{}) *GlobalStateFsckIssue {
+
+	issue := &GlobalStateFsckIssue{
+		Check:       check,
+		KeyHex:      hex.EncodeToString(key),
+		Description: fmt.Sprintf(format, args...),
+		Repairable:  repairable,
+	}
+	report.Issues = append(report.Issues, issue)
+	return issue
+}
+
+func (issue *GlobalStateFsckIssue) setRepairResult(err error) {
+	if err != nil {
+		issue.RepairError = err.Error()
+		return
+	}
+	issue.Repaired = true
+}
+
+// RunGlobalStateFsck walks every entry in global state and checks it against
+// chainView. Every problem found is included in the report. If repair is true,
+// problems that can be fixed safely are fixed as they're found. A failed repair
+// is recorded on its issue and doesn't stop the walk.
+func (fes *APIServer) RunGlobalStateFsck(chainView GlobalStateFsckChainView, repair bool) (
+	*GlobalStateFsckReport, error) {
+
+	report := &GlobalStateFsckReport{
+		Issues: []*GlobalStateFsckIssue{},
+	}
+	err := GlobalStateIteratePrefix(fes.GlobalStore, []byte{}, func(key []byte, value []byte) error {
+		report.NumKeysChecked++
+
+		entry := DecodeGlobalStateEntry(key, value, fes.Params)
+		if entry.DecodeError != "" {
+			report.addIssue(GlobalStateFsckUndecodableEntry, key, false,
+				"Entry under prefix %q can't be decoded: %v", entry.Prefix, entry.DecodeError)
+			return nil
+		}
+
+		switch {
+		case bytes.HasPrefix(key, _GlobalStatePrefixTstampNanosPostHash):
+			fes.fsckFeedPost(report, chainView, key, value, GlobalStateFsckWhitelistedPostNotVisible, repair)
+		case bytes.HasPrefix(key, _GlobalStatePrefixTstampNanosPinnedPostHash):
+			fes.fsckFeedPost(report, chainView, key, value, GlobalStateFsckPinnedPostNotVisible, repair)
+		case bytes.HasPrefix(key, _GlobalStatePrefixPhoneNumberToPhoneNumberMetadata):
+			return fes.fsckPhoneNumberMetadata(report, key, value)
+		case bytes.Equal(key, _GlobalStatePrefixForVerifiedMap):
+			return fes.fsckVerifiedMap(report, chainView, value, repair)
+		case bytes.HasPrefix(key, _GlobalStatePrefixPublicKeyToUserMetadata):
+			return fes.fsckBlockedPublicKeys(report, key, value, repair)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("RunGlobalStateFsck: %v", err)
+	}
+
+	return report, nil
+}
+
+// fsckFeedPost checks that a whitelisted or pinned post is still visible.
+func (fes *APIServer) fsckFeedPost(report *GlobalStateFsckReport, chainView GlobalStateFsckChainView,
+	key []byte, value []byte, check string, repair bool) {
+
+	// The key is [prefix][tstampNanos uint64][PostHash]
+	postHash := &lib.BlockHash{}
+	copy(postHash[:], key[1+8:])
+
+	var problem string
+	postEntry := chainView.GetPostEntryForPostHash(postHash)
+	if postEntry == nil {
+		problem = "does not exist"
+	} else if postEntry.IsDeleted() {
+		problem = "was deleted"
+	} else if postEntry.IsHidden {
+		problem = "is hidden"
+	} else {
+		return
+	}
+
+	issue := report.addIssue(check, key, true, "Post %v %v", hex.EncodeToString(postHash[:]), problem)
+	if repair {
+		issue.setRepairResult(fes.fsckDeleteEntry(key, value))
+	}
+}
+
+// fsckDeleteEntry deletes an entry unless it changed since we read it.
+func (fes *APIServer) fsckDeleteEntry(key []byte, value []byte) error {
+	swapped, err := fes.GlobalStateCompareAndSwap(key, value, nil)
+	if err != nil {
+		return err
+	}
+	if !swapped {
+		return fmt.Errorf("Entry changed while it was being checked")
+	}
+	return nil
+}
+
+// fsckPhoneNumberMetadata checks that the user a phone number points at still has
+// that phone number. Mismatches aren't repaired automatically since the phone
+// number metadata also records whether the number was already used to comp a
+// profile, and deleting it would let the number be used again.
+func (fes *APIServer) fsckPhoneNumberMetadata(report *GlobalStateFsckReport, key []byte, value []byte) error {
+	phoneNumberMetadata, err := fes.PIIKeyring.decodePhoneNumberMetadata(value)
+	if err != nil {
+		report.addIssue(GlobalStateFsckUndecodableEntry, key, false,
+			"Problem decoding PhoneNumberMetadata: %v", err)
+		return nil
+	}
+	if len(phoneNumberMetadata.PublicKey) == 0 {
+		return nil
+	}
+
+	userMetadata, err := fes.getUserMetadataFromGlobalState(lib.PkToString(phoneNumberMetadata.PublicKey, fes.Params))
+	if err != nil {
+		// The UserMetadata entry is reported on its own when it's walked.
+		return nil
+	}
+	if userMetadata.PhoneNumber != phoneNumberMetadata.PhoneNumber {
+		problem := "has a different phone number"
+		if userMetadata.PhoneNumber == "" {
+			problem = "has no phone number"
+		}
+		report.addIssue(GlobalStateFsckPhoneNumberMetadataMismatch, key, false,
+			"Phone number metadata points at %v, whose UserMetadata %v",
+			lib.PkToString(phoneNumberMetadata.PublicKey, fes.Params), problem)
+	}
+	return nil
+}
+
+// fsckVerifiedMap checks that every verified username still belongs to the
+// profile it was granted to.
+func (fes *APIServer) fsckVerifiedMap(report *GlobalStateFsckReport, chainView GlobalStateFsckChainView,
+	value []byte, repair bool) error {
+
+	verifiedMapStruct := VerifiedUsernameToPKID{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&verifiedMapStruct); err != nil {
+		return fmt.Errorf("fsckVerifiedMap: Problem decoding verified map: %v", err)
+	}
+
+	staleUsernames := make(map[string]*GlobalStateFsckIssue)
+	for username, pkid := range verifiedMapStruct.VerifiedUsernameToPKID {
+		var profileEntry *lib.ProfileEntry
+		if pkid != nil {
+			profileEntry = chainView.GetProfileEntryForPKID(pkid)
+		}
+		if profileEntry == nil {
+			staleUsernames[username] = report.addIssue(GlobalStateFsckVerifiedUsernameNotResolved,
+				_GlobalStatePrefixForVerifiedMap, true, "Verified username %v has no profile", username)
+		} else if strings.ToLower(string(profileEntry.Username)) != username {
+			staleUsernames[username] = report.addIssue(GlobalStateFsckVerifiedUsernameNotResolved,
+				_GlobalStatePrefixForVerifiedMap, true, "Verified username %v now belongs to a profile named %v",
+				username, string(profileEntry.Username))
+		}
+	}
+	if !repair || len(staleUsernames) == 0 {
+		return nil
+	}
+
+	err := fes.GlobalStateUpdate(_GlobalStatePrefixForVerifiedMap, func(verifiedMapBytes []byte) ([]byte, error) {
+		verifiedMapStruct := VerifiedUsernameToPKID{}
+		if err := gob.NewDecoder(bytes.NewReader(verifiedMapBytes)).Decode(&verifiedMapStruct); err != nil {
+			return nil, err
+		}
+		for username := range staleUsernames {
+			delete(verifiedMapStruct.VerifiedUsernameToPKID, username)
+		}
+		metadataDataBuf := bytes.NewBuffer([]byte{})
+		if err := gob.NewEncoder(metadataDataBuf).Encode(verifiedMapStruct); err != nil {
+			return nil, err
+		}
+		return metadataDataBuf.Bytes(), nil
+	})
+	for _, issue := range staleUsernames {
+		issue.setRepairResult(err)
+	}
+	return nil
+}
+
+// fsckBlockedPublicKeys checks that every public key a user has blocked is a valid
+// public key on this network.
+func (fes *APIServer) fsckBlockedPublicKeys(report *GlobalStateFsckReport, key []byte, value []byte,
+	repair bool) error {
+
+	userPublicKeyBytes := key[len(_GlobalStatePrefixPublicKeyToUserMetadata):]
+	userMetadata, err := fes.PIIKeyring.decodeUserMetadata(value)
+	if err != nil {
+		report.addIssue(GlobalStateFsckUndecodableEntry, key, false,
+			"Problem decoding UserMetadata: %v", err)
+		return nil
+	}
+
+	staleBlockedPublicKeys := make(map[string]*GlobalStateFsckIssue)
+	for blockedPublicKey := range userMetadata.BlockedPublicKeys {
+		var problem string
+		blockedPublicKeyBytes, _, err := lib.Base58CheckDecode(blockedPublicKey)
+		if err != nil || len(blockedPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
+			problem = "is not a valid public key"
+		} else if lib.PkToString(blockedPublicKeyBytes, fes.Params) != blockedPublicKey {
+			problem = "is not a public key on this network"
+		} else if bytes.Equal(blockedPublicKeyBytes, userPublicKeyBytes) {
+			problem = "is the user's own public key"
+		} else {
+			continue
+		}
+		staleBlockedPublicKeys[blockedPublicKey] = report.addIssue(GlobalStateFsckStaleBlockedPublicKey, key, true,
+			"Blocked public key %v %v", blockedPublicKey, problem)
+	}
+	if !repair || len(staleBlockedPublicKeys) == 0 {
+		return nil
+	}
+
+	_, err = fes.updateUserMetadataInGlobalState(userPublicKeyBytes, func(userMetadata *UserMetadata) error {
+		for blockedPublicKey := range staleBlockedPublicKeys {
+			delete(userMetadata.BlockedPublicKeys, blockedPublicKey)
+		}
+		return nil
+	})
+	for _, issue := range staleBlockedPublicKeys {
+		issue.setRepairResult(err)
+	}
+	return nil
+}
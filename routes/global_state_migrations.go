@@ -0,0 +1,194 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/bitclout/core/lib"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/golang/glog"
+)
+
+const (
+	// The number of keys fetched per call to Seek when walking a prefix.
+	globalStateIterateBatchSize = 1000
+)
+
+// GlobalStateMigration rewrites every value under Prefix. Migrations are run in
+// order of Version and each one is run at most once per global state db.
+type GlobalStateMigration struct {
+	Version uint64
+	Name    string
+	Prefix  []byte
+	// Migrate takes an existing key/value pair and returns the new value to store.
+	// Returning a nil value leaves the entry untouched.
+	Migrate func(key []byte, value []byte) (_newValue []byte, _err error)
+}
+
+// globalStateMigrations must be kept sorted by Version. To change the layout of a
+// gob-encoded struct, add a new migration to the end of this list.
+var globalStateMigrations = []*GlobalStateMigration{
+	{
+		Version: 1,
+		Name:    "BackfillUserMetadataPublicKey",
+		Prefix:  _GlobalStatePrefixPublicKeyToUserMetadata,
+		Migrate: migrateUserMetadata(func(key []byte, userMetadata *UserMetadata) error {
+			// Older entries were sometimes stored without a public key. The public key
+			// is always the suffix of the key so we can fill it in.
+			if len(userMetadata.PublicKey) != btcec.PubKeyBytesLenCompressed {
+				userMetadata.PublicKey = append([]byte{}, key[len(_GlobalStatePrefixPublicKeyToUserMetadata):]...)
+			}
+			return nil
+		}),
+	},
+	{
+		Version: 2,
+		Name:    "BackfillPhoneNumberMetadataPhoneNumber",
+		Prefix:  _GlobalStatePrefixPhoneNumberToPhoneNumberMetadata,
+		Migrate: migratePhoneNumberMetadata(func(key []byte, phoneNumberMetadata *PhoneNumberMetadata) error {
+			// Unless blind indexing is enabled, the key holds the E.164 formatted number.
+			keySuffix := key[len(_GlobalStatePrefixPhoneNumberToPhoneNumberMetadata):]
+			if phoneNumberMetadata.PhoneNumber == "" && phoneNumberMetadata.EncryptedPhoneNumber == nil &&
+				isPlaintextPhoneNumberKeySuffix(keySuffix) {
+				phoneNumberMetadata.PhoneNumber = string(keySuffix)
+			}
+			return nil
+		}),
+	},
+}
+
+// migrateUserMetadata returns a Migrate function that decodes a gob-encoded
+// UserMetadata, applies updateFunc, and re-encodes it.
+func migrateUserMetadata(updateFunc func(key []byte, userMetadata *UserMetadata) error,
+) func(key []byte, value []byte) ([]byte, error) {
+	return func(key []byte, value []byte) ([]byte, error) {
+		userMetadata := UserMetadata{}
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&userMetadata); err != nil {
+			return nil, fmt.Errorf("migrateUserMetadata: Problem decoding value: %v", err)
+		}
+		if err := updateFunc(key, &userMetadata); err != nil {
+			return nil, err
+		}
+		metadataDataBuf := bytes.NewBuffer([]byte{})
+		if err := gob.NewEncoder(metadataDataBuf).Encode(&userMetadata); err != nil {
+			return nil, fmt.Errorf("migrateUserMetadata: Problem encoding value: %v", err)
+		}
+		return metadataDataBuf.Bytes(), nil
+	}
+}
+
+// migratePhoneNumberMetadata returns a Migrate function that decodes a gob-encoded
+// PhoneNumberMetadata, applies updateFunc, and re-encodes it.
+func migratePhoneNumberMetadata(updateFunc func(key []byte, phoneNumberMetadata *PhoneNumberMetadata) error,
+) func(key []byte, value []byte) ([]byte, error) {
+	return func(key []byte, value []byte) ([]byte, error) {
+		phoneNumberMetadata := PhoneNumberMetadata{}
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&phoneNumberMetadata); err != nil {
+			return nil, fmt.Errorf("migratePhoneNumberMetadata: Problem decoding value: %v", err)
+		}
+		if err := updateFunc(key, &phoneNumberMetadata); err != nil {
+			return nil, err
+		}
+		metadataDataBuf := bytes.NewBuffer([]byte{})
+		if err := gob.NewEncoder(metadataDataBuf).Encode(&phoneNumberMetadata); err != nil {
+			return nil, fmt.Errorf("migratePhoneNumberMetadata: Problem encoding value: %v", err)
+		}
+		return metadataDataBuf.Bytes(), nil
+	}
+}
+
+// GlobalStateIteratePrefix calls iterFunc for every key/value pair under prefix,
+// fetching them from the store in batches.
+func GlobalStateIteratePrefix(store GlobalStore, prefix []byte,
+	iterFunc func(key []byte, value []byte) error) error {
+
+	startKey := prefix
+	for {
+		keys, vals, err := store.Seek(startKey, prefix, 0 /*maxKeyLen*/, globalStateIterateBatchSize,
+			false /*reverse*/, true /*fetchValues*/)
+		if err != nil {
+			return fmt.Errorf("GlobalStateIteratePrefix: Problem seeking prefix %v: %v", prefix, err)
+		}
+		for ii, key := range keys {
+			if err := iterFunc(key, vals[ii]); err != nil {
+				return err
+			}
+		}
+		if len(keys) < globalStateIterateBatchSize {
+			return nil
+		}
+		// The next page starts at the smallest key strictly greater than the last one.
+		startKey = append(append([]byte{}, keys[len(keys)-1]...), 0)
+	}
+}
+
+// GetGlobalStateSchemaVersion returns the schema version stored in global state.
+// A db that has never been migrated is at version zero.
+func GetGlobalStateSchemaVersion(store GlobalStore) (uint64, error) {
+	versionBytes, err := store.Get(_GlobalStatePrefixSchemaVersion)
+	if err != nil {
+		return 0, fmt.Errorf("GetGlobalStateSchemaVersion: Problem getting version: %v", err)
+	}
+	if len(versionBytes) == 0 {
+		return 0, nil
+	}
+	if len(versionBytes) != 8 {
+		return 0, fmt.Errorf("GetGlobalStateSchemaVersion: Invalid version bytes: %v", versionBytes)
+	}
+	return lib.DecodeUint64(versionBytes), nil
+}
+
+// RunGlobalStateMigrations brings global state up to the latest schema version.
+// The version is bumped after each migration completes so an interrupted run picks
+// up where it left off. Migrations should be idempotent since a migration that was
+// interrupted part-way through will be run again from the start.
+//
+// This should only be called by the node that owns the global state db, never by
+// nodes that proxy to a remote node.
+func RunGlobalStateMigrations(store GlobalStore) error {
+	if err := ValidateGlobalStatePrefixes(); err != nil {
+		return err
+	}
+
+	currentVersion, err := GetGlobalStateSchemaVersion(store)
+	if err != nil {
+		return fmt.Errorf("RunGlobalStateMigrations: %v", err)
+	}
+
+	for _, migration := range globalStateMigrations {
+		if migration.Version <= currentVersion {
+			continue
+		}
+		glog.Infof("RunGlobalStateMigrations: Running migration %d (%s)", migration.Version, migration.Name)
+
+		numMigrated := 0
+		err := GlobalStateIteratePrefix(store, migration.Prefix, func(key []byte, value []byte) error {
+			newValue, err := migration.Migrate(key, value)
+			if err != nil {
+				return fmt.Errorf("Problem migrating key %v: %v", key, err)
+			}
+			if newValue == nil || bytes.Equal(newValue, value) {
+				return nil
+			}
+			if err := store.Put(key, newValue); err != nil {
+				return fmt.Errorf("Problem putting key %v: %v", key, err)
+			}
+			numMigrated++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("RunGlobalStateMigrations: Migration %d (%s) failed: %v",
+				migration.Version, migration.Name, err)
+		}
+
+		if err := store.Put(_GlobalStatePrefixSchemaVersion, lib.EncodeUint64(migration.Version)); err != nil {
+			return fmt.Errorf("RunGlobalStateMigrations: Problem updating schema version: %v", err)
+		}
+		currentVersion = migration.Version
+		glog.Infof("RunGlobalStateMigrations: Finished migration %d (%s), rewrote %d entries",
+			migration.Version, migration.Name, numMigrated)
+	}
+
+	return nil
+}
@@ -0,0 +1,526 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+const (
+	// The length of the keys passed to NewPIIKeyring.
+	PIIKeyBytes = 32
+	// The length of the random key each encrypted field is sealed with.
+	piiDataKeyBytes = 32
+	// The number of bytes of sha256(key) used to identify a key.
+	piiKeyIDBytes = 8
+
+	// Used as additional data when sealing so that an encrypted field can't be
+	// copied into a different field or struct.
+	piiFieldUserMetadataEmail              = "UserMetadata.Email"
+	piiFieldUserMetadataPhoneNumber        = "UserMetadata.PhoneNumber"
+	piiFieldPhoneNumberMetadataPhoneNumber = "PhoneNumberMetadata.PhoneNumber"
+)
+
+// EncryptedPII holds a single encrypted field. The field is encrypted with a random
+// data key, and the data key is in turn encrypted ("wrapped") with the node's PII
+// key. Rotating the PII key only requires re-wrapping the data key.
+type EncryptedPII struct {
+	// Identifies the PII key that wrapped the data key.
+	KeyID string
+	// The AES-GCM nonce followed by the sealed data key.
+	WrappedDataKey []byte
+	// The AES-GCM nonce followed by the sealed field.
+	Ciphertext []byte
+}
+
+// PIIKeyring holds the keys used to protect personal information in global state.
+// Email addresses and phone numbers are encrypted with the current key and can be
+// decrypted with the current key or any previous key. Phone number metadata is
+// keyed by an HMAC of the phone number rather than the number itself so that it
+// can still be looked up.
+//
+// A nil *PIIKeyring is valid and leaves everything in plaintext.
+type PIIKeyring struct {
+	currentKeyID string
+	aeadByKeyID  map[string]cipher.AEAD
+	indexKey     []byte
+}
+
+func decodePIIKey(keyHex string) ([]byte, error) {
+	key, err := hex.DecodeString(keyHex)
+	if err != nil {
+		return nil, fmt.Errorf("Problem decoding key as hex: %v", err)
+	}
+	if len(key) != PIIKeyBytes {
+		return nil, fmt.Errorf("Key has length %d but must be %d bytes", len(key), PIIKeyBytes)
+	}
+	return key, nil
+}
+
+func newPIIAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func piiKeyID(key []byte) string {
+	keyHash := sha256.Sum256(key)
+	return hex.EncodeToString(keyHash[:piiKeyIDBytes])
+}
+
+// NewPIIKeyring creates a keyring from hex-encoded keys. currentKeyHex is used for
+// all new encryption, previousKeysHex are only used to decrypt data that hasn't
+// been rotated yet, and indexKeyHex keys the phone number HMAC. Returns nil if no
+// keys are provided.
+func NewPIIKeyring(currentKeyHex string, previousKeysHex []string, indexKeyHex string) (*PIIKeyring, error) {
+	if currentKeyHex == "" && indexKeyHex == "" && len(previousKeysHex) == 0 {
+		return nil, nil
+	}
+	// The phone number is part of the key for phone number metadata, so encrypting
+	// it without also blinding the key wouldn't hide anything.
+	if currentKeyHex == "" || indexKeyHex == "" {
+		return nil, fmt.Errorf("NewPIIKeyring: Both a PII key and a PII index key are required")
+	}
+
+	keyring := &PIIKeyring{
+		aeadByKeyID: make(map[string]cipher.AEAD),
+	}
+	for ii, keyHex := range append([]string{currentKeyHex}, previousKeysHex...) {
+		key, err := decodePIIKey(keyHex)
+		if err != nil {
+			return nil, fmt.Errorf("NewPIIKeyring: Problem with key %d: %v", ii, err)
+		}
+		aead, err := newPIIAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("NewPIIKeyring: Problem creating cipher for key %d: %v", ii, err)
+		}
+		keyID := piiKeyID(key)
+		if ii == 0 {
+			keyring.currentKeyID = keyID
+		}
+		keyring.aeadByKeyID[keyID] = aead
+	}
+
+	indexKey, err := decodePIIKey(indexKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("NewPIIKeyring: Problem with index key: %v", err)
+	}
+	keyring.indexKey = indexKey
+
+	return keyring, nil
+}
+
+func sealPII(aead cipher.AEAD, plaintext []byte, fieldName string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(fieldName)), nil
+}
+
+func openPII(aead cipher.AEAD, sealed []byte, fieldName string) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("Sealed data is too short")
+	}
+	nonceSize := aead.NonceSize()
+	return aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(fieldName))
+}
+
+// encrypt seals plaintext with a fresh data key. Empty values are left empty.
+func (keyring *PIIKeyring) encrypt(plaintext string, fieldName string) (*EncryptedPII, error) {
+	if plaintext == "" {
+		return nil, nil
+	}
+
+	dataKey := make([]byte, piiDataKeyBytes)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, fmt.Errorf("PIIKeyring.encrypt: Problem generating data key: %v", err)
+	}
+	dataAEAD, err := newPIIAEAD(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("PIIKeyring.encrypt: %v", err)
+	}
+	ciphertext, err := sealPII(dataAEAD, []byte(plaintext), fieldName)
+	if err != nil {
+		return nil, fmt.Errorf("PIIKeyring.encrypt: Problem sealing %v: %v", fieldName, err)
+	}
+	wrappedDataKey, err := sealPII(keyring.aeadByKeyID[keyring.currentKeyID], dataKey, fieldName)
+	if err != nil {
+		return nil, fmt.Errorf("PIIKeyring.encrypt: Problem wrapping data key: %v", err)
+	}
+
+	return &EncryptedPII{
+		KeyID:          keyring.currentKeyID,
+		WrappedDataKey: wrappedDataKey,
+		Ciphertext:     ciphertext,
+	}, nil
+}
+
+func (keyring *PIIKeyring) unwrapDataKey(encrypted *EncryptedPII, fieldName string) ([]byte, error) {
+	if keyring == nil {
+		return nil, fmt.Errorf("%v is encrypted but no PII key is configured", fieldName)
+	}
+	aead, exists := keyring.aeadByKeyID[encrypted.KeyID]
+	if !exists {
+		return nil, fmt.Errorf("%v is encrypted with unknown key %v", fieldName, encrypted.KeyID)
+	}
+	dataKey, err := openPII(aead, encrypted.WrappedDataKey, fieldName)
+	if err != nil {
+		return nil, fmt.Errorf("Problem unwrapping data key for %v: %v", fieldName, err)
+	}
+	return dataKey, nil
+}
+
+func (keyring *PIIKeyring) decrypt(encrypted *EncryptedPII, fieldName string) (string, error) {
+	if encrypted == nil {
+		return "", nil
+	}
+
+	dataKey, err := keyring.unwrapDataKey(encrypted, fieldName)
+	if err != nil {
+		return "", fmt.Errorf("PIIKeyring.decrypt: %v", err)
+	}
+	dataAEAD, err := newPIIAEAD(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("PIIKeyring.decrypt: %v", err)
+	}
+	plaintext, err := openPII(dataAEAD, encrypted.Ciphertext, fieldName)
+	if err != nil {
+		return "", fmt.Errorf("PIIKeyring.decrypt: Problem opening %v: %v", fieldName, err)
+	}
+	return string(plaintext), nil
+}
+
+// rewrap re-wraps the data key of an encrypted field with the current key. The
+// field itself is not re-encrypted. Returns false if the field was already wrapped
+// with the current key.
+func (keyring *PIIKeyring) rewrap(encrypted *EncryptedPII, fieldName string) (
+	_rewrapped *EncryptedPII, _changed bool, _err error) {
+
+	if encrypted == nil || encrypted.KeyID == keyring.currentKeyID {
+		return encrypted, false, nil
+	}
+
+	dataKey, err := keyring.unwrapDataKey(encrypted, fieldName)
+	if err != nil {
+		return nil, false, fmt.Errorf("PIIKeyring.rewrap: %v", err)
+	}
+	wrappedDataKey, err := sealPII(keyring.aeadByKeyID[keyring.currentKeyID], dataKey, fieldName)
+	if err != nil {
+		return nil, false, fmt.Errorf("PIIKeyring.rewrap: Problem wrapping data key: %v", err)
+	}
+	return &EncryptedPII{
+		KeyID:          keyring.currentKeyID,
+		WrappedDataKey: wrappedDataKey,
+		Ciphertext:     encrypted.Ciphertext,
+	}, true, nil
+}
+
+// phoneNumberIndex returns the HMAC of an E.164 formatted phone number that is
+// used in place of the number in global state keys.
+func (keyring *PIIKeyring) phoneNumberIndex(formattedNumber string) []byte {
+	mac := hmac.New(sha256.New, keyring.indexKey)
+	mac.Write([]byte(formattedNumber))
+	return mac.Sum(nil)
+}
+
+// sealUserMetadata returns a copy of userMetadata with its personal information
+// encrypted. userMetadata itself is left untouched.
+func (keyring *PIIKeyring) sealUserMetadata(userMetadata *UserMetadata) (*UserMetadata, error) {
+	if keyring == nil {
+		return userMetadata, nil
+	}
+
+	sealedUserMetadata := *userMetadata
+	var err error
+	sealedUserMetadata.EncryptedEmail, err = keyring.encrypt(
+		userMetadata.Email, piiFieldUserMetadataEmail)
+	if err != nil {
+		return nil, err
+	}
+	sealedUserMetadata.EncryptedPhoneNumber, err = keyring.encrypt(
+		userMetadata.PhoneNumber, piiFieldUserMetadataPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	sealedUserMetadata.Email = ""
+	sealedUserMetadata.PhoneNumber = ""
+	return &sealedUserMetadata, nil
+}
+
+// openUserMetadata decrypts the personal information in userMetadata in place.
+// Entries written before encryption was enabled are left as they are.
+func (keyring *PIIKeyring) openUserMetadata(userMetadata *UserMetadata) error {
+	if userMetadata.EncryptedEmail != nil {
+		email, err := keyring.decrypt(userMetadata.EncryptedEmail, piiFieldUserMetadataEmail)
+		if err != nil {
+			return err
+		}
+		userMetadata.Email = email
+		userMetadata.EncryptedEmail = nil
+	}
+	if userMetadata.EncryptedPhoneNumber != nil {
+		phoneNumber, err := keyring.decrypt(userMetadata.EncryptedPhoneNumber, piiFieldUserMetadataPhoneNumber)
+		if err != nil {
+			return err
+		}
+		userMetadata.PhoneNumber = phoneNumber
+		userMetadata.EncryptedPhoneNumber = nil
+	}
+	return nil
+}
+
+// sealPhoneNumberMetadata returns a copy of phoneNumberMetadata with the phone
+// number encrypted. phoneNumberMetadata itself is left untouched.
+func (keyring *PIIKeyring) sealPhoneNumberMetadata(phoneNumberMetadata *PhoneNumberMetadata) (
+	*PhoneNumberMetadata, error) {
+
+	if keyring == nil {
+		return phoneNumberMetadata, nil
+	}
+
+	sealedPhoneNumberMetadata := *phoneNumberMetadata
+	var err error
+	sealedPhoneNumberMetadata.EncryptedPhoneNumber, err = keyring.encrypt(
+		phoneNumberMetadata.PhoneNumber, piiFieldPhoneNumberMetadataPhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	sealedPhoneNumberMetadata.PhoneNumber = ""
+	return &sealedPhoneNumberMetadata, nil
+}
+
+// openPhoneNumberMetadata decrypts the phone number in phoneNumberMetadata in place.
+func (keyring *PIIKeyring) openPhoneNumberMetadata(phoneNumberMetadata *PhoneNumberMetadata) error {
+	if phoneNumberMetadata.EncryptedPhoneNumber != nil {
+		phoneNumber, err := keyring.decrypt(
+			phoneNumberMetadata.EncryptedPhoneNumber, piiFieldPhoneNumberMetadataPhoneNumber)
+		if err != nil {
+			return err
+		}
+		phoneNumberMetadata.PhoneNumber = phoneNumber
+		phoneNumberMetadata.EncryptedPhoneNumber = nil
+	}
+	return nil
+}
+
+// encodeUserMetadata gob-encodes userMetadata, encrypting its personal
+// information first if a keyring is configured.
+func (keyring *PIIKeyring) encodeUserMetadata(userMetadata *UserMetadata) ([]byte, error) {
+	sealedUserMetadata, err := keyring.sealUserMetadata(userMetadata)
+	if err != nil {
+		return nil, err
+	}
+	metadataDataBuf := bytes.NewBuffer([]byte{})
+	if err = gob.NewEncoder(metadataDataBuf).Encode(sealedUserMetadata); err != nil {
+		return nil, err
+	}
+	return metadataDataBuf.Bytes(), nil
+}
+
+// decodeUserMetadata is the inverse of encodeUserMetadata.
+func (keyring *PIIKeyring) decodeUserMetadata(userMetadataBytes []byte) (*UserMetadata, error) {
+	userMetadata := UserMetadata{}
+	if err := gob.NewDecoder(bytes.NewReader(userMetadataBytes)).Decode(&userMetadata); err != nil {
+		return nil, err
+	}
+	if err := keyring.openUserMetadata(&userMetadata); err != nil {
+		return nil, err
+	}
+	return &userMetadata, nil
+}
+
+// encodePhoneNumberMetadata gob-encodes phoneNumberMetadata, encrypting the phone
+// number first if a keyring is configured.
+func (keyring *PIIKeyring) encodePhoneNumberMetadata(phoneNumberMetadata *PhoneNumberMetadata) ([]byte, error) {
+	sealedPhoneNumberMetadata, err := keyring.sealPhoneNumberMetadata(phoneNumberMetadata)
+	if err != nil {
+		return nil, err
+	}
+	metadataDataBuf := bytes.NewBuffer([]byte{})
+	if err = gob.NewEncoder(metadataDataBuf).Encode(sealedPhoneNumberMetadata); err != nil {
+		return nil, err
+	}
+	return metadataDataBuf.Bytes(), nil
+}
+
+// decodePhoneNumberMetadata is the inverse of encodePhoneNumberMetadata.
+func (keyring *PIIKeyring) decodePhoneNumberMetadata(phoneNumberMetadataBytes []byte) (*PhoneNumberMetadata, error) {
+	phoneNumberMetadata := PhoneNumberMetadata{}
+	if err := gob.NewDecoder(bytes.NewReader(phoneNumberMetadataBytes)).Decode(&phoneNumberMetadata); err != nil {
+		return nil, err
+	}
+	if err := keyring.openPhoneNumberMetadata(&phoneNumberMetadata); err != nil {
+		return nil, err
+	}
+	return &phoneNumberMetadata, nil
+}
+
+// isPlaintextPhoneNumberKeySuffix returns true if the suffix of a phone number
+// metadata key is an E.164 phone number rather than an HMAC.
+func isPlaintextPhoneNumberKeySuffix(keySuffix []byte) bool {
+	// E.164 numbers have at most 15 digits.
+	if len(keySuffix) < 2 || len(keySuffix) > 16 || keySuffix[0] != '+' {
+		return false
+	}
+	for _, digit := range keySuffix[1:] {
+		if digit < '0' || digit > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// RotateGlobalStatePII brings every entry that holds personal information up to
+// date with keyring. Plaintext emails and phone numbers are encrypted, data keys
+// wrapped with a previous key are re-wrapped with the current key, and phone
+// number metadata stored under the plaintext number is moved to its HMAC key.
+// Entries that are already up to date are left alone, so it's safe to run this
+// repeatedly. Once it finishes, previous keys can be dropped from the config.
+func RotateGlobalStatePII(store GlobalStore, keyring *PIIKeyring) (_numUpdated int, _err error) {
+	if keyring == nil {
+		return 0, fmt.Errorf("RotateGlobalStatePII: A PII key and PII index key are required")
+	}
+
+	numUpdated := 0
+	err := GlobalStateIteratePrefix(store, _GlobalStatePrefixPublicKeyToUserMetadata, func(key []byte, value []byte) error {
+		userMetadata := UserMetadata{}
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&userMetadata); err != nil {
+			return fmt.Errorf("Problem decoding UserMetadata for key %v: %v", key, err)
+		}
+
+		changed := userMetadata.Email != "" || userMetadata.PhoneNumber != ""
+		var emailChanged, phoneNumberChanged bool
+		var err error
+		userMetadata.EncryptedEmail, emailChanged, err = keyring.rewrap(
+			userMetadata.EncryptedEmail, piiFieldUserMetadataEmail)
+		if err != nil {
+			return fmt.Errorf("Problem rotating key %v: %v", key, err)
+		}
+		userMetadata.EncryptedPhoneNumber, phoneNumberChanged, err = keyring.rewrap(
+			userMetadata.EncryptedPhoneNumber, piiFieldUserMetadataPhoneNumber)
+		if err != nil {
+			return fmt.Errorf("Problem rotating key %v: %v", key, err)
+		}
+		if !changed && !emailChanged && !phoneNumberChanged {
+			return nil
+		}
+
+		// Plaintext fields take precedence since they're only ever set by a node
+		// that doesn't have encryption enabled.
+		if userMetadata.Email != "" {
+			if userMetadata.EncryptedEmail, err = keyring.encrypt(
+				userMetadata.Email, piiFieldUserMetadataEmail); err != nil {
+				return err
+			}
+			userMetadata.Email = ""
+		}
+		if userMetadata.PhoneNumber != "" {
+			if userMetadata.EncryptedPhoneNumber, err = keyring.encrypt(
+				userMetadata.PhoneNumber, piiFieldUserMetadataPhoneNumber); err != nil {
+				return err
+			}
+			userMetadata.PhoneNumber = ""
+		}
+		metadataDataBuf := bytes.NewBuffer([]byte{})
+		if err = gob.NewEncoder(metadataDataBuf).Encode(&userMetadata); err != nil {
+			return fmt.Errorf("Problem encoding UserMetadata for key %v: %v", key, err)
+		}
+		if err = rotateGlobalStatePIIEntry(store, key, value, key, metadataDataBuf.Bytes()); err != nil {
+			return err
+		}
+		numUpdated++
+		return nil
+	})
+	if err != nil {
+		return numUpdated, fmt.Errorf("RotateGlobalStatePII: %v", err)
+	}
+
+	err = GlobalStateIteratePrefix(store, _GlobalStatePrefixPhoneNumberToPhoneNumberMetadata, func(key []byte, value []byte) error {
+		phoneNumberMetadata := PhoneNumberMetadata{}
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&phoneNumberMetadata); err != nil {
+			return fmt.Errorf("Problem decoding PhoneNumberMetadata for key %v: %v", key, err)
+		}
+
+		// Keys written before blind indexing was enabled hold the E.164 number.
+		newKey := key
+		keySuffix := key[len(_GlobalStatePrefixPhoneNumberToPhoneNumberMetadata):]
+		if isPlaintextPhoneNumberKeySuffix(keySuffix) {
+			newKey = append(append([]byte{}, _GlobalStatePrefixPhoneNumberToPhoneNumberMetadata...),
+				keyring.phoneNumberIndex(string(keySuffix))...)
+		}
+
+		changed := phoneNumberMetadata.PhoneNumber != "" || !bytes.Equal(newKey, key)
+		var phoneNumberChanged bool
+		var err error
+		phoneNumberMetadata.EncryptedPhoneNumber, phoneNumberChanged, err = keyring.rewrap(
+			phoneNumberMetadata.EncryptedPhoneNumber, piiFieldPhoneNumberMetadataPhoneNumber)
+		if err != nil {
+			return fmt.Errorf("Problem rotating key %v: %v", key, err)
+		}
+		if !changed && !phoneNumberChanged {
+			return nil
+		}
+
+		if phoneNumberMetadata.PhoneNumber != "" {
+			if phoneNumberMetadata.EncryptedPhoneNumber, err = keyring.encrypt(
+				phoneNumberMetadata.PhoneNumber, piiFieldPhoneNumberMetadataPhoneNumber); err != nil {
+				return err
+			}
+			phoneNumberMetadata.PhoneNumber = ""
+		}
+		metadataDataBuf := bytes.NewBuffer([]byte{})
+		if err = gob.NewEncoder(metadataDataBuf).Encode(&phoneNumberMetadata); err != nil {
+			return fmt.Errorf("Problem encoding PhoneNumberMetadata for key %v: %v", key, err)
+		}
+		if err = rotateGlobalStatePIIEntry(store, key, value, newKey, metadataDataBuf.Bytes()); err != nil {
+			return err
+		}
+		numUpdated++
+		return nil
+	})
+	if err != nil {
+		return numUpdated, fmt.Errorf("RotateGlobalStatePII: %v", err)
+	}
+
+	return numUpdated, nil
+}
+
+// rotateGlobalStatePIIEntry replaces the entry at oldKey with newValue at newKey.
+// If an entry already exists at newKey it was written by a node with blind
+// indexing enabled and is newer than the entry at oldKey, so it is kept.
+func rotateGlobalStatePIIEntry(store GlobalStore, oldKey []byte, oldValue []byte,
+	newKey []byte, newValue []byte) error {
+
+	if bytes.Equal(oldKey, newKey) {
+		swapped, err := store.CompareAndSwap(oldKey, oldValue, newValue)
+		if err != nil {
+			return fmt.Errorf("Problem updating key %v: %v", oldKey, err)
+		}
+		if !swapped {
+			return fmt.Errorf("Key %v changed while rotating. Stop the node and try again.", oldKey)
+		}
+		return nil
+	}
+
+	if _, err := store.CompareAndSwap(newKey, nil, newValue); err != nil {
+		return fmt.Errorf("Problem putting key %v: %v", newKey, err)
+	}
+	swapped, err := store.CompareAndSwap(oldKey, oldValue, nil)
+	if err != nil {
+		return fmt.Errorf("Problem deleting key %v: %v", oldKey, err)
+	}
+	if !swapped {
+		return fmt.Errorf("Key %v changed while rotating. Stop the node and try again.", oldKey)
+	}
+	return nil
+}
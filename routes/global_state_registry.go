@@ -0,0 +1,74 @@
+package routes
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// GlobalStatePrefix describes one of the key prefixes used in global state.
+type GlobalStatePrefix struct {
+	// A human-readable name for the prefix, e.g. "PublicKeyToUserMetadata".
+	Name string
+	// The raw prefix bytes that every key in this section of global state starts with.
+	Prefix []byte
+	// Optional. Used to turn entries under this prefix into readable JSON.
+	Decode GlobalStateDecodeFunc
+}
+
+// globalStatePrefixRegistry holds every prefix registered via registerGlobalStatePrefix
+// in the order they were registered.
+var globalStatePrefixRegistry []*GlobalStatePrefix
+
+// registerGlobalStatePrefix records a prefix in the registry and returns it so it
+// can be assigned directly to a _GlobalStatePrefix... var. Collisions are not
+// detected here because we want to be able to report all of them at once from
+// ValidateGlobalStatePrefixes rather than panicking during package init.
+func registerGlobalStatePrefix(prefix []byte, name string, decode GlobalStateDecodeFunc) []byte {
+	globalStatePrefixRegistry = append(globalStatePrefixRegistry, &GlobalStatePrefix{
+		Name:   name,
+		Prefix: prefix,
+		Decode: decode,
+	})
+	return prefix
+}
+
+// GlobalStatePrefixes returns all registered prefixes sorted by their prefix bytes.
+func GlobalStatePrefixes() []*GlobalStatePrefix {
+	prefixes := append([]*GlobalStatePrefix{}, globalStatePrefixRegistry...)
+	sort.Slice(prefixes, func(ii, jj int) bool {
+		return bytes.Compare(prefixes[ii].Prefix, prefixes[jj].Prefix) < 0
+	})
+	return prefixes
+}
+
+// GetGlobalStatePrefixForKey returns the registered prefix that the given key
+// falls under, or nil if the key doesn't match any registered prefix.
+func GetGlobalStatePrefixForKey(key []byte) *GlobalStatePrefix {
+	for _, prefix := range globalStatePrefixRegistry {
+		if bytes.HasPrefix(key, prefix.Prefix) {
+			return prefix
+		}
+	}
+	return nil
+}
+
+// ValidateGlobalStatePrefixes returns an error if two registered prefixes share an
+// ID or if one prefix is a prefix of another, since either would cause keys from
+// different sections of global state to be mixed together.
+func ValidateGlobalStatePrefixes() error {
+	for ii, prefixA := range globalStatePrefixRegistry {
+		if len(prefixA.Prefix) == 0 {
+			return fmt.Errorf("ValidateGlobalStatePrefixes: Prefix %v is empty", prefixA.Name)
+		}
+		for _, prefixB := range globalStatePrefixRegistry[ii+1:] {
+			if bytes.HasPrefix(prefixA.Prefix, prefixB.Prefix) ||
+				bytes.HasPrefix(prefixB.Prefix, prefixA.Prefix) {
+
+				return fmt.Errorf("ValidateGlobalStatePrefixes: Prefix %v %v collides with prefix %v %v",
+					prefixA.Name, prefixA.Prefix, prefixB.Name, prefixB.Prefix)
+			}
+		}
+	}
+	return nil
+}
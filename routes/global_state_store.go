@@ -0,0 +1,478 @@
+package routes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/bitclout/core/lib"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/pkg/errors"
+)
+
+// GlobalStore is the interface the APIServer uses to set and fetch global state.
+// Handlers should never talk to a backend directly. Instead they go through
+// fes.GlobalStatePut, fes.GlobalStateGet, etc. which delegate to whichever
+// GlobalStore was injected into the APIServer. This makes it easy to add new
+// backends and to test handlers without a badger db on disk.
+type GlobalStore interface {
+	// Put sets the value for the given key.
+	Put(key []byte, value []byte) error
+
+	// Get returns the value for the given key. If the key is not present, a nil
+	// value is returned without an error.
+	Get(key []byte) (_value []byte, _err error)
+
+	// BatchGet returns one value for each key in keyList. Keys that are not present
+	// map to an empty (non-nil) byte slice.
+	BatchGet(keyList [][]byte) (_valueList [][]byte, _err error)
+
+	// Delete removes the given key. Deleting a key that isn't present is not an error.
+	Delete(key []byte) error
+
+	// Seek has the same semantics as lib.DBGetPaginatedKeysAndValuesForPrefix.
+	Seek(startPrefix []byte, validForPrefix []byte, maxKeyLen int, numToFetch int,
+		reverse bool, fetchValues bool) (_keysFound [][]byte, _valsFound [][]byte, _err error)
+
+	// CompareAndSwap atomically sets key to newValue, but only if its current value
+	// equals expectedValue. A nil expectedValue means the key must not be present and
+	// a nil newValue deletes the key. If the value changed underneath us, swapped is
+	// false and the caller should re-read the key and try again.
+	CompareAndSwap(key []byte, expectedValue []byte, newValue []byte) (_swapped bool, _err error)
+}
+
+// globalStateVersioner is implemented by the GlobalStores that know when global
+// state has changed, so that responses built from it can be cached.
+type globalStateVersioner interface {
+	// globalStateVersion returns bytes that change whenever any key does. If that
+	// can't be known right now, ok is false.
+	globalStateVersion() (_version []byte, _ok bool)
+}
+
+// globalStateValuesEqual compares two values the way CompareAndSwap does. A nil
+// value represents a key that is not present, which is different from a key that
+// is present with an empty value.
+func globalStateValuesEqual(currentValue []byte, expectedValue []byte) bool {
+	if currentValue == nil || expectedValue == nil {
+		return currentValue == nil && expectedValue == nil
+	}
+	return bytes.Equal(currentValue, expectedValue)
+}
+
+// validateGlobalStore returns an error if store has nothing behind it, i.e. it's
+// nil, a nil pointer, or a store without a db or remote node to talk to.
+// Otherwise a misconfigured node would only find out on its first request.
+func validateGlobalStore(store GlobalStore) error {
+	if store == nil {
+		return fmt.Errorf("A global state db or a global state remote node is required")
+	}
+	if storeValue := reflect.ValueOf(store); storeValue.Kind() == reflect.Ptr && storeValue.IsNil() {
+		return fmt.Errorf("A global state db or a global state remote node is required")
+	}
+	switch typedStore := store.(type) {
+	case *BadgerGlobalStore:
+		if typedStore.db == nil {
+			return fmt.Errorf("The global state db is nil")
+		}
+	case *RemoteGlobalStore:
+		if typedStore.Client == nil || typedStore.Client.RemoteNode == "" {
+			return fmt.Errorf("The global state remote node is not set")
+		}
+	case *CachingGlobalStore:
+		return validateGlobalStore(typedStore.remote)
+	case *ChangeLogGlobalStore:
+		return validateGlobalStore(typedStore.store)
+	}
+	return nil
+}
+
+// BadgerGlobalStore stores global state in a local badger db. This is what a
+// node uses when it is not configured with a --global-state-remote-node.
+type BadgerGlobalStore struct {
+	db *badger.DB
+}
+
+func NewBadgerGlobalStore(db *badger.DB) *BadgerGlobalStore {
+	return &BadgerGlobalStore{
+		db: db,
+	}
+}
+
+// DB returns the underlying badger db.
+func (bgs *BadgerGlobalStore) DB() *badger.DB {
+	return bgs.db
+}
+
+func (bgs *BadgerGlobalStore) Put(key []byte, value []byte) error {
+	return bgs.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(key, value)
+	})
+}
+
+func (bgs *BadgerGlobalStore) Get(key []byte) (_value []byte, _err error) {
+	var retValue []byte
+	err := bgs.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return nil
+		}
+		// Copy into a non-nil slice so that an empty value isn't mistaken for a
+		// missing key, like in CompareAndSwap.
+		retValue, err = item.ValueCopy([]byte{})
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("BadgerGlobalStore.Get: Error copying value into new slice: %v", err)
+	}
+
+	return retValue, nil
+}
+
+func (bgs *BadgerGlobalStore) BatchGet(keyList [][]byte) (_valueList [][]byte, _err error) {
+	var retValueList [][]byte
+	err := bgs.db.View(func(txn *badger.Txn) error {
+		for _, key := range keyList {
+			item, err := txn.Get(key)
+			if err != nil {
+				retValueList = append(retValueList, []byte{})
+				continue
+			}
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			} else {
+				retValueList = append(retValueList, value)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("BadgerGlobalStore.BatchGet: Error copying value into new slice: %v", err)
+	}
+
+	return retValueList, nil
+}
+
+func (bgs *BadgerGlobalStore) Delete(key []byte) error {
+	return bgs.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(key)
+	})
+}
+
+func (bgs *BadgerGlobalStore) Seek(startPrefix []byte, validForPrefix []byte, maxKeyLen int,
+	numToFetch int, reverse bool, fetchValues bool) (_keysFound [][]byte, _valsFound [][]byte, _err error) {
+
+	retKeys, retVals, err := lib.DBGetPaginatedKeysAndValuesForPrefix(bgs.db, startPrefix,
+		validForPrefix, maxKeyLen, numToFetch, reverse, fetchValues)
+	if err != nil {
+		return nil, nil, fmt.Errorf("BadgerGlobalStore.Seek: Error getting paginated keys and values: %v", err)
+	}
+
+	return retKeys, retVals, nil
+}
+
+func (bgs *BadgerGlobalStore) CompareAndSwap(key []byte, expectedValue []byte, newValue []byte) (
+	_swapped bool, _err error) {
+
+	swapped := false
+	err := bgs.db.Update(func(txn *badger.Txn) error {
+		var currentValue []byte
+		item, err := txn.Get(key)
+		if err == nil {
+			currentValue, err = item.ValueCopy([]byte{})
+			if err != nil {
+				return err
+			}
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		if !globalStateValuesEqual(currentValue, expectedValue) {
+			return nil
+		}
+
+		if newValue == nil {
+			err = txn.Delete(key)
+		} else {
+			err = txn.Set(key, newValue)
+		}
+		if err != nil {
+			return err
+		}
+		swapped = true
+		return nil
+	})
+	// Badger detects when another transaction wrote the key between our read and
+	// our write. Treat that the same as a value mismatch so the caller retries.
+	if err == badger.ErrConflict {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("BadgerGlobalStore.CompareAndSwap: %v", err)
+	}
+
+	return swapped, nil
+}
+
+// RemoteGlobalStore proxies all global state calls to another node's
+// /api/v1/global-state/* routes. Any failure to reach the remote node is returned
+// as a *GlobalStateRemoteError rather than looking like a missing key.
+type RemoteGlobalStore struct {
+	Client *GlobalStateClient
+}
+
+func NewRemoteGlobalStore(remoteNode string, sharedSecret string) *RemoteGlobalStore {
+	return &RemoteGlobalStore{
+		Client: NewGlobalStateClient(remoteNode, sharedSecret),
+	}
+}
+
+// createGlobalStateRemoteRequest returns a signed request for a call to one of the
+// global state routes on the given remote node.
+func createGlobalStateRemoteRequest(remoteNode string, sharedSecret string, routePath string,
+	req interface{}) (*http.Request, error) {
+
+	json_data, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("Could not marshal JSON: %v", err)
+	}
+
+	return newSignedGlobalStateRequest(remoteNode, sharedSecret, routePath, json_data)
+}
+
+func (rgs *RemoteGlobalStore) Put(key []byte, value []byte) error {
+	req := GlobalStatePutRemoteRequest{
+		Key:   key,
+		Value: value,
+	}
+	if err := rgs.Client.Call(RoutePathGlobalStatePutRemote, &req, nil, 0, true /*idempotent*/); err != nil {
+		return errors.Wrapf(err, "RemoteGlobalStore.Put: ")
+	}
+
+	// No error means nothing to return.
+	return nil
+}
+
+func (rgs *RemoteGlobalStore) Get(key []byte) (_value []byte, _err error) {
+	req := GlobalStateGetRemoteRequest{
+		Key: key,
+	}
+	res := GlobalStateGetRemoteResponse{}
+	if err := rgs.Client.Call(RoutePathGlobalStateGetRemote, &req, &res, 0, true /*idempotent*/); err != nil {
+		return nil, errors.Wrapf(err, "RemoteGlobalStore.Get: ")
+	}
+
+	return res.Value, nil
+}
+
+func (rgs *RemoteGlobalStore) BatchGet(keyList [][]byte) (_valueList [][]byte, _err error) {
+	req := GlobalStateBatchGetRemoteRequest{
+		KeyList: keyList,
+	}
+	res := GlobalStateBatchGetRemoteResponse{}
+	if err := rgs.Client.Call(RoutePathGlobalStateBatchGetRemote, &req, &res, 0, true /*idempotent*/); err != nil {
+		return nil, errors.Wrapf(err, "RemoteGlobalStore.BatchGet: ")
+	}
+	if len(res.ValueList) != len(keyList) {
+		return nil, fmt.Errorf("RemoteGlobalStore.BatchGet: Expected %d values but got %d",
+			len(keyList), len(res.ValueList))
+	}
+
+	return res.ValueList, nil
+}
+
+func (rgs *RemoteGlobalStore) Delete(key []byte) error {
+	req := GlobalStateDeleteRemoteRequest{
+		Key: key,
+	}
+	if err := rgs.Client.Call(RoutePathGlobalStateDeleteRemote, &req, nil, 0, true /*idempotent*/); err != nil {
+		return errors.Wrapf(err, "RemoteGlobalStore.Delete: ")
+	}
+
+	// No error means nothing to return.
+	return nil
+}
+
+func (rgs *RemoteGlobalStore) Seek(startPrefix []byte, validForPrefix []byte, maxKeyLen int,
+	numToFetch int, reverse bool, fetchValues bool) (_keysFound [][]byte, _valsFound [][]byte, _err error) {
+
+	req := GlobalStateSeekRemoteRequest{
+		StartPrefix:    startPrefix,
+		ValidForPrefix: validForPrefix,
+		MaxKeyLen:      maxKeyLen,
+		NumToFetch:     numToFetch,
+		Reverse:        reverse,
+		FetchValues:    fetchValues,
+	}
+	res := GlobalStateSeekRemoteResponse{}
+	if err := rgs.Client.Call(RoutePathGlobalStateSeekRemote, &req, &res, 0, true /*idempotent*/); err != nil {
+		return nil, nil, errors.Wrapf(err, "RemoteGlobalStore.Seek: ")
+	}
+
+	return res.KeysFound, res.ValsFound, nil
+}
+
+func (rgs *RemoteGlobalStore) CompareAndSwap(key []byte, expectedValue []byte, newValue []byte) (
+	_swapped bool, _err error) {
+
+	req := GlobalStateCompareAndSwapRemoteRequest{
+		Key:           key,
+		ExpectedValue: expectedValue,
+		NewValue:      newValue,
+	}
+	res := GlobalStateCompareAndSwapRemoteResponse{}
+	// A swap that timed out may have been applied, in which case retrying would
+	// report it as failed. Leave it to the caller to re-read and decide.
+	if err := rgs.Client.Call(RoutePathGlobalStateCompareAndSwapRemote, &req, &res, 0, false /*idempotent*/); err != nil {
+		return false, errors.Wrapf(err, "RemoteGlobalStore.CompareAndSwap: ")
+	}
+
+	return res.Swapped, nil
+}
+
+// MemoryGlobalStore keeps global state in a map. It is not persisted anywhere
+// and is mainly useful for tests.
+type MemoryGlobalStore struct {
+	mtx  sync.RWMutex
+	data map[string][]byte
+}
+
+func NewMemoryGlobalStore() *MemoryGlobalStore {
+	return &MemoryGlobalStore{
+		data: make(map[string][]byte),
+	}
+}
+
+func (mgs *MemoryGlobalStore) Put(key []byte, value []byte) error {
+	mgs.mtx.Lock()
+	defer mgs.mtx.Unlock()
+
+	mgs.data[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (mgs *MemoryGlobalStore) Get(key []byte) (_value []byte, _err error) {
+	mgs.mtx.RLock()
+	defer mgs.mtx.RUnlock()
+
+	value, exists := mgs.data[string(key)]
+	if !exists {
+		return nil, nil
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (mgs *MemoryGlobalStore) BatchGet(keyList [][]byte) (_valueList [][]byte, _err error) {
+	mgs.mtx.RLock()
+	defer mgs.mtx.RUnlock()
+
+	var retValueList [][]byte
+	for _, key := range keyList {
+		value, exists := mgs.data[string(key)]
+		if !exists {
+			retValueList = append(retValueList, []byte{})
+			continue
+		}
+		retValueList = append(retValueList, append([]byte{}, value...))
+	}
+	return retValueList, nil
+}
+
+func (mgs *MemoryGlobalStore) Delete(key []byte) error {
+	mgs.mtx.Lock()
+	defer mgs.mtx.Unlock()
+
+	delete(mgs.data, string(key))
+	return nil
+}
+
+func (mgs *MemoryGlobalStore) Seek(startPrefix []byte, validForPrefix []byte, maxKeyLen int,
+	numToFetch int, reverse bool, fetchValues bool) (_keysFound [][]byte, _valsFound [][]byte, _err error) {
+
+	mgs.mtx.RLock()
+	defer mgs.mtx.RUnlock()
+
+	sortedKeys := []string{}
+	for key := range mgs.data {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	// Mirror the badger iterator semantics used by DBGetPaginatedKeysAndValuesForPrefix.
+	// When iterating backwards, the seek key is padded with 0xFF up to maxKeyLen so
+	// that it sorts after every key that could exist with this prefix.
+	seekKey := startPrefix
+	if reverse {
+		seekKey = make([]byte, maxKeyLen)
+		for ii := 0; ii < maxKeyLen; ii++ {
+			if ii < len(startPrefix) {
+				seekKey[ii] = startPrefix[ii]
+			} else {
+				seekKey[ii] = 0xFF
+			}
+		}
+	}
+
+	keysFound := [][]byte{}
+	valsFound := [][]byte{}
+	addKey := func(key string) bool {
+		if !bytes.HasPrefix([]byte(key), validForPrefix) || len(keysFound) >= numToFetch {
+			return false
+		}
+		keysFound = append(keysFound, []byte(key))
+		if fetchValues {
+			valsFound = append(valsFound, append([]byte{}, mgs.data[key]...))
+		}
+		return true
+	}
+
+	if reverse {
+		// Find the last key that is <= seekKey and walk backwards.
+		startIdx := sort.Search(len(sortedKeys), func(ii int) bool {
+			return sortedKeys[ii] > string(seekKey)
+		}) - 1
+		for ii := startIdx; ii >= 0; ii-- {
+			if !addKey(sortedKeys[ii]) {
+				break
+			}
+		}
+	} else {
+		// Find the first key that is >= seekKey and walk forwards.
+		startIdx := sort.SearchStrings(sortedKeys, string(seekKey))
+		for ii := startIdx; ii < len(sortedKeys); ii++ {
+			if !addKey(sortedKeys[ii]) {
+				break
+			}
+		}
+	}
+
+	return keysFound, valsFound, nil
+}
+
+func (mgs *MemoryGlobalStore) CompareAndSwap(key []byte, expectedValue []byte, newValue []byte) (
+	_swapped bool, _err error) {
+
+	mgs.mtx.Lock()
+	defer mgs.mtx.Unlock()
+
+	currentValue := mgs.data[string(key)]
+	if !globalStateValuesEqual(currentValue, expectedValue) {
+		return false, nil
+	}
+
+	if newValue == nil {
+		delete(mgs.data, string(key))
+	} else {
+		mgs.data[string(key)] = append([]byte{}, newValue...)
+	}
+	return true, nil
+}
@@ -2,12 +2,22 @@ package routes
 
 import (
 	"bytes"
+	"encoding/gob"
 	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/bitclout/core/lib"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -67,11 +77,8 @@ func TestGlobalStateServicePutGetDeleteWithRemoteNode(t *testing.T) {
 	// Getting when no value is present should return nil without an
 	// error.
 	{
-		url, json_data, err := apiServer.CreateGlobalStateGetRequest([]byte("woo"))
+		request, err := apiServer.CreateGlobalStateGetRequest([]byte("woo"))
 		require.NoError(err)
-		request, _ := http.NewRequest(
-			"POST", url, bytes.NewBuffer(json_data))
-		request.Header.Set("Content-Type", "application/json")
 		response := httptest.NewRecorder()
 		apiServer.router.ServeHTTP(response, request)
 		assert.Equal(200, response.Code, "200 response expected")
@@ -85,11 +92,8 @@ func TestGlobalStateServicePutGetDeleteWithRemoteNode(t *testing.T) {
 
 	// Putting then getting a value should work.
 	{
-		url, json_data, err := apiServer.CreateGlobalStatePutRequest([]byte("woo"), []byte("hoo"))
+		request, err := apiServer.CreateGlobalStatePutRequest([]byte("woo"), []byte("hoo"))
 		require.NoError(err)
-		request, _ := http.NewRequest(
-			"POST", url, bytes.NewBuffer(json_data))
-		request.Header.Set("Content-Type", "application/json")
 		response := httptest.NewRecorder()
 		apiServer.router.ServeHTTP(response, request)
 		assert.Equal(200, response.Code, "200 response expected")
@@ -100,12 +104,8 @@ func TestGlobalStateServicePutGetDeleteWithRemoteNode(t *testing.T) {
 		}
 	}
 	{
-		url, json_data, err := apiServer.CreateGlobalStateGetRequest([]byte("woo"))
+		request, err := apiServer.CreateGlobalStateGetRequest([]byte("woo"))
 		require.NoError(err)
-		request, _ := http.NewRequest(
-			"POST", url,
-			bytes.NewBuffer(json_data))
-		request.Header.Set("Content-Type", "application/json")
 		response := httptest.NewRecorder()
 		apiServer.router.ServeHTTP(response, request)
 		assert.Equal(200, response.Code, "200 response expected")
@@ -119,12 +119,10 @@ func TestGlobalStateServicePutGetDeleteWithRemoteNode(t *testing.T) {
 
 	// Batch get should work.
 	{
-		url, json_data, err := apiServer.CreateGlobalStateBatchGetRequest(
+		request, err := apiServer.CreateGlobalStateBatchGetRequest(
 			[][]byte{[]byte("woo"), []byte("fantastic"), []byte("great")},
 		)
 		require.NoError(err)
-		request, _ := http.NewRequest("POST", url, bytes.NewBuffer(json_data))
-		request.Header.Set("Content-Type", "application/json")
 		response := httptest.NewRecorder()
 		apiServer.router.ServeHTTP(response, request)
 		assert.Equal(200, response.Code, "200 response expected")
@@ -145,12 +143,8 @@ func TestGlobalStateServicePutGetDeleteWithRemoteNode(t *testing.T) {
 
 	// Deleting a value should make it no longer gettable.
 	{
-		url, json_data, err := apiServer.CreateGlobalStateDeleteRequest([]byte("woo"))
+		request, err := apiServer.CreateGlobalStateDeleteRequest([]byte("woo"))
 		require.NoError(err)
-		request, _ := http.NewRequest(
-			"POST", url,
-			bytes.NewBuffer(json_data))
-		request.Header.Set("Content-Type", "application/json")
 		response := httptest.NewRecorder()
 		apiServer.router.ServeHTTP(response, request)
 		assert.Equal(200, response.Code, "200 response expected")
@@ -161,12 +155,8 @@ func TestGlobalStateServicePutGetDeleteWithRemoteNode(t *testing.T) {
 		}
 	}
 	{
-		url, json_data, err := apiServer.CreateGlobalStateGetRequest([]byte("woo"))
+		request, err := apiServer.CreateGlobalStateGetRequest([]byte("woo"))
 		require.NoError(err)
-		request, _ := http.NewRequest(
-			"POST", url,
-			bytes.NewBuffer(json_data))
-		request.Header.Set("Content-Type", "application/json")
 		response := httptest.NewRecorder()
 		apiServer.router.ServeHTTP(response, request)
 		assert.Equal(200, response.Code, "200 response expected")
@@ -187,29 +177,975 @@ func TestGlobalStateServiceURLCreation(t *testing.T) {
 	apiServer, _, _ := newTestAPIServer(
 		t, "https://bitclout.com:17001" /*globalStateRemoteNode*/)
 
+	checkRequest := func(request *http.Request, expectedURL string) {
+		assert.Equal(expectedURL, request.URL.String())
+		// The secret should never be sent in the clear.
+		assert.NotContains(request.URL.String(), globalStateSharedSecret)
+		assert.NotEmpty(request.Header.Get(GlobalStateTimestampHeader))
+		assert.NotEmpty(request.Header.Get(GlobalStateNonceHeader))
+		assert.NotEmpty(request.Header.Get(GlobalStateSignatureHeader))
+	}
+
 	{
-		url, _, err := apiServer.CreateGlobalStateGetRequest([]byte("woo"))
+		request, err := apiServer.CreateGlobalStateGetRequest([]byte("woo"))
 		require.NoError(err)
-		assert.Equal("https://bitclout.com:17001/api/v1/global-state/get?shared_secret=abcdef", url)
+		checkRequest(request, "https://bitclout.com:17001/api/v1/global-state/get")
 	}
 
 	{
-		url, _, err := apiServer.CreateGlobalStatePutRequest([]byte("woo"), []byte("hoo"))
+		request, err := apiServer.CreateGlobalStatePutRequest([]byte("woo"), []byte("hoo"))
 		require.NoError(err)
-		assert.Equal("https://bitclout.com:17001/api/v1/global-state/put?shared_secret=abcdef", url)
+		checkRequest(request, "https://bitclout.com:17001/api/v1/global-state/put")
 	}
 
 	{
-		url, _, err := apiServer.CreateGlobalStateBatchGetRequest(
+		request, err := apiServer.CreateGlobalStateBatchGetRequest(
 			[][]byte{[]byte("woo"), []byte("fantastic"), []byte("great")},
 		)
 		require.NoError(err)
-		assert.Equal("https://bitclout.com:17001/api/v1/global-state/batch-get?shared_secret=abcdef", url)
+		checkRequest(request, "https://bitclout.com:17001/api/v1/global-state/batch-get")
+	}
+
+	{
+		request, err := apiServer.CreateGlobalStateDeleteRequest([]byte("woo"))
+		require.NoError(err)
+		checkRequest(request, "https://bitclout.com:17001/api/v1/global-state/delete")
+	}
+}
+
+func TestGlobalStateRequestSignature(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	_, _ = assert, require
+
+	publicApiServer, privateApiServer, _ := newTestAPIServer(
+		t, "" /*globalStateRemoteNode*/)
+
+	serve := func(apiServer *APIServer, request *http.Request) *httptest.ResponseRecorder {
+		response := httptest.NewRecorder()
+		apiServer.router.ServeHTTP(response, request)
+		return response
+	}
+	signedRequest := func(secret string) *http.Request {
+		jsonData, err := json.Marshal(&GlobalStateGetRemoteRequest{Key: []byte("woo")})
+		require.NoError(err)
+		request, err := newSignedGlobalStateRequest("", secret, RoutePathGlobalStateGetRemote, jsonData)
+		require.NoError(err)
+		return request
+	}
+
+	// A correctly signed request should be accepted exactly once.
+	{
+		request := signedRequest(globalStateSharedSecret)
+		jsonData, err := json.Marshal(&GlobalStateGetRemoteRequest{Key: []byte("woo")})
+		require.NoError(err)
+		replay, err := http.NewRequest("POST", RoutePathGlobalStateGetRemote, bytes.NewBuffer(jsonData))
+		require.NoError(err)
+		replay.Header = request.Header.Clone()
+
+		assert.Equal(200, serve(publicApiServer, request).Code)
+		assert.Equal(401, serve(publicApiServer, replay).Code)
+	}
+
+	// Requests signed with the wrong secret or without a signature are rejected.
+	{
+		assert.Equal(401, serve(publicApiServer, signedRequest("wrongsecret")).Code)
+
+		request := signedRequest(globalStateSharedSecret)
+		request.Header.Del(GlobalStateSignatureHeader)
+		assert.Equal(401, serve(publicApiServer, request).Code)
+	}
+
+	// Tampering with the body invalidates the signature.
+	{
+		request := signedRequest(globalStateSharedSecret)
+		jsonData, err := json.Marshal(&GlobalStateGetRemoteRequest{Key: []byte("wee")})
+		require.NoError(err)
+		request.Body = ioutil.NopCloser(bytes.NewBuffer(jsonData))
+		assert.Equal(401, serve(publicApiServer, request).Code)
+	}
+
+	// Stale timestamps are rejected.
+	{
+		jsonData, err := json.Marshal(&GlobalStateGetRemoteRequest{Key: []byte("woo")})
+		require.NoError(err)
+		request, err := http.NewRequest("POST", RoutePathGlobalStateGetRemote, bytes.NewBuffer(jsonData))
+		require.NoError(err)
+		request.Header.Set("Content-Type", "application/json")
+		timestamp := fmt.Sprintf("%d", time.Now().Add(-2*GlobalStateMaxClockSkew).UnixNano())
+		request.Header.Set(GlobalStateTimestampHeader, timestamp)
+		request.Header.Set(GlobalStateNonceHeader, "abcd")
+		request.Header.Set(GlobalStateSignatureHeader, computeGlobalStateSignature(
+			globalStateSharedSecret, "POST", RoutePathGlobalStateGetRemote, timestamp, "abcd", jsonData))
+		assert.Equal(401, serve(publicApiServer, request).Code)
+	}
+
+	// Both the current and previous secrets are accepted during a rotation.
+	{
+		publicApiServer.GlobalStatePreviousSharedSecret = "oldsecret"
+		assert.Equal(200, serve(publicApiServer, signedRequest("oldsecret")).Code)
+		assert.Equal(200, serve(publicApiServer, signedRequest(globalStateSharedSecret)).Code)
+		publicApiServer.GlobalStatePreviousSharedSecret = ""
+		assert.Equal(401, serve(publicApiServer, signedRequest("oldsecret")).Code)
 	}
 
+	// A node without a secret rejects everything.
 	{
-		url, _, err := apiServer.CreateGlobalStateDeleteRequest([]byte("woo"))
+		assert.Equal(401, serve(privateApiServer, signedRequest("")).Code)
+		assert.Equal(401, serve(privateApiServer, signedRequest(globalStateSharedSecret)).Code)
+	}
+}
+
+func TestMemoryGlobalStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	_, _ = assert, require
+
+	store := NewMemoryGlobalStore()
+
+	// Getting when no value is present should return nil without an
+	// error.
+	val, err := store.Get([]byte("woo"))
+	require.NoError(err)
+	require.Nil(val)
+
+	// Putting then getting a value should work.
+	require.NoError(store.Put([]byte("woo"), []byte("hoo")))
+	val, err = store.Get([]byte("woo"))
+	require.NoError(err)
+	require.Equal([]byte("hoo"), val)
+
+	// Batch get should return empty values for missing keys.
+	valueList, err := store.BatchGet([][]byte{[]byte("woo"), []byte("great")})
+	require.NoError(err)
+	require.Equal([][]byte{[]byte("hoo"), {}}, valueList)
+
+	// Seeking should respect the prefix, the ordering, and the limit.
+	require.NoError(store.Put([]byte{1, 1}, []byte("a")))
+	require.NoError(store.Put([]byte{1, 2}, []byte("b")))
+	require.NoError(store.Put([]byte{1, 3}, []byte("c")))
+	require.NoError(store.Put([]byte{2, 1}, []byte("d")))
+	keys, vals, err := store.Seek([]byte{1}, []byte{1}, 2, 2, false, true)
+	require.NoError(err)
+	require.Equal([][]byte{{1, 1}, {1, 2}}, keys)
+	require.Equal([][]byte{[]byte("a"), []byte("b")}, vals)
+	keys, vals, err = store.Seek([]byte{1}, []byte{1}, 2, 10, true, false)
+	require.NoError(err)
+	require.Equal([][]byte{{1, 3}, {1, 2}, {1, 1}}, keys)
+	require.Empty(vals)
+
+	// Deleting a value should make it no longer gettable.
+	require.NoError(store.Delete([]byte("woo")))
+	val, err = store.Get([]byte("woo"))
+	require.NoError(err)
+	require.Nil(val)
+}
+
+func TestValidateGlobalStore(t *testing.T) {
+	require := require.New(t)
+
+	var nilBadgerStore *BadgerGlobalStore
+	changeLogStore, err := NewChangeLogGlobalStore(NewBadgerGlobalStore(nil))
+	require.NoError(err)
+	for _, store := range []GlobalStore{
+		nil,
+		nilBadgerStore,
+		NewBadgerGlobalStore(nil),
+		NewRemoteGlobalStore("", globalStateSharedSecret),
+		NewCachingGlobalStore(NewRemoteGlobalStore("", globalStateSharedSecret)),
+		changeLogStore,
+	} {
+		require.Error(validateGlobalStore(store))
+	}
+
+	globalStateDB, _ := GetTestBadgerDb()
+	defer globalStateDB.Close()
+	changeLogStore, err = NewChangeLogGlobalStore(NewBadgerGlobalStore(globalStateDB))
+	require.NoError(err)
+	for _, store := range []GlobalStore{
+		NewMemoryGlobalStore(),
+		changeLogStore,
+		NewCachingGlobalStore(NewRemoteGlobalStore("http://localhost:17001", globalStateSharedSecret)),
+	} {
+		require.NoError(validateGlobalStore(store))
+	}
+
+	// A node with neither a global state db nor a remote node doesn't start.
+	_, err = NewAPIServer(nil, nil, nil, nil, nil, &lib.BitCloutTestnetParams, testJSONPort,
+		testMinFeeRateNanosPerKB, "", 20000, nil,
+		nil, "", "", "", nil,
+		[]string{}, false, []string{},
+		"", "", false, nil, "", 0,
+		"", "", "", "", false, []string{}, nil, nil)
+	require.Error(err)
+}
+
+func TestGlobalStatePrefixesDoNotCollide(t *testing.T) {
+	require := require.New(t)
+
+	require.NoError(ValidateGlobalStatePrefixes())
+
+	// A duplicate prefix should be caught.
+	registryBefore := globalStatePrefixRegistry
+	defer func() { globalStatePrefixRegistry = registryBefore }()
+	registerGlobalStatePrefix([]byte{0}, "Duplicate", nil)
+	require.Error(ValidateGlobalStatePrefixes())
+}
+
+func TestGlobalStateMigrations(t *testing.T) {
+	require := require.New(t)
+
+	store := NewMemoryGlobalStore()
+	pkBytes := make([]byte, btcec.PubKeyBytesLenCompressed)
+	pkBytes[0] = 2
+
+	// Store a UserMetadata without a public key.
+	metadataDataBuf := bytes.NewBuffer([]byte{})
+	require.NoError(gob.NewEncoder(metadataDataBuf).Encode(&UserMetadata{Email: "a@b.com"}))
+	require.NoError(store.Put(GlobalStateKeyForPublicKeyToUserMetadata(pkBytes), metadataDataBuf.Bytes()))
+
+	version, err := GetGlobalStateSchemaVersion(store)
+	require.NoError(err)
+	require.Equal(uint64(0), version)
+
+	require.NoError(RunGlobalStateMigrations(store))
+
+	version, err = GetGlobalStateSchemaVersion(store)
+	require.NoError(err)
+	require.Equal(globalStateMigrations[len(globalStateMigrations)-1].Version, version)
+
+	userMetadataBytes, err := store.Get(GlobalStateKeyForPublicKeyToUserMetadata(pkBytes))
+	require.NoError(err)
+	userMetadata := UserMetadata{}
+	require.NoError(gob.NewDecoder(bytes.NewReader(userMetadataBytes)).Decode(&userMetadata))
+	require.Equal(pkBytes, userMetadata.PublicKey)
+	require.Equal("a@b.com", userMetadata.Email)
+
+	// Running the migrations again should be a no-op.
+	require.NoError(RunGlobalStateMigrations(store))
+}
+
+func TestGlobalStateCompareAndSwap(t *testing.T) {
+	require := require.New(t)
+
+	for _, store := range []GlobalStore{NewMemoryGlobalStore(), NewBadgerGlobalStore(func() *badger.DB {
+		db, _ := GetTestBadgerDb()
+		return db
+	}())} {
+		// A nil expected value means the key must not be present.
+		swapped, err := store.CompareAndSwap([]byte("woo"), nil, []byte("hoo"))
+		require.NoError(err)
+		require.True(swapped)
+		swapped, err = store.CompareAndSwap([]byte("woo"), nil, []byte("hoo"))
+		require.NoError(err)
+		require.False(swapped)
+
+		// Swapping with a stale value should fail and leave the value untouched.
+		swapped, err = store.CompareAndSwap([]byte("woo"), []byte("stale"), []byte("new"))
+		require.NoError(err)
+		require.False(swapped)
+		val, err := store.Get([]byte("woo"))
+		require.NoError(err)
+		require.Equal([]byte("hoo"), val)
+
+		// Swapping with the current value should work, and a nil new value deletes.
+		swapped, err = store.CompareAndSwap([]byte("woo"), []byte("hoo"), nil)
+		require.NoError(err)
+		require.True(swapped)
+		val, err = store.Get([]byte("woo"))
+		require.NoError(err)
+		require.Nil(val)
+	}
+}
+
+func TestGlobalStateUpdateEmptyValue(t *testing.T) {
+	require := require.New(t)
+
+	for _, store := range []GlobalStore{NewMemoryGlobalStore(), NewBadgerGlobalStore(func() *badger.DB {
+		db, _ := GetTestBadgerDb()
+		return db
+	}())} {
+		fes := &APIServer{GlobalStore: store}
+		require.NoError(fes.GlobalStatePut([]byte("woo"), []byte{}))
+
+		// An empty value is read back as present, so the update goes through on
+		// the first try.
+		val, err := fes.GlobalStateGet([]byte("woo"))
+		require.NoError(err)
+		require.NotNil(val)
+		require.Empty(val)
+		numAttempts := 0
+		require.NoError(fes.GlobalStateUpdate([]byte("woo"), func(oldValue []byte) ([]byte, error) {
+			numAttempts++
+			require.NotNil(oldValue)
+			return []byte("hoo"), nil
+		}))
+		require.Equal(1, numAttempts)
+		val, err = fes.GlobalStateGet([]byte("woo"))
 		require.NoError(err)
-		assert.Equal("https://bitclout.com:17001/api/v1/global-state/delete?shared_secret=abcdef", url)
+		require.Equal([]byte("hoo"), val)
+	}
+}
+
+func TestGlobalStateUpdateUserMetadata(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	apiServer := &APIServer{GlobalStore: NewMemoryGlobalStore()}
+	pkBytes := make([]byte, btcec.PubKeyBytesLenCompressed)
+	pkBytes[0] = 2
+
+	// Concurrent updates to different fields of the same user should all land.
+	var wg sync.WaitGroup
+	for ii := 0; ii < 5; ii++ {
+		wg.Add(1)
+		go func(ii int) {
+			defer wg.Done()
+			_, err := apiServer.updateUserMetadataInGlobalState(pkBytes, func(userMetadata *UserMetadata) error {
+				if userMetadata.BlockedPublicKeys == nil {
+					userMetadata.BlockedPublicKeys = make(map[string]struct{})
+				}
+				userMetadata.BlockedPublicKeys[fmt.Sprintf("pk%d", ii)] = struct{}{}
+				return nil
+			})
+			assert.NoError(err)
+		}(ii)
 	}
+	wg.Wait()
+
+	userMetadataBytes, err := apiServer.GlobalStateGet(GlobalStateKeyForPublicKeyToUserMetadata(pkBytes))
+	require.NoError(err)
+	userMetadata := UserMetadata{}
+	require.NoError(gob.NewDecoder(bytes.NewReader(userMetadataBytes)).Decode(&userMetadata))
+	require.Equal(pkBytes, userMetadata.PublicKey)
+	require.Len(userMetadata.BlockedPublicKeys, 5)
+}
+
+func TestDecodeGlobalStateEntry(t *testing.T) {
+	require := require.New(t)
+
+	pkBytes := make([]byte, btcec.PubKeyBytesLenCompressed)
+	pkBytes[0] = 2
+	metadataDataBuf := bytes.NewBuffer([]byte{})
+	require.NoError(gob.NewEncoder(metadataDataBuf).Encode(&UserMetadata{PublicKey: pkBytes, Email: "a@b.com"}))
+	key := GlobalStateKeyForPublicKeyToUserMetadata(pkBytes)
+
+	entry := DecodeGlobalStateEntry(key, metadataDataBuf.Bytes(), &lib.BitCloutTestnetParams)
+	require.Empty(entry.DecodeError)
+	require.Equal("PublicKeyToUserMetadata", entry.Prefix)
+	decodedValue, ok := entry.Value.(*decodedUserMetadata)
+	require.True(ok)
+	require.Equal("a@b.com", decodedValue.Email)
+	require.Equal(lib.PkToString(pkBytes, &lib.BitCloutTestnetParams), decodedValue.PublicKeyBase58Check)
+
+	// The entry should survive a round trip through JSON with its raw bytes intact.
+	entryJSON, err := json.Marshal(entry)
+	require.NoError(err)
+	parsedEntry := GlobalStateEntry{}
+	require.NoError(json.Unmarshal(entryJSON, &parsedEntry))
+	parsedKey, parsedValue, err := parsedEntry.Bytes()
+	require.NoError(err)
+	require.Equal(key, parsedKey)
+	require.Equal(metadataDataBuf.Bytes(), parsedValue)
+
+	// Keys under an unknown prefix are still exported.
+	entry = DecodeGlobalStateEntry([]byte{255, 1}, []byte{2}, &lib.BitCloutTestnetParams)
+	require.NotEmpty(entry.DecodeError)
+	require.Equal("ff01", entry.KeyHex)
+}
+
+func TestChangeLogGlobalStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	_, _ = assert, require
+
+	changeLog, err := NewChangeLogGlobalStore(NewMemoryGlobalStore())
+	require.NoError(err)
+
+	// A caller that doesn't know the epoch is told to reset.
+	epoch, changes, latestSequence, reset := changeLog.ChangesSince("", 0, 0)
+	assert.True(reset)
+	assert.Empty(changes)
+	assert.Equal(uint64(0), latestSequence)
+
+	// Writes are recorded but reads and failed swaps are not.
+	require.NoError(changeLog.Put([]byte("woo"), []byte("hoo")))
+	_, err = changeLog.Get([]byte("woo"))
+	require.NoError(err)
+	swapped, err := changeLog.CompareAndSwap([]byte("woo"), []byte("wrong"), []byte("hee"))
+	require.NoError(err)
+	require.False(swapped)
+	swapped, err = changeLog.CompareAndSwap([]byte("woo"), []byte("hoo"), []byte("hee"))
+	require.NoError(err)
+	require.True(swapped)
+	require.NoError(changeLog.Delete([]byte("fantastic")))
+
+	_, changes, latestSequence, reset = changeLog.ChangesSince(epoch, 0, 0)
+	assert.False(reset)
+	assert.Equal(uint64(3), latestSequence)
+	require.Len(changes, 3)
+	assert.Equal([]byte("woo"), changes[0].Key)
+	assert.Equal([]byte("woo"), changes[1].Key)
+	assert.Equal([]byte("fantastic"), changes[2].Key)
+
+	_, changes, _, reset = changeLog.ChangesSince(epoch, 2, 0)
+	assert.False(reset)
+	require.Len(changes, 1)
+	assert.Equal(uint64(3), changes[0].Sequence)
+
+	// Long-polling returns as soon as a change is made.
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(changeLog.Put([]byte("great"), []byte("stuff")))
+	}()
+	_, changes, _, reset = changeLog.ChangesSince(epoch, 3, 10*time.Second)
+	assert.False(reset)
+	require.Len(changes, 1)
+	assert.Equal([]byte("great"), changes[0].Key)
+
+	// A caller that falls too far behind is told to reset.
+	for ii := 0; ii < globalStateChangeLogSize; ii++ {
+		require.NoError(changeLog.Put([]byte("woo"), []byte("hoo")))
+	}
+	_, _, _, reset = changeLog.ChangesSince(epoch, 1, 0)
+	assert.True(reset)
+}
+
+func TestCachingGlobalStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	_, _ = assert, require
+
+	apiServer, _, _ := newTestAPIServer(
+		t, "" /*globalStateRemoteNode*/)
+	primary := httptest.NewServer(apiServer.router)
+	defer primary.Close()
+
+	cachingStore := NewCachingGlobalStore(
+		NewRemoteGlobalStore(primary.URL, globalStateSharedSecret))
+	go cachingStore.Start()
+	defer cachingStore.Stop()
+
+	isCached := func(key string) bool {
+		cachingStore.mtx.RLock()
+		defer cachingStore.mtx.RUnlock()
+		_, exists := cachingStore.cache[key]
+		return exists
+	}
+	changeLog := apiServer.GlobalStore.(*ChangeLogGlobalStore)
+	waitForFeed := func() {
+		require.Eventually(func() bool {
+			changeLog.mtx.Lock()
+			latestSequence := changeLog.latestSequence
+			changeLog.mtx.Unlock()
+
+			cachingStore.mtx.RLock()
+			defer cachingStore.mtx.RUnlock()
+			return cachingStore.isFreshLocked() && cachingStore.sequence == latestSequence
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	// Reads are cached, including for missing keys.
+	require.NoError(apiServer.GlobalStatePut([]byte("woo"), []byte("hoo")))
+	waitForFeed()
+	val, err := cachingStore.Get([]byte("woo"))
+	require.NoError(err)
+	assert.Equal([]byte("hoo"), val)
+	assert.True(isCached("woo"))
+	vals, err := cachingStore.BatchGet([][]byte{[]byte("woo"), []byte("fantastic")})
+	require.NoError(err)
+	assert.Equal([][]byte{[]byte("hoo"), {}}, vals)
+	assert.True(isCached("fantastic"))
+	val, err = cachingStore.Get([]byte("fantastic"))
+	require.NoError(err)
+	assert.Nil(val)
+
+	// A write on the primary invalidates the cached value.
+	require.NoError(apiServer.GlobalStatePut([]byte("woo"), []byte("hee")))
+	require.Eventually(func() bool { return !isCached("woo") }, 5*time.Second, 10*time.Millisecond)
+	val, err = cachingStore.Get([]byte("woo"))
+	require.NoError(err)
+	assert.Equal([]byte("hee"), val)
+
+	// Writes through the cache go to the primary.
+	require.NoError(cachingStore.Put([]byte("fantastic"), []byte("great")))
+	waitForFeed()
+	val, err = apiServer.GlobalStateGet([]byte("fantastic"))
+	require.NoError(err)
+	assert.Equal([]byte("great"), val)
+	val, err = cachingStore.Get([]byte("fantastic"))
+	require.NoError(err)
+	assert.Equal([]byte("great"), val)
+
+	// Cached values are still served if the primary goes away.
+	primary.Close()
+	val, err = cachingStore.Get([]byte("woo"))
+	require.NoError(err)
+	assert.Equal([]byte("hee"), val)
+}
+
+func TestGlobalStateClient(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+	_, _ = assert, require
+
+	// The remote node fails in whichever way failMode says.
+	var mtx sync.Mutex
+	var failMode string
+	numCalls := 0
+	setFailMode := func(mode string) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		failMode, numCalls = mode, 0
+	}
+	getNumCalls := func() int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return numCalls
+	}
+	remote := httptest.NewServer(http.HandlerFunc(func(ww http.ResponseWriter, rr *http.Request) {
+		mtx.Lock()
+		numCalls++
+		callNum, mode := numCalls, failMode
+		mtx.Unlock()
+
+		switch mode {
+		case "unavailable-once":
+			if callNum == 1 {
+				ww.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "bad-request":
+			_AddCodedError(ww, ErrorCodeBadRequest, "GlobalStateGetRemote: Something went wrong")
+			return
+		case "garbage":
+			ww.Write([]byte("{not json"))
+			return
+		case "slow":
+			time.Sleep(200 * time.Millisecond)
+		}
+		json.NewEncoder(ww).Encode(&GlobalStateGetRemoteResponse{Value: []byte("hoo")})
+	}))
+	defer remote.Close()
+
+	remoteStore := NewRemoteGlobalStore(remote.URL, globalStateSharedSecret)
+	remoteStore.Client.Timeout = 100 * time.Millisecond
+	getRemoteError := func(err error) *GlobalStateRemoteError {
+		require.Error(err)
+		remoteErr, ok := errors.Cause(err).(*GlobalStateRemoteError)
+		require.True(ok, "Expected a *GlobalStateRemoteError but got %v", err)
+		return remoteErr
+	}
+
+	// Transient failures are retried.
+	{
+		setFailMode("unavailable-once")
+		val, err := remoteStore.Get([]byte("woo"))
+		require.NoError(err)
+		assert.Equal([]byte("hoo"), val)
+		assert.Equal(2, getNumCalls())
+	}
+
+	// Errors reported by the remote node are surfaced and not retried.
+	{
+		setFailMode("bad-request")
+		val, err := remoteStore.Get([]byte("woo"))
+		assert.Nil(val)
+		remoteErr := getRemoteError(err)
+		assert.Equal(http.StatusBadRequest, remoteErr.StatusCode)
+		assert.Equal("GlobalStateGetRemote: Something went wrong", remoteErr.RemoteError)
+		assert.Equal(1, getNumCalls())
+	}
+
+	// Responses that can't be decoded are errors rather than empty values.
+	{
+		setFailMode("garbage")
+		_, err := remoteStore.Get([]byte("woo"))
+		getRemoteError(err)
+		assert.Equal(GlobalStateRemoteMaxAttempts, getNumCalls())
+	}
+
+	// Calls time out.
+	{
+		setFailMode("slow")
+		_, err := remoteStore.Get([]byte("woo"))
+		remoteErr := getRemoteError(err)
+		assert.Equal(0, remoteErr.StatusCode)
+	}
+
+	// Compare-and-swap is never retried.
+	{
+		setFailMode("garbage")
+		_, err := remoteStore.CompareAndSwap([]byte("woo"), nil, []byte("hoo"))
+		getRemoteError(err)
+		assert.Equal(1, getNumCalls())
+	}
+
+	// Moderation filters fail closed when the remote node is unreachable rather
+	// than treating every public key as unrestricted.
+	{
+		remote.Close()
+		apiServer, _, _ := newTestAPIServer(t, remote.URL /*globalStateRemoteNode*/)
+		filteredPubKeys, err := apiServer.FilterOutRestrictedPubKeysFromList(
+			[][]byte{[]byte("somepublickey")}, nil, "")
+		assert.Error(err)
+		assert.Nil(filteredPubKeys)
+	}
+}
+
+func TestAdminGlobalStateBrowser(t *testing.T) {
+	require := require.New(t)
+
+	apiServer := &APIServer{GlobalStore: NewMemoryGlobalStore(), Params: &lib.BitCloutTestnetParams}
+	call := func(handler http.HandlerFunc, req interface{}, res interface{}) int {
+		reqBytes, err := json.Marshal(req)
+		require.NoError(err)
+		response := httptest.NewRecorder()
+		handler(response, httptest.NewRequest("POST", "/", bytes.NewReader(reqBytes)))
+		if response.Code == http.StatusOK && res != nil {
+			require.NoError(json.NewDecoder(response.Body).Decode(res))
+		}
+		return response.Code
+	}
+
+	// Every registered prefix is listed.
+	prefixesRes := AdminGetGlobalStatePrefixesResponse{}
+	require.Equal(http.StatusOK, call(apiServer.AdminGetGlobalStatePrefixes,
+		&AdminGetGlobalStatePrefixesRequest{}, &prefixesRes))
+	require.Len(prefixesRes.Prefixes, len(GlobalStatePrefixes()))
+
+	// Page through three blacklisted profiles two at a time.
+	for ii := byte(1); ii <= 3; ii++ {
+		pkBytes := make([]byte, btcec.PubKeyBytesLenCompressed)
+		pkBytes[0] = 2
+		pkBytes[1] = ii
+		require.NoError(apiServer.GlobalStatePut(GlobalStateKeyForBlacklistedProfile(pkBytes), lib.IsBlacklisted))
+	}
+	entriesRes := AdminGetGlobalStateEntriesResponse{}
+	require.Equal(http.StatusOK, call(apiServer.AdminGetGlobalStateEntries, &AdminGetGlobalStateEntriesRequest{
+		PrefixName: "PublicKeyToBlacklistState",
+		NumToFetch: 2,
+	}, &entriesRes))
+	require.Len(entriesRes.Entries, 2)
+	require.NotEmpty(entriesRes.NextStartKeyHex)
+	firstEntry := entriesRes.Entries[0]
+	require.Empty(firstEntry.DecodeError)
+
+	require.Equal(http.StatusOK, call(apiServer.AdminGetGlobalStateEntries, &AdminGetGlobalStateEntriesRequest{
+		PrefixName:  "PublicKeyToBlacklistState",
+		StartKeyHex: entriesRes.NextStartKeyHex,
+		NumToFetch:  2,
+	}, &entriesRes))
+	require.Len(entriesRes.Entries, 1)
+	require.Empty(entriesRes.NextStartKeyHex)
+
+	// Unknown prefixes are rejected.
+	require.Equal(http.StatusBadRequest, call(apiServer.AdminGetGlobalStateEntries,
+		&AdminGetGlobalStateEntriesRequest{PrefixName: "NotAPrefix"}, nil))
+
+	// Edit the first entry, then try again with the now stale expected value.
+	updateReq := &AdminUpdateGlobalStateEntryRequest{
+		AdminPublicKey:   "admin",
+		KeyHex:           firstEntry.KeyHex,
+		ExpectedValueHex: firstEntry.ValueHex,
+		NewValueHex:      "00",
+	}
+	updateRes := AdminUpdateGlobalStateEntryResponse{}
+	require.Equal(http.StatusOK, call(apiServer.AdminUpdateGlobalStateEntry, updateReq, &updateRes))
+	require.Equal("00", updateRes.Entry.ValueHex)
+	require.Equal(http.StatusConflict, call(apiServer.AdminUpdateGlobalStateEntry, updateReq, nil))
+
+	// Delete it.
+	require.Equal(http.StatusOK, call(apiServer.AdminUpdateGlobalStateEntry, &AdminUpdateGlobalStateEntryRequest{
+		AdminPublicKey:   "admin",
+		KeyHex:           firstEntry.KeyHex,
+		ExpectedValueHex: "00",
+		Delete:           true,
+	}, nil))
+	firstKey, _, err := firstEntry.Bytes()
+	require.NoError(err)
+	value, err := apiServer.GlobalStateGet(firstKey)
+	require.NoError(err)
+	require.Nil(value)
+
+	// Keys outside of the registry and the audit logs themselves can't be edited.
+	require.Equal(http.StatusBadRequest, call(apiServer.AdminUpdateGlobalStateEntry,
+		&AdminUpdateGlobalStateEntryRequest{KeyHex: "ff01", NewValueHex: "00"}, nil))
+	require.Equal(http.StatusBadRequest, call(apiServer.AdminUpdateGlobalStateEntry,
+		&AdminUpdateGlobalStateEntryRequest{KeyHex: "0a01", NewValueHex: "00"}, nil))
+	require.Equal(http.StatusBadRequest, call(apiServer.AdminUpdateGlobalStateEntry,
+		&AdminUpdateGlobalStateEntryRequest{KeyHex: "0d01", NewValueHex: "00"}, nil))
+
+	// Both changes are in the audit log, most recent first.
+	auditLogsRes := AdminGetGlobalStateAuditLogsResponse{}
+	require.Equal(http.StatusOK, call(apiServer.AdminGetGlobalStateAuditLogs,
+		&AdminGetGlobalStateAuditLogsRequest{NumToFetch: 1}, &auditLogsRes))
+	require.Len(auditLogsRes.AuditLogs, 1)
+	require.NotZero(auditLogsRes.NextBeforeTstampNanos)
+	latestLog := auditLogsRes.AuditLogs[0]
+	require.Empty(latestLog.DecodeError)
+	require.Equal("GlobalStateAuditLog", latestLog.Prefix)
+	latestLogValue := latestLog.Value.(map[string]interface{})
+	require.Equal(true, latestLogValue["IsDeletion"])
+	require.Equal("admin", latestLogValue["AdminPublicKeyBase58Check"])
+
+	require.Equal(http.StatusOK, call(apiServer.AdminGetGlobalStateAuditLogs, &AdminGetGlobalStateAuditLogsRequest{
+		BeforeTstampNanos: auditLogsRes.NextBeforeTstampNanos,
+	}, &auditLogsRes))
+	require.Len(auditLogsRes.AuditLogs, 1)
+	require.Zero(auditLogsRes.NextBeforeTstampNanos)
+	require.Equal(false, auditLogsRes.AuditLogs[0].Value.(map[string]interface{})["IsDeletion"])
+}
+
+func TestGlobalStatePIIEncryption(t *testing.T) {
+	require := require.New(t)
+
+	key1 := strings.Repeat("01", PIIKeyBytes)
+	key2 := strings.Repeat("02", PIIKeyBytes)
+	indexKey := strings.Repeat("03", PIIKeyBytes)
+	keyring1, err := NewPIIKeyring(key1, nil, indexKey)
+	require.NoError(err)
+
+	// Keys must come in pairs and be the right length.
+	_, err = NewPIIKeyring(key1, nil, "")
+	require.Error(err)
+	_, err = NewPIIKeyring("01", nil, indexKey)
+	require.Error(err)
+	noKeyring, err := NewPIIKeyring("", nil, "")
+	require.NoError(err)
+	require.Nil(noKeyring)
+
+	store := NewMemoryGlobalStore()
+	plaintextServer := &APIServer{GlobalStore: store, Params: &lib.BitCloutTestnetParams}
+	encryptingServer := &APIServer{GlobalStore: store, Params: &lib.BitCloutTestnetParams, PIIKeyring: keyring1}
+
+	pkBytes1 := make([]byte, btcec.PubKeyBytesLenCompressed)
+	pkBytes1[0] = 2
+	pkBytes2 := make([]byte, btcec.PubKeyBytesLenCompressed)
+	pkBytes2[0] = 3
+	phoneNumber := "+14155552671"
+
+	// An entry written before encryption was enabled.
+	_, err = plaintextServer.updateUserMetadataInGlobalState(pkBytes1, func(userMetadata *UserMetadata) error {
+		userMetadata.Email = "old@example.com"
+		return nil
+	})
+	require.NoError(err)
+	_, err = plaintextServer.updatePhoneNumberMetadataInGlobalState(phoneNumber, func(phoneNumberMetadata *PhoneNumberMetadata) error {
+		phoneNumberMetadata.PhoneNumber = phoneNumber
+		phoneNumberMetadata.PublicKey = pkBytes1
+		return nil
+	})
+	require.NoError(err)
+
+	// An entry written with encryption enabled. Nothing readable hits the store.
+	_, err = encryptingServer.updateUserMetadataInGlobalState(pkBytes2, func(userMetadata *UserMetadata) error {
+		userMetadata.Email = "new@example.com"
+		userMetadata.PhoneNumber = phoneNumber
+		return nil
+	})
+	require.NoError(err)
+	rawUserMetadata, err := store.Get(GlobalStateKeyForPublicKeyToUserMetadata(pkBytes2))
+	require.NoError(err)
+	require.False(bytes.Contains(rawUserMetadata, []byte("new@example.com")))
+	require.False(bytes.Contains(rawUserMetadata, []byte(phoneNumber)))
+
+	userMetadata, err := encryptingServer.getUserMetadataFromGlobalState(
+		lib.PkToString(pkBytes2, &lib.BitCloutTestnetParams))
+	require.NoError(err)
+	require.Equal("new@example.com", userMetadata.Email)
+	require.Equal(phoneNumber, userMetadata.PhoneNumber)
+	require.Nil(userMetadata.EncryptedEmail)
+	_, err = plaintextServer.getUserMetadataFromGlobalState(lib.PkToString(pkBytes2, &lib.BitCloutTestnetParams))
+	require.Error(err)
+
+	// The phone number written under its plaintext key is still found, and moves to
+	// its HMAC key the next time it's updated.
+	legacyKey, err := GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber, nil)
+	require.NoError(err)
+	indexedKey, err := GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber, keyring1)
+	require.NoError(err)
+	require.NotEqual(legacyKey, indexedKey)
+	phoneNumberMetadata, err := encryptingServer.getPhoneNumberMetadataFromGlobalState(phoneNumber)
+	require.NoError(err)
+	require.Equal(pkBytes1, phoneNumberMetadata.PublicKey)
+	_, err = encryptingServer.updatePhoneNumberMetadataInGlobalState(phoneNumber, func(phoneNumberMetadata *PhoneNumberMetadata) error {
+		phoneNumberMetadata.ShouldCompProfileCreation = true
+		return nil
+	})
+	require.NoError(err)
+	legacyValue, err := store.Get(legacyKey)
+	require.NoError(err)
+	require.Nil(legacyValue)
+	indexedValue, err := store.Get(indexedKey)
+	require.NoError(err)
+	require.False(bytes.Contains(indexedValue, []byte(phoneNumber)))
+	phoneNumberMetadata, err = encryptingServer.getPhoneNumberMetadataFromGlobalState(phoneNumber)
+	require.NoError(err)
+	require.Equal(pkBytes1, phoneNumberMetadata.PublicKey)
+	require.Equal(phoneNumber, phoneNumberMetadata.PhoneNumber)
+	require.True(phoneNumberMetadata.ShouldCompProfileCreation)
+
+	// Rotate to a new key. The plaintext entry gets encrypted and the encrypted
+	// entries get re-wrapped.
+	keyring2, err := NewPIIKeyring(key2, []string{key1}, indexKey)
+	require.NoError(err)
+	numUpdated, err := RotateGlobalStatePII(store, keyring2)
+	require.NoError(err)
+	require.Equal(3, numUpdated)
+	numUpdated, err = RotateGlobalStatePII(store, keyring2)
+	require.NoError(err)
+	require.Equal(0, numUpdated)
+
+	// Once rotated, the old key is no longer needed.
+	keyring2Only, err := NewPIIKeyring(key2, nil, indexKey)
+	require.NoError(err)
+	rotatedServer := &APIServer{GlobalStore: store, Params: &lib.BitCloutTestnetParams, PIIKeyring: keyring2Only}
+	for pkBytes, email := range map[string]string{string(pkBytes1): "old@example.com", string(pkBytes2): "new@example.com"} {
+		userMetadata, err = rotatedServer.getUserMetadataFromGlobalState(
+			lib.PkToString([]byte(pkBytes), &lib.BitCloutTestnetParams))
+		require.NoError(err)
+		require.Equal(email, userMetadata.Email)
+	}
+	phoneNumberMetadata, err = rotatedServer.getPhoneNumberMetadataFromGlobalState(phoneNumber)
+	require.NoError(err)
+	require.Equal(phoneNumber, phoneNumberMetadata.PhoneNumber)
+	_, err = encryptingServer.getUserMetadataFromGlobalState(lib.PkToString(pkBytes2, &lib.BitCloutTestnetParams))
+	require.Error(err)
+}
+
+type fakeFsckChainView struct {
+	posts    map[lib.BlockHash]*lib.PostEntry
+	profiles map[lib.PKID]*lib.ProfileEntry
+}
+
+func (view *fakeFsckChainView) GetPostEntryForPostHash(postHash *lib.BlockHash) *lib.PostEntry {
+	return view.posts[*postHash]
+}
+
+func (view *fakeFsckChainView) GetProfileEntryForPKID(pkid *lib.PKID) *lib.ProfileEntry {
+	return view.profiles[*pkid]
+}
+
+func TestGlobalStateFsck(t *testing.T) {
+	require := require.New(t)
+
+	fes := &APIServer{GlobalStore: NewMemoryGlobalStore(), Params: &lib.BitCloutTestnetParams}
+	chainView := &fakeFsckChainView{
+		posts:    make(map[lib.BlockHash]*lib.PostEntry),
+		profiles: make(map[lib.PKID]*lib.ProfileEntry),
+	}
+
+	// A visible post, a post that doesn't exist, and a hidden pinned post.
+	visiblePostHash := &lib.BlockHash{1}
+	missingPostHash := &lib.BlockHash{2}
+	hiddenPostHash := &lib.BlockHash{3}
+	chainView.posts[*visiblePostHash] = &lib.PostEntry{PostHash: visiblePostHash}
+	chainView.posts[*hiddenPostHash] = &lib.PostEntry{PostHash: hiddenPostHash, IsHidden: true}
+	require.NoError(fes.GlobalStatePut(GlobalStateKeyForTstampPostHash(1, visiblePostHash), []byte{1}))
+	require.NoError(fes.GlobalStatePut(GlobalStateKeyForTstampPostHash(2, missingPostHash), []byte{1}))
+	require.NoError(fes.GlobalStatePut(GlobalStateKeyForTstampPinnedPostHash(3, hiddenPostHash), []byte{1}))
+
+	// One verified username that still resolves, one without a profile and one
+	// whose profile was renamed.
+	chainView.profiles[lib.PKID{1}] = &lib.ProfileEntry{Username: []byte("Alice")}
+	chainView.profiles[lib.PKID{3}] = &lib.ProfileEntry{Username: []byte("dave")}
+	verifiedMapBuf := bytes.NewBuffer([]byte{})
+	require.NoError(gob.NewEncoder(verifiedMapBuf).Encode(VerifiedUsernameToPKID{
+		VerifiedUsernameToPKID: map[string]*lib.PKID{
+			"alice": {1},
+			"bob":   {2},
+			"carol": {3},
+		},
+	}))
+	require.NoError(fes.GlobalStatePut(_GlobalStatePrefixForVerifiedMap, verifiedMapBuf.Bytes()))
+
+	// A user who blocked a valid key, a garbage key and themselves.
+	pkBytes1 := make([]byte, btcec.PubKeyBytesLenCompressed)
+	pkBytes1[0] = 2
+	pkBytes2 := make([]byte, btcec.PubKeyBytesLenCompressed)
+	pkBytes2[0] = 3
+	pk1 := lib.PkToString(pkBytes1, fes.Params)
+	pk2 := lib.PkToString(pkBytes2, fes.Params)
+	_, err := fes.updateUserMetadataInGlobalState(pkBytes1, func(userMetadata *UserMetadata) error {
+		userMetadata.BlockedPublicKeys = map[string]struct{}{pk1: {}, pk2: {}, "notakey": {}}
+		return nil
+	})
+	require.NoError(err)
+
+	// Phone number metadata pointing at a user without a phone number.
+	_, err = fes.updatePhoneNumberMetadataInGlobalState("+14155552671", func(phoneNumberMetadata *PhoneNumberMetadata) error {
+		phoneNumberMetadata.PhoneNumber = "+14155552671"
+		phoneNumberMetadata.PublicKey = pkBytes1
+		return nil
+	})
+	require.NoError(err)
+
+	// An entry under a prefix nobody registered.
+	require.NoError(fes.GlobalStatePut([]byte{0xFF, 1}, []byte{1}))
+
+	countChecks := func(report *GlobalStateFsckReport) map[string]int {
+		counts := make(map[string]int)
+		for _, issue := range report.Issues {
+			counts[issue.Check]++
+		}
+		return counts
+	}
+	expectedChecks := map[string]int{
+		GlobalStateFsckWhitelistedPostNotVisible:   1,
+		GlobalStateFsckPinnedPostNotVisible:        1,
+		GlobalStateFsckVerifiedUsernameNotResolved: 2,
+		GlobalStateFsckStaleBlockedPublicKey:       2,
+		GlobalStateFsckPhoneNumberMetadataMismatch: 1,
+		GlobalStateFsckUndecodableEntry:            1,
+	}
+
+	// Without repair, everything is reported and nothing changes.
+	report, err := fes.RunGlobalStateFsck(chainView, false)
+	require.NoError(err)
+	require.Equal(7, report.NumKeysChecked)
+	require.Equal(expectedChecks, countChecks(report))
+	for _, issue := range report.Issues {
+		require.False(issue.Repaired)
+	}
+	missingPostValue, err := fes.GlobalStateGet(GlobalStateKeyForTstampPostHash(2, missingPostHash))
+	require.NoError(err)
+	require.NotNil(missingPostValue)
+
+	// With repair, every repairable issue is fixed.
+	report, err = fes.RunGlobalStateFsck(chainView, true)
+	require.NoError(err)
+	require.Equal(expectedChecks, countChecks(report))
+	for _, issue := range report.Issues {
+		require.Equal(issue.Repairable, issue.Repaired, issue.Description)
+		require.Empty(issue.RepairError)
+	}
+
+	missingPostValue, err = fes.GlobalStateGet(GlobalStateKeyForTstampPostHash(2, missingPostHash))
+	require.NoError(err)
+	require.Nil(missingPostValue)
+	hiddenPostValue, err := fes.GlobalStateGet(GlobalStateKeyForTstampPinnedPostHash(3, hiddenPostHash))
+	require.NoError(err)
+	require.Nil(hiddenPostValue)
+	visiblePostValue, err := fes.GlobalStateGet(GlobalStateKeyForTstampPostHash(1, visiblePostHash))
+	require.NoError(err)
+	require.NotNil(visiblePostValue)
+
+	verifiedMapBytes, err := fes.GlobalStateGet(_GlobalStatePrefixForVerifiedMap)
+	require.NoError(err)
+	verifiedMapStruct := VerifiedUsernameToPKID{}
+	require.NoError(gob.NewDecoder(bytes.NewReader(verifiedMapBytes)).Decode(&verifiedMapStruct))
+	require.Equal(map[string]*lib.PKID{"alice": {1}}, verifiedMapStruct.VerifiedUsernameToPKID)
+
+	userMetadata, err := fes.getUserMetadataFromGlobalState(pk1)
+	require.NoError(err)
+	require.Equal(map[string]struct{}{pk2: {}}, userMetadata.BlockedPublicKeys)
+
+	// Only the issues that need a human are left.
+	report, err = fes.RunGlobalStateFsck(chainView, true)
+	require.NoError(err)
+	require.Equal(map[string]int{
+		GlobalStateFsckPhoneNumberMetadataMismatch: 1,
+		GlobalStateFsckUndecodableEntry:            1,
+	}, countChecks(report))
 }
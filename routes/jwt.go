@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	"github.com/btcsuite/btcd/btcec"
+	"github.com/dgrijalva/jwt-go/v4"
+)
+
+const (
+	// DefaultJWTMaxLifetime is used when APIServer.JWTMaxLifetime isn't set.
+	DefaultJWTMaxLifetime = 10 * time.Minute
+	// A JWT may expire this much later than JWTMaxLifetime from now, in case the
+	// signer's clock is ahead of ours.
+	JWTMaxClockSkew = time.Minute
+)
+
+// JWTAllowedAlgorithms are the signing algorithms ValidateJWT accepts. JWTs are
+// signed with the secp256k1 key behind the public key they're for, which rules out
+// the HMAC and RSA algorithms, and "none" is never acceptable.
+var JWTAllowedAlgorithms = []string{jwt.SigningMethodES256.Alg()}
+
+// parseJWT returns the claims of jwtToken if it was signed by publicKey with one of
+// JWTAllowedAlgorithms, expires within JWTMaxLifetime, and is meant for this node.
+func (fes *APIServer) parseJWT(publicKey string, jwtToken string) (*jwt.StandardClaims, error) {
+	pubKeyBytes, _, err := lib.Base58CheckDecode(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("parseJWT: Problem decoding public key: %v", err)
+	}
+	pubKey, err := btcec.ParsePubKey(pubKeyBytes, btcec.S256())
+	if err != nil {
+		return nil, fmt.Errorf("parseJWT: Problem parsing public key: %v", err)
+	}
+
+	// The audience is checked below since we may accept more than one.
+	claims := &jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(jwtToken, claims, func(token *jwt.Token) (interface{}, error) {
+		for _, alg := range JWTAllowedAlgorithms {
+			if token.Method.Alg() == alg {
+				return pubKey.ToECDSA(), nil
+			}
+		}
+		return nil, fmt.Errorf("Signing algorithm %v is not allowed", token.Method.Alg())
+	}, jwt.WithoutAudienceValidation())
+	if err != nil {
+		return nil, fmt.Errorf("parseJWT: %v", err)
+	}
+	// ParseWithClaims already returns an error for invalid tokens, this is just
+	// in case.
+	if token == nil || !token.Valid {
+		return nil, fmt.Errorf("parseJWT: Token is not valid")
+	}
+
+	// Expired tokens are rejected by ParseWithClaims, but tokens that never expire
+	// or that expire far in the future aren't.
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("parseJWT: Token has no exp claim")
+	}
+	maxLifetime := fes.JWTMaxLifetime
+	if maxLifetime == 0 {
+		maxLifetime = DefaultJWTMaxLifetime
+	}
+	if claims.ExpiresAt.Time.After(time.Now().Add(maxLifetime + JWTMaxClockSkew)) {
+		return nil, fmt.Errorf("parseJWT: Token expires at %v, which is more than %v from now",
+			claims.ExpiresAt.Time, maxLifetime)
+	}
+
+	// Every token must be bound to this node, otherwise a token signed for any
+	// other node could be replayed here. A node with no audiences configured
+	// accepts no tokens at all.
+	if len(fes.JWTAudiences) == 0 {
+		return nil, fmt.Errorf("parseJWT: No JWT audiences are configured for this node")
+	}
+	hasAudience := false
+	for _, audience := range claims.Audience {
+		for _, allowedAudience := range fes.JWTAudiences {
+			if audience == allowedAudience {
+				hasAudience = true
+			}
+		}
+	}
+	if !hasAudience {
+		return nil, fmt.Errorf("parseJWT: Token's aud %v doesn't include any of %v",
+			claims.Audience, fes.JWTAudiences)
+	}
+
+	return claims, nil
+}
+
+// ValidateJWT returns true if jwtToken proves the caller owns publicKey. Otherwise
+// the error says what's wrong with the token. See parseJWT for what's checked.
+func (fes *APIServer) ValidateJWT(publicKey string, jwtToken string) (bool, error) {
+	if _, err := fes.parseJWT(publicKey, jwtToken); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ValidateAdminJWT is like ValidateJWT, but the token must also have a jti claim
+// that hasn't been used before. This means a leaked admin token can't be used
+// again, so admin clients need to sign a new token for every request.
+func (fes *APIServer) ValidateAdminJWT(publicKey string, jwtToken string) (bool, error) {
+	claims, err := fes.parseJWT(publicKey, jwtToken)
+	if err != nil {
+		return false, err
+	}
+	if claims.ID == "" {
+		return false, fmt.Errorf("ValidateAdminJWT: Token has no jti claim")
+	}
+	// The jti only needs to be remembered until the token expires, after which
+	// parseJWT rejects it anyway.
+	if !fes.adminJWTNonceCache.checkAndAdd(publicKey+":"+claims.ID, time.Now(), claims.ExpiresAt.Time) {
+		return false, fmt.Errorf("ValidateAdminJWT: Token has already been used")
+	}
+	return true, nil
+}
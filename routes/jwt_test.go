@@ -0,0 +1,118 @@
+package routes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJWT(t *testing.T) {
+	require := require.New(t)
+
+	user := newTestAdmin(t)
+	otherUser := newTestAdmin(t)
+	fes := &APIServer{JWTAudiences: []string{"node.example.com", "localhost:17001"}}
+	inAMinute := jwt.At(time.Now().Add(time.Minute))
+
+	requireValid := func(jwtToken string) {
+		isValid, err := fes.ValidateJWT(user.publicKeyBase58Check, jwtToken)
+		require.NoError(err)
+		require.True(isValid)
+	}
+	requireInvalid := func(jwtToken string) {
+		isValid, err := fes.ValidateJWT(user.publicKeyBase58Check, jwtToken)
+		require.Error(err)
+		require.False(isValid)
+	}
+
+	requireValid(user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: inAMinute,
+		Audience:  jwt.ClaimStrings{"localhost:17001"},
+	}))
+
+	// Tokens that don't parse are rejected rather than causing a panic.
+	requireInvalid("")
+	requireInvalid("garbage")
+	// Tokens signed by someone else.
+	requireInvalid(otherUser.signJWT(&jwt.StandardClaims{
+		ExpiresAt: inAMinute,
+		Audience:  jwt.ClaimStrings{"node.example.com"},
+	}))
+	// Tokens that don't expire, expire too late, or have expired.
+	requireInvalid(user.signJWT(&jwt.StandardClaims{
+		Audience: jwt.ClaimStrings{"node.example.com"},
+	}))
+	requireInvalid(user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: jwt.At(time.Now().Add(time.Hour)),
+		Audience:  jwt.ClaimStrings{"node.example.com"},
+	}))
+	requireInvalid(user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: jwt.At(time.Now().Add(-time.Minute)),
+		Audience:  jwt.ClaimStrings{"node.example.com"},
+	}))
+	// Tokens for another node, or for no node in particular.
+	requireInvalid(user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: inAMinute,
+		Audience:  jwt.ClaimStrings{"other.example.com"},
+	}))
+	requireInvalid(user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: inAMinute,
+	}))
+	// Tokens that use an algorithm other than ES256.
+	for _, method := range []jwt.SigningMethod{jwt.SigningMethodHS256, jwt.SigningMethodNone} {
+		var key interface{} = []byte(user.publicKeyBase58Check)
+		if method == jwt.SigningMethodNone {
+			key = jwt.UnsafeAllowNoneSignatureType
+		}
+		jwtToken, err := jwt.NewWithClaims(method, &jwt.StandardClaims{
+			ExpiresAt: inAMinute,
+			Audience:  jwt.ClaimStrings{"node.example.com"},
+		}).SignedString(key)
+		require.NoError(err)
+		requireInvalid(jwtToken)
+	}
+
+	// Without JWTAudiences, no token is accepted.
+	fes.JWTAudiences = nil
+	requireInvalid(user.signJWT(&jwt.StandardClaims{ExpiresAt: inAMinute}))
+	requireInvalid(user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: inAMinute,
+		Audience:  jwt.ClaimStrings{"node.example.com"},
+	}))
+	fes.JWTAudiences = []string{"node.example.com"}
+	// The maximum lifetime can be changed.
+	fes.JWTMaxLifetime = 2 * time.Hour
+	requireValid(user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: jwt.At(time.Now().Add(time.Hour)),
+		Audience:  jwt.ClaimStrings{"node.example.com"},
+	}))
+
+	// Admin tokens need a jti and can only be used once.
+	isValid, err := fes.ValidateAdminJWT(user.publicKeyBase58Check, user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: inAMinute,
+		Audience:  jwt.ClaimStrings{"node.example.com"},
+	}))
+	require.Error(err)
+	require.False(isValid)
+	adminJWT := user.signJWT(&jwt.StandardClaims{
+		ExpiresAt: inAMinute,
+		Audience:  jwt.ClaimStrings{"node.example.com"},
+		ID:        "1",
+	})
+	isValid, err = fes.ValidateAdminJWT(user.publicKeyBase58Check, adminJWT)
+	require.NoError(err)
+	require.True(isValid)
+	isValid, err = fes.ValidateAdminJWT(user.publicKeyBase58Check, adminJWT)
+	require.Error(err)
+	require.False(isValid)
+	// Other users may pick the same jti.
+	isValid, err = fes.ValidateAdminJWT(otherUser.publicKeyBase58Check, otherUser.signJWT(&jwt.StandardClaims{
+		ExpiresAt: inAMinute,
+		Audience:  jwt.ClaimStrings{"node.example.com"},
+		ID:        "1",
+	}))
+	require.NoError(err)
+	require.True(isValid)
+}
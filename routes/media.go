@@ -110,51 +110,51 @@ type UploadImageResponse struct {
 func (fes *APIServer) UploadImage(ww http.ResponseWriter, req *http.Request) {
 	err := req.ParseMultipartForm(10 << 20)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UploadImage: Problem parsing multipart form data: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("UploadImage: Problem parsing multipart form data: %v", err))
 		return
 	}
 
 	JWT := req.Form["JWT"]
 	userPublicKey := req.Form["UserPublicKeyBase58Check"]
 	if len(JWT) == 0 {
-		_AddBadRequestError(ww, fmt.Sprintf("No JWT provided"))
+		_AddCodedError(ww, ErrorCodeInvalidJWT, fmt.Sprintf("No JWT provided"))
 		return
 	}
 	if len(userPublicKey) == 0 {
-		_AddBadRequestError(ww, fmt.Sprintf("No public key provided"))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("No public key provided"))
 		return
 	}
 	isValid, err := fes.ValidateJWT(userPublicKey[0], JWT[0])
 	if !isValid {
-		_AddBadRequestError(ww, fmt.Sprintf("UploadImage: Invalid token: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidJWT, fmt.Sprintf("UploadImage: Invalid token: %v", err))
 		return
 	}
 
 	file, fileHeader, err := req.FormFile("file")
 	defer file.Close()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UploadImage: Problem getting file from form data: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("UploadImage: Problem getting file from form data: %v", err))
 		return
 	}
 	if fileHeader.Size > MaxRequestBodySizeBytes {
-		_AddBadRequestError(ww, fmt.Sprintf("File too large."))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("File too large."))
 		return
 	}
 	fileExtension, err := mapMimeTypeToExtension(fileHeader.Header.Get("Content-Type"))
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UploadImage: problem extracting file extension: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("UploadImage: problem extracting file extension: %v", err))
 		return
 	}
 	buf := bytes.NewBuffer(nil)
 	if _, err = io.Copy(buf, file); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UploadImage: problem copying file to buffer: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("UploadImage: problem copying file to buffer: %v", err))
 		return
 	}
 
 	encodedFileString := base64.StdEncoding.EncodeToString(buf.Bytes())
 	imageURL, err := fes.uploadSingleImage(encodedFileString, fileExtension)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UploadImage: problem uploading image: %v", err))
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf("UploadImage: problem uploading image: %v", err))
 		return
 	}
 
@@ -163,7 +163,7 @@ func (fes *APIServer) UploadImage(ww http.ResponseWriter, req *http.Request) {
 		ImageURL: imageURL,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UploadImage: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("UploadImage: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -242,14 +242,14 @@ func (fes *APIServer) GetFullTikTokURL(ww http.ResponseWriter, req *http.Request
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetFullTikTokURLRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 			"GetFullTikTokURL: Problem parsing request body: %v", err))
 		return
 	}
 	tiktokURL := fmt.Sprintf("https://vm.tiktok.com/%v", requestData.TikTokShortVideoID)
 	// Make sure the url matches the TikTok short URL regex.
 	if !lib.TikTokShortURLRegex.Match([]byte(tiktokURL)) {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"GetFullTikTokURL: TikTokShortVideoURL does not conform to regex: %v", tiktokURL))
 		return
 	}
@@ -257,25 +257,25 @@ func (fes *APIServer) GetFullTikTokURL(ww http.ResponseWriter, req *http.Request
 	client := &http.Client{}
 	req, err := http.NewRequest("GET", tiktokURL, nil)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetFullTikTokURL: creating GET request: %v", err))
 		return
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf(
 			"GetFullTikTokURL: error performing GET request: %v", err))
 		return
 	}
 	// If the response is not a 200 or 302, raise an error.
 	if resp.StatusCode != 200 && resp.StatusCode != 302 {
-		_AddBadRequestError(ww, fmt.Sprintf("GetFullTikTokURL: GET request did not return 200 or 302 status code but instead a status code of %v", resp.StatusCode))
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf("GetFullTikTokURL: GET request did not return 200 or 302 status code but instead a status code of %v", resp.StatusCode))
 		return
 	}
 	finalURL := resp.Request.URL
 	// If we didn't get the final destination URL, that is an error.
 	if finalURL == nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf(
 			"GetFullTikTokURL: response did not include redirected url"))
 		return
 	}
@@ -283,7 +283,7 @@ func (fes *APIServer) GetFullTikTokURL(ww http.ResponseWriter, req *http.Request
 	// Convert the final URL to a string and verify that it meets the full TikTok URL regex.
 	fullURL := finalURL.String()
 	if !lib.TikTokFullURLRegex.Match([]byte(fullURL)) {
-		_AddBadRequestError(ww, fmt.Sprintf("GetFullTikTokURL: destination url did not conform to tiktok full URL format: %v", fullURL))
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf("GetFullTikTokURL: destination url did not conform to tiktok full URL format: %v", fullURL))
 		return
 	}
 
@@ -291,7 +291,7 @@ func (fes *APIServer) GetFullTikTokURL(ww http.ResponseWriter, req *http.Request
 		FullTikTokURL: fullURL,
 	}
 	if err = json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("TikTokMobileCurl: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("TikTokMobileCurl: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
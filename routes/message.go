@@ -391,14 +391,14 @@ func (fes *APIServer) GetMessagesStateless(ww http.ResponseWriter, rr *http.Requ
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	getMessagesRequest := GetMessagesStatelessRequest{}
 	if err := decoder.Decode(&getMessagesRequest); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetMessagesStateless: Error parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetMessagesStateless: Error parsing request body: %v", err))
 		return
 	}
 
 	// Decode the public key into bytes.
 	publicKeyBytes, _, err := lib.Base58CheckDecode(getMessagesRequest.PublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetMessagesStateless: Problem decoding user public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetMessagesStateless: Problem decoding user public key: %v", err))
 		return
 	}
 
@@ -406,7 +406,7 @@ func (fes *APIServer) GetMessagesStateless(ww http.ResponseWriter, rr *http.Requ
 	if getMessagesRequest.FetchAfterPublicKeyBase58Check != "" {
 		fetchAfterPublicKeyBytes, _, err = lib.Base58CheckDecode(getMessagesRequest.FetchAfterPublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetMessagesStateless: Problem decoding fetch after public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetMessagesStateless: Problem decoding fetch after public key: %v", err))
 			return
 		}
 	}
@@ -416,7 +416,7 @@ func (fes *APIServer) GetMessagesStateless(ww http.ResponseWriter, rr *http.Requ
 		getMessagesRequest.NumToFetch, getMessagesRequest.HoldersOnly, getMessagesRequest.HoldingsOnly,
 		getMessagesRequest.FollowersOnly, getMessagesRequest.FollowingOnly, getMessagesRequest.SortAlgorithm)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetMessagesStateless: Problem fetching and decrypting messages: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetMessagesStateless: Problem fetching and decrypting messages: %v", err))
 		return
 	}
 
@@ -430,7 +430,7 @@ func (fes *APIServer) GetMessagesStateless(ww http.ResponseWriter, rr *http.Requ
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetMessages: Problem serializing object to JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetMessages: Problem serializing object to JSON: %v", err))
 		return
 	}
 }
@@ -460,14 +460,14 @@ func (fes *APIServer) SendMessageStateless(ww http.ResponseWriter, req *http.Req
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SendMessageStatelessRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendMessageStateless: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SendMessageStateless: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the sender public key.
 	senderPkBytes, _, err := lib.Base58CheckDecode(requestData.SenderPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendMessageStateless: Problem decoding sender "+
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("SendMessageStateless: Problem decoding sender "+
 			"base58 public key %s: %v", requestData.SenderPublicKeyBase58Check, err))
 		return
 	}
@@ -475,7 +475,7 @@ func (fes *APIServer) SendMessageStateless(ww http.ResponseWriter, req *http.Req
 	// Decode the recipient's public key.
 	recipientPkBytes, _, err := lib.Base58CheckDecode(requestData.RecipientPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendMessageStateless: Problem decoding recipient "+
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("SendMessageStateless: Problem decoding recipient "+
 			"base58 public key %s: %v", requestData.RecipientPublicKeyBase58Check, err))
 		return
 	}
@@ -487,13 +487,13 @@ func (fes *APIServer) SendMessageStateless(ww http.ResponseWriter, req *http.Req
 		tstamp,
 		requestData.MinFeeRateNanosPerKB, fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendMessageStateless: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("SendMessageStateless: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendBitClout: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -508,7 +508,7 @@ func (fes *APIServer) SendMessageStateless(ww http.ResponseWriter, req *http.Req
 		TransactionHex:    hex.EncodeToString(txnBytes),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendMessageStateless: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendMessageStateless: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -523,31 +523,31 @@ func (fes *APIServer) MarkContactMessagesRead(ww http.ResponseWriter, req *http.
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := MarkContactMessagesReadRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("MarkUserContactMessagesRead: Problem parsing request body: %v", err))
 		return
 	}
 
 	isValid, err := fes.ValidateJWT(requestData.UserPublicKeyBase58Check, requestData.JWT)
 	if !isValid {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Invalid token: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidJWT, fmt.Sprintf("MarkUserContactMessagesRead: Invalid token: %v", err))
 		return
 	}
 
 	userPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.UserPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem decoding user public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("MarkUserContactMessagesRead: Problem decoding user public key: %v", err))
 		return
 	}
 
 	contactPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.ContactPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem decoding contact public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("MarkUserContactMessagesRead: Problem decoding contact public key: %v", err))
 		return
 	}
 
 	err = fes.markContactMessagesRead(userPublicKeyBytes, contactPublicKeyBytes)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem marking thread as read: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem marking thread as read: %v", err))
 		return
 	}
 }
@@ -561,25 +561,25 @@ func (fes *APIServer) MarkAllMessagesRead(ww http.ResponseWriter, req *http.Requ
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := MarkAllMessagesReadRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("MarkUserContactMessagesRead: Problem parsing request body: %v", err))
 		return
 	}
 
 	isValid, err := fes.ValidateJWT(requestData.UserPublicKeyBase58Check, requestData.JWT)
 	if !isValid {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Invalid token: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidJWT, fmt.Sprintf("MarkUserContactMessagesRead: Invalid token: %v", err))
 		return
 	}
 
 	userPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.UserPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem decoding user public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("MarkUserContactMessagesRead: Problem decoding user public key: %v", err))
 		return
 	}
 
 	err = fes.markAllMessagesRead(userPublicKeyBytes)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem marking threads as read: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("MarkUserContactMessagesRead: Problem marking threads as read: %v", err))
 		return
 	}
 }
@@ -624,7 +624,14 @@ func (fes *APIServer) markContactMessagesRead(userPublicKeyBytes []byte, contact
 	tStampNanosBytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(tStampNanosBytes, tStampNanos)
 
-	err := fes.GlobalStatePut(dbKey, tStampNanosBytes)
+	// Only ever move the read time stamp forward. Without the compare-and-swap, a
+	// slow request could overwrite a newer time stamp written by a concurrent one.
+	err := fes.GlobalStateUpdate(dbKey, func(oldTstampNanosBytes []byte) ([]byte, error) {
+		if len(oldTstampNanosBytes) == 8 && binary.LittleEndian.Uint64(oldTstampNanosBytes) >= tStampNanos {
+			return oldTstampNanosBytes, nil
+		}
+		return tStampNanosBytes, nil
+	})
 	if err != nil {
 		return errors.Wrap(fmt.Errorf(
 			"putUserContactMostRecentReadTime: Problem putting updated tStampNanosBytes: %v", err), "")
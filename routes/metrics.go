@@ -0,0 +1,133 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The metrics exposed on RoutePathMetrics. Everything is registered with the
+// default Prometheus registry so that code without access to the APIServer, like
+// the GlobalStateClient, can record metrics too.
+var (
+	metricAPIRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bitclout_api_requests_total",
+		Help: "Requests served, by route and status code.",
+	}, []string{"route", "code"})
+	metricAPIRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bitclout_api_request_errors_total",
+		Help: "Requests that failed with a 4xx or 5xx status, by route.",
+	}, []string{"route"})
+	metricAPIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bitclout_api_request_duration_seconds",
+		Help:    "How long requests took to serve, by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route"})
+
+	metricTxindexLagBlocks = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bitclout_txindex_lag_blocks",
+		Help: "How many blocks the txindex is behind the block tip. Zero if --txindex isn't set.",
+	})
+	metricMempoolTxns = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bitclout_mempool_transactions",
+		Help: "Transactions in the mempool.",
+	})
+
+	metricGlobalStateRemoteCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bitclout_global_state_remote_call_duration_seconds",
+		Help:    "How long calls to the global state remote node took, including retries, by route path.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"path"})
+	metricGlobalStateRemoteCallFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bitclout_global_state_remote_call_failures_total",
+		Help: "Calls to the global state remote node that failed after all retries, by route path.",
+	}, []string{"path"})
+
+	metricStarterBitCloutSends = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bitclout_starter_bitclout_sends_total",
+		Help: "Attempts to send starter BitClout from the starter seed, by result.",
+	}, []string{"result"})
+	metricStarterBitCloutSentNanos = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "bitclout_starter_bitclout_sent_nanos_total",
+		Help: "Nanos of starter BitClout sent successfully.",
+	})
+	metricTwilioSends = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bitclout_twilio_sends_total",
+		Help: "Verification texts sent through Twilio, by result.",
+	}, []string{"result"})
+
+	metricBlockTemplateRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bitclout_block_template_requests_total",
+		Help: "Block template requests from miners, by result.",
+	}, []string{"result"})
+	metricBlockSubmissions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bitclout_block_submissions_total",
+		Help: "Blocks submitted by miners, by whether they were added to the main chain, " +
+			"a side chain, as orphans or rejected.",
+	}, []string{"result"})
+)
+
+// Values of the result label.
+const (
+	metricResultSuccess = "success"
+	metricResultFailure = "failure"
+)
+
+func metricResult(err error) string {
+	if err != nil {
+		return metricResultFailure
+	}
+	return metricResultSuccess
+}
+
+// metricsHandler is created once since promhttp.Handler registers metrics about
+// itself.
+var metricsHandler = promhttp.Handler()
+
+// InstrumentRoute wraps a route's handler so that its requests are counted and
+// timed.
+func InstrumentRoute(inner http.Handler, routeName string) http.Handler {
+	requestDuration := metricAPIRequestDuration.WithLabelValues(routeName)
+	requestErrors := metricAPIRequestErrors.WithLabelValues(routeName)
+
+	return http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		recorder := &statusRecordingResponseWriter{ResponseWriter: ww}
+		inner.ServeHTTP(recorder, req)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		requestDuration.Observe(time.Since(start).Seconds())
+		metricAPIRequests.WithLabelValues(routeName, strconv.Itoa(status)).Inc()
+		if status >= 400 {
+			requestErrors.Inc()
+		}
+	})
+}
+
+// updateMetricGauges sets the gauges that are cheaper to compute when scraped
+// than to keep up to date.
+func (fes *APIServer) updateMetricGauges() {
+	if fes.mempool != nil {
+		metricMempoolTxns.Set(float64(fes.mempool.Count()))
+	}
+
+	txindexLag := 0.0
+	if fes.blockchain != nil && fes.TxIndexChain != nil {
+		txindexLag = float64(fes.blockchain.BlockTip().Height) - float64(fes.TxIndexChain.BlockTip().Height)
+	}
+	metricTxindexLagBlocks.Set(txindexLag)
+}
+
+// Metrics serves metrics in the Prometheus text format.
+func (fes *APIServer) Metrics(ww http.ResponseWriter, req *http.Request) {
+	fes.updateMetricGauges()
+	metricsHandler.ServeHTTP(ww, req)
+}
@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	require := require.New(t)
+
+	handler := InstrumentRoute(http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("fail") != "" {
+			_AddCodedError(ww, ErrorCodeBadRequest, "failed")
+		}
+	}), "TestMetricsRoute")
+	for _, url := range []string{"/", "/", "/?fail=1"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+
+	// Calls to a remote node that isn't there count as failures.
+	client := NewGlobalStateClient("http://127.0.0.1:1", "secret")
+	client.MaxAttempts = 1
+	require.Error(client.Call("/test-metrics-path", struct{}{}, nil, 0, true))
+
+	fes := &APIServer{}
+	response := httptest.NewRecorder()
+	fes.Metrics(response, httptest.NewRequest("GET", RoutePathMetrics, nil))
+	require.Equal(http.StatusOK, response.Code)
+
+	body := response.Body.String()
+	require.Contains(body, `bitclout_api_requests_total{code="200",route="TestMetricsRoute"} 2`)
+	require.Contains(body, `bitclout_api_requests_total{code="400",route="TestMetricsRoute"} 1`)
+	require.Contains(body, `bitclout_api_request_errors_total{route="TestMetricsRoute"} 1`)
+	require.Contains(body, `bitclout_api_request_duration_seconds_count{route="TestMetricsRoute"} 3`)
+	require.Contains(body, `bitclout_global_state_remote_call_failures_total{path="/test-metrics-path"} 1`)
+	require.Contains(body, `bitclout_txindex_lag_blocks 0`)
+}
@@ -56,7 +56,7 @@ type GetBlockTemplateResponse struct {
 func (fes *APIServer) GetBlockTemplate(ww http.ResponseWriter, req *http.Request) {
 
 	if fes.blockProducer == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetBlockTemplate: This node is not running a block producer. "+
+		_AddCodedError(ww, ErrorCodeFeatureDisabled, fmt.Sprintf("GetBlockTemplate: This node is not running a block producer. "+
 				"Restart it with --max_block_templates_to_cache > 0"))
 		return
 	}
@@ -64,13 +64,13 @@ func (fes *APIServer) GetBlockTemplate(ww http.ResponseWriter, req *http.Request
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetBlockTemplateRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetBlockTemplate: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetBlockTemplate: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Reject requests for v0 headers to phase them out.
 	if requestData.HeaderVersion == lib.HeaderVersion0 {
-		_AddBadRequestError(ww, fmt.Sprintf("GetBlockTemplate: Error: Header version v0 not supported. " +
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("GetBlockTemplate: Error: Header version v0 not supported. " +
 				"Please upgrade your miner to request v1 headers, and to hash " +
 				"with CloutHashV1"))
 		return
@@ -79,14 +79,15 @@ func (fes *APIServer) GetBlockTemplate(ww http.ResponseWriter, req *http.Request
 	// Decode the public key
 	pkBytes, _, err := lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetBlockTemplate: Problem parsing public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetBlockTemplate: Problem parsing public key: %v", err))
 		return
 	}
 
 	blockID, headers, extraDatas, diffTarget, err := fes.blockProducer.GetHeadersAndExtraDatas(
 		pkBytes, requestData.NumHeaders, requestData.HeaderVersion)
+	metricBlockTemplateRequests.WithLabelValues(metricResult(err)).Inc()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetBlockTemplate: Problem generating headers: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetBlockTemplate: Problem generating headers: %v", err))
 		return
 	}
 
@@ -99,7 +100,7 @@ func (fes *APIServer) GetBlockTemplate(ww http.ResponseWriter, req *http.Request
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetBlockTemplate: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetBlockTemplate: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -125,7 +126,7 @@ type SubmitBlockResponse struct {
 func (fes *APIServer) SubmitBlock(ww http.ResponseWriter, req *http.Request) {
 
 	if fes.blockProducer == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitBlock: This node is not running a block producer. "+
+		_AddCodedError(ww, ErrorCodeFeatureDisabled, fmt.Sprintf("SubmitBlock: This node is not running a block producer. "+
 				"Restart it with --max_block_templates_to_cache > 0"))
 		return
 	}
@@ -133,21 +134,21 @@ func (fes *APIServer) SubmitBlock(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SubmitBlockRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitBlock: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SubmitBlock: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the public key
 	pkBytes, _, err := lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitBlock: Problem parsing public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("SubmitBlock: Problem parsing public key: %v", err))
 		return
 	}
 
 	// Look up the block for the corresponding BlockID
 	blockFound, err := fes.blockProducer.GetCopyOfRecentBlock(requestData.BlockID)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitBlock: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SubmitBlock: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -157,14 +158,14 @@ func (fes *APIServer) SubmitBlock(ww http.ResponseWriter, req *http.Request) {
 
 	header := &lib.MsgBitCloutHeader{}
 	if err := header.FromBytes(requestData.Header); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitBlock: Problem parsing header: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SubmitBlock: Problem parsing header: %v", err))
 		return
 	}
 	blockFound.Header = header
 
 	// This will sign the block with the BlockProducer's key, if a key was set
 	if err := fes.blockProducer.SignBlock(blockFound); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("Error signing block: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("Error signing block: %v", err))
 		return
 	}
 
@@ -178,13 +179,24 @@ func (fes *APIServer) SubmitBlock(ww http.ResponseWriter, req *http.Request) {
 		blockFound, true /*verifySignatures*/)
 	glog.Debugf("Called ProcessBlock: isMainChain=(%v), isOrphan=(%v), err=(%v)",
 		isMainChain, isOrphan, err)
+	switch {
+	case err != nil:
+		metricBlockSubmissions.WithLabelValues("rejected").Inc()
+	case isMainChain:
+		metricBlockSubmissions.WithLabelValues("main_chain").Inc()
+	case isOrphan:
+		metricBlockSubmissions.WithLabelValues("orphan").Inc()
+	default:
+		metricBlockSubmissions.WithLabelValues("side_chain").Inc()
+	}
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("ERROR calling ProcessBlock: isMainChain=(%v), isOrphan=(%v), err=(%v)",
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("ERROR calling ProcessBlock: isMainChain=(%v), isOrphan=(%v), err=(%v)",
 				isMainChain, isOrphan, err))
 		return
 	}
 
 	// If we connected a block on the main chain, force an update in the BlockProducer
+	// and the txindex.
 	if isMainChain {
 		err = fes.blockProducer.UpdateLatestBlockTemplate()
 		if err != nil {
@@ -192,6 +204,7 @@ func (fes *APIServer) SubmitBlock(ww http.ResponseWriter, req *http.Request) {
 			// being in the middle of processing a block or something.
 			glog.Errorf("Error producing block template: %v", err)
 		}
+		fes.NotifyBlockChainUpdated()
 	}
 
 	// TODO: It would probably be nice if we could return some new headers to the miner
@@ -203,7 +216,7 @@ func (fes *APIServer) SubmitBlock(ww http.ResponseWriter, req *http.Request) {
 		IsOrphan:    isOrphan,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitBlock: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SubmitBlock: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -0,0 +1,379 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenAPISpec is an OpenAPI 3 document describing the routes the server serves.
+// Only the parts of the format that BuildOpenAPISpec uses are modeled.
+//
+// The spec is checked in as openapi.json so that changes to a route's request
+// or response types show up in review. Run the tests with -update-openapi-spec
+// to regenerate it after changing a route.
+type OpenAPISpec struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Tags       []OpenAPITag                            `json:"tags"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type OpenAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Tags        []string                    `json:"tags"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+	// Set on routes that are wrapped with CheckAdminPublicKey, which expect an
+	// AdminPublicKey in the request body.
+	RequiresAdmin bool `json:"x-requires-admin,omitempty"`
+	// The role an admin needs to call the route, see AdminRole.
+	RequiredAdminRole AdminRole `json:"x-required-admin-role,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                       `json:"description"`
+	Content     map[string]*OpenAPIMediaType `json:"content,omitempty"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	// Types from other packages, like lib.PostEntry, aren't described field by
+	// field. Their Go type is recorded instead.
+	GoType string `json:"x-go-type,omitempty"`
+}
+
+type OpenAPIComponents struct {
+	Schemas map[string]*OpenAPISchema `json:"schemas"`
+}
+
+const (
+	openAPIVersion       = "3.0.3"
+	openAPIContentJSON   = "application/json"
+	openAPIErrorResponse = "ErrorResponse"
+)
+
+// Route groups, used as the spec's tags.
+const (
+	OpenAPITagFrontend    = "Frontend"
+	OpenAPITagAPI         = "API"
+	OpenAPITagGlobalState = "GlobalState"
+)
+
+// openAPIPathVariableRegex matches the variables in a mux route pattern, e.g.
+// {blockHashHexOrblockHeight:[0-9abcdefABCDEF]+}.
+var openAPIPathVariableRegex = regexp.MustCompile(`{([^:}]+)(:[^}]+)?}`)
+
+var (
+	routesPkgPath = reflect.TypeOf(Route{}).PkgPath()
+	timeType      = reflect.TypeOf(time.Time{})
+	byteSliceType = reflect.TypeOf([]byte{})
+)
+
+// openAPISchemaBuilder turns Go types into schemas, adding a component for each
+// struct from this package that it comes across.
+type openAPISchemaBuilder struct {
+	components map[string]*OpenAPISchema
+}
+
+func (builder *openAPISchemaBuilder) schemaForType(tt reflect.Type) *OpenAPISchema {
+	for tt.Kind() == reflect.Ptr {
+		tt = tt.Elem()
+	}
+
+	if tt == timeType {
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+	if tt.PkgPath() != "" && tt.PkgPath() != routesPkgPath {
+		return &OpenAPISchema{GoType: tt.String()}
+	}
+	if tt == byteSliceType || (tt.Kind() == reflect.Slice && tt.Elem().Kind() == reflect.Uint8) {
+		return &OpenAPISchema{Type: "string", Format: "byte"}
+	}
+
+	switch tt.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: tt.Kind().String()}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &OpenAPISchema{Type: "array", Items: builder.schemaForType(tt.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: builder.schemaForType(tt.Elem())}
+	case reflect.Struct:
+		if tt.Name() == "" {
+			return builder.structSchema(tt)
+		}
+		if _, exists := builder.components[tt.Name()]; !exists {
+			// Add the component before filling it in so recursive types refer to
+			// themselves rather than looping forever.
+			builder.components[tt.Name()] = &OpenAPISchema{}
+			*builder.components[tt.Name()] = *builder.structSchema(tt)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + tt.Name()}
+	}
+
+	// Interfaces and anything else can hold any value.
+	return &OpenAPISchema{}
+}
+
+func (builder *openAPISchemaBuilder) structSchema(tt reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	builder.addStructFields(tt, schema.Properties)
+	return schema
+}
+
+// addStructFields adds the fields of tt the way encoding/json would encode them,
+// including the fields of embedded structs.
+func (builder *openAPISchemaBuilder) addStructFields(tt reflect.Type, properties map[string]*OpenAPISchema) {
+	for ii := 0; ii < tt.NumField(); ii++ {
+		field := tt.Field(ii)
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		tagParts := strings.Split(field.Tag.Get("json"), ",")
+		if tagParts[0] == "-" && len(tagParts) == 1 {
+			continue
+		}
+		name := tagParts[0]
+
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			builder.addStructFields(fieldType, properties)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := builder.schemaForType(field.Type)
+		for _, option := range tagParts[1:] {
+			if option == "string" {
+				schema = &OpenAPISchema{Type: "string"}
+			}
+		}
+		properties[name] = schema
+	}
+}
+
+// openAPIPath converts a mux route pattern to an OpenAPI path, returning the
+// names of its variables.
+func openAPIPath(pattern string) (_path string, _variables []string) {
+	variables := []string{}
+	for _, match := range openAPIPathVariableRegex.FindAllStringSubmatch(pattern, -1) {
+		variables = append(variables, match[1])
+	}
+	return openAPIPathVariableRegex.ReplaceAllString(pattern, "{$1}"), variables
+}
+
+// uploadImageFormSchema describes the multipart form UploadImage takes instead of
+// a JSON body.
+var uploadImageFormSchema = &OpenAPISchema{
+	Type: "object",
+	Properties: map[string]*OpenAPISchema{
+		"file":                     {Type: "string", Format: "binary"},
+		"UserPublicKeyBase58Check": {Type: "string"},
+		"JWT":                      {Type: "string"},
+	},
+}
+
+// BuildOpenAPISpec describes the routes in routesByTag, using routeRequestTypes
+// and routeResponseTypes for their bodies. OPTIONS methods, which only exist for
+// CORS preflight requests, are left out.
+func BuildOpenAPISpec(routesByTag map[string][]Route) (*OpenAPISpec, error) {
+	builder := &openAPISchemaBuilder{components: make(map[string]*OpenAPISchema)}
+	errorCodes := []string{}
+	for code := range errorCodeStatuses {
+		errorCodes = append(errorCodes, string(code))
+	}
+	sort.Strings(errorCodes)
+	builder.components[openAPIErrorResponse] = &OpenAPISchema{
+		Type: "object",
+		Properties: map[string]*OpenAPISchema{
+			"error":   {Type: "string"},
+			"code":    {Type: "string", Enum: errorCodes},
+			"details": {Type: "object", AdditionalProperties: &OpenAPISchema{}},
+		},
+	}
+
+	spec := &OpenAPISpec{
+		OpenAPI: openAPIVersion,
+		Info: OpenAPIInfo{
+			Title: "BitClout backend",
+			Description: "Generated from the route table and the request and response types of each " +
+				"route. Routes under /api/v0 are also served without the /api/v0 prefix.",
+			Version: "v0",
+		},
+		Tags: []OpenAPITag{
+			{OpenAPITagFrontend, "Routes used by the frontend."},
+			{OpenAPITagAPI, "The exchange API. Errors are returned with Error, Code " +
+				"and Details fields rather than error, code and details."},
+			{OpenAPITagGlobalState, "Routes the global state owner serves to replicas. " +
+				"Requests must be signed with the shared secret, see CheckGlobalStateSignature."},
+		},
+		Paths: make(map[string]map[string]*OpenAPIOperation),
+	}
+
+	tags := []string{}
+	for tag := range routesByTag {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	for _, tag := range tags {
+		for _, route := range routesByTag[tag] {
+			path, variables := openAPIPath(route.Pattern)
+			if spec.Paths[path] == nil {
+				spec.Paths[path] = make(map[string]*OpenAPIOperation)
+			}
+
+			for _, method := range route.Method {
+				if method == "OPTIONS" {
+					continue
+				}
+				method = strings.ToLower(method)
+				if _, exists := spec.Paths[path][method]; exists {
+					return nil, fmt.Errorf("BuildOpenAPISpec: Route %v has the same path and method "+
+						"as another route: %v %v", route.Name, method, path)
+				}
+
+				operation := &OpenAPIOperation{
+					OperationID: route.Name,
+					Tags:        []string{tag},
+					Responses: map[string]*OpenAPIResponse{
+						"200": {Description: "Success"},
+						"default": {
+							Description: "Error",
+							Content: map[string]*OpenAPIMediaType{
+								openAPIContentJSON: {Schema: &OpenAPISchema{
+									Ref: "#/components/schemas/" + openAPIErrorResponse}},
+							},
+						},
+					},
+					RequiresAdmin:     route.RequiredRole != AdminRoleNone,
+					RequiredAdminRole: route.RequiredRole,
+				}
+
+				for _, variable := range variables {
+					operation.Parameters = append(operation.Parameters, &OpenAPIParameter{
+						Name:     variable,
+						In:       "path",
+						Required: true,
+						Schema:   &OpenAPISchema{Type: "string"},
+					})
+				}
+
+				if route.Name == "UploadImage" {
+					operation.RequestBody = &OpenAPIRequestBody{
+						Required: true,
+						Content: map[string]*OpenAPIMediaType{
+							"multipart/form-data": {Schema: uploadImageFormSchema},
+						},
+					}
+				} else if requestType, exists := routeRequestTypes[route.Name]; exists {
+					operation.RequestBody = &OpenAPIRequestBody{
+						Required: true,
+						Content: map[string]*OpenAPIMediaType{
+							openAPIContentJSON: {Schema: builder.schemaForType(requestType)},
+						},
+					}
+				}
+				if responseType, exists := routeResponseTypes[route.Name]; exists {
+					operation.Responses["200"].Content = map[string]*OpenAPIMediaType{
+						openAPIContentJSON: {Schema: builder.schemaForType(responseType)},
+					}
+				}
+
+				spec.Paths[path][method] = operation
+			}
+		}
+	}
+
+	spec.Components.Schemas = builder.components
+	return spec, nil
+}
+
+// OpenAPISpec builds the spec for every route the server serves.
+func (fes *APIServer) OpenAPISpec() (*OpenAPISpec, error) {
+	return BuildOpenAPISpec(map[string][]Route{
+		OpenAPITagFrontend:    fes.FrontendRoutes(),
+		OpenAPITagAPI:         fes.APIRoutes(),
+		OpenAPITagGlobalState: fes.GlobalStateRoutes(),
+	})
+}
+
+// MarshalOpenAPISpec encodes the spec the way it's checked in.
+func MarshalOpenAPISpec(spec *OpenAPISpec) ([]byte, error) {
+	specBytes, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("MarshalOpenAPISpec: Problem encoding spec: %v", err)
+	}
+	return append(specBytes, '\n'), nil
+}
+
+// GetOpenAPISpec serves the OpenAPI spec for this node's routes.
+func (fes *APIServer) GetOpenAPISpec(ww http.ResponseWriter, req *http.Request) {
+	spec, err := fes.OpenAPISpec()
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("GetOpenAPISpec: %v", err))
+		return
+	}
+	specBytes, err := MarshalOpenAPISpec(spec)
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("GetOpenAPISpec: %v", err))
+		return
+	}
+	ww.Header().Set("Content-Type", openAPIContentJSON)
+	ww.Write(specBytes)
+}
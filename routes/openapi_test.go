@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitclout/core/lib"
+	"github.com/stretchr/testify/require"
+)
+
+var updateOpenAPISpec = flag.Bool("update-openapi-spec", false,
+	"Regenerate openapi.json from the route table")
+
+const openAPISpecPath = "openapi.json"
+
+func TestOpenAPISpec(t *testing.T) {
+	require := require.New(t)
+
+	fes := &APIServer{Params: &lib.BitCloutTestnetParams}
+	spec, err := fes.OpenAPISpec()
+	require.NoError(err)
+	specBytes, err := MarshalOpenAPISpec(spec)
+	require.NoError(err)
+
+	if *updateOpenAPISpec {
+		require.NoError(ioutil.WriteFile(openAPISpecPath, specBytes, 0644))
+	}
+	checkedInBytes, err := ioutil.ReadFile(openAPISpecPath)
+	require.NoError(err)
+	require.Equal(string(checkedInBytes), string(specBytes),
+		"A route or its types changed. Run go test ./routes -run TestOpenAPISpec -update-openapi-spec "+
+			"to regenerate %v, and make sure the frontend is updated to match.", openAPISpecPath)
+
+	// Every route is described, under the path the router serves it on.
+	routeNames := make(map[string]bool)
+	for _, route := range fes.AllRoutes() {
+		routeNames[route.Name] = true
+		path, _ := openAPIPath(route.Pattern)
+		require.Contains(spec.Paths, path)
+		for _, method := range route.Method {
+			if method == "OPTIONS" {
+				continue
+			}
+			require.Equal(route.Name, spec.Paths[path][strings.ToLower(method)].OperationID)
+		}
+	}
+	for routeName := range routeResponseTypes {
+		require.True(routeNames[routeName], "routeResponseTypes has an unknown route %v", routeName)
+	}
+
+	// Spot check a few routes.
+	getPosts := spec.Paths[RoutePathGetPostsStateless]["post"]
+	require.Equal([]string{OpenAPITagFrontend}, getPosts.Tags)
+	require.Equal("#/components/schemas/GetPostsStatelessRequest",
+		getPosts.RequestBody.Content[openAPIContentJSON].Schema.Ref)
+	require.Equal("#/components/schemas/GetPostsStatelessResponse",
+		getPosts.Responses["200"].Content[openAPIContentJSON].Schema.Ref)
+	require.Equal(&OpenAPISchema{Type: "string"},
+		spec.Components.Schemas["GetPostsStatelessRequest"].Properties["ReaderPublicKeyBase58Check"])
+	require.False(getPosts.RequiresAdmin)
+
+	require.True(spec.Paths[RoutePathAdminPinPost]["post"].RequiresAdmin)
+	require.Equal(AdminRoleFeedCurator, spec.Paths[RoutePathAdminPinPost]["post"].RequiredAdminRole)
+
+	require.True(spec.Paths[RoutePathReprocessBitcoinBlock]["post"].RequiresAdmin)
+	require.Equal(AdminRoleNodeOperator, spec.Paths[RoutePathReprocessBitcoinBlock]["post"].RequiredAdminRole)
+
+	// The handler serves the same spec.
+	response := httptest.NewRecorder()
+	fes.GetOpenAPISpec(response, httptest.NewRequest("GET", RoutePathGetOpenAPISpec, nil))
+	require.Equal(http.StatusOK, response.Code)
+	require.Equal(string(specBytes), response.Body.String())
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	require := require.New(t)
+
+	type inner struct {
+		Embedded string
+	}
+	type recursive struct {
+		inner
+		Renamed    uint64 `json:"renamed,omitempty"`
+		AsString   int64  `json:",string"`
+		Skipped    string `json:"-"`
+		unexported string
+		Bytes      []byte
+		Children   []*recursive
+		ByName     map[string]bool
+		Post       *lib.PostEntry
+	}
+
+	builder := &openAPISchemaBuilder{components: make(map[string]*OpenAPISchema)}
+	require.Equal(&OpenAPISchema{Ref: "#/components/schemas/recursive"},
+		builder.schemaForType(reflect.TypeOf(&recursive{})))
+	require.Equal(map[string]*OpenAPISchema{
+		"Embedded": {Type: "string"},
+		"renamed":  {Type: "integer", Format: "uint64"},
+		"AsString": {Type: "string"},
+		"Bytes":    {Type: "string", Format: "byte"},
+		"Children": {Type: "array", Items: &OpenAPISchema{Ref: "#/components/schemas/recursive"}},
+		"ByName":   {Type: "object", AdditionalProperties: &OpenAPISchema{Type: "boolean"}},
+		"Post":     {GoType: "lib.PostEntry"},
+	}, builder.components["recursive"].Properties)
+	require.NotContains(builder.components, "inner")
+}
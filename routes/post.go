@@ -1,8 +1,6 @@
 package routes
 
 import (
-	"bytes"
-	"encoding/gob"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -529,6 +527,70 @@ func (fes *APIServer) GetPostEntriesForGlobalWhitelist(
 	_profilesByPublicKey map[lib.PkMapKey]*lib.ProfileEntry,
 	_postEntryReaderStates map[lib.BlockHash]*lib.PostEntryReaderState, err error) {
 
+	// We add the readers posts later so we skip them here to avoid duplicates.
+	postEntries, err := fes.getGlobalWhitelistPostEntries(startPostHash, readerPK, numToFetch, utxoView)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// If we don't have any postEntries at this point, bail.
+	profileEntries := make(map[lib.PkMapKey]*lib.ProfileEntry)
+	postEntryReaderStates := make(map[lib.BlockHash]*lib.PostEntryReaderState)
+
+	// Now that we have the whitelist posts, we need to insert the user's posts.
+	if readerPK != nil {
+		minTimestampNanos, maxTimestampNanos := globalWhitelistTimestampRange(startPostHash, postEntries, utxoView)
+		readerPostEntries, err := fes.getReaderPostEntriesForGlobalWhitelist(
+			readerPK, minTimestampNanos, maxTimestampNanos, utxoView)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		postEntries = append(postEntries, readerPostEntries...)
+	}
+	// Sort the postEntries by time.
+	sort.Slice(postEntries, func(ii, jj int) bool {
+		return postEntries[ii].TimestampNanos > postEntries[jj].TimestampNanos
+	})
+
+	// Only add pinned posts if we are starting from the top of the feed.
+	if startPostHash == nil {
+		pinnedPostEntries, err := fes.getPinnedPostEntries(utxoView)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		postEntries = append(pinnedPostEntries, postEntries...)
+	}
+
+	if len(postEntries) == 0 {
+		return postEntries, profileEntries, postEntryReaderStates, nil
+	}
+
+	for _, postEntry := range postEntries {
+		{
+			profileEntry := utxoView.GetProfileEntryForPublicKey(postEntry.PosterPublicKey)
+			if profileEntry != nil {
+				profileEntries[lib.MakePkMapKey(profileEntry.PublicKey)] = profileEntry
+			}
+		}
+	}
+	// Create reader state map. Ie, whether the reader has liked the post, etc.
+	// If nil is passed in as the readerPK, this is skipped.
+	if readerPK != nil {
+		for _, postEntry := range postEntries {
+			postEntryReaderState := utxoView.GetPostEntryReaderState(readerPK, postEntry)
+			postEntryReaderStates[*postEntry.PostHash] = postEntryReaderState
+		}
+	}
+
+	return postEntries, profileEntries, postEntryReaderStates, nil
+}
+
+// getGlobalWhitelistPostEntries returns up to numToFetch posts from the global feed,
+// newest first, starting after startPostHash. Posts by skipPublicKey are left out.
+func (fes *APIServer) getGlobalWhitelistPostEntries(
+	startPostHash *lib.BlockHash, skipPublicKey []byte, numToFetch int, utxoView *lib.UtxoView) (
+	_postEntries []*lib.PostEntry, _err error) {
+
 	var startPost *lib.PostEntry
 	if startPostHash != nil {
 		startPost = utxoView.GetPostEntryForPostHash(startPostHash)
@@ -560,7 +622,7 @@ func (fes *APIServer) GetPostEntriesForGlobalWhitelist(
 			maxKeyLen /*maxKeyLen -- ignored since reverse is false*/, numToFetch-len(postEntries), true, /*reverse*/
 			false /*fetchValues*/)
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("GetPostEntriesForGlobalWhitelist: Getting posts for reader: %v", err)
+			return nil, fmt.Errorf("GetPostEntriesForGlobalWhitelist: Getting posts for reader: %v", err)
 		}
 		// If there are no keys left, then there are no more postEntries to get so we exit the loop.
 		if len(keys) == 0 || (len(keys) == 1 && skipFirstEntry) {
@@ -581,8 +643,7 @@ func (fes *APIServer) GetPostEntriesForGlobalWhitelist(
 			// Get the postEntry from the utxoView.
 			postEntry := utxoView.GetPostEntryForPostHash(postHash)
 
-			if readerPK != nil && postEntry != nil && reflect.DeepEqual(postEntry.PosterPublicKey, readerPK) {
-				// We add the readers posts later so we can skip them here to avoid duplicates.
+			if skipPublicKey != nil && postEntry != nil && reflect.DeepEqual(postEntry.PosterPublicKey, skipPublicKey) {
 				continue
 			}
 
@@ -595,101 +656,89 @@ func (fes *APIServer) GetPostEntriesForGlobalWhitelist(
 		skipFirstEntry = true
 	}
 
-	// If we don't have any postEntries at this point, bail.
-	profileEntries := make(map[lib.PkMapKey]*lib.ProfileEntry)
-	postEntryReaderStates := make(map[lib.BlockHash]*lib.PostEntryReaderState)
+	return postEntries, nil
+}
 
-	// Now that we have the whitelist posts, we need to insert the user's posts.
-	if readerPK != nil {
-		maxTimestampNanos := uint64(time.Now().UTC().UnixNano()) // current tstamp
-		if startPost != nil {
-			maxTimestampNanos = startPost.TimestampNanos
-		}
-		var minTimestampNanos uint64
-		if len(postEntries) == 0 {
-			minTimestampNanos = 0
-		} else {
-			minTimestampNanos = postEntries[len(postEntries)-1].TimestampNanos
-		}
+// globalWhitelistTimestampRange returns the range of timestamps covered by a page
+// of the global feed that starts after startPostHash and ends with the last of
+// postEntries. The reader's own posts in this range are added to the page.
+func globalWhitelistTimestampRange(
+	startPostHash *lib.BlockHash, postEntries []*lib.PostEntry, utxoView *lib.UtxoView) (
+	_minTimestampNanos uint64, _maxTimestampNanos uint64) {
 
-		_, dbPostAndCommentHashes, _, err := lib.DBGetAllPostsAndCommentsForPublicKeyOrderedByTimestamp(
-			utxoView.Handle, readerPK, false /*fetchEntries*/, minTimestampNanos, maxTimestampNanos,
-		)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("GetPostEntriesForGlobalWhitelist: Getting posts for reader: %v", err)
+	maxTimestampNanos := uint64(time.Now().UTC().UnixNano()) // current tstamp
+	if startPostHash != nil {
+		if startPost := utxoView.GetPostEntryForPostHash(startPostHash); startPost != nil {
+			maxTimestampNanos = startPost.TimestampNanos
 		}
+	}
+	var minTimestampNanos uint64
+	if len(postEntries) == 0 {
+		minTimestampNanos = 0
+	} else {
+		minTimestampNanos = postEntries[len(postEntries)-1].TimestampNanos
+	}
+	return minTimestampNanos, maxTimestampNanos
+}
 
-		// Load all the relevant post hashes into the view.
-		for _, dbPostOrCommentHash := range dbPostAndCommentHashes {
-			utxoView.GetPostEntryForPostHash(dbPostOrCommentHash)
-		}
+// getReaderPostEntriesForGlobalWhitelist returns the reader's top-level posts made
+// after minTimestampNanos and at or before maxTimestampNanos.
+func (fes *APIServer) getReaderPostEntriesForGlobalWhitelist(
+	readerPK []byte, minTimestampNanos uint64, maxTimestampNanos uint64, utxoView *lib.UtxoView) (
+	_postEntries []*lib.PostEntry, _err error) {
 
-		// Loop through all the posts in the view and add those that are relevant.
-		for _, postEntry := range utxoView.PostHashToPostEntry {
-			// Skip deleted / hidden posts and any comments.
-			if postEntry.IsDeleted() || postEntry.IsHidden || len(postEntry.ParentStakeID) != 0 {
-				continue
-			}
+	_, dbPostAndCommentHashes, _, err := lib.DBGetAllPostsAndCommentsForPublicKeyOrderedByTimestamp(
+		utxoView.Handle, readerPK, false /*fetchEntries*/, minTimestampNanos, maxTimestampNanos,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("GetPostEntriesForGlobalWhitelist: Getting posts for reader: %v", err)
+	}
 
-			if postEntry.TimestampNanos <= maxTimestampNanos && postEntry.TimestampNanos > minTimestampNanos {
-				if reflect.DeepEqual(postEntry.PosterPublicKey, readerPK) {
-					postEntries = append(postEntries, postEntry)
-				}
-			}
-		}
+	// Load all the relevant post hashes into the view.
+	for _, dbPostOrCommentHash := range dbPostAndCommentHashes {
+		utxoView.GetPostEntryForPostHash(dbPostOrCommentHash)
 	}
-	// Sort the postEntries by time.
-	sort.Slice(postEntries, func(ii, jj int) bool {
-		return postEntries[ii].TimestampNanos > postEntries[jj].TimestampNanos
-	})
 
-	{
-		// Only add pinned posts if we are starting from the top of the feed.
-		if startPostHash == nil {
-			// Get all pinned posts and prepend them to the list of postEntries
-			pinnedStartKey := _GlobalStatePrefixTstampNanosPinnedPostHash
-			// todo: how many posts can we really pin?
-			keys, _, err := fes.GlobalStateSeek(pinnedStartKey, pinnedStartKey, maxKeyLen, 10, true, false)
-			if err != nil {
-				return nil, nil, nil, fmt.Errorf("GetPostEntriesForWhitelist: Getting pinned posts: %v", err)
-			}
+	// Loop through all the posts in the view and add those that are relevant.
+	var postEntries []*lib.PostEntry
+	for _, postEntry := range utxoView.PostHashToPostEntry {
+		// Skip deleted / hidden posts and any comments.
+		if postEntry.IsDeleted() || postEntry.IsHidden || len(postEntry.ParentStakeID) != 0 {
+			continue
+		}
 
-			var pinnedPostEntries []*lib.PostEntry
-			for _, dbKeyBytes := range keys {
-				postHash := &lib.BlockHash{}
-				copy(postHash[:], dbKeyBytes[1+len(maxBigEndianUint64Bytes):][:])
-				postEntry := utxoView.GetPostEntryForPostHash(postHash)
-				if postEntry != nil {
-					postEntry.IsPinned = true
-					pinnedPostEntries = append(pinnedPostEntries, postEntry)
-				}
+		if postEntry.TimestampNanos <= maxTimestampNanos && postEntry.TimestampNanos > minTimestampNanos {
+			if reflect.DeepEqual(postEntry.PosterPublicKey, readerPK) {
+				postEntries = append(postEntries, postEntry)
 			}
-			postEntries = append(pinnedPostEntries, postEntries...)
 		}
 	}
+	return postEntries, nil
+}
 
-	if len(postEntries) == 0 {
-		return postEntries, profileEntries, postEntryReaderStates, nil
+// getPinnedPostEntries returns the posts pinned to the top of the global feed.
+func (fes *APIServer) getPinnedPostEntries(utxoView *lib.UtxoView) (_postEntries []*lib.PostEntry, _err error) {
+	// Get all pinned posts and prepend them to the list of postEntries
+	pinnedStartKey := _GlobalStatePrefixTstampNanosPinnedPostHash
+	maxBigEndianUint64Bytes := []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	maxKeyLen := 1 + len(maxBigEndianUint64Bytes) + lib.HashSizeBytes
+	// todo: how many posts can we really pin?
+	keys, _, err := fes.GlobalStateSeek(pinnedStartKey, pinnedStartKey, maxKeyLen, 10, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("GetPostEntriesForWhitelist: Getting pinned posts: %v", err)
 	}
 
-	for _, postEntry := range postEntries {
-		{
-			profileEntry := utxoView.GetProfileEntryForPublicKey(postEntry.PosterPublicKey)
-			if profileEntry != nil {
-				profileEntries[lib.MakePkMapKey(profileEntry.PublicKey)] = profileEntry
-			}
-		}
-	}
-	// Create reader state map. Ie, whether the reader has liked the post, etc.
-	// If nil is passed in as the readerPK, this is skipped.
-	if readerPK != nil {
-		for _, postEntry := range postEntries {
-			postEntryReaderState := utxoView.GetPostEntryReaderState(readerPK, postEntry)
-			postEntryReaderStates[*postEntry.PostHash] = postEntryReaderState
+	var pinnedPostEntries []*lib.PostEntry
+	for _, dbKeyBytes := range keys {
+		postHash := &lib.BlockHash{}
+		copy(postHash[:], dbKeyBytes[1+len(maxBigEndianUint64Bytes):][:])
+		postEntry := utxoView.GetPostEntryForPostHash(postHash)
+		if postEntry != nil {
+			postEntry.IsPinned = true
+			pinnedPostEntries = append(pinnedPostEntries, postEntry)
 		}
 	}
-
-	return postEntries, profileEntries, postEntryReaderStates, nil
+	return pinnedPostEntries, nil
 }
 
 // GetPostsStateless ...
@@ -697,7 +746,7 @@ func (fes *APIServer) GetPostsStateless(ww http.ResponseWriter, req *http.Reques
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetPostsStatelessRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsStateless: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetPostsStateless: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -707,7 +756,7 @@ func (fes *APIServer) GetPostsStateless(ww http.ResponseWriter, req *http.Reques
 		var err error
 		readerPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.ReaderPublicKeyBase58Check)
 		if requestData.ReaderPublicKeyBase58Check != "" && err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetPostsStateless: Problem decoding user public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetPostsStateless: Problem decoding user public key: %v", err))
 			return
 		}
 	}
@@ -717,7 +766,7 @@ func (fes *APIServer) GetPostsStateless(ww http.ResponseWriter, req *http.Reques
 	if requestData.PostHashHex != "" {
 		postHashBytes, err := hex.DecodeString(requestData.PostHashHex)
 		if err != nil || len(postHashBytes) != lib.HashSizeBytes {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"GetPostsStateless: Error parsing post hash %v: %v",
 				requestData.PostHashHex, err))
 			return
@@ -733,14 +782,38 @@ func (fes *APIServer) GetPostsStateless(ww http.ResponseWriter, req *http.Reques
 	}
 
 	if startPostHash == nil && numToFetch == 1 {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsStateless: Must provide PostHashHex when NumToFetch is 1"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("GetPostsStateless: Must provide PostHashHex when NumToFetch is 1"))
+		return
+	}
+
+	// The global feed and the by-clout feed are mostly the same for every reader,
+	// so they're built once per chain state and shared.
+	useFeedCache, err := fes.canServePostsStatelessFromFeedCache(&requestData, readerPublicKeyBytes)
+	if err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf("GetPostsStateless: %v", err))
+		return
+	}
+	if useFeedCache {
+		postEntryResponses, err := fes.getPostsStatelessFromFeedCache(
+			&requestData, startPostHash, readerPublicKeyBytes, numToFetch)
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("GetPostsStateless: Error fetching posts: %v", err))
+			return
+		}
+		res := &GetPostsStatelessResponse{
+			PostsFound: filterAndSortPostsStateless(&requestData, postEntryResponses),
+		}
+		if err := json.NewEncoder(ww).Encode(res); err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf(
+				"GetPostsStateless: Problem encoding response as JSON: %v", err))
+		}
 		return
 	}
 
 	// Get a view with all the mempool transactions (used to get all posts / reader state).
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsStateless: Error fetching mempool view"))
+		_AddInternalServerError(ww, fmt.Sprintf("GetPostsStateless: Error fetching mempool view"))
 		return
 	}
 
@@ -776,7 +849,7 @@ func (fes *APIServer) GetPostsStateless(ww http.ResponseWriter, req *http.Reques
 	}
 
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsStateless: Error fetching posts: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetPostsStateless: Error fetching posts: %v", err))
 		return
 	}
 
@@ -790,13 +863,13 @@ func (fes *APIServer) GetPostsStateless(ww http.ResponseWriter, req *http.Reques
 	// Get a utxoView.
 	utxoView, err = fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsStateless: Error constucting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetPostsStateless: Error constucting utxoView: %v", err))
 		return
 	}
 
 	blockedPubKeys, err := fes.GetBlockedPubKeysForUser(readerPublicKeyBytes)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsStateless: Error fetching blocked pub keys for user: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetPostsStateless: Error fetching blocked pub keys for user: %v", err))
 		return
 	}
 
@@ -840,6 +913,24 @@ func (fes *APIServer) GetPostsStateless(ww http.ResponseWriter, req *http.Reques
 		}
 	}
 
+	postEntryResponses = filterAndSortPostsStateless(&requestData, postEntryResponses)
+
+	// Return the posts found.
+	res := &GetPostsStatelessResponse{
+		PostsFound: postEntryResponses,
+	}
+	if err := json.NewEncoder(ww).Encode(res); err != nil {
+		_AddInternalServerError(ww, fmt.Sprintf(
+			"GetPostsStateless: Problem encoding response as JSON: %v", err))
+		return
+	}
+}
+
+// filterAndSortPostsStateless applies GetPostsStateless's PostContent filter and
+// OrderBy to postEntryResponses.
+func filterAndSortPostsStateless(
+	requestData *GetPostsStatelessRequest, postEntryResponses []*PostEntryResponse) []*PostEntryResponse {
+
 	if requestData.PostContent != "" {
 		lowercaseFilter := strings.ToLower(requestData.PostContent)
 		filteredResponses := []*PostEntryResponse{}
@@ -907,15 +998,7 @@ func (fes *APIServer) GetPostsStateless(ww http.ResponseWriter, req *http.Reques
 		})
 	}
 
-	// Return the posts found.
-	res := &GetPostsStatelessResponse{
-		PostsFound: postEntryResponses,
-	}
-	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
-			"GetPostsStateless: Problem encoding response as JSON: %v", err))
-		return
-	}
+	return postEntryResponses
 }
 
 type GetSinglePostRequest struct {
@@ -938,19 +1021,19 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetSinglePostRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetSinglePost: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the postHash.
 	var postHash *lib.BlockHash
 	if requestData.PostHashHex == "" {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Must provide a PostHashHex to fetch"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("GetSinglePost: Must provide a PostHashHex to fetch"))
 		return
 	} else {
 		postHashBytes, err := hex.DecodeString(requestData.PostHashHex)
 		if err != nil || len(postHashBytes) != lib.HashSizeBytes {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"GetPostsStateless: Error parsing post hash %v: %v",
 				requestData.PostHashHex, err))
 			return
@@ -965,7 +1048,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 		var err error
 		readerPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.ReaderPublicKeyBase58Check)
 		if requestData.ReaderPublicKeyBase58Check != "" && err != nil {
-			_AddBadRequestError(ww,
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey,
 				fmt.Sprintf("GetSinglePost: Problem decoding user public key: %v : %s", err, requestData.ReaderPublicKeyBase58Check))
 			return
 		}
@@ -974,21 +1057,21 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 	// Get a view with all the mempool transactions.
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Error constucting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetSinglePost: Error constucting utxoView: %v", err))
 		return
 	}
 
 	// Fetch the postEntry requested.
 	postEntry := utxoView.GetPostEntryForPostHash(postHash)
 	if postEntry == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Could not find postEntry for PostHashHex: %s", requestData.PostHashHex))
+		_AddCodedError(ww, ErrorCodePostNotFound, fmt.Sprintf("GetSinglePost: Could not find postEntry for PostHashHex: %s", requestData.PostHashHex))
 		return
 	}
 
 	// Fetch the commentEntries for the post.
 	commentEntries, err := utxoView.GetCommentEntriesForParentStakeID(postHash[:])
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Error getting commentEntries: %v: %s", err, requestData.PostHashHex))
+		_AddInternalServerError(ww, fmt.Sprintf("GetSinglePost: Error getting commentEntries: %v: %s", err, requestData.PostHashHex))
 		return
 	}
 
@@ -1002,7 +1085,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 	// Get profiles blocked by the reader.
 	blockedPublicKeys, err := fes.GetBlockedPubKeysForUser(readerPublicKeyBytes)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Problem getting blocked public keys for user: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetSinglePost: Problem getting blocked public keys for user: %v", err))
 		return
 	}
 
@@ -1018,7 +1101,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 		rootParent := parentPostEntries[0]
 		rootBlockedPublicKeys, err = fes.GetBlockedPubKeysForUser(rootParent.PosterPublicKey)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddInternalServerError(ww, fmt.Sprintf(
 				"GetSinglePost: Problem with GetBlockedPubKeysForUser for root entry: publicKey: %v %v", lib.PkToString(rootParent.PosterPublicKey, fes.Params), err))
 			return
 		}
@@ -1026,7 +1109,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 		// If the current post entry we're at is the root, then use that to determine who is blocked.
 		rootBlockedPublicKeys, err = fes.GetBlockedPubKeysForUser(postEntry.PosterPublicKey)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddInternalServerError(ww, fmt.Sprintf(
 				"GetSinglePost: Problem with GetBlockedPubKeysForUser for current post entry: publicKey: %v %v", lib.PkToString(postEntry.PosterPublicKey, fes.Params), err))
 			return
 		}
@@ -1064,7 +1147,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 	filteredProfilePubKeyMap, err := fes.FilterOutRestrictedPubKeysFromMap(
 		profilePubKeyMap, readerPublicKeyBytes, "leaderboard" /*moderationType*/)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Error filtering out restricted profiles: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetSinglePost: Error filtering out restricted profiles: %v", err))
 		return
 	}
 
@@ -1079,17 +1162,17 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 		var currentPosterUserMetadataBytes []byte
 		currentPosterUserMetadataBytes, err = fes.GlobalStateGet(currentPosterUserMetadataKey)
 		if err != nil {
-			_AddBadRequestError(ww,
+			_AddInternalServerError(ww,
 				fmt.Sprintf("GetSinglePost: Problem getting currentPoster uset metadata from global state: %v", err))
 			return
 		}
 		// If the currentPoster's userMetadata doesn't exist, then they are no greylisted, so we can exit.
 		if currentPosterUserMetadataBytes != nil {
 			// Decode the currentPoster's userMetadata.
-			currentPosterUserMetadata := UserMetadata{}
-			err = gob.NewDecoder(bytes.NewReader(currentPosterUserMetadataBytes)).Decode(&currentPosterUserMetadata)
+			var currentPosterUserMetadata *UserMetadata
+			currentPosterUserMetadata, err = fes.PIIKeyring.decodeUserMetadata(currentPosterUserMetadataBytes)
 			if err != nil {
-				_AddBadRequestError(ww,
+				_AddInternalServerError(ww,
 					fmt.Sprintf("GetSinglePost: Problem decoding currentPoster user metadata: %v", err))
 				return
 			}
@@ -1104,7 +1187,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 
 	// If the profile that posted this post is not in our filtered list, return with error.
 	if filteredProfilePubKeyMap[lib.MakePkMapKey(postEntry.PosterPublicKey)] == nil && !isCurrentPosterGreylisted {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: The poster public key for this post is restricted."))
+		_AddCodedError(ww, ErrorCodeForbidden, fmt.Sprintf("GetSinglePost: The poster public key for this post is restricted."))
 		return
 	}
 
@@ -1129,14 +1212,14 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 
 	// If the profile that posted this post does not have a profile, return with error.
 	if pubKeyToProfileEntryResponseMap[lib.MakePkMapKey(postEntry.PosterPublicKey)] == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: The poster public key for this post is restricted."))
+		_AddCodedError(ww, ErrorCodeForbidden, fmt.Sprintf("GetSinglePost: The poster public key for this post is restricted."))
 		return
 	}
 
 	// Create the postEntryResponse.
 	postEntryResponse, err := fes._postEntryToResponse(postEntry, requestData.AddGlobalFeedBool /*AddGlobalFeedBool*/, fes.Params, utxoView, readerPublicKeyBytes, 2)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Error creating postEntryResponse: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetSinglePost: Error creating postEntryResponse: %v", err))
 		return
 	}
 
@@ -1157,7 +1240,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 		parentEntryResponse, err := fes._postEntryToResponse(parentEntry, requestData.AddGlobalFeedBool /*AddGlobalFeed*/, fes.Params, utxoView, readerPublicKeyBytes, 2)
 
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Error creating parentEntryResponse: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("GetSinglePost: Error creating parentEntryResponse: %v", err))
 			return
 		}
 
@@ -1188,7 +1271,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 		// Build the comments entry response and append.
 		commentEntryResponse, err := fes._postEntryToResponse(commentEntry, requestData.AddGlobalFeedBool /*AddGlobalFeed*/, fes.Params, utxoView, readerPublicKeyBytes, 2)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Error creating commentEntryResponse: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("GetSinglePost: Error creating commentEntryResponse: %v", err))
 			return
 		}
 		commentEntryResponse.ProfileEntryResponse = commentProfileEntryResponse
@@ -1275,7 +1358,7 @@ func (fes *APIServer) GetSinglePost(ww http.ResponseWriter, req *http.Request) {
 		PostFound: postEntryResponse,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetSinglePost: Problem encoding response as JSON: %v", err))
 		return
 	}
@@ -1307,14 +1390,14 @@ func (fes *APIServer) GetPostsForPublicKey(ww http.ResponseWriter, req *http.Req
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetPostsForPublicKeyRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsForPublicKey: Error parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetPostsForPublicKey: Error parsing request body: %v", err))
 		return
 	}
 
 	// Get a view
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsForPublicKey: Error getting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetPostsForPublicKey: Error getting utxoView: %v", err))
 		return
 	}
 
@@ -1323,7 +1406,7 @@ func (fes *APIServer) GetPostsForPublicKey(ww http.ResponseWriter, req *http.Req
 	if requestData.PublicKeyBase58Check != "" {
 		publicKeyBytes, _, err = lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetPostsForPublicKey: Problem decoding user public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetPostsForPublicKey: Problem decoding user public key: %v", err))
 			return
 		}
 	} else {
@@ -1332,7 +1415,7 @@ func (fes *APIServer) GetPostsForPublicKey(ww http.ResponseWriter, req *http.Req
 
 		// Return an error if we failed to find a profile entry
 		if profileEntry == nil {
-			_AddNotFoundError(ww, fmt.Sprintf("GetPostsForPublicKey: could not find profile for username: %v", username))
+			_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("GetPostsForPublicKey: could not find profile for username: %v", username))
 			return
 		}
 		publicKeyBytes = profileEntry.PublicKey
@@ -1342,7 +1425,7 @@ func (fes *APIServer) GetPostsForPublicKey(ww http.ResponseWriter, req *http.Req
 	if requestData.ReaderPublicKeyBase58Check != "" {
 		readerPk, _, err = lib.Base58CheckDecode(requestData.ReaderPublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetPostsForPublicKey: Problem decoding reader public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetPostsForPublicKey: Problem decoding reader public key: %v", err))
 			return
 		}
 	}
@@ -1353,7 +1436,7 @@ func (fes *APIServer) GetPostsForPublicKey(ww http.ResponseWriter, req *http.Req
 	if requestData.LastPostHashHex != "" {
 		startPostHashBytes, err = hex.DecodeString(requestData.LastPostHashHex)
 		if err != nil || len(startPostHashBytes) != lib.HashSizeBytes {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"GetPostsForPublicKey: Error parsing post hash %v: %v",
 				requestData.LastPostHashHex, err))
 			return
@@ -1365,7 +1448,7 @@ func (fes *APIServer) GetPostsForPublicKey(ww http.ResponseWriter, req *http.Req
 	// Get Posts Ordered by time.
 	posts, err := utxoView.GetPostsPaginatedForPublicKeyOrderedByTimestamp(publicKeyBytes, startPostHash, requestData.NumToFetch)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetPostsForPublicKey: Problem getting paginated posts: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetPostsForPublicKey: Problem getting paginated posts: %v", err))
 		return
 	}
 
@@ -1394,7 +1477,7 @@ func (fes *APIServer) GetPostsForPublicKey(ww http.ResponseWriter, req *http.Req
 		var postEntryResponse *PostEntryResponse
 		postEntryResponse, err = fes._postEntryToResponse(post, true, fes.Params, utxoView, readerPk, 2)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetPostsForPublicKey: Problem converting post entry to response: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("GetPostsForPublicKey: Problem converting post entry to response: %v", err))
 			return
 		}
 		if readerPk != nil {
@@ -1459,7 +1542,7 @@ func (fes *APIServer) GetDiamondedPosts(ww http.ResponseWriter, req *http.Reques
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetPostsDiamondedBySenderForReceiverRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 			"GetDiamondedPosts: Problem parsing request body: %v", err))
 		return
 	}
@@ -1467,7 +1550,7 @@ func (fes *APIServer) GetDiamondedPosts(ww http.ResponseWriter, req *http.Reques
 	// Get a view
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Error getting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetDiamondedPosts: Error getting utxoView: %v", err))
 		return
 	}
 
@@ -1477,19 +1560,19 @@ func (fes *APIServer) GetDiamondedPosts(ww http.ResponseWriter, req *http.Reques
 		// Decode the receiver public key for which we are fetching posts that were diamonded.
 		receiverPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.ReceiverPublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Problem decoding receiver public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetDiamondedPosts: Problem decoding receiver public key: %v", err))
 			return
 		}
 		receiverProfileEntry = utxoView.GetProfileEntryForPublicKey(receiverPublicKeyBytes)
 	} else if requestData.ReceiverUsername != "" {
 		receiverProfileEntry = utxoView.GetProfileEntryForUsername([]byte(strings.ToLower(requestData.ReceiverUsername)))
 		if receiverProfileEntry == nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: No profile entry found for receiver username: %v", requestData.ReceiverUsername))
+			_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("GetDiamondedPosts: No profile entry found for receiver username: %v", requestData.ReceiverUsername))
 			return
 		}
 		receiverPublicKeyBytes = receiverProfileEntry.PublicKey
 	} else {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Neither ReceiverPublicKeyBase58Check nor ReceiverUsername provided"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("GetDiamondedPosts: Neither ReceiverPublicKeyBase58Check nor ReceiverUsername provided"))
 		return
 	}
 
@@ -1499,40 +1582,40 @@ func (fes *APIServer) GetDiamondedPosts(ww http.ResponseWriter, req *http.Reques
 		// Decode the sender public key for which we are fetching posts that were diamonded.
 		senderPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.SenderPublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Problem decoding sender public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetDiamondedPosts: Problem decoding sender public key: %v", err))
 			return
 		}
 		senderProfileEntry = utxoView.GetProfileEntryForPublicKey(senderPublicKeyBytes)
 	} else if requestData.SenderUsername != "" {
 		senderProfileEntry = utxoView.GetProfileEntryForUsername([]byte(strings.ToLower(requestData.SenderUsername)))
 		if senderProfileEntry == nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: No profile entry found for sender username: %v", requestData.SenderUsername))
+			_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("GetDiamondedPosts: No profile entry found for sender username: %v", requestData.SenderUsername))
 			return
 		}
 		senderPublicKeyBytes = senderProfileEntry.PublicKey
 	} else {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Neither SenderPublicKeyBase58Check nor SenderUsername provided"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("GetDiamondedPosts: Neither SenderPublicKeyBase58Check nor SenderUsername provided"))
 		return
 	}
 
 	// Decode the reader public key.
 	readerPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.ReaderPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Problem decoding reader public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetDiamondedPosts: Problem decoding reader public key: %v", err))
 		return
 	}
 
 	// Get the DiamondEntries for this receiver-sender pair of public keys.
 	diamondEntries, err := utxoView.GetDiamondEntriesForSenderToReceiver(receiverPublicKeyBytes, senderPublicKeyBytes)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Problem getting diamond entries: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetDiamondedPosts: Problem getting diamond entries: %v", err))
 		return
 	}
 
 	// Grab verified username map pointer so we can verify the profiles.
 	verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetDiamondedPosts: Error fetching verifiedMap: %v", err))
 		return
 	}
@@ -1546,7 +1629,7 @@ func (fes *APIServer) GetDiamondedPosts(ww http.ResponseWriter, req *http.Reques
 			var postEntryResponse *PostEntryResponse
 			postEntryResponse, err = fes._postEntryToResponse(postEntry, false, fes.Params, utxoView, readerPublicKeyBytes, 2)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Problem converting post entry to response: %v", err))
+				_AddInternalServerError(ww, fmt.Sprintf("GetDiamondedPosts: Problem converting post entry to response: %v", err))
 				return
 			}
 			postEntryReaderState := utxoView.GetPostEntryReaderState(readerPublicKeyBytes, postEntry)
@@ -1555,7 +1638,7 @@ func (fes *APIServer) GetDiamondedPosts(ww http.ResponseWriter, req *http.Reques
 			if postEntry.ParentStakeID != nil && len(postEntry.ParentStakeID) == lib.HashSizeBytes {
 				parentPostEntry := utxoView.GetPostEntryForPostHash(lib.StakeIDToHash(postEntry.ParentStakeID))
 				if parentPostEntry == nil {
-					_AddBadRequestError(ww, fmt.Sprintf(
+					_AddInternalServerError(ww, fmt.Sprintf(
 							"GetDiamondedPosts: Problem getting parent post with postHash %v for postEntry with hash %v",
 							hex.EncodeToString(postEntry.ParentStakeID), hex.EncodeToString(postEntry.PostHash[:])))
 					return
@@ -1563,7 +1646,7 @@ func (fes *APIServer) GetDiamondedPosts(ww http.ResponseWriter, req *http.Reques
 				var parentPostEntryResponse *PostEntryResponse
 				parentPostEntryResponse, err = fes._postEntryToResponse(parentPostEntry, false, fes.Params, utxoView, readerPublicKeyBytes, 2)
 				if err != nil {
-					_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Problem converting parent post entry to response: %v", err))
+					_AddInternalServerError(ww, fmt.Sprintf("GetDiamondedPosts: Problem converting parent post entry to response: %v", err))
 				}
 				parentProfileEntry := utxoView.GetProfileEntryForPublicKey(parentPostEntry.PosterPublicKey)
 				parentPostEntryResponse.ProfileEntryResponse = _profileEntryToResponse(parentProfileEntry, fes.Params, verifiedMap, utxoView)
@@ -1609,7 +1692,7 @@ func (fes *APIServer) GetDiamondedPosts(ww http.ResponseWriter, req *http.Reques
 		SenderProfileEntryResponse: _profileEntryToResponse(senderProfileEntry, fes.Params, verifiedMap, utxoView),
 	}
 	if err = json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondedPosts: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetDiamondedPosts: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
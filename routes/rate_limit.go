@@ -0,0 +1,409 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	"github.com/golang/glog"
+	"github.com/sasha-s/go-deadlock"
+)
+
+const (
+	// How often stores drop buckets that have refilled completely. A full bucket
+	// behaves exactly like a missing one so nothing is lost by dropping it.
+	rateLimitPruneInterval = 10 * time.Minute
+)
+
+// RateLimitQuota configures a token bucket. The bucket holds up to Burst tokens
+// and refills at RequestsPerMinute. Each request takes one token and requests that
+// find the bucket empty are rejected.
+type RateLimitQuota struct {
+	RequestsPerMinute float64
+	Burst             int
+}
+
+// interval is how long it takes the bucket to gain a single token.
+func (quota RateLimitQuota) interval() time.Duration {
+	return time.Duration(float64(time.Minute) / quota.RequestsPerMinute)
+}
+
+func (quota RateLimitQuota) validate() error {
+	if quota.RequestsPerMinute <= 0 {
+		return fmt.Errorf("RequestsPerMinute must be positive")
+	}
+	if quota.Burst < 1 {
+		return fmt.Errorf("Burst must be at least 1")
+	}
+	return nil
+}
+
+// RouteRateLimit is the rate limit for a single route. Requests are counted
+// separately against the client's IP and against the public key in the request
+// body, and must be allowed by both. A nil quota means that key isn't limited.
+type RouteRateLimit struct {
+	PerIP        *RateLimitQuota `json:",omitempty"`
+	PerPublicKey *RateLimitQuota `json:",omitempty"`
+	// The field of the JSON request body holding the public key that PerPublicKey
+	// counts against. Requests without it are only limited by IP.
+	PublicKeyField string `json:",omitempty"`
+}
+
+func (routeRateLimit *RouteRateLimit) validate() error {
+	if routeRateLimit.PerIP != nil {
+		if err := routeRateLimit.PerIP.validate(); err != nil {
+			return fmt.Errorf("PerIP: %v", err)
+		}
+	}
+	if routeRateLimit.PerPublicKey != nil {
+		if err := routeRateLimit.PerPublicKey.validate(); err != nil {
+			return fmt.Errorf("PerPublicKey: %v", err)
+		}
+		if routeRateLimit.PublicKeyField == "" {
+			return fmt.Errorf("PublicKeyField is required when PerPublicKey is set")
+		}
+	}
+	return nil
+}
+
+// DefaultRouteRateLimits are the rate limits applied by route name when rate
+// limiting is enabled. They cover the routes that are expensive to serve or that
+// cost us money, like sending texts through Twilio.
+var DefaultRouteRateLimits = map[string]RouteRateLimit{
+	"SendPhoneNumberVerificationText": {
+		PerIP:          &RateLimitQuota{RequestsPerMinute: 1, Burst: 5},
+		PerPublicKey:   &RateLimitQuota{RequestsPerMinute: 0.2, Burst: 3},
+		PublicKeyField: "PublicKeyBase58Check",
+	},
+	"SubmitPhoneNumberVerificationCode": {
+		PerIP:          &RateLimitQuota{RequestsPerMinute: 10, Burst: 10},
+		PerPublicKey:   &RateLimitQuota{RequestsPerMinute: 5, Burst: 10},
+		PublicKeyField: "PublicKeyBase58Check",
+	},
+	"GetPostsStateless": {
+		PerIP:          &RateLimitQuota{RequestsPerMinute: 120, Burst: 60},
+		PerPublicKey:   &RateLimitQuota{RequestsPerMinute: 60, Burst: 30},
+		PublicKeyField: "ReaderPublicKeyBase58Check",
+	},
+	"GetNotifications": {
+		PerIP:          &RateLimitQuota{RequestsPerMinute: 120, Burst: 60},
+		PerPublicKey:   &RateLimitQuota{RequestsPerMinute: 60, Burst: 30},
+		PublicKeyField: "PublicKeyBase58Check",
+	},
+}
+
+// LoadRouteRateLimits returns DefaultRouteRateLimits with the limits from the JSON
+// file at path layered on top. The file maps route names to RouteRateLimits, and a
+// route mapped to null isn't limited at all. If path is empty the defaults are
+// returned as is.
+func LoadRouteRateLimits(path string) (map[string]RouteRateLimit, error) {
+	routeRateLimits := make(map[string]RouteRateLimit)
+	for routeName, routeRateLimit := range DefaultRouteRateLimits {
+		routeRateLimits[routeName] = routeRateLimit
+	}
+	if path == "" {
+		return routeRateLimits, nil
+	}
+
+	fileBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("LoadRouteRateLimits: Problem reading %v: %v", path, err)
+	}
+	overrides := make(map[string]*RouteRateLimit)
+	if err := json.Unmarshal(fileBytes, &overrides); err != nil {
+		return nil, fmt.Errorf("LoadRouteRateLimits: Problem parsing %v: %v", path, err)
+	}
+	for routeName, routeRateLimit := range overrides {
+		if routeRateLimit == nil {
+			delete(routeRateLimits, routeName)
+			continue
+		}
+		if err := routeRateLimit.validate(); err != nil {
+			return nil, fmt.Errorf("LoadRouteRateLimits: Invalid limit for %v: %v", routeName, err)
+		}
+		routeRateLimits[routeName] = *routeRateLimit
+	}
+	return routeRateLimits, nil
+}
+
+// takeRateLimitToken takes a token from a bucket. Rather than storing a token
+// count and a refill time, a bucket is stored as the time at which it will be full
+// again, which lets it be updated with a single compare-and-swap. A bucket that
+// has never been used has a zero fullAt. If the bucket is empty, the returned
+// retryAfter is how long until it has a token again and fullAt is unchanged.
+func takeRateLimitToken(fullAt time.Time, now time.Time, quota RateLimitQuota) (
+	_newFullAt time.Time, _retryAfter time.Duration) {
+
+	interval := quota.interval()
+	if fullAt.Before(now) {
+		fullAt = now
+	}
+	newFullAt := fullAt.Add(interval)
+
+	// Since the bucket holds Burst tokens, it can never be more than Burst
+	// intervals away from full.
+	if earliestAllowed := newFullAt.Add(-time.Duration(quota.Burst) * interval); earliestAllowed.After(now) {
+		return fullAt, earliestAllowed.Sub(now)
+	}
+	return newFullAt, 0
+}
+
+// RateLimitStore holds the token buckets for a RateLimiter.
+type RateLimitStore interface {
+	// Take takes a token from the bucket with the given key. It returns zero if the
+	// request is allowed, and otherwise how long the caller should wait.
+	Take(bucketKey string, quota RateLimitQuota, now time.Time) (_retryAfter time.Duration, _err error)
+}
+
+// MemoryRateLimitStore keeps buckets in memory, so limits only apply per node.
+type MemoryRateLimitStore struct {
+	mtx           deadlock.Mutex
+	fullAtByKey   map[string]time.Time
+	lastPruneTime time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		fullAtByKey: make(map[string]time.Time),
+	}
+}
+
+func (store *MemoryRateLimitStore) Take(bucketKey string, quota RateLimitQuota, now time.Time) (
+	_retryAfter time.Duration, _err error) {
+
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+
+	if now.Sub(store.lastPruneTime) > rateLimitPruneInterval {
+		for existingKey, fullAt := range store.fullAtByKey {
+			if !fullAt.After(now) {
+				delete(store.fullAtByKey, existingKey)
+			}
+		}
+		store.lastPruneTime = now
+	}
+
+	newFullAt, retryAfter := takeRateLimitToken(store.fullAtByKey[bucketKey], now, quota)
+	if retryAfter == 0 {
+		store.fullAtByKey[bucketKey] = newFullAt
+	}
+	return retryAfter, nil
+}
+
+// GlobalStateRateLimitStore keeps buckets in global state so that limits are
+// shared by every node using the same global state.
+//
+// The GlobalStore passed in should not be a ChangeLogGlobalStore or a
+// CachingGlobalStore. Buckets change on nearly every request, and recording those
+// changes would push real changes out of the change log.
+type GlobalStateRateLimitStore struct {
+	store GlobalStore
+
+	mtx           deadlock.Mutex
+	lastPruneTime time.Time
+	isPruning     bool
+}
+
+func NewGlobalStateRateLimitStore(store GlobalStore) *GlobalStateRateLimitStore {
+	return &GlobalStateRateLimitStore{
+		store: store,
+	}
+}
+
+func globalStateKeyForRateLimitBucket(bucketKey string) []byte {
+	bucketKeyHash := sha256.Sum256([]byte(bucketKey))
+	key := append([]byte{}, _GlobalStatePrefixRateLimitBucket...)
+	key = append(key, bucketKeyHash[:]...)
+	return key
+}
+
+func (store *GlobalStateRateLimitStore) Take(bucketKey string, quota RateLimitQuota, now time.Time) (
+	_retryAfter time.Duration, _err error) {
+
+	store.maybeStartPrune(now)
+
+	key := globalStateKeyForRateLimitBucket(bucketKey)
+	for attempt := 0; attempt < GlobalStateMaxUpdateAttempts; attempt++ {
+		oldValue, err := store.store.Get(key)
+		if err != nil {
+			return 0, fmt.Errorf("GlobalStateRateLimitStore.Take: Problem getting bucket: %v", err)
+		}
+		var fullAt time.Time
+		if len(oldValue) == 8 {
+			fullAt = time.Unix(0, int64(lib.DecodeUint64(oldValue)))
+		}
+
+		newFullAt, retryAfter := takeRateLimitToken(fullAt, now, quota)
+		if retryAfter > 0 {
+			return retryAfter, nil
+		}
+
+		swapped, err := store.store.CompareAndSwap(key, oldValue, lib.EncodeUint64(uint64(newFullAt.UnixNano())))
+		if err != nil {
+			return 0, fmt.Errorf("GlobalStateRateLimitStore.Take: Problem swapping bucket: %v", err)
+		}
+		if swapped {
+			return 0, nil
+		}
+	}
+
+	return 0, fmt.Errorf("GlobalStateRateLimitStore.Take: Gave up after %d attempts due to concurrent updates",
+		GlobalStateMaxUpdateAttempts)
+}
+
+// maybeStartPrune kicks off a prune in the background if it has been long enough
+// since the last one, so that requests never wait on it.
+func (store *GlobalStateRateLimitStore) maybeStartPrune(now time.Time) {
+	store.mtx.Lock()
+	defer store.mtx.Unlock()
+
+	if store.isPruning || now.Sub(store.lastPruneTime) <= rateLimitPruneInterval {
+		return
+	}
+	store.isPruning = true
+	store.lastPruneTime = now
+
+	go func() {
+		if err := store.prune(now); err != nil {
+			glog.Errorf("GlobalStateRateLimitStore: %v", err)
+		}
+		store.mtx.Lock()
+		store.isPruning = false
+		store.mtx.Unlock()
+	}()
+}
+
+// prune deletes every bucket that is full as of now.
+func (store *GlobalStateRateLimitStore) prune(now time.Time) error {
+	err := GlobalStateIteratePrefix(store.store, _GlobalStatePrefixRateLimitBucket, func(key []byte, value []byte) error {
+		if len(value) == 8 && int64(lib.DecodeUint64(value)) > now.UnixNano() {
+			return nil
+		}
+		// If another node took a token since we read the bucket, it isn't full and
+		// the swap simply fails.
+		_, err := store.store.CompareAndSwap(key, value, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("prune: %v", err)
+	}
+	return nil
+}
+
+// RateLimiter enforces RouteRateLimits on incoming requests.
+type RateLimiter struct {
+	store           RateLimitStore
+	routeRateLimits map[string]RouteRateLimit
+	// If set, the client IP is read from this header instead of the connection.
+	// Only set this when the node is behind a proxy that always sets the header.
+	ipHeader string
+}
+
+func NewRateLimiter(store RateLimitStore, routeRateLimits map[string]RouteRateLimit, ipHeader string) *RateLimiter {
+	return &RateLimiter{
+		store:           store,
+		routeRateLimits: routeRateLimits,
+		ipHeader:        ipHeader,
+	}
+}
+
+// clientIP returns the IP the request came from.
+func (limiter *RateLimiter) clientIP(req *http.Request) string {
+	if limiter.ipHeader != "" {
+		// Headers like X-Forwarded-For hold a list of addresses. Anything before
+		// the last one could have been sent by the client, so the last one, which
+		// was added by our proxy, is the only one we can trust.
+		if headerValue := req.Header.Get(limiter.ipHeader); headerValue != "" {
+			addresses := strings.Split(headerValue, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// publicKeyFromBody returns the value of publicKeyField in the JSON request body,
+// or an empty string if there isn't one. The body is left in place for the
+// handler.
+func publicKeyFromBody(req *http.Request, publicKeyField string) string {
+	if req.Body == nil {
+		return ""
+	}
+	bodyBytes, err := ioutil.ReadAll(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
+	req.Body = ioutil.NopCloser(bytes.NewReader(bodyBytes))
+	if err != nil {
+		return ""
+	}
+
+	// Bad bodies are left for the handler to complain about.
+	requestFields := make(map[string]interface{})
+	if err := json.Unmarshal(bodyBytes, &requestFields); err != nil {
+		return ""
+	}
+	publicKey, _ := requestFields[publicKeyField].(string)
+	return publicKey
+}
+
+// take takes a token from a bucket and writes a 429 response if it was empty. If
+// the store fails, the request is allowed since an outage in global state
+// shouldn't take down every limited route with it.
+func (limiter *RateLimiter) take(ww http.ResponseWriter, routeName string, bucketKey string,
+	quota RateLimitQuota, now time.Time) bool {
+
+	retryAfter, err := limiter.store.Take(bucketKey, quota, now)
+	if err != nil {
+		glog.Errorf("RateLimit: Problem checking limit for %v, allowing request: %v", routeName, err)
+		return true
+	}
+	if retryAfter == 0 {
+		return true
+	}
+
+	retryAfterSeconds := int(math.Ceil(retryAfter.Seconds()))
+	ww.Header().Set("Retry-After", fmt.Sprintf("%d", retryAfterSeconds))
+	_AddAPIError(ww, NewAPIError(ErrorCodeRateLimited, fmt.Sprintf("%v: Too many requests, try again in %d seconds",
+		routeName, retryAfterSeconds)).WithDetail("RetryAfterSeconds", retryAfterSeconds))
+	return false
+}
+
+// RateLimit wraps a route's handler with its RouteRateLimit, if it has one.
+func (limiter *RateLimiter) RateLimit(inner http.Handler, routeName string) http.Handler {
+	routeRateLimit, exists := limiter.routeRateLimits[routeName]
+	if !exists {
+		return inner
+	}
+
+	return http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		now := time.Now()
+
+		if routeRateLimit.PerIP != nil {
+			bucketKey := routeName + "/ip/" + limiter.clientIP(req)
+			if !limiter.take(ww, routeName, bucketKey, *routeRateLimit.PerIP, now) {
+				return
+			}
+		}
+
+		if routeRateLimit.PerPublicKey != nil {
+			if publicKey := publicKeyFromBody(req, routeRateLimit.PublicKeyField); publicKey != "" {
+				bucketKey := routeName + "/pk/" + publicKey
+				if !limiter.take(ww, routeName, bucketKey, *routeRateLimit.PerPublicKey, now) {
+					return
+				}
+			}
+		}
+
+		inner.ServeHTTP(ww, req)
+	})
+}
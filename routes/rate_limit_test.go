@@ -0,0 +1,165 @@
+package routes
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitStores(t *testing.T) {
+	require := require.New(t)
+
+	// One token per second with room for two.
+	quota := RateLimitQuota{RequestsPerMinute: 60, Burst: 2}
+	now := time.Unix(1000, 0)
+
+	globalStore := NewMemoryGlobalStore()
+	globalStateRateLimitStore := NewGlobalStateRateLimitStore(globalStore)
+	// Keep the background prune out of the way.
+	globalStateRateLimitStore.lastPruneTime = now
+
+	for _, store := range []RateLimitStore{NewMemoryRateLimitStore(), globalStateRateLimitStore} {
+		for ii := 0; ii < 2; ii++ {
+			retryAfter, err := store.Take("bucket", quota, now)
+			require.NoError(err)
+			require.Zero(retryAfter)
+		}
+		retryAfter, err := store.Take("bucket", quota, now)
+		require.NoError(err)
+		require.Equal(time.Second, retryAfter)
+
+		// Other buckets aren't affected.
+		retryAfter, err = store.Take("other bucket", quota, now)
+		require.NoError(err)
+		require.Zero(retryAfter)
+
+		// Half a second later there's still no token, and a second later there's
+		// exactly one.
+		retryAfter, err = store.Take("bucket", quota, now.Add(500*time.Millisecond))
+		require.NoError(err)
+		require.Equal(500*time.Millisecond, retryAfter)
+		retryAfter, err = store.Take("bucket", quota, now.Add(time.Second))
+		require.NoError(err)
+		require.Zero(retryAfter)
+		retryAfter, err = store.Take("bucket", quota, now.Add(time.Second))
+		require.NoError(err)
+		require.Equal(time.Second, retryAfter)
+	}
+
+	// Buckets are stored under hashed keys and dropped once they're full again.
+	keys, _, err := globalStore.Seek(_GlobalStatePrefixRateLimitBucket, _GlobalStatePrefixRateLimitBucket,
+		0, 10, false, false)
+	require.NoError(err)
+	require.Len(keys, 2)
+	require.NoError(globalStateRateLimitStore.prune(now.Add(time.Second)))
+	keys, _, err = globalStore.Seek(_GlobalStatePrefixRateLimitBucket, _GlobalStatePrefixRateLimitBucket,
+		0, 10, false, false)
+	require.NoError(err)
+	require.Len(keys, 1)
+	require.NoError(globalStateRateLimitStore.prune(now.Add(time.Minute)))
+	keys, _, err = globalStore.Seek(_GlobalStatePrefixRateLimitBucket, _GlobalStatePrefixRateLimitBucket,
+		0, 10, false, false)
+	require.NoError(err)
+	require.Len(keys, 0)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	require := require.New(t)
+
+	routeRateLimits := map[string]RouteRateLimit{
+		"Limited": {
+			PerIP:          &RateLimitQuota{RequestsPerMinute: 1, Burst: 2},
+			PerPublicKey:   &RateLimitQuota{RequestsPerMinute: 1, Burst: 1},
+			PublicKeyField: "PublicKeyBase58Check",
+		},
+	}
+	limiter := NewRateLimiter(NewMemoryRateLimitStore(), routeRateLimits, "")
+
+	// The handler must still be able to read the body after the limiter has.
+	var lastBody string
+	inner := http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		bodyBytes, err := ioutil.ReadAll(req.Body)
+		require.NoError(err)
+		lastBody = string(bodyBytes)
+	})
+	limited := limiter.RateLimit(inner, "Limited")
+	unlimited := limiter.RateLimit(inner, "Unlimited")
+
+	makeRequest := func(handler http.Handler, remoteAddr string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		request.RemoteAddr = remoteAddr
+		request.Header.Set("X-Forwarded-For", "1.1.1.1, 9.9.9.9")
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		return response
+	}
+
+	response := makeRequest(limited, "1.2.3.4:1000", `{"PublicKeyBase58Check": "pk1"}`)
+	require.Equal(http.StatusOK, response.Code)
+	require.Equal(`{"PublicKeyBase58Check": "pk1"}`, lastBody)
+
+	// The same public key from a different IP is rejected by the public key quota.
+	response = makeRequest(limited, "5.6.7.8:1000", `{"PublicKeyBase58Check": "pk1"}`)
+	require.Equal(http.StatusTooManyRequests, response.Code)
+	require.Equal("60", response.Header().Get("Retry-After"))
+
+	// A different public key from the first IP is allowed until that IP runs out.
+	response = makeRequest(limited, "1.2.3.4:2000", `{"PublicKeyBase58Check": "pk2"}`)
+	require.Equal(http.StatusOK, response.Code)
+	response = makeRequest(limited, "1.2.3.4:3000", `{"PublicKeyBase58Check": "pk3"}`)
+	require.Equal(http.StatusTooManyRequests, response.Code)
+	require.Contains(response.Body.String(), "Too many requests")
+
+	// Routes without a limit aren't touched, and the header is ignored unless
+	// the limiter was told to use it.
+	for ii := 0; ii < 5; ii++ {
+		response = makeRequest(unlimited, "1.2.3.4:1000", `{"PublicKeyBase58Check": "pk1"}`)
+		require.Equal(http.StatusOK, response.Code)
+	}
+
+	// Behind a proxy, only the last address in the header is trusted.
+	headerLimiter := NewRateLimiter(NewMemoryRateLimitStore(), routeRateLimits, "X-Forwarded-For")
+	request := httptest.NewRequest("POST", "/", nil)
+	request.Header.Set("X-Forwarded-For", "1.1.1.1, 9.9.9.9")
+	require.Equal("9.9.9.9", headerLimiter.clientIP(request))
+	request.Header.Del("X-Forwarded-For")
+	require.Equal("192.0.2.1", headerLimiter.clientIP(request))
+}
+
+func TestLoadRouteRateLimits(t *testing.T) {
+	require := require.New(t)
+
+	routeRateLimits, err := LoadRouteRateLimits("")
+	require.NoError(err)
+	require.Equal(DefaultRouteRateLimits, routeRateLimits)
+
+	dir, err := ioutil.TempDir("", "rate-limits")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rate-limits.json")
+
+	require.NoError(ioutil.WriteFile(path, []byte(`{
+		"GetPostsStateless": null,
+		"GetSinglePost": {"PerIP": {"RequestsPerMinute": 30, "Burst": 10}}
+	}`), 0600))
+	routeRateLimits, err = LoadRouteRateLimits(path)
+	require.NoError(err)
+	require.NotContains(routeRateLimits, "GetPostsStateless")
+	require.Equal(RouteRateLimit{PerIP: &RateLimitQuota{RequestsPerMinute: 30, Burst: 10}},
+		routeRateLimits["GetSinglePost"])
+	require.Equal(DefaultRouteRateLimits["GetNotifications"], routeRateLimits["GetNotifications"])
+
+	// A per public key quota needs to know where to find the public key.
+	require.NoError(ioutil.WriteFile(path, []byte(`{
+		"GetSinglePost": {"PerPublicKey": {"RequestsPerMinute": 30, "Burst": 10}}
+	}`), 0600))
+	_, err = LoadRouteRateLimits(path)
+	require.Error(err)
+}
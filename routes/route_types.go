@@ -0,0 +1,173 @@
+package routes
+
+import "reflect"
+
+// routeRequestTypes maps each route's name to the type of the JSON body its
+// handler decodes. Routes that don't take a JSON body are left out. Middleware
+// uses this to make sense of a request without running the handler, e.g. to log
+// the fields tagged with safeForLogging.
+//
+// Remember to add new routes here along with their Route.
+var routeRequestTypes = map[string]reflect.Type{
+	"SendBitClout":                          reflect.TypeOf(SendBitCloutRequest{}),
+	"BurnBitcoin":                           reflect.TypeOf(BurnBitcoinRequest{}),
+	"SubmitTransaction":                     reflect.TypeOf(SubmitTransactionRequest{}),
+	"DeleteIdentities":                      reflect.TypeOf(DeleteIdentityRequest{}),
+	"ReprocessBitcoinBlock":                 reflect.TypeOf(ReprocessBitcoinBlockRequest{}),
+	"GetUsersStateless":                     reflect.TypeOf(GetUsersStatelessRequest{}),
+	"SendPhoneNumberVerificationText":       reflect.TypeOf(SendPhoneNumberVerificationTextRequest{}),
+	"SubmitPhoneNumberVerificationCode":     reflect.TypeOf(SubmitPhoneNumberVerificationCodeRequest{}),
+	"SubmitPost":                            reflect.TypeOf(SubmitPostRequest{}),
+	"GetPostsStateless":                     reflect.TypeOf(GetPostsStatelessRequest{}),
+	"UpdateProfile":                         reflect.TypeOf(UpdateProfileRequest{}),
+	"GetProfiles":                           reflect.TypeOf(GetProfilesRequest{}),
+	"GetSingleProfile":                      reflect.TypeOf(GetSingleProfileRequest{}),
+	"GetPostsForPublicKey":                  reflect.TypeOf(GetPostsForPublicKeyRequest{}),
+	"GetDiamondsForPublicKey":               reflect.TypeOf(GetDiamondsForPublicKeyRequest{}),
+	"GetDiamondedPosts":                     reflect.TypeOf(GetPostsDiamondedBySenderForReceiverRequest{}),
+	"GetHodlersForPublicKey":                reflect.TypeOf(GetHodlersForPublicKeyRequest{}),
+	"GetFollowsStateless":                   reflect.TypeOf(GetFollowsStatelessRequest{}),
+	"CreateFollowTxnStateless":              reflect.TypeOf(CreateFollowTxnStatelessRequest{}),
+	"CreateLikeStateless":                   reflect.TypeOf(CreateLikeStatelessRequest{}),
+	"BuyOrSellCreatorCoin":                  reflect.TypeOf(BuyOrSellCreatorCoinRequest{}),
+	"TransferCreatorCoin":                   reflect.TypeOf(TransferCreatorCoinRequest{}),
+	"SendDiamonds":                          reflect.TypeOf(SendDiamondsRequest{}),
+	"GetNotifications":                      reflect.TypeOf(GetNotificationsRequest{}),
+	"GetAppState":                           reflect.TypeOf(GetAppStateRequest{}),
+	"UpdateUserGlobalMetadata":              reflect.TypeOf(UpdateUserGlobalMetadataRequest{}),
+	"GetUserGlobalMetadata":                 reflect.TypeOf(GetUserGlobalMetadataRequest{}),
+	"NodeControl":                           reflect.TypeOf(NodeControlRequest{}),
+	"AdminUpdateUserGlobalMetadata":         reflect.TypeOf(AdminUpdateUserGlobalMetadataRequest{}),
+	"AdminGetVerifiedUsers":                 reflect.TypeOf(AdminGetVerifiedUsersRequest{}),
+	"AdminGetUsernameVerificationAuditLogs": reflect.TypeOf(AdminGetUsernameVerificationAuditLogsRequest{}),
+	"AdminGrantVerificationBadge":           reflect.TypeOf(AdminGrantVerificationBadgeRequest{}),
+	"AdminRemoveVerificationBadge":          reflect.TypeOf(AdminRemoveVerificationBadgeRequest{}),
+	"AdminGetAllUserGlobalMetadata":         reflect.TypeOf(AdminGetAllUserGlobalMetadataRequest{}),
+	"AdminGetUserGlobalMetadata":            reflect.TypeOf(AdminGetUserGlobalMetadataRequest{}),
+	"AdminUpdateGlobalFeed":                 reflect.TypeOf(AdminUpdateGlobalFeedRequest{}),
+	"AdminPinPost":                          reflect.TypeOf(AdminPinPostRequest{}),
+	"AdminRemoveNilPosts":                   reflect.TypeOf(AdminRemoveNilPostsRequest{}),
+	"AdminGetGlobalStatePrefixes":           reflect.TypeOf(AdminGetGlobalStatePrefixesRequest{}),
+	"AdminGetGlobalStateEntries":            reflect.TypeOf(AdminGetGlobalStateEntriesRequest{}),
+	"AdminUpdateGlobalStateEntry":           reflect.TypeOf(AdminUpdateGlobalStateEntryRequest{}),
+	"AdminGetGlobalStateAuditLogs":          reflect.TypeOf(AdminGetGlobalStateAuditLogsRequest{}),
+	"AdminGlobalStateFsck":                  reflect.TypeOf(AdminGlobalStateFsckRequest{}),
+	"AdminGetMempoolStats":                  reflect.TypeOf(AdminGetMempoolStatsRequest{}),
+	"SwapIdentity":                          reflect.TypeOf(SwapIdentityRequest{}),
+	"UpdateGlobalParams":                    reflect.TypeOf(UpdateGlobalParamsRequest{}),
+	"GetGlobalParams":                       reflect.TypeOf(GetGlobalParamsRequest{}),
+	"EvictUnminedBitcoinTxns":               reflect.TypeOf(EvictUnminedBitcoinTxnsRequest{}),
+	"AdminRebuildTxindex":                   reflect.TypeOf(AdminRebuildTxindexRequest{}),
+	"AdminGetTxindexRebuildStatus":          reflect.TypeOf(AdminGetTxindexRebuildStatusRequest{}),
+	"AdminGetAdminRoles":                    reflect.TypeOf(AdminGetAdminRolesRequest{}),
+	"AdminUpdateAdminRoles":                 reflect.TypeOf(AdminUpdateAdminRolesRequest{}),
+	"AdminGetAdminAuditLogs":                reflect.TypeOf(AdminGetAdminAuditLogsRequest{}),
+	"GetSinglePost":                         reflect.TypeOf(GetSinglePostRequest{}),
+	"BlockPublicKey":                        reflect.TypeOf(BlockPublicKeyRequest{}),
+	"BlockGetTxn":                           reflect.TypeOf(GetTxnRequest{}),
+	"SendMessageStateless":                  reflect.TypeOf(SendMessageStatelessRequest{}),
+	"GetMessagesStateless":                  reflect.TypeOf(GetMessagesStatelessRequest{}),
+	"MarkContactMessagesRead":               reflect.TypeOf(MarkContactMessagesReadRequest{}),
+	"MarkAllMessagesRead":                   reflect.TypeOf(MarkAllMessagesReadRequest{}),
+	"GetBlockTemplate":                      reflect.TypeOf(GetBlockTemplateRequest{}),
+	"SubmitBlock":                           reflect.TypeOf(SubmitBlockRequest{}),
+	"GetFullTikTokURL":                      reflect.TypeOf(GetFullTikTokURLRequest{}),
+	"APIKeyPair":                            reflect.TypeOf(APIKeyPairRequest{}),
+	"APIBalance":                            reflect.TypeOf(APIBalanceRequest{}),
+	"APITransferBitClout":                   reflect.TypeOf(APITransferBitCloutRequest{}),
+	"APITransactionInfo":                    reflect.TypeOf(APITransactionInfoRequest{}),
+	"APINodeInfo":                           reflect.TypeOf(APINodeInfoRequest{}),
+	"APIBlock":                              reflect.TypeOf(APIBlockRequest{}),
+	"GlobalStatePutRemote":                  reflect.TypeOf(GlobalStatePutRemoteRequest{}),
+	"GlobalStateGetRemote":                  reflect.TypeOf(GlobalStateGetRemoteRequest{}),
+	"GlobalStateBatchGetRemote":             reflect.TypeOf(GlobalStateBatchGetRemoteRequest{}),
+	"GlobalStateDeleteRemote":               reflect.TypeOf(GlobalStateDeleteRemoteRequest{}),
+	"GlobalStateSeekRemote":                 reflect.TypeOf(GlobalStateSeekRemoteRequest{}),
+	"GlobalStateCompareAndSwapRemote":       reflect.TypeOf(GlobalStateCompareAndSwapRemoteRequest{}),
+	"GlobalStateChangesRemote":              reflect.TypeOf(GlobalStateChangesRemoteRequest{}),
+}
+
+// routeResponseTypes maps each route's name to the type of the JSON body its
+// handler responds with when it succeeds. Routes that don't respond with JSON,
+// or respond with an empty body, are left out. BuildOpenAPISpec uses this along
+// with routeRequestTypes to describe each route.
+//
+// Remember to add new routes here along with their Route.
+var routeResponseTypes = map[string]reflect.Type{
+	"GetExchangeRate":                       reflect.TypeOf(GetExchangeRateResponse{}),
+	"SendBitClout":                          reflect.TypeOf(SendBitCloutResponse{}),
+	"BurnBitcoin":                           reflect.TypeOf(BurnBitcoinResponse{}),
+	"SubmitTransaction":                     reflect.TypeOf(SubmitTransactionResponse{}),
+	"DeleteIdentities":                      reflect.TypeOf(DeleteIdentityResponse{}),
+	"ReprocessBitcoinBlock":                 reflect.TypeOf(ReprocessBitcoinBlockResponse{}),
+	"GetUsersStateless":                     reflect.TypeOf(GetUsersResponse{}),
+	"SendPhoneNumberVerificationText":       reflect.TypeOf(SendPhoneNumberVerificationTextResponse{}),
+	"UploadImage":                           reflect.TypeOf(UploadImageResponse{}),
+	"SubmitPost":                            reflect.TypeOf(SubmitPostResponse{}),
+	"GetPostsStateless":                     reflect.TypeOf(GetPostsStatelessResponse{}),
+	"UpdateProfile":                         reflect.TypeOf(UpdateProfileResponse{}),
+	"GetProfiles":                           reflect.TypeOf(GetProfilesResponse{}),
+	"GetSingleProfile":                      reflect.TypeOf(GetSingleProfileResponse{}),
+	"GetPostsForPublicKey":                  reflect.TypeOf(GetPostsForPublicKeyResponse{}),
+	"GetDiamondsForPublicKey":               reflect.TypeOf(GetDiamondsForPublicKeyResponse{}),
+	"GetDiamondedPosts":                     reflect.TypeOf(GetPostsDiamondedBySenderForReceiverResponse{}),
+	"GetHodlersForPublicKey":                reflect.TypeOf(GetHodlersForPublicKeyResponse{}),
+	"GetFollowsStateless":                   reflect.TypeOf(GetFollowsResponse{}),
+	"CreateFollowTxnStateless":              reflect.TypeOf(CreateFollowTxnStatelessResponse{}),
+	"CreateLikeStateless":                   reflect.TypeOf(CreateLikeStatelessResponse{}),
+	"BuyOrSellCreatorCoin":                  reflect.TypeOf(BuyOrSellCreatorCoinResponse{}),
+	"TransferCreatorCoin":                   reflect.TypeOf(TransferCreatorCoinResponse{}),
+	"SendDiamonds":                          reflect.TypeOf(SendDiamondsResponse{}),
+	"GetNotifications":                      reflect.TypeOf(GetNotificationsResponse{}),
+	"GetAppState":                           reflect.TypeOf(GetAppStateResponse{}),
+	"UpdateUserGlobalMetadata":              reflect.TypeOf(UpdateUserGlobalMetadataResponse{}),
+	"GetUserGlobalMetadata":                 reflect.TypeOf(GetUserGlobalMetadataResponse{}),
+	"NodeControl":                           reflect.TypeOf(NodeControlResponse{}),
+	"AdminUpdateUserGlobalMetadata":         reflect.TypeOf(AdminUpdateUserGlobalMetadataResponse{}),
+	"AdminGetVerifiedUsers":                 reflect.TypeOf(AdminGetVerifiedUsersResponse{}),
+	"AdminGetUsernameVerificationAuditLogs": reflect.TypeOf(AdminGetUsernameVerificationAuditLogsResponse{}),
+	"AdminGrantVerificationBadge":           reflect.TypeOf(AdminGrantVerificationBadgeResponse{}),
+	"AdminRemoveVerificationBadge":          reflect.TypeOf(AdminRemoveVerificationBadgeResponse{}),
+	"AdminGetAllUserGlobalMetadata":         reflect.TypeOf(AdminGetAllUserGlobalMetadataResponse{}),
+	"AdminGetUserGlobalMetadata":            reflect.TypeOf(AdminGetUserGlobalMetadataResponse{}),
+	"AdminUpdateGlobalFeed":                 reflect.TypeOf(AdminUpdateGlobalFeedResponse{}),
+	"AdminPinPost":                          reflect.TypeOf(AdminPinPostResponse{}),
+	"AdminRemoveNilPosts":                   reflect.TypeOf(AdminRemoveNilPostsResponse{}),
+	"AdminGetGlobalStatePrefixes":           reflect.TypeOf(AdminGetGlobalStatePrefixesResponse{}),
+	"AdminGetGlobalStateEntries":            reflect.TypeOf(AdminGetGlobalStateEntriesResponse{}),
+	"AdminUpdateGlobalStateEntry":           reflect.TypeOf(AdminUpdateGlobalStateEntryResponse{}),
+	"AdminGetGlobalStateAuditLogs":          reflect.TypeOf(AdminGetGlobalStateAuditLogsResponse{}),
+	"AdminGlobalStateFsck":                  reflect.TypeOf(AdminGlobalStateFsckResponse{}),
+	"AdminGetMempoolStats":                  reflect.TypeOf(AdminGetMempoolStatsResponse{}),
+	"SwapIdentity":                          reflect.TypeOf(SwapIdentityResponse{}),
+	"UpdateGlobalParams":                    reflect.TypeOf(UpdateGlobalParamsResponse{}),
+	"GetGlobalParams":                       reflect.TypeOf(GetGlobalParamsResponse{}),
+	"EvictUnminedBitcoinTxns":               reflect.TypeOf(EvictUnminedBitcoinTxnsResponse{}),
+	"AdminRebuildTxindex":                   reflect.TypeOf(AdminRebuildTxindexResponse{}),
+	"AdminGetTxindexRebuildStatus":          reflect.TypeOf(AdminGetTxindexRebuildStatusResponse{}),
+	"AdminGetAdminRoles":                    reflect.TypeOf(AdminGetAdminRolesResponse{}),
+	"AdminUpdateAdminRoles":                 reflect.TypeOf(AdminUpdateAdminRolesResponse{}),
+	"AdminGetAdminAuditLogs":                reflect.TypeOf(AdminGetAdminAuditLogsResponse{}),
+	"GetSinglePost":                         reflect.TypeOf(GetSinglePostResponse{}),
+	"BlockPublicKey":                        reflect.TypeOf(BlockPublicKeyResponse{}),
+	"BlockGetTxn":                           reflect.TypeOf(GetTxnResponse{}),
+	"SendMessageStateless":                  reflect.TypeOf(SendMessageStatelessResponse{}),
+	"GetMessagesStateless":                  reflect.TypeOf(GetMessagesResponse{}),
+	"GetBlockTemplate":                      reflect.TypeOf(GetBlockTemplateResponse{}),
+	"SubmitBlock":                           reflect.TypeOf(SubmitBlockResponse{}),
+	"GetFullTikTokURL":                      reflect.TypeOf(GetFullTikTokURLResponse{}),
+	"APIBase":                               reflect.TypeOf(APIBaseResponse{}),
+	"APIKeyPair":                            reflect.TypeOf(APIKeyPairResponse{}),
+	"APIBalance":                            reflect.TypeOf(APIBalanceResponse{}),
+	"APITransferBitClout":                   reflect.TypeOf(APITransferBitCloutResponse{}),
+	"APITransactionInfo":                    reflect.TypeOf(APITransactionInfoResponse{}),
+	"APINodeInfo":                           reflect.TypeOf(APINodeInfoResponse{}),
+	"APIBlock":                              reflect.TypeOf(APIBlockResponse{}),
+	"GlobalStatePutRemote":                  reflect.TypeOf(GlobalStatePutRemoteResponse{}),
+	"GlobalStateGetRemote":                  reflect.TypeOf(GlobalStateGetRemoteResponse{}),
+	"GlobalStateBatchGetRemote":             reflect.TypeOf(GlobalStateBatchGetRemoteResponse{}),
+	"GlobalStateDeleteRemote":               reflect.TypeOf(GlobalStateDeleteRemoteResponse{}),
+	"GlobalStateSeekRemote":                 reflect.TypeOf(GlobalStateSeekRemoteResponse{}),
+	"GlobalStateCompareAndSwapRemote":       reflect.TypeOf(GlobalStateCompareAndSwapRemoteResponse{}),
+	"GlobalStateChangesRemote":              reflect.TypeOf(GlobalStateChangesRemoteResponse{}),
+}
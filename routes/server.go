@@ -2,19 +2,19 @@ package routes
 
 import (
 	"bytes"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	fmt "fmt"
-	"github.com/btcsuite/btcd/btcec"
-	"github.com/dgrijalva/jwt-go/v4"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bitclout/core/lib"
-	chainlib "github.com/btcsuite/btcd/blockchain"
 	"github.com/dgraph-io/badger/v3"
 	"github.com/golang/glog"
 	"github.com/kevinburke/twilio-go"
@@ -34,6 +34,12 @@ const (
 )
 
 const (
+	// metrics.go
+	RoutePathMetrics = "/metrics"
+
+	// openapi.go
+	RoutePathGetOpenAPISpec = "/api/v0/openapi.json"
+
 	// base.go
 	RoutePathHealthCheck              = "/api/v0/health-check"
 	RoutePathGetExchangeRate          = "/api/v0/get-exchange-rate"
@@ -89,13 +95,15 @@ const (
 	RoutePathGetBlockTemplate = "/api/v0/get-block-template"
 	RoutePathSubmitBlock      = "/api/v0/submit-block"
 
-	// Admin route paths can only be accessed if a user's public key is whitelisted as an admin.
+	// Admin route paths can only be accessed by admins that have the route's RequiredRole.
 
 	// admin_node.go
 	RoutePathNodeControl                           = "/api/v0/admin/node-control"
 	RoutePathReprocessBitcoinBlock                 = "/api/v0/admin/reprocess-bitcoin-block"
 	RoutePathAdminGetMempoolStats                  = "/api/v0/admin/get-mempool-stats"
 	RoutePathEvictUnminedBitcoinTxns               = "/api/v0/admin/evict-unmined-bitcoin-txns"
+	RoutePathAdminRebuildTxindex                   = "/api/v0/admin/rebuild-txindex"
+	RoutePathAdminGetTxindexRebuildStatus          = "/api/v0/admin/get-txindex-rebuild-status"
 
 	// admin_transaction.go
 	RoutePathGetGlobalParams                       = "/api/v0/admin/get-global-params"
@@ -115,6 +123,20 @@ const (
 	RoutePathAdminUpdateGlobalFeed                 = "/api/v0/admin/update-global-feed"
 	RoutePathAdminPinPost                          = "/api/v0/admin/pin-post"
 	RoutePathAdminRemoveNilPosts                   = "/api/v0/admin/remove-nil-posts"
+
+	// admin_global_state.go
+	RoutePathAdminGetGlobalStatePrefixes           = "/api/v0/admin/get-global-state-prefixes"
+	RoutePathAdminGetGlobalStateEntries            = "/api/v0/admin/get-global-state-entries"
+	RoutePathAdminUpdateGlobalStateEntry           = "/api/v0/admin/update-global-state-entry"
+	RoutePathAdminGetGlobalStateAuditLogs          = "/api/v0/admin/get-global-state-audit-logs"
+	RoutePathAdminGlobalStateFsck                  = "/api/v0/admin/global-state-fsck"
+
+	// admin_roles.go
+	RoutePathAdminGetAdminRoles                    = "/api/v0/admin/get-admin-roles"
+	RoutePathAdminUpdateAdminRoles                 = "/api/v0/admin/update-admin-roles"
+
+	// admin_audit_log.go
+	RoutePathAdminGetAdminAuditLogs                = "/api/v0/admin/get-admin-audit-logs"
 )
 
 // APIServer provides the interface between the blockchain and things like the
@@ -152,14 +174,26 @@ type APIServer struct {
 	// with the goings-on of the main chain.
 	TxIndexChain *lib.Blockchain
 
-	// Used for getting/setting the global state. Usually either a db is set OR
-	// a remote node is set-- not both. When a remote node is set, global state
-	// is set and fetched from that node. Otherwise, it is set/fetched from the
-	// db. This makes it easy to run a local node in development.
-	GlobalStateDB                     *badger.DB
+	// Used for getting/setting the global state. Usually this is either a
+	// BadgerGlobalStore backed by a local db OR a RemoteGlobalStore that proxies
+	// to another node. When a remote node is set, global state is set and fetched
+	// from that node. Otherwise, it is set/fetched from the db. This makes it easy
+	// to run a local node in development.
+	GlobalStore                       GlobalStore
 	GlobalStateRemoteNode             string
 	GlobalStateRemoteNodeSharedSecret string
 
+	// Requests to our own /api/v1/global-state/* routes are accepted if they are
+	// signed with either GlobalStateRemoteNodeSharedSecret or this secret. Setting
+	// this to the old secret allows secrets to be rotated without downtime.
+	GlobalStatePreviousSharedSecret string
+	// Nonces from recently accepted global state requests. Used to reject replays.
+	globalStateNonceCache *nonceCache
+
+	// Optional. When set, email addresses and phone numbers are encrypted before
+	// they're written to global state and phone number keys are blinded.
+	PIIKeyring *PIIKeyring
+
 	AccessControlAllowOrigins           []string
 	SecureHeaderMiddlewareIsDevelopment bool
 	SecureHeaderMiddlewareAllowedHost   []string
@@ -192,8 +226,66 @@ type APIServer struct {
 	// Optional. If true and twilio and starter bitclout seed configured, node will comp profile creation.
 	IsCompProfileCreation bool
 
-	// Optional, restricts access to the admin panel to these public keys
+	// Optional. These public keys are superadmins. Other admins are granted roles
+	// in global state, see GetAdminRoles.
 	AdminPublicKeys []string
+	// When set, anyone can call the admin routes. Otherwise only admins with a
+	// route's RequiredRole can.
+	AdminOpenAccess bool
+
+	// JWTs must expire within this long of being checked. Defaults to
+	// DefaultJWTMaxLifetime.
+	JWTMaxLifetime time.Duration
+	// JWTs must have one of these as their aud claim, which stops tokens signed
+	// for another node from being used on this one. When empty, no JWT is accepted.
+	JWTAudiences []string
+	// The jti claims of admin JWTs that have been used and haven't expired yet.
+	adminJWTNonceCache nonceCache
+
+	// Optional. When set, routes with a RouteRateLimit reject clients that make
+	// too many requests.
+	RateLimiter *RateLimiter
+
+	// Optional. When set, requests are written to a structured access log.
+	AccessLogger *AccessLogger
+
+	// Pages of the global feed, the by-clout feed and the leaderboard, shared by
+	// all readers.
+	feedCache feedCache
+	// Limits how many rejected admin requests are added to the audit log.
+	adminAuditLogRejections adminAuditLogRejections
+	// Bumped every time the node says a transaction entered or a block changed
+	// the mempool. Part of chainStateVersion. Read and written atomically.
+	mempoolVersion uint64
+
+	// Optional. When set, the API is served over TLS with these certificates.
+	TLSCertificates *CertificateReloader
+	// When set, the routes in ClientCertificateRoutePrefixes can only be called
+	// with a client certificate signed by TLSCertificates' CA bundle.
+	RequireClientCertificate bool
+
+	// Optional. How long Stop keeps serving requests after HealthCheck starts
+	// failing, so load balancers have time to take the node out of rotation.
+	ShutdownDelay time.Duration
+
+	// lifecycleLock protects the fields below, which are set by Start and Stop.
+	lifecycleLock sync.Mutex
+	httpServer    *http.Server
+	// The address the server is listening on, once it is.
+	listenAddr net.Addr
+	// Closed by Stop to stop the txindex loop.
+	txindexQuit chan struct{}
+	// Closed when the txindex loop has returned.
+	txindexLoopDone chan struct{}
+	// Tells the txindex loop that the block tip may have moved. It holds a single
+	// signal, so however many blocks arrive during an update only one more update
+	// runs after it, and notifying never blocks the caller.
+	txindexUpdateSignal chan struct{}
+	// The txindex rebuild started by RebuildTxindex or AdminRebuildTxindex.
+	txindexRebuild txindexRebuild
+	// Set to 1 by Stop. Read atomically so HealthCheck and UpdateTxindex don't
+	// need the lock.
+	isStopping int32
 }
 
 // NewAPIServer ...
@@ -208,9 +300,11 @@ func NewAPIServer(_backendServer *lib.Server,
 	_starterBitCloutSeed string,
 	_starterBitCloutAmountNanos uint64,
 	_starterBitCloutPrefixExceptionMap map[string]uint64,
-	globalStateDB *badger.DB,
+	globalStore GlobalStore,
 	globalStateRemoteNode string,
 	globalStateRemoteNodeSharedSecret string,
+	globalStatePreviousSharedSecret string,
+	piiKeyring *PIIKeyring,
 	accessControlAllowOrigins []string,
 	secureHeaderMiddlewareIsDevelopment bool,
 	secureHeaderMiddlewareAllowedHost []string,
@@ -226,122 +320,36 @@ func NewAPIServer(_backendServer *lib.Server,
 	googleBucketName string,
 	compProfileCreation bool,
 	adminPublicKeys []string,
+	rateLimiter *RateLimiter,
+	accessLogger *AccessLogger,
 ) (*APIServer, error) {
 
 	var txIndexChain *lib.Blockchain
 	if _txindexDB != nil {
-		// See if we have a best chain hash stored in the txindex db.
-		bestBlockHashBeforeInit := lib.DbGetBestHash(_txindexDB, lib.ChainTypeBitCloutBlock)
-
-		// If we haven't initialized the txIndexChain before, set up the
-		// seed mappings.
-		if bestBlockHashBeforeInit == nil {
-
-			// Add the seed balances. Originate them from the architect public key and
-			// set their block as the genesis block.
-			{
-				dummyPk := lib.ArchitectPubKeyBase58Check
-				dummyTxn := &lib.MsgBitCloutTxn{
-					TxInputs:  []*lib.BitCloutInput{},
-					TxOutputs: params.SeedBalances,
-					TxnMeta:   &lib.BlockRewardMetadataa{},
-					PublicKey: lib.MustBase58CheckDecode(dummyPk),
-				}
-				affectedPublicKeys := []*lib.AffectedPublicKey{}
-				totalOutput := uint64(0)
-				for _, seedBal := range params.SeedBalances {
-					affectedPublicKeys = append(affectedPublicKeys, &lib.AffectedPublicKey{
-						PublicKeyBase58Check: lib.PkToString(seedBal.PublicKey, params),
-						Metadata:             "GenesisBlockSeedBalance",
-					})
-					totalOutput += seedBal.AmountNanos
-				}
-				err := lib.DbPutTxindexTransactionMappings(_txindexDB, dummyTxn, params, &lib.TransactionMetadata{
-					TransactorPublicKeyBase58Check: dummyPk,
-					AffectedPublicKeys:             affectedPublicKeys,
-					BlockHashHex:                   lib.GenesisBlockHashHex,
-					TxnIndexInBlock:                uint64(0),
-					// Just set some dummy metadata
-					BasicTransferTxindexMetadata: &lib.BasicTransferTxindexMetadata{
-						TotalInputNanos:  0,
-						TotalOutputNanos: totalOutput,
-						FeeNanos:         0,
-					},
-				})
-				if err != nil {
-					return nil, fmt.Errorf("NewAPIServer: Error initializing seed balances in txindex: %v", err)
-				}
-			}
-
-			// Add the other seed txns to the txn index.
-			for txnIndex, txnHex := range params.SeedTxns {
-				txnBytes, err := hex.DecodeString(txnHex)
-				if err != nil {
-					return nil, fmt.Errorf(
-						"NewAPIServer: Error decoding seed "+
-							"txn HEX: %v, txn index: %v, txn hex: %v",
-						err, txnIndex, txnHex)
-				}
-				txn := &lib.MsgBitCloutTxn{}
-				if err := txn.FromBytes(txnBytes); err != nil {
-					return nil, fmt.Errorf(
-						"NewAPIServer: Error decoding seed "+
-							"txn BYTES: %v, txn index: %v, txn hex: %v",
-						err, txnIndex, txnHex)
-				}
-				err = lib.DbPutTxindexTransactionMappings(_txindexDB, txn, params, &lib.TransactionMetadata{
-					TransactorPublicKeyBase58Check: lib.PkToString(txn.PublicKey, params),
-					// Note that we don't set AffectedPublicKeys for the SeedTxns
-					BlockHashHex:    lib.GenesisBlockHashHex,
-					TxnIndexInBlock: uint64(0),
-					// Just set some dummy metadata
-					BasicTransferTxindexMetadata: &lib.BasicTransferTxindexMetadata{
-						TotalInputNanos:  0,
-						TotalOutputNanos: 0,
-						FeeNanos:         0,
-					},
-				})
-				if err != nil {
-					return nil, fmt.Errorf("NewAPIServer: Error initializing seed txn %v in txindex: %v", txn, err)
-				}
-			}
-		}
-
-		// Ignore all the notifications from the txindex blockchain object
-		txIndexBlockchainNotificationChan := make(chan *lib.ServerMessage, 1000)
-		go func() {
-			for {
-				<-txIndexBlockchainNotificationChan
-			}
-		}()
-
-		// This blockchain object should already be initialized.
-		var err error
 		// Only set a BitcoinManager if we have one. This makes some tests pass.
 		var bitcoinManager *lib.BitcoinManager
 		if _backendServer != nil && _backendServer.GetBitcoinManager() != nil {
 			bitcoinManager = _backendServer.GetBitcoinManager()
 		}
-		// Note that we *DONT* pass _backendServer here because the server
-		// is already tied to the to the main blockchain.
-		txIndexChain, err = lib.NewBlockchain(
-			[]string{}, 0,
-			params, chainlib.NewMedianTime(), _txindexDB,
-			bitcoinManager, nil)
+		// At this point, we should have set up a blockchain object for our
+		// txindex, and initialized all of the seed txns and seed balances
+		// correctly. Attaching blocks to our txnindex blockchain or adding
+		// txns to our txindex should work smoothly now.
+		var err error
+		txIndexChain, err = NewTxindexChain(_txindexDB, params, bitcoinManager)
 		if err != nil {
 			return nil, fmt.Errorf(
 				"NewAPIServer: Error initializing TxIndex on APIServer: %v", err)
 		}
+	}
 
-		// At this point, we should have set up a blockchain object for our
-		// txindex, and initialized all of the seed txns and seed balances
-		// correctly. Attaching blocks to our txnindex blockchain or adding
-		// txns to our txindex should work smoothly now.
+	if err := validateGlobalStore(globalStore); err != nil {
+		return nil, fmt.Errorf("NewAPIServer: Error: %v", err)
 	}
 
-	if globalStateDB == nil && globalStateRemoteNode == "" {
-		return nil, fmt.Errorf(
-			"NewAPIServer: Error: A globalStateDB or a globalStateRemoteNode is required")
+	// Catch two global state prefixes sharing the same ID before we touch any data.
+	if err := ValidateGlobalStatePrefixes(); err != nil {
+		return nil, fmt.Errorf("NewAPIServer: %v", err)
 	}
 
 	fes := &APIServer{
@@ -360,9 +368,13 @@ func NewAPIServer(_backendServer *lib.Server,
 		StarterBitCloutSeed:                 _starterBitCloutSeed,
 		StarterBitCloutAmountNanos:          _starterBitCloutAmountNanos,
 		StarterBitCloutPrefixExceptionMap:   _starterBitCloutPrefixExceptionMap,
-		GlobalStateDB:                       globalStateDB,
+		GlobalStore:                         globalStore,
 		GlobalStateRemoteNode:               globalStateRemoteNode,
 		GlobalStateRemoteNodeSharedSecret:   globalStateRemoteNodeSharedSecret,
+		GlobalStatePreviousSharedSecret:     globalStatePreviousSharedSecret,
+		globalStateNonceCache:               &nonceCache{},
+		txindexUpdateSignal:                 make(chan struct{}, 1),
+		PIIKeyring:                          piiKeyring,
 		AccessControlAllowOrigins:           accessControlAllowOrigins,
 		SecureHeaderMiddlewareIsDevelopment: secureHeaderMiddlewareIsDevelopment,
 		SecureHeaderMiddlewareAllowedHost:   secureHeaderMiddlewareAllowedHost,
@@ -378,6 +390,8 @@ func NewAPIServer(_backendServer *lib.Server,
 		GoogleBucketName:                    googleBucketName,
 		IsCompProfileCreation:               compProfileCreation,
 		AdminPublicKeys:                     adminPublicKeys,
+		RateLimiter:                         rateLimiter,
+		AccessLogger:                        accessLogger,
 	}
 
 	return fes, nil
@@ -385,25 +399,28 @@ func NewAPIServer(_backendServer *lib.Server,
 
 // Route ...
 type Route struct {
-	Name           string
-	Method         []string
-	Pattern        string
-	HandlerFunc    http.HandlerFunc
-	CheckPublicKey bool
+	Name        string
+	Method      []string
+	Pattern     string
+	HandlerFunc http.HandlerFunc
+	// The role an admin needs to call the route. AdminRoleNone for routes that
+	// aren't admin routes.
+	RequiredRole AdminRole
 }
 
-// InitRoutes ...
+// FrontendRoutes are the routes used by the frontend.
 // Note: Be very careful when editing existing routes in this list.
 // This *must* be kept in-sync with the backend-api.service.ts file in the
-// frontend code. If not, then requests will fail.
-func (fes *APIServer) NewRouter() *muxtrace.Router {
+// frontend code. If not, then requests will fail. The OpenAPI spec checked in
+// as openapi.json describes these routes, see BuildOpenAPISpec.
+func (fes *APIServer) FrontendRoutes() []Route {
 	var FrontendRoutes = []Route{
 		{
 			"Index",
 			[]string{"GET"},
 			"/",
 			fes.Index,
-			false,
+			AdminRoleNone,
 		},
 
 		{
@@ -411,7 +428,21 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"GET"},
 			RoutePathHealthCheck,
 			fes.HealthCheck,
-			false,
+			AdminRoleNone,
+		},
+		{
+			"Metrics",
+			[]string{"GET"},
+			RoutePathMetrics,
+			fes.Metrics,
+			AdminRoleNone,
+		},
+		{
+			"GetOpenAPISpec",
+			[]string{"GET"},
+			RoutePathGetOpenAPISpec,
+			fes.GetOpenAPISpec,
+			AdminRoleNone,
 		},
 
 		// Routes for populating various UI elements.
@@ -420,7 +451,7 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"GET"},
 			RoutePathGetExchangeRate,
 			fes.GetExchangeRate,
-			false,
+			AdminRoleNone,
 		},
 
 		// Route for sending BitClout
@@ -429,7 +460,7 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathSendBitClout,
 			fes.SendBitClout,
-			false,
+			AdminRoleNone,
 		},
 
 		// Route for burning Bitcoin for BitClout
@@ -438,7 +469,7 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathBurnBitcoin,
 			fes.BurnBitcoinStateless,
-			false,
+			AdminRoleNone,
 		},
 
 		// Route for submitting signed transactions for network broadcast
@@ -447,7 +478,7 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathSubmitTransaction,
 			fes.SubmitTransaction,
-			false,
+			AdminRoleNone,
 		},
 
 		// Temporary route to wipe seedinfo cookies
@@ -456,16 +487,16 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathDeleteIdentities,
 			fes.DeleteIdentities,
-			false,
+			AdminRoleNone,
 		},
 
 		// Endpoint to trigger the reprocessing of a particular Bitcoin block.
 		{
 			"ReprocessBitcoinBlock",
-			[]string{"GET"},
-			RoutePathReprocessBitcoinBlock + "/{blockHashHexOrblockHeight:[0-9abcdefABCDEF]+}",
+			[]string{"POST", "OPTIONS"},
+			RoutePathReprocessBitcoinBlock,
 			fes.ReprocessBitcoinBlock,
-			false,
+			AdminRoleNodeOperator,
 		},
 		// Endpoint to trigger granting a user a verified badge
 
@@ -475,35 +506,35 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetUsersStateless,
 			fes.GetUsersStateless,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"SendPhoneNumberVerificationText",
 			[]string{"POST", "OPTIONS"},
 			RoutePathSendPhoneNumberVerificationText,
 			fes.SendPhoneNumberVerificationText,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"SubmitPhoneNumberVerificationCode",
 			[]string{"POST", "OPTIONS"},
 			RoutePathSubmitPhoneNumberVerificationCode,
 			fes.SubmitPhoneNumberVerificationCode,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"UploadImage",
 			[]string{"POST", "OPTIONS"},
 			RoutePathUploadImage,
 			fes.UploadImage,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"SubmitPost",
 			[]string{"POST", "OPTIONS"},
 			RoutePathSubmitPost,
 			fes.SubmitPost,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetPostsStateless",
@@ -511,14 +542,14 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			RoutePathGetPostsStateless,
 			fes.GetPostsStateless,
 			// CheckSecret: No need to check the secret since this is a read-only endpoint.
-			false,
+			AdminRoleNone,
 		},
 		{
 			"UpdateProfile",
 			[]string{"POST", "OPTIONS"},
 			RoutePathUpdateProfile,
 			fes.UpdateProfile,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetProfiles",
@@ -526,112 +557,112 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			RoutePathGetProfiles,
 			fes.GetProfiles,
 			// CheckSecret: No need to check the secret since this is a read-only endpoint.
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetSingleProfile",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetSingleProfile,
 			fes.GetSingleProfile,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetPostsForPublicKey",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetPostsForPublicKey,
 			fes.GetPostsForPublicKey,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetDiamondsForPublicKey",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetDiamondsForPublicKey,
 			fes.GetDiamondsForPublicKey,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetDiamondedPosts",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetDiamondedPosts,
 			fes.GetDiamondedPosts,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetHodlersForPublicKey",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetHodlersForPublicKey,
 			fes.GetHodlersForPublicKey,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetFollowsStateless",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetFollowsStateless,
 			fes.GetFollowsStateless,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"CreateFollowTxnStateless",
 			[]string{"POST", "OPTIONS"},
 			RoutePathCreateFollowTxnStateless,
 			fes.CreateFollowTxnStateless,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"CreateLikeStateless",
 			[]string{"POST", "OPTIONS"},
 			RoutePathCreateLikeStateless,
 			fes.CreateLikeStateless,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"BuyOrSellCreatorCoin",
 			[]string{"POST", "OPTIONS"},
 			RoutePathBuyOrSellCreatorCoin,
 			fes.BuyOrSellCreatorCoin,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"TransferCreatorCoin",
 			[]string{"POST", "OPTIONS"},
 			RoutePathTransferCreatorCoin,
 			fes.TransferCreatorCoin,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"SendDiamonds",
 			[]string{"POST", "OPTIONS"},
 			RoutePathSendDiamonds,
 			fes.SendDiamonds,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetNotifications",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetNotifications,
 			fes.GetNotifications,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetAppState",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetAppState,
 			fes.GetAppState,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"UpdateUserGlobalMetadata",
 			[]string{"POST", "OPTIONS"},
 			RoutePathUpdateUserGlobalMetadata,
 			fes.UpdateUserGlobalMetadata,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetUserGlobalMetadata",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetUserGlobalMetadata,
 			fes.GetUserGlobalMetadata,
-			false,
+			AdminRoleNone,
 		},
 
 		// Begin all /admin routes
@@ -642,112 +673,182 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathNodeControl,
 			fes.NodeControl,
-			true,
+			AdminRoleNodeOperator,
 		},
 		{
 			"AdminUpdateUserGlobalMetadata",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminUpdateUserGlobalMetadata,
 			fes.AdminUpdateUserGlobalMetadata,
-			true,
+			AdminRoleModerator,
 		},
 		{
 			"AdminGetVerifiedUsers",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminGetVerifiedUsers,
 			fes.AdminGetVerifiedUsers,
-			true, // Check Secret
+			AdminRoleVerifier,
 		},
 		{
 			"AdminGetUsernameVerificationAuditLogs",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminGetUsernameVerificationAuditLogs,
 			fes.AdminGetUsernameVerificationAuditLogs,
-			true, // Check Secret
+			AdminRoleVerifier,
 		},
 		{
 			"AdminGrantVerificationBadge",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminGrantVerificationBadge,
 			fes.AdminGrantVerificationBadge,
-			true, // Check Secret
+			AdminRoleVerifier,
 		},
 		{
 			"AdminRemoveVerificationBadge",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminRemoveVerificationBadge,
 			fes.AdminRemoveVerificationBadge,
-			true, // Check Secret
+			AdminRoleVerifier,
 		},
 		{
 			"AdminGetAllUserGlobalMetadata",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminGetAllUserGlobalMetadata,
 			fes.AdminGetAllUserGlobalMetadata,
-			true,
+			AdminRoleModerator,
 		},
 		{
 			"AdminGetUserGlobalMetadata",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminGetUserGlobalMetadata,
 			fes.AdminGetUserGlobalMetadata,
-			true,
+			AdminRoleModerator,
 		},
 		{
 			"AdminUpdateGlobalFeed",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminUpdateGlobalFeed,
 			fes.AdminUpdateGlobalFeed,
-			true,
+			AdminRoleFeedCurator,
 		},
 		{
 			"AdminPinPost",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminPinPost,
 			fes.AdminPinPost,
-			true, // CheckSecret
+			AdminRoleFeedCurator,
 		},
 		{
 			"AdminRemoveNilPosts",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminRemoveNilPosts,
 			fes.AdminRemoveNilPosts,
-			true,
+			AdminRoleFeedCurator,
+		},
+		{
+			"AdminGetGlobalStatePrefixes",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminGetGlobalStatePrefixes,
+			fes.AdminGetGlobalStatePrefixes,
+			AdminRoleSuperadmin,
+		},
+		{
+			"AdminGetGlobalStateEntries",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminGetGlobalStateEntries,
+			fes.AdminGetGlobalStateEntries,
+			AdminRoleSuperadmin,
+		},
+		{
+			"AdminUpdateGlobalStateEntry",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminUpdateGlobalStateEntry,
+			fes.AdminUpdateGlobalStateEntry,
+			AdminRoleSuperadmin,
+		},
+		{
+			"AdminGetGlobalStateAuditLogs",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminGetGlobalStateAuditLogs,
+			fes.AdminGetGlobalStateAuditLogs,
+			AdminRoleSuperadmin,
+		},
+		{
+			"AdminGlobalStateFsck",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminGlobalStateFsck,
+			fes.AdminGlobalStateFsck,
+			AdminRoleSuperadmin,
 		},
 		{
 			"AdminGetMempoolStats",
 			[]string{"POST", "OPTIONS"},
 			RoutePathAdminGetMempoolStats,
 			fes.AdminGetMempoolStats,
-			true,
+			AdminRoleNodeOperator,
 		},
 		{
 			"SwapIdentity",
 			[]string{"POST", "OPTIONS"},
 			RoutePathSwapIdentity,
 			fes.SwapIdentity,
-			true,
+			AdminRoleSuperadmin,
 		},
 		{
 			"UpdateGlobalParams",
 			[]string{"POST", "OPTIONS"},
 			RoutePathUpdateGlobalParams,
 			fes.UpdateGlobalParams,
-			true,
+			AdminRoleSuperadmin,
 		},
 		{
 			"GetGlobalParams",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetGlobalParams,
 			fes.GetGlobalParams,
-			true,
+			AdminRoleNodeOperator,
 		},
 		{
 			"EvictUnminedBitcoinTxns",
 			[]string{"POST", "OPTIONS"},
 			RoutePathEvictUnminedBitcoinTxns,
 			fes.EvictUnminedBitcoinTxns,
-			true,
+			AdminRoleNodeOperator,
+		},
+		{
+			"AdminRebuildTxindex",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminRebuildTxindex,
+			fes.AdminRebuildTxindex,
+			AdminRoleNodeOperator,
+		},
+		{
+			"AdminGetTxindexRebuildStatus",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminGetTxindexRebuildStatus,
+			fes.AdminGetTxindexRebuildStatus,
+			AdminRoleNodeOperator,
+		},
+		{
+			"AdminGetAdminRoles",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminGetAdminRoles,
+			fes.AdminGetAdminRoles,
+			AdminRoleSuperadmin,
+		},
+		{
+			"AdminUpdateAdminRoles",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminUpdateAdminRoles,
+			fes.AdminUpdateAdminRoles,
+			AdminRoleSuperadmin,
+		},
+		{
+			"AdminGetAdminAuditLogs",
+			[]string{"POST", "OPTIONS"},
+			RoutePathAdminGetAdminAuditLogs,
+			fes.AdminGetAdminAuditLogs,
+			AdminRoleSuperadmin,
 		},
 		// End all /admin routes
 
@@ -756,21 +857,21 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetSinglePost,
 			fes.GetSinglePost,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"BlockPublicKey",
 			[]string{"POST", "OPTIONS"},
 			RoutePathBlockPublicKey,
 			fes.BlockPublicKey,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"BlockGetTxn",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetTxn,
 			fes.GetTxn,
-			false,
+			AdminRoleNone,
 		},
 
 		// message.go
@@ -779,28 +880,28 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathSendMessageStateless,
 			fes.SendMessageStateless,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"GetMessagesStateless",
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetMessagesStateless,
 			fes.GetMessagesStateless,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"MarkContactMessagesRead",
 			[]string{"POST", "OPTIONS"},
 			RoutePathMarkContactMessagesRead,
 			fes.MarkContactMessagesRead,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"MarkAllMessagesRead",
 			[]string{"POST", "OPTIONS"},
 			RoutePathMarkAllMessagesRead,
 			fes.MarkAllMessagesRead,
-			false,
+			AdminRoleNone,
 		},
 
 		// Paths for the mining pool
@@ -809,14 +910,14 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetBlockTemplate,
 			fes.GetBlockTemplate,
-			false,
+			AdminRoleNone,
 		},
 		{
 			"SubmitBlock",
 			[]string{"POST", "OPTIONS"},
 			RoutePathSubmitBlock,
 			fes.SubmitBlock,
-			false,
+			AdminRoleNone,
 		},
 
 		{
@@ -824,10 +925,23 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 			[]string{"POST", "OPTIONS"},
 			RoutePathGetFullTikTokURL,
 			fes.GetFullTikTokURL,
-			false,
+			AdminRoleNone,
 		},
 	}
+	return FrontendRoutes
+}
 
+// AllRoutes returns every route the server serves.
+func (fes *APIServer) AllRoutes() []Route {
+	// We serve multiple groups of routes from this endpoint.
+	fullRouteList := append([]Route{}, fes.FrontendRoutes()...)
+	fullRouteList = append(fullRouteList, fes.APIRoutes()...)
+	fullRouteList = append(fullRouteList, fes.GlobalStateRoutes()...)
+	return fullRouteList
+}
+
+// InitRoutes ...
+func (fes *APIServer) NewRouter() *muxtrace.Router {
 	router := muxtrace.NewRouter().StrictSlash(true)
 
 	// Set secure headers
@@ -838,12 +952,7 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 	)
 	router.Use(secureMiddleware.Handler)
 
-	// We serve multiple groups of routes from this endpoint.
-	fullRouteList := append([]Route{}, FrontendRoutes...)
-	fullRouteList = append(fullRouteList, fes.APIRoutes()...)
-	fullRouteList = append(fullRouteList, fes.GlobalStateRoutes()...)
-
-	for _, route := range fullRouteList {
+	for _, route := range fes.AllRoutes() {
 		var handler http.Handler
 
 		handler = route.HandlerFunc
@@ -855,11 +964,35 @@ func (fes *APIServer) NewRouter() *muxtrace.Router {
 		// then A will be called first B will be called second, and C will be called
 		// last.
 
-		// Anyone can access the admin panel if no public keys exist
-		if route.CheckPublicKey && len(fes.AdminPublicKeys) > 0 {
-			handler = fes.CheckAdminPublicKey(handler)
+		if ETagRoutes[route.Name] {
+			handler = fes.CheckETag(handler, route.Name)
+		}
+		// Admin routes are closed unless the operator opted into open access.
+		if route.RequiredRole != AdminRoleNone && !fes.AdminOpenAccess {
+			handler = fes.CheckAdminPublicKey(handler, route.RequiredRole)
+		}
+		// The audit log runs before CheckAdminPublicKey so that it sees rejected
+		// requests too.
+		if route.RequiredRole != AdminRoleNone {
+			handler = fes.AdminAuditLog(handler, route.Name)
+		}
+		if fes.RequireClientCertificate && routeRequiresClientCertificate(route) {
+			handler = fes.CheckClientCertificate(handler)
 		}
-		handler = Logger(handler, route.Name)
+		// Rate limiting runs after AddHeaders so that preflight requests aren't
+		// counted and rejected requests still get CORS headers.
+		if fes.RateLimiter != nil {
+			handler = fes.RateLimiter.RateLimit(handler, route.Name)
+		}
+		// Compression runs inside logging and instrumentation so that they see the
+		// size of the response as it was sent.
+		handler = Compress(handler)
+		if fes.AccessLogger != nil {
+			handler = fes.AccessLogger.AccessLog(handler, route.Name)
+		} else {
+			handler = Logger(handler, route.Name)
+		}
+		handler = InstrumentRoute(handler, route.Name)
 		handler = AddHeaders(handler, fes.AccessControlAllowOrigins)
 
 		router.
@@ -936,7 +1069,8 @@ func AddHeaders(inner http.Handler, allowedOrigins []string) http.Handler {
 			w.Header().Add("Access-Control-Allow-Credentials", "true")
 
 			w.Header().Set("Access-Control-Allow-Origin", actualOrigin)
-			w.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept")
+			w.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, If-None-Match")
+			w.Header().Set("Access-Control-Expose-Headers", "ETag")
 			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, DELETE, OPTIONS")
 		}
 		// Otherwise, don't add any headers. This should make a CORS request fail.
@@ -967,13 +1101,14 @@ type AdminRequest struct {
 	AdminPublicKey string
 }
 
-// CheckSecret ...
-func (fes *APIServer) CheckAdminPublicKey(inner http.Handler) http.Handler {
+// CheckAdminPublicKey only serves requests signed by an admin that has
+// requiredRole.
+func (fes *APIServer) CheckAdminPublicKey(inner http.Handler, requiredRole AdminRole) http.Handler {
 	return http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
 		requestData := AdminRequest{}
 
 		if req.Body == nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 				"CheckAdminPublicKey: Request has no Body attribute"))
 			return
 		}
@@ -982,7 +1117,7 @@ func (fes *APIServer) CheckAdminPublicKey(inner http.Handler) http.Handler {
 		// from the bytes we read because you can only read the body once
 		bodyBytes, err := ioutil.ReadAll(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("CheckAdminPublicKey: %v", err))
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("CheckAdminPublicKey: %v", err))
 			return
 		}
 
@@ -990,76 +1125,180 @@ func (fes *APIServer) CheckAdminPublicKey(inner http.Handler) http.Handler {
 		decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
 		err = decoder.Decode(&requestData)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 				"CheckAdminPublicKey: Problem parsing request body: %v", err))
 			return
 		}
 
 		if requestData.AdminPublicKey == "" {
-			_AddBadRequestError(ww, "CheckAdminPublicKey: Missing AdminPublicKey param")
+			_AddCodedError(ww, ErrorCodeBadRequest, "CheckAdminPublicKey: Missing AdminPublicKey param")
 			return
 		}
 
-		isValid, err := fes.ValidateJWT(requestData.AdminPublicKey, requestData.JWT)
+		isValid, err := fes.ValidateAdminJWT(requestData.AdminPublicKey, requestData.JWT)
 		if !isValid {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeInvalidJWT, fmt.Sprintf(
 				"CheckAdminPublicKey: Invalid token: %v", err))
 			return
 		}
 
-		for _, adminPubKey := range fes.AdminPublicKeys {
-			if adminPubKey == requestData.AdminPublicKey {
-				// We found a match, serve the request
-				inner.ServeHTTP(ww, req)
-				return
-			}
+		roles, err := fes.GetAdminRoles(requestData.AdminPublicKey)
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("CheckAdminPublicKey: Problem getting roles: %v", err))
+			return
+		}
+		if !hasAdminRole(roles, requiredRole) {
+			_AddAPIError(ww, NewAPIError(ErrorCodeForbidden, fmt.Sprintf(
+				"CheckAdminPublicKey: This route requires the %v role", requiredRole)).
+				WithDetail("RequiredRole", requiredRole))
+			return
 		}
 
-		_AddBadRequestError(ww, "CheckAdminPublicKey: Not an admin")
+		markAdminCheckPassed(req)
+		inner.ServeHTTP(ww, req)
 	})
 }
 
-func (fes *APIServer) ValidateJWT(publicKey string, jwtToken string) (bool, error) {
-	pubKeyBytes, _, err := lib.Base58CheckDecode(publicKey)
-	if err != nil {
-		return false, err
+// Start serves the API on JSONPort and, if --txindex was passed, keeps the
+// txindex up to date in the background. It blocks until Stop is called.
+func (fes *APIServer) Start() {
+	fes.initState()
+
+	fes.lifecycleLock.Lock()
+	if fes.IsStopping() {
+		fes.lifecycleLock.Unlock()
+		glog.Info("APIServer.Start: Not starting APIServer because Stop was already called")
+		return
 	}
 
-	pubKey, err := btcec.ParsePubKey(pubKeyBytes, btcec.S256())
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", fes.JSONPort))
 	if err != nil {
-		return false, err
+		fes.lifecycleLock.Unlock()
+		glog.Errorf("APIServer.Start: Problem listening on port %d: %v", fes.JSONPort, err)
+		return
 	}
-
-	token, err := jwt.Parse(jwtToken, func(token *jwt.Token) (interface{}, error) {
-		return pubKey.ToECDSA(), nil
-	})
-
-	return token.Valid, err
-}
-
-// Start ...
-func (fes *APIServer) Start() {
-	fes.initState()
+	fes.listenAddr = listener.Addr()
+	fes.httpServer = &http.Server{Handler: fes.router}
+	if fes.TLSCertificates != nil {
+		fes.httpServer.TLSConfig = fes.TLSCertificates.ServerTLSConfig()
+	}
+	httpServer := fes.httpServer
 
 	if fes.TxIndexChain != nil {
 		glog.Info("Starting txindex update thread because --txindex was passed. " +
 			"Waiting for node to fully sync...")
 
-		// Run a loop to continuously update the txindex. Note that this is a noop
-		// except when run the first time or when a new block has arrived.
-		go func() {
-			for {
-				fes.tryUpdateTxindex()
-				time.Sleep(1 * time.Second)
-			}
-		}()
+		fes.txindexQuit = make(chan struct{})
+		fes.txindexLoopDone = make(chan struct{})
+		go fes.runTxindexLoop(fes.txindexQuit, fes.txindexLoopDone)
 	} else {
 		glog.Info("NOT starting txindex update thread because --txindex was NOT " +
 			"passed. This means some API endpoints that rely on --txindex will not work.")
 	}
+	fes.lifecycleLock.Unlock()
+
+	if httpServer.TLSConfig != nil {
+		glog.Infof("Listening to SSL JSON API connections on port :%d", fes.JSONPort)
+		err = httpServer.ServeTLS(listener, "", "")
+	} else {
+		glog.Infof("Listening to NON-SSL JSON API connections on port :%d", fes.JSONPort)
+		err = httpServer.Serve(listener)
+	}
+	if err != http.ErrServerClosed {
+		glog.Errorf("APIServer.Start: Problem serving JSON API: %v", err)
+	}
+}
+
+const (
+	// How often the txindex loop checks whether the block tip has moved past the
+	// txindex without being told. The loop is normally woken by the node's block
+	// events, so this is only a safety net in case one is missed.
+	txindexTipCheckInterval = 30 * time.Second
+	// The most blocks one txindex update attaches before releasing TxIndexLock
+	// and starting the next one. Keeps Stop and the txindex routes responsive
+	// while the txindex catches up.
+	txindexMaxBlocksPerUpdate = 1000
+	// How long the txindex loop waits after a failed update before trying again.
+	txindexRetryDelay = 1 * time.Second
+)
+
+// NotifyBlockChainUpdated tells the txindex loop that blocks were connected to or
+// disconnected from the main chain, so the txindex is updated right away rather
+// than at the next tip check. It never blocks.
+func (fes *APIServer) NotifyBlockChainUpdated() {
+	select {
+	case fes.txindexUpdateSignal <- struct{}{}:
+	default:
+		// An update is already pending and will pick up these blocks too.
+	}
+}
+
+// HandleBlockConnected is registered with the node's event manager and is called
+// for every block connected to the main chain, whether it was mined here or came
+// from a peer.
+func (fes *APIServer) HandleBlockConnected(event *lib.BlockEvent) {
+	// The block's transactions leave the mempool.
+	fes.bumpMempoolVersion()
+	fes.NotifyBlockChainUpdated()
+}
+
+// HandleBlockDisconnected is registered with the node's event manager and is
+// called for every block disconnected from the main chain during a reorg.
+func (fes *APIServer) HandleBlockDisconnected(event *lib.BlockEvent) {
+	// The block's transactions go back into the mempool.
+	fes.bumpMempoolVersion()
+	fes.NotifyBlockChainUpdated()
+}
+
+// HandleTransactionConnected is registered with the node's event manager and is
+// called for every transaction the mempool accepts.
+func (fes *APIServer) HandleTransactionConnected(event *lib.TransactionEvent) {
+	fes.bumpMempoolVersion()
+}
 
-	glog.Infof("Listening to NON-SSL JSON API connections on port :%d", fes.JSONPort)
-	glog.Error(http.ListenAndServe(fmt.Sprintf(":%d", fes.JSONPort), fes.router))
+// runTxindexLoop updates the txindex whenever NotifyBlockChainUpdated is called
+// or the block tip moves past the txindex, until quit is closed.
+func (fes *APIServer) runTxindexLoop(quit <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	tipCheckTicker := time.NewTicker(txindexTipCheckInterval)
+	defer tipCheckTicker.Stop()
+
+	// Catch up on whatever arrived while the node was down.
+	fes.NotifyBlockChainUpdated()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-fes.txindexUpdateSignal:
+		case <-tipCheckTicker.C:
+			if !fes.isTxindexBehindBlockTip() {
+				continue
+			}
+		}
+		if !fes.tryUpdateTxindex() {
+			select {
+			case <-quit:
+				return
+			case <-time.After(txindexRetryDelay):
+			}
+			continue
+		}
+		// If the update stopped at txindexMaxBlocksPerUpdate, carry on with the next
+		// batch.
+		if !fes.IsStopping() && fes.isTxindexBehindBlockTip() {
+			fes.NotifyBlockChainUpdated()
+		}
+	}
+}
+
+// isTxindexBehindBlockTip returns true if the txindex tip isn't the block tip.
+func (fes *APIServer) isTxindexBehindBlockTip() bool {
+	txindexTip := fes.TxIndexChain.BlockTip()
+	blockTip := fes.blockchain.BlockTip()
+	if txindexTip == nil || blockTip == nil {
+		return false
+	}
+	return *txindexTip.Hash != *blockTip.Hash
 }
 
 // A helper function to initialize the APIServer. Useful for testing.
@@ -1068,19 +1307,78 @@ func (fes *APIServer) initState() {
 	fes.router = fes.NewRouter()
 }
 
-func (fes *APIServer) tryUpdateTxindex() {
+// tryUpdateTxindex updates the txindex if the node is synced. It returns false if
+// the node isn't synced yet or the update failed.
+func (fes *APIServer) tryUpdateTxindex() bool {
 	// If the node is not fully synced, don't do an update.
 	if fes.blockchain.ChainState() != lib.SyncStateFullyCurrent {
 		glog.Debugf("tryUpdateTxindex: Waiting for node to sync before updating txindex.")
-		return
+		return false
 	}
 	// If the node is fully synced, then try an update.
-	if err := fes.UpdateTxindex(); err != nil {
+	if err := fes.updateTxindex(txindexMaxBlocksPerUpdate); err != nil {
 		glog.Error(fmt.Errorf("tryUpdateTxindex: Problem running update: %v", err))
+		return false
 	}
+	return true
 }
 
-// Stop...
-func (fes *APIServer) Stop() {
+// Stop shuts the APIServer down gracefully. HealthCheck starts failing right
+// away, and after ShutdownDelay the server stops accepting connections. Stop then
+// waits for in-flight requests to finish and for the txindex loop to return, so
+// the txindex db can be closed safely once it returns nil. If ctx is done first,
+// open connections are closed and ctx's error is returned.
+func (fes *APIServer) Stop(ctx context.Context) error {
 	glog.Info("APIServer.Stop: Gracefully shutting down APIServer")
+
+	fes.lifecycleLock.Lock()
+	if !atomic.CompareAndSwapInt32(&fes.isStopping, 0, 1) {
+		fes.lifecycleLock.Unlock()
+		return nil
+	}
+	httpServer, txindexQuit, txindexLoopDone := fes.httpServer, fes.txindexQuit, fes.txindexLoopDone
+	fes.lifecycleLock.Unlock()
+
+	if httpServer != nil && fes.ShutdownDelay > 0 {
+		glog.Infof("APIServer.Stop: Failing health checks for %v before shutting down", fes.ShutdownDelay)
+		select {
+		case <-time.After(fes.ShutdownDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	// Let an update that's in progress stop at the next block while requests drain.
+	if txindexQuit != nil {
+		close(txindexQuit)
+	}
+
+	if httpServer != nil {
+		if err := httpServer.Shutdown(ctx); err != nil {
+			_ = httpServer.Close()
+			return fmt.Errorf("APIServer.Stop: Problem waiting for requests to finish: %v", err)
+		}
+	}
+
+	if txindexLoopDone != nil {
+		select {
+		case <-txindexLoopDone:
+		case <-ctx.Done():
+			return fmt.Errorf("APIServer.Stop: Problem waiting for txindex update to stop: %v", ctx.Err())
+		}
+	}
+	if txindexRebuildDone := fes.txindexRebuild.runningDone(); txindexRebuildDone != nil {
+		select {
+		case <-txindexRebuildDone:
+		case <-ctx.Done():
+			return fmt.Errorf("APIServer.Stop: Problem waiting for txindex rebuild to stop: %v", ctx.Err())
+		}
+	}
+
+	glog.Info("APIServer.Stop: APIServer stopped")
+	return nil
+}
+
+// IsStopping returns true once Stop has been called.
+func (fes *APIServer) IsStopping() bool {
+	return atomic.LoadInt32(&fes.isStopping) == 1
 }
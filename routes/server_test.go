@@ -0,0 +1,130 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIServerStartStop(t *testing.T) {
+	require := require.New(t)
+
+	fes := &APIServer{Params: &lib.BitCloutTestnetParams}
+	startReturned := make(chan struct{})
+	go func() {
+		fes.Start()
+		close(startReturned)
+	}()
+
+	// Port 0 picks a free port.
+	var baseURL string
+	require.Eventually(func() bool {
+		fes.lifecycleLock.Lock()
+		defer fes.lifecycleLock.Unlock()
+		if fes.listenAddr == nil {
+			return false
+		}
+		baseURL = fmt.Sprintf("http://%v", fes.listenAddr)
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	res, err := http.Get(baseURL + "/")
+	require.NoError(err)
+	res.Body.Close()
+	require.Equal(http.StatusOK, res.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(fes.Stop(ctx))
+	select {
+	case <-startReturned:
+	case <-time.After(5 * time.Second):
+		require.Fail("Start should return once the server is stopped")
+	}
+	_, err = http.Get(baseURL + "/")
+	require.Error(err)
+
+	// Readiness fails from now on.
+	response := httptest.NewRecorder()
+	fes.HealthCheck(response, httptest.NewRequest("GET", RoutePathHealthCheck, nil))
+	require.Equal(http.StatusServiceUnavailable, response.Code)
+	apiErr := &APIError{}
+	require.NoError(json.NewDecoder(response.Body).Decode(apiErr))
+	require.Equal(ErrorCodeShuttingDown, apiErr.Code)
+
+	// Stopping twice is fine, and a stopped server can't be started again.
+	require.NoError(fes.Stop(ctx))
+	fes.Start()
+}
+
+func TestAPIServerStopDelay(t *testing.T) {
+	require := require.New(t)
+
+	fes := &APIServer{Params: &lib.BitCloutTestnetParams, ShutdownDelay: time.Hour}
+	go fes.Start()
+	require.Eventually(func() bool {
+		fes.lifecycleLock.Lock()
+		defer fes.lifecycleLock.Unlock()
+		return fes.httpServer != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The delay is cut short when the context is done, and the server still shuts
+	// down since nothing is in flight.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	startTime := time.Now()
+	require.NoError(fes.Stop(ctx))
+	require.True(time.Since(startTime) < 5*time.Second)
+	require.True(fes.IsStopping())
+}
+
+func TestTxindexLoop(t *testing.T) {
+	require := require.New(t)
+
+	fes, _, miner := newTestAPIServer(t, "" /*globalStateRemoteNode*/)
+	require.Equal(uint32(0), fes.TxIndexChain.BlockTip().Height)
+	require.True(fes.isTxindexBehindBlockTip())
+
+	// Updates can be limited to a number of blocks at a time.
+	require.NoError(fes.updateTxindex(1 /*maxBlocksToAttach*/))
+	require.Equal(uint32(1), fes.TxIndexChain.BlockTip().Height)
+	require.NoError(fes.updateTxindex(1 /*maxBlocksToAttach*/))
+	require.Equal(fes.blockchain.BlockTip().Height, fes.TxIndexChain.BlockTip().Height)
+	require.False(fes.isTxindexBehindBlockTip())
+
+	// Notifying never blocks, however many blocks come in before the loop gets
+	// to them.
+	for ii := 0; ii < 10; ii++ {
+		fes.NotifyBlockChainUpdated()
+	}
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go fes.runTxindexLoop(quit, done)
+
+	// The loop picks up new blocks as soon as the node says they're connected.
+	for ii := 0; ii < 3; ii++ {
+		block, err := miner.MineAndProcessSingleBlock(0 /*threadIndex*/, fes.mempool)
+		require.NoError(err)
+		fes.HandleBlockConnected(&lib.BlockEvent{Block: block})
+	}
+	require.Eventually(func() bool {
+		fes.TxIndexLock.RLock()
+		defer fes.TxIndexLock.RUnlock()
+		return !fes.isTxindexBehindBlockTip()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(quit)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail("The txindex loop should return once quit is closed")
+	}
+}
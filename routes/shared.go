@@ -13,10 +13,6 @@ import (
 	"github.com/tyler-smith/go-bip39"
 )
 
-func _AddBadRequestError(ww http.ResponseWriter, errorString string) {
-	_AddHttpError(ww, errorString, http.StatusBadRequest)
-}
-
 func _AddNotFoundError(ww http.ResponseWriter, errorString string) {
 	_AddHttpError(ww, errorString, http.StatusNotFound)
 }
@@ -28,9 +24,7 @@ func _AddInternalServerError(ww http.ResponseWriter, errorString string) {
 func _AddHttpError(ww http.ResponseWriter, errorString string, statusCode int) {
 	glog.Error(errorString)
 	ww.WriteHeader(statusCode)
-	json.NewEncoder(ww).Encode(struct {
-		Error string `json:"error"`
-	}{Error: errorString})
+	json.NewEncoder(ww).Encode(NewAPIError(errorCodeForStatus(statusCode), errorString))
 }
 
 type TransactionInfo struct {
@@ -105,6 +99,9 @@ type User struct {
 
 	// Is this user an admin
 	IsAdmin bool
+	// The roles this admin has. Lets the frontend only show the parts of the
+	// admin panel the user can use.
+	AdminRoles []AdminRole
 }
 
 type BalanceEntryResponse struct {
@@ -181,10 +178,11 @@ func (fes *APIServer) GetVerifiedUsernameToPKIDMap() (_verificationMap map[strin
 
 // TODO: We may want to move this into getUserMetadataFromGlobalState and change
 // the other usage to use getUserMetadataFromGlobalState
-func makeUserMetadata(userMetadataBytes []byte, userPublicKeyBytes []byte) (_userMetadata *UserMetadata, _err error) {
-	userMetadata := UserMetadata{}
+func (fes *APIServer) makeUserMetadata(userMetadataBytes []byte, userPublicKeyBytes []byte) (_userMetadata *UserMetadata, _err error) {
+	userMetadata := &UserMetadata{}
 	if userMetadataBytes != nil {
-		err := gob.NewDecoder(bytes.NewReader(userMetadataBytes)).Decode(&userMetadata)
+		var err error
+		userMetadata, err = fes.PIIKeyring.decodeUserMetadata(userMetadataBytes)
 		if err != nil {
 			return nil, errors.Wrap(fmt.Errorf(
 				"makeUserMetadata: Problem getting metadata from global state: %v", err), "")
@@ -193,7 +191,7 @@ func makeUserMetadata(userMetadataBytes []byte, userPublicKeyBytes []byte) (_use
 		// If this is a brand new user metadata object we need to add its public key.
 		userMetadata.PublicKey = userPublicKeyBytes
 	}
-	return &userMetadata, nil
+	return userMetadata, nil
 }
 
 func (fes *APIServer) getUserMetadataFromGlobalState(
@@ -212,7 +210,7 @@ func (fes *APIServer) getUserMetadataFromGlobalState(
 			"getUserMetadataFromGlobalState: Problem with GlobalStateGet: %v", err), "")
 	}
 
-	userMetadata, err := makeUserMetadata(userMetadataBytes, userPublicKeyBytes)
+	userMetadata, err := fes.makeUserMetadata(userMetadataBytes, userPublicKeyBytes)
 	if err != nil {
 		return nil, errors.Wrap(fmt.Errorf(
 			"getUserMetadataFromGlobalState: Problem with makeUserMetadata: %v", err), "")
@@ -226,21 +224,44 @@ func (fes *APIServer) getUserMetadataFromGlobalState(
 	return userMetadata, nil
 }
 
-func (fes *APIServer) putUserMetadataInGlobalState(
-	userMetadata *UserMetadata,
-) (_err error) {
-	dbKey := GlobalStateKeyForPublicKeyToUserMetadata(userMetadata.PublicKey)
+// updateUserMetadataInGlobalState atomically applies updateFunc to a user's metadata.
+// If another request updates the same user concurrently, the metadata is re-read and
+// updateFunc is called again, so updateFunc must be safe to call more than once.
+// Returns the metadata that was written.
+func (fes *APIServer) updateUserMetadataInGlobalState(
+	userPublicKeyBytes []byte,
+	updateFunc func(userMetadata *UserMetadata) error,
+) (_userMetadata *UserMetadata, _err error) {
+	var updatedUserMetadata *UserMetadata
+	dbKey := GlobalStateKeyForPublicKeyToUserMetadata(userPublicKeyBytes)
+	err := fes.GlobalStateUpdate(dbKey, func(userMetadataBytes []byte) ([]byte, error) {
+		userMetadata, err := fes.makeUserMetadata(userMetadataBytes, userPublicKeyBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		// Check if we need to add the public key to userMetadata
+		if len(userMetadata.PublicKey) != 33 {
+			userMetadata.PublicKey = userPublicKeyBytes
+		}
+
+		if err = updateFunc(userMetadata); err != nil {
+			return nil, err
+		}
 
-	// Encode the updated entry and stick it in the database.
-	metadataDataBuf := bytes.NewBuffer([]byte{})
-	gob.NewEncoder(metadataDataBuf).Encode(userMetadata)
-	err := fes.GlobalStatePut(dbKey, metadataDataBuf.Bytes())
+		newUserMetadataBytes, err := fes.PIIKeyring.encodeUserMetadata(userMetadata)
+		if err != nil {
+			return nil, err
+		}
+		updatedUserMetadata = userMetadata
+		return newUserMetadataBytes, nil
+	})
 	if err != nil {
-		return errors.Wrap(fmt.Errorf(
-			"AdminUpdateUserGlobalMetadata: Problem putting updated user metadata: %v", err), "")
+		return nil, errors.Wrap(fmt.Errorf(
+			"updateUserMetadataInGlobalState: Problem updating user metadata: %v", err), "")
 	}
 
-	return nil
+	return updatedUserMetadata, nil
 }
 
 func (fes *APIServer) SendSeedBitClout(recipientPkBytes []byte, amountNanos uint64) error {
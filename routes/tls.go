@@ -0,0 +1,191 @@
+package routes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ClientCertificateRoutePrefixes are the routes that need a verified client
+// certificate when APIServer.RequireClientCertificate is set.
+var ClientCertificateRoutePrefixes = []string{
+	"/api/v0/admin/",
+	"/api/v1/global-state/",
+}
+
+// CertificateReloader holds a certificate and a CA bundle loaded from files, and
+// reloads them when Reload is called so they can be rotated without restarting
+// the node. The same type is used on both sides of a connection: a server uses
+// the CA bundle to verify client certificates and a client uses it to verify the
+// server.
+type CertificateReloader struct {
+	// Either both or neither of these are set.
+	CertFile string
+	KeyFile  string
+	// Optional.
+	CAFile string
+
+	mtx         sync.RWMutex
+	certificate *tls.Certificate
+	caPool      *x509.CertPool
+}
+
+// NewCertificateReloader loads the files passed in. Empty file names are skipped.
+func NewCertificateReloader(certFile string, keyFile string, caFile string) (*CertificateReloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("NewCertificateReloader: A certificate needs both a cert file and a key file")
+	}
+	reloader := &CertificateReloader{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		CAFile:   caFile,
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload reads the files again. If any of them can't be read, the certificates
+// that were loaded before are kept.
+func (reloader *CertificateReloader) Reload() error {
+	var certificate *tls.Certificate
+	if reloader.CertFile != "" {
+		loadedCertificate, err := tls.LoadX509KeyPair(reloader.CertFile, reloader.KeyFile)
+		if err != nil {
+			return fmt.Errorf("CertificateReloader.Reload: Problem loading certificate %v: %v",
+				reloader.CertFile, err)
+		}
+		certificate = &loadedCertificate
+	}
+
+	var caPool *x509.CertPool
+	if reloader.CAFile != "" {
+		caBytes, err := ioutil.ReadFile(reloader.CAFile)
+		if err != nil {
+			return fmt.Errorf("CertificateReloader.Reload: Problem reading CA file %v: %v",
+				reloader.CAFile, err)
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caBytes) {
+			return fmt.Errorf("CertificateReloader.Reload: No PEM certificates found in CA file %v",
+				reloader.CAFile)
+		}
+	}
+
+	reloader.mtx.Lock()
+	defer reloader.mtx.Unlock()
+	reloader.certificate = certificate
+	reloader.caPool = caPool
+	return nil
+}
+
+func (reloader *CertificateReloader) getCertificate() (*tls.Certificate, *x509.CertPool) {
+	reloader.mtx.RLock()
+	defer reloader.mtx.RUnlock()
+	return reloader.certificate, reloader.caPool
+}
+
+// ServerTLSConfig returns the config for serving with the current certificate.
+// When there's a CA bundle, clients may present a certificate signed by it.
+// Certificates are optional during the handshake since only some routes need one,
+// see CheckClientCertificate.
+func (reloader *CertificateReloader) ServerTLSConfig() *tls.Config {
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		certificate, _ := reloader.getCertificate()
+		if certificate == nil {
+			return nil, fmt.Errorf("ServerTLSConfig: No certificate loaded")
+		}
+		return certificate, nil
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: getCertificate,
+		// Called for every handshake, so reloaded certificates are picked up by new
+		// connections.
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			certificate, caPool := reloader.getCertificate()
+			if certificate == nil {
+				return nil, fmt.Errorf("ServerTLSConfig: No certificate loaded")
+			}
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*certificate},
+				NextProtos:   []string{"h2", "http/1.1"},
+			}
+			if caPool != nil {
+				config.ClientCAs = caPool
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+			return config, nil
+		},
+	}
+}
+
+// ClientTLSConfig returns the config for connecting to a server. The current
+// certificate, if any, is presented when the server asks for one. When there's a
+// CA bundle, the server's certificate must be signed by it instead of by one of
+// the system's CAs.
+func (reloader *CertificateReloader) ClientTLSConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			certificate, _ := reloader.getCertificate()
+			if certificate == nil {
+				// An empty certificate tells the server we don't have one.
+				return &tls.Certificate{}, nil
+			}
+			return certificate, nil
+		},
+	}
+	if reloader.CAFile != "" {
+		// RootCAs is fixed once the config is in use, so the server is verified
+		// against the current CA bundle by hand instead.
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			_, caPool := reloader.getCertificate()
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("ClientTLSConfig: Server sent no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, certificate := range state.PeerCertificates[1:] {
+				intermediates.AddCert(certificate)
+			}
+			_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+				DNSName:       state.ServerName,
+				Roots:         caPool,
+				Intermediates: intermediates,
+			})
+			return err
+		}
+	}
+	return config
+}
+
+// routeRequiresClientCertificate returns true if route is one of the
+// ClientCertificateRoutePrefixes.
+func routeRequiresClientCertificate(route Route) bool {
+	for _, prefix := range ClientCertificateRoutePrefixes {
+		if strings.HasPrefix(route.Pattern, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckClientCertificate rejects requests that weren't made with a client
+// certificate signed by the CA bundle in TLSCertificates.
+func (fes *APIServer) CheckClientCertificate(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(ww http.ResponseWriter, req *http.Request) {
+		if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 {
+			_AddCodedError(ww, ErrorCodeUnauthorized,
+				"CheckClientCertificate: This route requires a client certificate")
+			return
+		}
+		inner.ServeHTTP(ww, req)
+	})
+}
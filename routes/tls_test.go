@@ -0,0 +1,192 @@
+package routes
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	"github.com/stretchr/testify/require"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	privateKey  *ecdsa.PrivateKey
+	certPEM     []byte
+	keyPEM      []byte
+}
+
+// newTestCertificate returns a certificate for localhost signed by parent, or a
+// self-signed CA if parent is nil.
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	require := require.New(t)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(err)
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	signer, signerKey := template, privateKey
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		template.DNSNames = []string{"localhost"}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+		signer, signerKey = parent.certificate, parent.privateKey
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, signer, &privateKey.PublicKey, signerKey)
+	require.NoError(err)
+	certificate, err := x509.ParseCertificate(certBytes)
+	require.NoError(err)
+	keyBytes, err := x509.MarshalECPrivateKey(privateKey)
+	require.NoError(err)
+
+	return &testCertificate{
+		certificate: certificate,
+		privateKey:  privateKey,
+		certPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}),
+		keyPEM:      pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}),
+	}
+}
+
+// writeTestCertificate writes cert to dir and returns the paths of its cert and
+// key files.
+func writeTestCertificate(t *testing.T, dir string, name string, cert *testCertificate) (string, string) {
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, ioutil.WriteFile(certFile, cert.certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(keyFile, cert.keyPEM, 0600))
+	return certFile, keyFile
+}
+
+func TestCertificateReloader(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	ca := newTestCertificate(t, "ca", nil)
+	caFile, _ := writeTestCertificate(t, dir, "ca", ca)
+	certFile, keyFile := writeTestCertificate(t, dir, "server", newTestCertificate(t, "first", ca))
+
+	_, err := NewCertificateReloader(certFile, "", caFile)
+	require.Error(err)
+	reloader, err := NewCertificateReloader(certFile, keyFile, caFile)
+	require.NoError(err)
+
+	servedCommonName := func() string {
+		certificate, err := reloader.ServerTLSConfig().GetCertificate(&tls.ClientHelloInfo{})
+		require.NoError(err)
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		require.NoError(err)
+		return leaf.Subject.CommonName
+	}
+	require.Equal("first", servedCommonName())
+
+	// Reloading picks up a rotated certificate.
+	writeTestCertificate(t, dir, "server", newTestCertificate(t, "second", ca))
+	require.NoError(reloader.Reload())
+	require.Equal("second", servedCommonName())
+
+	// A broken file is reported and the last good certificate is kept.
+	require.NoError(ioutil.WriteFile(keyFile, []byte("garbage"), 0600))
+	require.Error(reloader.Reload())
+	require.Equal("second", servedCommonName())
+}
+
+func TestCheckClientCertificate(t *testing.T) {
+	require := require.New(t)
+	dir := t.TempDir()
+
+	ca := newTestCertificate(t, "ca", nil)
+	caFile, _ := writeTestCertificate(t, dir, "ca", ca)
+	serverCertFile, serverKeyFile := writeTestCertificate(t, dir, "server", newTestCertificate(t, "server", ca))
+	clientCertFile, clientKeyFile := writeTestCertificate(t, dir, "client", newTestCertificate(t, "client", ca))
+	otherCA := newTestCertificate(t, "other-ca", nil)
+	otherCertFile, otherKeyFile := writeTestCertificate(t, dir, "other", newTestCertificate(t, "other", otherCA))
+
+	serverCertificates, err := NewCertificateReloader(serverCertFile, serverKeyFile, caFile)
+	require.NoError(err)
+	fes := &APIServer{
+		Params:                              &lib.BitCloutTestnetParams,
+		GlobalStore:                         NewMemoryGlobalStore(),
+		AccessControlAllowOrigins:           []string{"*"},
+		SecureHeaderMiddlewareIsDevelopment: true,
+		TLSCertificates:                     serverCertificates,
+		RequireClientCertificate:            true,
+		// Only the certificate is under test here.
+		AdminOpenAccess: true,
+	}
+	server := httptest.NewUnstartedServer(fes.NewRouter())
+	server.TLS = serverCertificates.ServerTLSConfig()
+	server.StartTLS()
+	defer server.Close()
+	baseURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	newClient := func(certFile string, keyFile string) *http.Client {
+		clientCertificates, err := NewCertificateReloader(certFile, keyFile, caFile)
+		require.NoError(err)
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: clientCertificates.ClientTLSConfig()}}
+	}
+	call := func(client *http.Client, routePath string) (*http.Response, error) {
+		return client.Post(baseURL+routePath, "application/json", bytes.NewBufferString("{}"))
+	}
+
+	// Routes outside of ClientCertificateRoutePrefixes don't need a certificate.
+	withoutCertificate := newClient("", "")
+	res, err := withoutCertificate.Get(baseURL + "/")
+	require.NoError(err)
+	res.Body.Close()
+	require.Equal(http.StatusOK, res.StatusCode)
+
+	// Admin routes do, including under their legacy path.
+	for _, routePath := range []string{
+		RoutePathAdminGetGlobalStatePrefixes,
+		strings.TrimPrefix(RoutePathAdminGetGlobalStatePrefixes, "/api/v0"),
+	} {
+		res, err = call(withoutCertificate, routePath)
+		require.NoError(err)
+		apiErr := &APIError{}
+		require.NoError(json.NewDecoder(res.Body).Decode(apiErr))
+		res.Body.Close()
+		require.Equal(http.StatusUnauthorized, res.StatusCode)
+		require.Equal(ErrorCodeUnauthorized, apiErr.Code)
+	}
+
+	res, err = call(newClient(clientCertFile, clientKeyFile), RoutePathAdminGetGlobalStatePrefixes)
+	require.NoError(err)
+	res.Body.Close()
+	require.Equal(http.StatusOK, res.StatusCode)
+
+	// Certificates from other CAs are rejected during the handshake.
+	_, err = call(newClient(otherCertFile, otherKeyFile), RoutePathAdminGetGlobalStatePrefixes)
+	require.Error(err)
+
+	// Clients only trust servers signed by their CA bundle.
+	otherCAFile, _ := writeTestCertificate(t, dir, "other-ca", otherCA)
+	distrustingCertificates, err := NewCertificateReloader("", "", otherCAFile)
+	require.NoError(err)
+	distrustingClient := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: distrustingCertificates.ClientTLSConfig()}}
+	_, err = distrustingClient.Get(baseURL + "/")
+	require.Error(err)
+}
@@ -35,19 +35,19 @@ func (fes *APIServer) GetTxn(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetTxnRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetTxn: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetTxn: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the postHash.
 	var txnHash *lib.BlockHash
 	if requestData.TxnHashHex == "" {
-		_AddBadRequestError(ww, fmt.Sprintf("GetTxn: Must provide a TxnHashHex."))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("GetTxn: Must provide a TxnHashHex."))
 		return
 	} else {
 		txnHashBytes, err := hex.DecodeString(requestData.TxnHashHex)
 		if err != nil || len(txnHashBytes) != lib.HashSizeBytes {
-			_AddBadRequestError(ww, fmt.Sprintf("GetTxn: Error parsing post hash %v: %v",
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("GetTxn: Error parsing post hash %v: %v",
 				requestData.TxnHashHex, err))
 			return
 		}
@@ -61,7 +61,7 @@ func (fes *APIServer) GetTxn(ww http.ResponseWriter, req *http.Request) {
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSinglePost: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetSinglePost: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -82,26 +82,26 @@ func (fes *APIServer) SubmitTransaction(ww http.ResponseWriter, req *http.Reques
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SubmitTransactionRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitTransactionRequest: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SubmitTransactionRequest: Problem parsing request body: %v", err))
 		return
 	}
 
 	txnBytes, err := hex.DecodeString(requestData.TransactionHex)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitTransactionRequest: Problem deserializing transaction hex: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SubmitTransactionRequest: Problem deserializing transaction hex: %v", err))
 		return
 	}
 
 	txn := &lib.MsgBitCloutTxn{}
 	err = txn.FromBytes(txnBytes)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitTransactionRequest: Problem deserializing transaction from bytes: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SubmitTransactionRequest: Problem deserializing transaction from bytes: %v", err))
 		return
 	}
 
 	err = fes.backendServer.VerifyAndBroadcastTransaction(txn)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitTransaction: Problem processing transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("SubmitTransaction: Problem processing transaction: %v", err))
 		return
 	}
 
@@ -113,12 +113,12 @@ func (fes *APIServer) SubmitTransaction(ww http.ResponseWriter, req *http.Reques
 	if txn.TxnMeta.GetTxnType() == lib.TxnTypeSubmitPost {
 		err = fes._afterProcessSubmitPostTransaction(txn, res)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("_afterSubmitPostTransaction: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("_afterSubmitPostTransaction: %v", err))
 		}
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitTransactionResponse: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SubmitTransactionResponse: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -246,14 +246,14 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := UpdateProfileRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateProfile: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("UpdateProfile: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the public key
 	updaterPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.UpdaterPublicKeyBase58Check)
 	if err != nil || len(updaterPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"UpdateProfile: Problem decoding public key %s: %v",
 			requestData.UpdaterPublicKeyBase58Check, err))
 		return
@@ -262,22 +262,22 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 	// Validate that the user can create a profile
 	userMetadata, err := fes.getUserMetadataFromGlobalState(requestData.UpdaterPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateProfile: Problem with getUserMetadataFromGlobalState: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("UpdateProfile: Problem with getUserMetadataFromGlobalState: %v", err))
 		return
 	}
 
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateProfile: Error fetching mempool view: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("UpdateProfile: Error fetching mempool view: %v", err))
 		return
 	}
 	canCreateProfile, err := fes.canUserCreateProfile(userMetadata, utxoView)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateProfile: Problem with canUserCreateProfile: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("UpdateProfile: Problem with canUserCreateProfile: %v", err))
 		return
 	}
 	if !canCreateProfile {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeForbidden, fmt.Sprintf(
 			"UpdateProfile: Not allowed to update profile. Please verify your phone number or buy BitClout."))
 		return
 	}
@@ -288,7 +288,7 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 	if requestData.ProfilePublicKeyBase58Check != "" {
 		profilePublicKeyBytess, _, err = lib.Base58CheckDecode(requestData.ProfilePublicKeyBase58Check)
 		if err != nil || len(profilePublicKeyBytess) != btcec.PubKeyBytesLenCompressed {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 				"UpdateProfile: Problem decoding public key %s: %v",
 				requestData.ProfilePublicKeyBase58Check, err))
 			return
@@ -297,18 +297,18 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 
 	if len(requestData.NewUsername) > 0 && (strings.Index(requestData.NewUsername, "BC") == 0 ||
 		strings.Index(requestData.NewUsername, "tBC") == 0) {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"UpdateProfile: Username cannot start with BC or tBC"))
 		return
 	}
 
 	if uint64(len([]byte(requestData.NewUsername))) > utxoView.Params.MaxUsernameLengthBytes {
-		_AddBadRequestError(ww, lib.RuleErrorProfileUsernameTooLong.Error())
+		_AddCodedError(ww, ErrorCodeBadRequest, lib.RuleErrorProfileUsernameTooLong.Error())
 		return
 	}
 
 	if uint64(len([]byte(requestData.NewDescription))) > utxoView.Params.MaxUserDescriptionLengthBytes {
-		_AddBadRequestError(ww, lib.RuleErrorProfileDescriptionTooLong.Error())
+		_AddCodedError(ww, ErrorCodeBadRequest, lib.RuleErrorProfileDescriptionTooLong.Error())
 		return
 	}
 
@@ -318,21 +318,21 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 		var resizedImageBytes []byte
 		resizedImageBytes, err = resizeAndConvertToWebp(requestData.NewProfilePic, uint(fes.Params.MaxProfilePicDimensions))
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("Problem resizing profile picture: %v", err))
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("Problem resizing profile picture: %v", err))
 			return
 		}
 		// Convert the image back into base64
 		webpBase64 := base64.StdEncoding.EncodeToString(resizedImageBytes)
 		requestData.NewProfilePic = "data:image/webp;base64," + webpBase64
 		if uint64(len([]byte(requestData.NewProfilePic))) > utxoView.Params.MaxProfilePicLengthBytes {
-			_AddBadRequestError(ww, lib.RuleErrorMaxProfilePicSize.Error())
+			_AddCodedError(ww, ErrorCodeBadRequest, lib.RuleErrorMaxProfilePicSize.Error())
 			return
 		}
 	}
 
 	// CreatorBasisPoints > 0 < max, uint64 can't be less than zero
 	if requestData.NewCreatorBasisPoints > fes.Params.MaxCreatorBasisPoints {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"UpdateProfile: Creator percentage must be less than %v percent",
 			fes.Params.MaxCreatorBasisPoints/100))
 		return
@@ -343,12 +343,12 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 
 		utxoView.GetProfileEntryForUsername([]byte(requestData.NewUsername))
 		if existingProfile, usernameExists := utxoView.ProfileUsernameToProfileEntry[lib.MakeUsernameMapKey([]byte(requestData.NewUsername))]; usernameExists && !existingProfile.IsDeleted() {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeConflict, fmt.Sprintf(
 				"UpdateProfile: Username %v already exists", string(existingProfile.Username)))
 			return
 		}
 		if !lib.UsernameRegex.Match([]byte(requestData.NewUsername)) {
-			_AddBadRequestError(ww, lib.RuleErrorInvalidUsername.Error())
+			_AddCodedError(ww, ErrorCodeBadRequest, lib.RuleErrorInvalidUsername.Error())
 			return
 		}
 	}
@@ -359,7 +359,7 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 	}
 	additionalFees, err := fes.CompProfileCreation(profilePublicKey, userMetadata, utxoView)
 	if err != nil {
-		_AddBadRequestError(ww, err.Error())
+		_AddCodedError(ww, errorCodeOf(err, ErrorCodeInternal), err.Error())
 		return
 	}
 
@@ -376,13 +376,13 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 		additionalFees,
 		requestData.MinFeeRateNanosPerKB, fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateProfile: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("UpdateProfile: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateProfile: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("UpdateProfile: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -396,7 +396,7 @@ func (fes *APIServer) UpdateProfile(ww http.ResponseWriter, req *http.Request) {
 		TxnHashHex:        txn.Hash().String(),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendMessage: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendMessage: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -448,14 +448,23 @@ func (fes *APIServer) CompProfileCreation(profilePublicKey []byte, userMetadata
 	compAmount := createProfileFeeNanos - (minStarterBitCloutNanos / 2)
 	// If the user won't have enough bitclout to cover the fee, this is an error.
 	if currentBalanceNanos+compAmount < createProfileFeeNanos {
-		return 0, errors.Wrap(fmt.Errorf("Creating a profile requires BitClout.  Please purchase some to create a profile."), "")
-	}
-	// Set should comp to false so we don't continually comp a public key
-	phoneNumberMetadata.ShouldCompProfileCreation = false
-	err = fes.putPhoneNumberMetadataInGlobalState(phoneNumberMetadata)
+		return 0, NewAPIError(ErrorCodeInsufficientBalance,
+			"Creating a profile requires BitClout.  Please purchase some to create a profile.")
+	}
+	// Set should comp to false so we don't continually comp a public key. This is
+	// done atomically so that two concurrent requests can't both claim the comp.
+	claimedComp := false
+	_, err = fes.updatePhoneNumberMetadataInGlobalState(userMetadata.PhoneNumber, func(phoneNumberMetadata *PhoneNumberMetadata) error {
+		claimedComp = phoneNumberMetadata.ShouldCompProfileCreation
+		phoneNumberMetadata.ShouldCompProfileCreation = false
+		return nil
+	})
 	if err != nil {
 		return 0, errors.Wrap(fmt.Errorf("UpdateProfile: Error setting ShouldComp to false for phone number metadata: %v", err), "")
 	}
+	if !claimedComp {
+		return additionalFees, nil
+	}
 	// Send the comp amount to the public key
 	err = fes.SendSeedBitClout(profilePublicKey, compAmount)
 	if err != nil {
@@ -526,13 +535,13 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := BurnBitcoinRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("BurnBitcoin: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Make sure the fee rate isn't negative.
 	if requestData.FeeRateSatoshisPerKB < 0 {
-		_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: BurnAmount %d or "+
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("BurnBitcoin: BurnAmount %d or "+
 			"FeeRateSatoshisPerKB %d cannot be negative",
 			requestData.BurnAmountSatoshis, requestData.FeeRateSatoshisPerKB))
 		return
@@ -546,7 +555,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 			requestData.LatestBitcionAPIResponse, requestData.BTCDepositAddress,
 			fes.Params)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Problem getting "+
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("BurnBitcoin: Problem getting "+
 				"Bitcoin UTXOs: %v", err))
 			return
 		}
@@ -559,7 +568,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 		txFee := lib.EstimateBitcoinTxFee(
 			len(bitcoinUtxos), 1, uint64(requestData.FeeRateSatoshisPerKB))
 		if int64(txFee) > totalInput {
-			_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Transaction fee %d is "+
+			_AddCodedError(ww, ErrorCodeInsufficientBalance, fmt.Sprintf("BurnBitcoin: Transaction fee %d is "+
 				"so high that we can't spend the inputs total=%d", txFee, totalInput))
 			return
 		}
@@ -571,7 +580,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 	// Prevent the user from creating a burn transaction with a dust output since
 	// this will result in the transaction being rejected by Bitcoin nodes.
 	if burnAmountSatoshis < 10000 {
-		_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: You must burn at least .0001 Bitcoins "+
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("BurnBitcoin: You must burn at least .0001 Bitcoins "+
 			"or else Bitcoin nodes will reject your transaction as \"dust.\""))
 		return
 	}
@@ -590,12 +599,12 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 	// Get the pubKey from the request
 	pkBytes, _, err := lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, "BurnBitcoin: Invalid public key")
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, "BurnBitcoin: Invalid public key")
 		return
 	}
 	addressPubKey, err := btcutil.NewAddressPubKey(pkBytes, fes.Params.BitcoinBtcdParams)
 	if err != nil {
-		_AddBadRequestError(ww, "BurnBitcoin: Invalid public key")
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, "BurnBitcoin: Invalid public key")
 		return
 	}
 	pubKey := addressPubKey.PubKey()
@@ -609,7 +618,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 		utxoSource)
 
 	if bitcoinSpendErr != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Problem creating Bitcoin spend "+
+		_AddCodedError(ww, transactionErrorCode(bitcoinSpendErr), fmt.Sprintf("BurnBitcoin: Problem creating Bitcoin spend "+
 				"transaction given input: %v", bitcoinSpendErr))
 		return
 	}
@@ -619,12 +628,12 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 	for ii, signedHash := range requestData.SignedHashes {
 		sig, err := hex.DecodeString(signedHash)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Failed to decode hash: %v", err))
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("BurnBitcoin: Failed to decode hash: %v", err))
 			return
 		}
 		parsedSig, err := btcec.ParseDERSignature(sig, btcec.S256())
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Parsing "+
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("BurnBitcoin: Parsing "+
 				"signature failed: %v: %v", signedHash, err))
 			return
 		}
@@ -638,7 +647,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 
 		sigScript, err := txscript.NewScriptBuilder().AddData(sig).AddData(pkData).Script()
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Failed to generate signature: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("BurnBitcoin: Failed to generate signature: %v", err))
 			return
 		}
 
@@ -650,7 +659,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 	bitcoinTxnBuffer := bytes.Buffer{}
 	err = bitcoinTxn.SerializeNoWitness(&bitcoinTxnBuffer)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Problem serializing Bitcoin transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("BurnBitcoin: Problem serializing Bitcoin transaction: %v", err))
 		return
 	}
 	bitcoinTxnBytes := bitcoinTxnBuffer.Bytes()
@@ -677,7 +686,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 					glog.Errorf("BurnBitcoin: ERROR: Blockonomics request to check RBF for txn "+
 						"hash %v failed. This is bad because it means users are not able to "+
 						"complete Bitcoin burns: %v", txIn.PreviousOutPoint.Hash.String(), err)
-					_AddBadRequestError(ww, fmt.Sprintf(
+					_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf(
 						"The nodes are still processing your deposit. Please wait a few seconds "+
 							"and try again."))
 					return
@@ -686,7 +695,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 				// RBF set.
 				if isRBF {
 					glog.Errorf("BurnBitcoin: ERROR: Blockonomics found RBF txn: %v", bitcoinTxnHash.String())
-					_AddBadRequestError(ww, fmt.Sprintf(
+					_AddCodedError(ww, ErrorCodeTransactionRejected, fmt.Sprintf(
 						"Your deposit has \"replace by fee\" set, "+
 							"which means we must wait for one confirmation on the Bitcoin blockchain before "+
 							"allowing you to buy. This usually takes about ten minutes.<br><br>"+
@@ -706,7 +715,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 				fes.BlockCypherAPIKey, fes.Params.BitcoinDoubleSpendWaitSeconds,
 				fes.Params)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Error broadcasting transaction: %v", err))
+				_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("BurnBitcoin: Error broadcasting transaction: %v", err))
 				return
 			}
 
@@ -718,7 +727,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 		if err := fes.backendServer.GetBitcoinManager().BroadcastTxnAndCheckAddedRedundant(
 			bitcoinTxn, 30 /*timeoutSecs*/, 10 /*numNodesToPing*/); err != nil {
 
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeTransactionRejected, fmt.Sprintf(
 				"BurnBitcoin: Error broadcasting transaction: %v", err))
 			return
 		}
@@ -750,7 +759,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 
 		// Broadcast the newly-created BitClout txn. This call is asynchronous.
 		if _, err := fes.backendServer.BroadcastTransaction(bitcloutTxn); err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Problem broadcasting "+
+			_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("BurnBitcoin: Problem broadcasting "+
 				"bitclout txn: %v", err))
 			return
 		}
@@ -758,26 +767,27 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 		/*********************************************************************
 		// Update our global state record of how much the user has bought
 		*********************************************************************/
-		userMetadata, err := fes.getUserMetadataFromGlobalState(requestData.PublicKeyBase58Check)
+		userPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
-				"BurnBitcoin: Problem with getUserMetadataFromGlobalState: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
+				"BurnBitcoin: Problem decoding public key: %v", err))
 			return
 		}
 
-		userMetadata.SatoshisBurnedSoFar += totalInputSatoshis
-
-		// If the user has burned enough to create a profile, update that boolean
-		if userMetadata.SatoshisBurnedSoFar >= fes.MinSatoshisBurnedForProfileCreation {
-			userMetadata.HasBurnedEnoughSatoshisToCreateProfile = true
-		}
-
 		// Update the amount of BitClout purchased so far based on the purchase
 		// the user just made
-		err = fes.putUserMetadataInGlobalState(userMetadata)
+		_, err = fes.updateUserMetadataInGlobalState(userPublicKeyBytes, func(userMetadata *UserMetadata) error {
+			userMetadata.SatoshisBurnedSoFar += totalInputSatoshis
+
+			// If the user has burned enough to create a profile, update that boolean
+			if userMetadata.SatoshisBurnedSoFar >= fes.MinSatoshisBurnedForProfileCreation {
+				userMetadata.HasBurnedEnoughSatoshisToCreateProfile = true
+			}
+			return nil
+		})
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
-				"BurnBitcoin: Problem with putUserMetadataInGlobalState: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf(
+				"BurnBitcoin: Problem with updateUserMetadataInGlobalState: %v", err))
 			return
 		}
 	}
@@ -800,7 +810,7 @@ func (fes *APIServer) BurnBitcoinStateless(ww http.ResponseWriter, req *http.Req
 		UnsignedHashes: unsignedHashes,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BurnBitcoin: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("BurnBitcoin: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -830,7 +840,7 @@ func (fes *APIServer) SendBitClout(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SendBitCloutRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SendBitClout: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -845,7 +855,7 @@ func (fes *APIServer) SendBitClout(ww http.ResponseWriter, req *http.Request) {
 		var err error
 		recipientPkBytes, _, err = lib.Base58CheckDecode(requestData.RecipientPublicKeyOrUsername)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Problem decoding recipient "+
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("SendBitClout: Problem decoding recipient "+
 				"base58 public key %s: %v", requestData.RecipientPublicKeyOrUsername, err))
 			return
 		}
@@ -854,28 +864,28 @@ func (fes *APIServer) SendBitClout(ww http.ResponseWriter, req *http.Request) {
 		// transactions.
 		utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Error generating "+
+			_AddInternalServerError(ww, fmt.Sprintf("SendBitClout: Error generating "+
 				"view to verify username: %v", err))
 			return
 		}
 		profileEntry := utxoView.GetProfileEntryForUsername(
 			[]byte(requestData.RecipientPublicKeyOrUsername))
 		if profileEntry == nil {
-			_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Profile with username "+
+			_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("SendBitClout: Profile with username "+
 				"%v does not exist", requestData.RecipientPublicKeyOrUsername))
 			return
 		}
 		recipientPkBytes = profileEntry.PublicKey
 	}
 	if len(recipientPkBytes) == 0 {
-		_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Unknown error parsing public key."))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("SendBitClout: Unknown error parsing public key."))
 		return
 	}
 
 	// Decode the sender public key.
 	senderPkBytes, _, err := lib.Base58CheckDecode(requestData.SenderPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Problem decoding sender base58 public key %s: %v", requestData.SenderPublicKeyBase58Check, err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("SendBitClout: Problem decoding sender base58 public key %s: %v", requestData.SenderPublicKeyBase58Check, err))
 		return
 	}
 
@@ -892,7 +902,7 @@ func (fes *APIServer) SendBitClout(ww http.ResponseWriter, req *http.Request) {
 			senderPkBytes, recipientPkBytes, requestData.MinFeeRateNanosPerKB,
 			fes.backendServer.GetMempool())
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Error processing MAX transaction: %v", err))
+			_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("SendBitClout: Error processing MAX transaction: %v", err))
 			return
 		}
 
@@ -927,7 +937,7 @@ func (fes *APIServer) SendBitClout(ww http.ResponseWriter, req *http.Request) {
 			fes.blockchain.AddInputsAndChangeToTransaction(
 				txnn, requestData.MinFeeRateNanosPerKB, fes.mempool)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Error processing transaction: %v", err))
+			_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("SendBitClout: Error processing transaction: %v", err))
 			return
 		}
 	}
@@ -935,7 +945,7 @@ func (fes *APIServer) SendBitClout(ww http.ResponseWriter, req *http.Request) {
 	// Sanity check that the input is equal to:
 	//   (spend amount + change amount + fees)
 	if totalInputt != (spendAmountt + changeAmountt + feeNanoss) {
-		_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: totalInput=%d is not equal "+
+		_AddInternalServerError(ww, fmt.Sprintf("SendBitClout: totalInput=%d is not equal "+
 			"to the sum of the (spend amount=%d, change=%d, and fees=%d) which sums "+
 			"to %d. This means there was likely a problem with CreateMaxSpend",
 			totalInputt, spendAmountt, changeAmountt, feeNanoss, (spendAmountt+changeAmountt+feeNanoss)))
@@ -949,7 +959,7 @@ func (fes *APIServer) SendBitClout(ww http.ResponseWriter, req *http.Request) {
 
 	txnBytes, err := txnn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendBitClout: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -968,7 +978,7 @@ func (fes *APIServer) SendBitClout(ww http.ResponseWriter, req *http.Request) {
 		TxnHashHex:               txnn.Hash().String(),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendBitClout: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendBitClout: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -996,14 +1006,14 @@ func (fes *APIServer) CreateLikeStateless(ww http.ResponseWriter, req *http.Requ
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := CreateLikeStatelessRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateLikeStateless: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("CreateLikeStateless: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the post hash for the liked post.
 	postHashBytes, err := hex.DecodeString(requestData.LikedPostHashHex)
 	if err != nil || len(postHashBytes) != lib.HashSizeBytes {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"GetLikesStateless: Error parsing post hash %v: %v",
 			requestData.LikedPostHashHex, err))
 		return
@@ -1012,7 +1022,7 @@ func (fes *APIServer) CreateLikeStateless(ww http.ResponseWriter, req *http.Requ
 	// Decode the reader public key.
 	readerPkBytes, _, err := lib.Base58CheckDecode(requestData.ReaderPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateLikeStateless: Problem decoding sender "+
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("CreateLikeStateless: Problem decoding sender "+
 			"base58 public key %s: %v", requestData.ReaderPublicKeyBase58Check, err))
 		return
 	}
@@ -1026,13 +1036,13 @@ func (fes *APIServer) CreateLikeStateless(ww http.ResponseWriter, req *http.Requ
 		readerPkBytes, postHash, requestData.IsUnlike,
 		requestData.MinFeeRateNanosPerKB, fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateLikeStateless: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("CreateLikeStateless: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateLikeStateless: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("CreateLikeStateless: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -1044,7 +1054,7 @@ func (fes *APIServer) CreateLikeStateless(ww http.ResponseWriter, req *http.Requ
 		TransactionHex:    hex.EncodeToString(txnBytes),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateLikeStateless: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("CreateLikeStateless: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -1093,14 +1103,14 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SubmitPostRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPost: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SubmitPost: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the public key
 	updaterPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.UpdaterPublicKeyBase58Check)
 	if err != nil || len(updaterPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"SubmitPost: Problem decoding public key %s: %v",
 			requestData.UpdaterPublicKeyBase58Check, err))
 		return
@@ -1113,7 +1123,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 		if len(requestData.ParentStakeID) == lib.HashSizeBytes*2 {
 			parentStakeID, err = hex.DecodeString(requestData.ParentStakeID)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf(
+				_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 					"SubmitPost: Problem decoding parent stake ID %v: %v",
 					requestData.ParentStakeID, err))
 				return
@@ -1123,14 +1133,14 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 
 			parentStakeID, _, err = lib.Base58CheckDecode(requestData.ParentStakeID)
 			if err != nil || len(parentStakeID) != btcec.PubKeyBytesLenCompressed {
-				_AddBadRequestError(ww, fmt.Sprintf(
+				_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 					"SubmitPost: Problem decoding parent stake ID as public key %v: %v",
 					requestData.ParentStakeID, err))
 				return
 			}
 
 		} else {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"SubmitPost: Unrecognized parent stake ID: %v",
 				requestData.ParentStakeID))
 			return
@@ -1143,13 +1153,13 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 	if requestData.PostHashHexToModify != "" {
 		postHashToModifyBytes, err := hex.DecodeString(requestData.PostHashHexToModify)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"SubmitPost: Problem decoding PostHashHexToModify %v: %v",
 				requestData.PostHashHexToModify, err))
 			return
 		}
 		if len(postHashToModifyBytes) != lib.HashSizeBytes {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"SubmitPost: Invalid length for PostHashHexToModify %v",
 				requestData.PostHashHexToModify))
 			return
@@ -1165,7 +1175,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 	if len(postHashToModify) == 0 {
 		// Verify that the body length is greater than the minimum.
 		if requestData.BodyObj == nil {
-			_AddBadRequestError(ww, fmt.Sprintf("SubmitPost: BodyObj is required"))
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SubmitPost: BodyObj is required"))
 			return
 		}
 
@@ -1176,7 +1186,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 			// Convert the post hash hex of the reclouted post to bytes
 			recloutPostHashBytes, err = hex.DecodeString(requestData.RecloutedPostHashHex)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("SubmitPost: Could not decode Reclout Post Hash Hex"))
+				_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SubmitPost: Could not decode Reclout Post Hash Hex"))
 			}
 			// Check that the post being reclouted isn't a reclout without a comment.  A user should only be able to reclout
 			// a reclout post if it is a quote reclout.
@@ -1184,7 +1194,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 				var utxoView *lib.UtxoView
 				utxoView, err = fes.backendServer.GetMempool().GetAugmentedUniversalView()
 				if err != nil {
-					_AddBadRequestError(ww, fmt.Sprintf("SubmitPost: Error getting utxoView"))
+					_AddInternalServerError(ww, fmt.Sprintf("SubmitPost: Error getting utxoView"))
 					return
 				}
 
@@ -1196,7 +1206,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 				// If the body of the post that we are trying to reclout is empty, this is an error as
 				// we do not want to allow a user to reclout
 				if lib.IsVanillaReclout(recloutPostEntry) {
-					_AddBadRequestError(ww, fmt.Sprintf("SubmitPost: Cannot reclout a post that is a reclout without a quote"))
+					_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SubmitPost: Cannot reclout a post that is a reclout without a quote"))
 					return
 				}
 			} else {
@@ -1206,7 +1216,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 		bodyBytes, err = fes.cleanBody(requestData.BodyObj, isReclout)
 
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"SubmitPost: Error validating body bytes: %v", err))
 			return
 		}
@@ -1216,7 +1226,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 		if requestData.RecloutedPostHashHex != "" {
 			recloutPostHashBytes, err = hex.DecodeString(requestData.RecloutedPostHashHex)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("SubmitPost: Could not decode Reclout Post Hash Hex"))
+				_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SubmitPost: Could not decode Reclout Post Hash Hex"))
 			}
 			isReclout = true
 			if requestData.BodyObj.Body != "" || len(requestData.BodyObj.ImageURLs) > 0 {
@@ -1226,7 +1236,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 		if requestData.BodyObj != nil {
 			bodyBytes, err = fes.cleanBody(requestData.BodyObj, isReclout /*isReclout*/)
 			if err != nil {
-				_AddBadRequestError(ww, err.Error())
+				_AddCodedError(ww, ErrorCodeBadRequest, err.Error())
 				return
 			}
 		}
@@ -1248,13 +1258,13 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 		requestData.IsHidden,
 		requestData.MinFeeRateNanosPerKB, fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPost: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("SubmitPost: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPost: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SubmitPost: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -1271,7 +1281,7 @@ func (fes *APIServer) SubmitPost(ww http.ResponseWriter, req *http.Request) {
 		TransactionHex:    hex.EncodeToString(txnBytes),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendMessage: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendMessage: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -1340,14 +1350,14 @@ func (fes *APIServer) CreateFollowTxnStateless(ww http.ResponseWriter, req *http
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := CreateFollowTxnStatelessRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateFollowTxnStateless: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("CreateFollowTxnStateless: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the follower public key.
 	followerPkBytes, _, err := lib.Base58CheckDecode(requestData.FollowerPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateFollowTxnStateless: Problem decoding sender "+
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("CreateFollowTxnStateless: Problem decoding sender "+
 			"base58 public key %s: %v", requestData.FollowerPublicKeyBase58Check, err))
 		return
 	}
@@ -1355,7 +1365,7 @@ func (fes *APIServer) CreateFollowTxnStateless(ww http.ResponseWriter, req *http
 	// Decode the followed person's public key.
 	followedPkBytes, _, err := lib.Base58CheckDecode(requestData.FollowedPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateFollowTxnStateless: Problem decoding recipient "+
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("CreateFollowTxnStateless: Problem decoding recipient "+
 			"base58 public key %s: %v", requestData.FollowedPublicKeyBase58Check, err))
 		return
 	}
@@ -1365,13 +1375,13 @@ func (fes *APIServer) CreateFollowTxnStateless(ww http.ResponseWriter, req *http
 		followerPkBytes, followedPkBytes, requestData.IsUnfollow,
 		requestData.MinFeeRateNanosPerKB, fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateFollowTxnStateless: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("CreateFollowTxnStateless: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateFollowTxnStateless: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("CreateFollowTxnStateless: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -1384,7 +1394,7 @@ func (fes *APIServer) CreateFollowTxnStateless(ww http.ResponseWriter, req *http
 		TransactionHex:    hex.EncodeToString(txnBytes),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("CreateFollowTxnStateless: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("CreateFollowTxnStateless: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -1447,14 +1457,14 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := BuyOrSellCreatorCoinRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("BuyOrSellCreatorCoin: Problem parsing request body: %v", err))
 		return
 	}
 
 	// Decode the updater public key
 	updaterPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.UpdaterPublicKeyBase58Check)
 	if err != nil || len(updaterPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"BuyOrSellCreatorCoin: Problem decoding updater public key %s: %v",
 			requestData.UpdaterPublicKeyBase58Check, err))
 		return
@@ -1463,20 +1473,20 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 	// Decode the creator public key
 	creatorPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.CreatorPublicKeyBase58Check)
 	if err != nil || len(creatorPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"BuyOrSellCreatorCoin: Problem decoding creator public key %s: %v",
 			requestData.CreatorPublicKeyBase58Check, err))
 		return
 	}
 
 	if requestData.BitCloutToSellNanos == 0 && requestData.CreatorCoinToSellNanos == 0 {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"BuyOrSellCreatorCoin: One of the following is required: "+
 				"{BitCloutToSellNanos, CreatorCoinToSellNanos}"))
 		return
 	}
 	if requestData.BitCloutToAddNanos != 0 {
-		_AddBadRequestError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: BitCloutToAddNanos not yet supported"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("BuyOrSellCreatorCoin: BitCloutToAddNanos not yet supported"))
 		return
 	}
 
@@ -1486,7 +1496,7 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 	} else if requestData.OperationType == "sell" {
 		operationType = lib.CreatorCoinOperationTypeSell
 	} else {
-		_AddBadRequestError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: OperationType \"%v\" not supported",
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("BuyOrSellCreatorCoin: OperationType \"%v\" not supported",
 			requestData.OperationType))
 		return
 	}
@@ -1505,7 +1515,7 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 		// Standard transaction fields
 		requestData.MinFeeRateNanosPerKB, fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: Problem adding inputs and change transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("BuyOrSellCreatorCoin: Problem adding inputs and change transaction: %v", err))
 		return
 	}
 
@@ -1518,7 +1528,7 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 	{
 		utxoView, err := fes.mempool.GetAugmentedUtxoViewForPublicKey(updaterPublicKeyBytes, txn)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: Problem computing view for transaction: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: Problem computing view for transaction: %v", err))
 			return
 		}
 		txHash := txn.Hash()
@@ -1527,7 +1537,7 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 			_, _, creatorCoinReturnedNanos, founderRewardNanos, _, err :=
 				utxoView.HelpConnectCreatorCoinBuy(txn, txHash, blockHeight, false /*verifySignatures*/)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: Problem connecting buy transaction: %v", err))
+				_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("BuyOrSellCreatorCoin: Problem connecting buy transaction: %v", err))
 				return
 			}
 			ExpectedCreatorCoinReturnedNanos = creatorCoinReturnedNanos
@@ -1536,13 +1546,13 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 			_, _, bitCloutreturnedNanos, _, err :=
 				utxoView.HelpConnectCreatorCoinSell(txn, txHash, blockHeight, false /*verifySignatures*/)
 			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: Problem connecting sell transaction: %v", err))
+				_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("BuyOrSellCreatorCoin: Problem connecting sell transaction: %v", err))
 				return
 			}
 			ExpectedBitCloutReturnedNanos = bitCloutreturnedNanos
 
 		} else {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 				"BuyOrSellCreatorCoin: OperationType \"%v\" not supported",
 				requestData.OperationType))
 			return
@@ -1551,7 +1561,7 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("BuyOrSellCreatorCoin: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -1570,7 +1580,7 @@ func (fes *APIServer) BuyOrSellCreatorCoin(ww http.ResponseWriter, req *http.Req
 		TxnHashHex:        txn.Hash().String(),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendMessage: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendMessage: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -1608,21 +1618,21 @@ func (fes *APIServer) TransferCreatorCoin(ww http.ResponseWriter, req *http.Requ
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := TransferCreatorCoinRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("TransferCreatorCoin: Problem parsing request body: %v", err))
 		return
 	}
 
 	if requestData.SenderPublicKeyBase58Check == "" ||
 		requestData.CreatorPublicKeyBase58Check == "" ||
 		requestData.ReceiverUsernameOrPublicKeyBase58Check == "" {
-		_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Must provide a sender, a creator, and a receiver."))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("TransferCreatorCoin: Must provide a sender, a creator, and a receiver."))
 		return
 	}
 
 	// Decode the updater public key
 	senderPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.SenderPublicKeyBase58Check)
 	if err != nil || len(senderPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Problem decoding sender public key %s: %v",
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("TransferCreatorCoin: Problem decoding sender public key %s: %v",
 			requestData.SenderPublicKeyBase58Check, err))
 		return
 	}
@@ -1630,7 +1640,7 @@ func (fes *APIServer) TransferCreatorCoin(ww http.ResponseWriter, req *http.Requ
 	// Decode the creator public key
 	creatorPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.CreatorPublicKeyBase58Check)
 	if err != nil || len(creatorPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Problem decoding creator public key %s: %v",
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("TransferCreatorCoin: Problem decoding creator public key %s: %v",
 			requestData.CreatorPublicKeyBase58Check, err))
 		return
 	}
@@ -1641,13 +1651,13 @@ func (fes *APIServer) TransferCreatorCoin(ww http.ResponseWriter, req *http.Requ
 		// The receiver string is too short to be a public key.  Lookup the username.
 		utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Problem fetching utxoView: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("TransferCreatorCoin: Problem fetching utxoView: %v", err))
 			return
 		}
 
 		profile := utxoView.GetProfileEntryForUsername([]byte(requestData.ReceiverUsernameOrPublicKeyBase58Check))
 		if profile == nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf(
 				"TransferCreatorCoin: Problem getting profile for username: %v : %s", err, requestData.ReceiverUsernameOrPublicKeyBase58Check))
 			return
 		}
@@ -1656,7 +1666,7 @@ func (fes *APIServer) TransferCreatorCoin(ww http.ResponseWriter, req *http.Requ
 		// Decode the receiver public key
 		receiverPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.ReceiverUsernameOrPublicKeyBase58Check)
 		if err != nil || len(receiverPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 				"TransferCreatorCoin: Problem decoding receiver public key %s: %v",
 				requestData.ReceiverUsernameOrPublicKeyBase58Check, err))
 			return
@@ -1664,12 +1674,12 @@ func (fes *APIServer) TransferCreatorCoin(ww http.ResponseWriter, req *http.Requ
 	}
 
 	if reflect.DeepEqual(senderPublicKeyBytes, receiverPublicKeyBytes) {
-		_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Sender and receiver cannot be the same."))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("TransferCreatorCoin: Sender and receiver cannot be the same."))
 		return
 	}
 
 	if requestData.CreatorCoinToTransferNanos < fes.Params.CreatorCoinAutoSellThresholdNanos {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"TransferCreatorCoin: CreatorCoinToTransferNanos must be greater than %d nanos",
 			fes.Params.CreatorCoinAutoSellThresholdNanos))
 		return
@@ -1684,13 +1694,13 @@ func (fes *APIServer) TransferCreatorCoin(ww http.ResponseWriter, req *http.Requ
 		// Standard transaction fields
 		requestData.MinFeeRateNanosPerKB, fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("TransferCreatorCoin: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("TransferCreatorCoin: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -1705,7 +1715,7 @@ func (fes *APIServer) TransferCreatorCoin(ww http.ResponseWriter, req *http.Requ
 		TxnHashHex:        txn.Hash().String(),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("TransferCreatorCoin: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("TransferCreatorCoin: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -1743,7 +1753,7 @@ func (fes *APIServer) SendDiamonds(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SendDiamondsRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendDiamonds: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SendDiamonds: Problem parsing request body: %v", err))
 		return
 
 	}
@@ -1751,14 +1761,14 @@ func (fes *APIServer) SendDiamonds(ww http.ResponseWriter, req *http.Request) {
 	if requestData.SenderPublicKeyBase58Check == "" ||
 		requestData.ReceiverPublicKeyBase58Check == "" ||
 		requestData.DiamondPostHashHex == "" {
-		_AddBadRequestError(ww, fmt.Sprintf("SendDiamonds: Must provide a sender, a receiver, and a post hash to diamond."))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SendDiamonds: Must provide a sender, a receiver, and a post hash to diamond."))
 		return
 	}
 
 	// Decode the sender public key
 	senderPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.SenderPublicKeyBase58Check)
 	if err != nil || len(senderPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"SendDiamonds: Problem decoding sender public key %s: %v",
 			requestData.SenderPublicKeyBase58Check, err))
 		return
@@ -1767,7 +1777,7 @@ func (fes *APIServer) SendDiamonds(ww http.ResponseWriter, req *http.Request) {
 	// Decode the receiver public key
 	receiverPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.ReceiverPublicKeyBase58Check)
 	if err != nil || len(receiverPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"SendDiamonds: Problem decoding receiver public key %s: %v",
 			requestData.ReceiverPublicKeyBase58Check, err))
 		return
@@ -1776,13 +1786,13 @@ func (fes *APIServer) SendDiamonds(ww http.ResponseWriter, req *http.Request) {
 	// Decode the diamond post hash.
 	diamondPostHashBytes, err := hex.DecodeString(requestData.DiamondPostHashHex)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"SendDiamonds: Problem decoding DiamondPostHashHex %v: %v",
 			requestData.DiamondPostHashHex, err))
 		return
 	}
 	if len(diamondPostHashBytes) != lib.HashSizeBytes {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf(
 			"SendDiamonds: Invalid length for DiamondPostHashHex %v",
 			requestData.DiamondPostHashHex))
 		return
@@ -1793,7 +1803,7 @@ func (fes *APIServer) SendDiamonds(ww http.ResponseWriter, req *http.Request) {
 	copy(diamondPostHash[:], diamondPostHashBytes[:])
 
 	if reflect.DeepEqual(senderPublicKeyBytes, receiverPublicKeyBytes) {
-		_AddBadRequestError(ww, fmt.Sprintf("SendDiamonds: Sender and receiver cannot be the same."))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SendDiamonds: Sender and receiver cannot be the same."))
 		return
 	}
 
@@ -1806,13 +1816,13 @@ func (fes *APIServer) SendDiamonds(ww http.ResponseWriter, req *http.Request) {
 		// Standard transaction fields
 		requestData.MinFeeRateNanosPerKB, fes.backendServer.GetMempool())
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendDiamonds: Problem creating transaction: %v", err))
+		_AddCodedError(ww, transactionErrorCode(err), fmt.Sprintf("SendDiamonds: Problem creating transaction: %v", err))
 		return
 	}
 
 	txnBytes, err := txn.ToBytes(true)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendDiamonds: Problem serializing transaction: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendDiamonds: Problem serializing transaction: %v", err))
 		return
 	}
 
@@ -1827,7 +1837,7 @@ func (fes *APIServer) SendDiamonds(ww http.ResponseWriter, req *http.Request) {
 		TxnHashHex:        txn.Hash().String(),
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendDiamonds: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SendDiamonds: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -0,0 +1,767 @@
+package routes
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/bitclout/core/lib"
+	chainlib "github.com/btcsuite/btcd/blockchain"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/golang/glog"
+	"github.com/sasha-s/go-deadlock"
+)
+
+// txindexPendingBlockKey holds the hash of the block whose transaction mappings
+// were written to the txindex db but which may not have been attached to the
+// txindex chain yet. Its first byte keeps it out of the range of prefixes the
+// core uses for the rest of the db.
+var txindexPendingBlockKey = append([]byte{0xff}, []byte("TxindexPendingBlock")...)
+
+// NewTxindexChain returns the txindex chain stored in txindexDB, adding the
+// mappings for the seed balances and seed txns first if the db is new.
+func NewTxindexChain(txindexDB *badger.DB, params *lib.BitCloutParams,
+	bitcoinManager *lib.BitcoinManager) (*lib.Blockchain, error) {
+
+	// See if we have a best chain hash stored in the txindex db.
+	bestBlockHashBeforeInit := lib.DbGetBestHash(txindexDB, lib.ChainTypeBitCloutBlock)
+
+	// If we haven't initialized the txIndexChain before, set up the
+	// seed mappings.
+	if bestBlockHashBeforeInit == nil {
+
+		// Add the seed balances. Originate them from the architect public key and
+		// set their block as the genesis block.
+		{
+			dummyPk := lib.ArchitectPubKeyBase58Check
+			dummyTxn := &lib.MsgBitCloutTxn{
+				TxInputs:  []*lib.BitCloutInput{},
+				TxOutputs: params.SeedBalances,
+				TxnMeta:   &lib.BlockRewardMetadataa{},
+				PublicKey: lib.MustBase58CheckDecode(dummyPk),
+			}
+			affectedPublicKeys := []*lib.AffectedPublicKey{}
+			totalOutput := uint64(0)
+			for _, seedBal := range params.SeedBalances {
+				affectedPublicKeys = append(affectedPublicKeys, &lib.AffectedPublicKey{
+					PublicKeyBase58Check: lib.PkToString(seedBal.PublicKey, params),
+					Metadata:             "GenesisBlockSeedBalance",
+				})
+				totalOutput += seedBal.AmountNanos
+			}
+			err := lib.DbPutTxindexTransactionMappings(txindexDB, dummyTxn, params, &lib.TransactionMetadata{
+				TransactorPublicKeyBase58Check: dummyPk,
+				AffectedPublicKeys:             affectedPublicKeys,
+				BlockHashHex:                   lib.GenesisBlockHashHex,
+				TxnIndexInBlock:                uint64(0),
+				// Just set some dummy metadata
+				BasicTransferTxindexMetadata: &lib.BasicTransferTxindexMetadata{
+					TotalInputNanos:  0,
+					TotalOutputNanos: totalOutput,
+					FeeNanos:         0,
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("NewTxindexChain: Error initializing seed balances in txindex: %v", err)
+			}
+		}
+
+		// Add the other seed txns to the txn index.
+		for txnIndex, txnHex := range params.SeedTxns {
+			txnBytes, err := hex.DecodeString(txnHex)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"NewTxindexChain: Error decoding seed "+
+						"txn HEX: %v, txn index: %v, txn hex: %v",
+					err, txnIndex, txnHex)
+			}
+			txn := &lib.MsgBitCloutTxn{}
+			if err := txn.FromBytes(txnBytes); err != nil {
+				return nil, fmt.Errorf(
+					"NewTxindexChain: Error decoding seed "+
+						"txn BYTES: %v, txn index: %v, txn hex: %v",
+					err, txnIndex, txnHex)
+			}
+			err = lib.DbPutTxindexTransactionMappings(txindexDB, txn, params, &lib.TransactionMetadata{
+				TransactorPublicKeyBase58Check: lib.PkToString(txn.PublicKey, params),
+				// Note that we don't set AffectedPublicKeys for the SeedTxns
+				BlockHashHex:    lib.GenesisBlockHashHex,
+				TxnIndexInBlock: uint64(0),
+				// Just set some dummy metadata
+				BasicTransferTxindexMetadata: &lib.BasicTransferTxindexMetadata{
+					TotalInputNanos:  0,
+					TotalOutputNanos: 0,
+					FeeNanos:         0,
+				},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("NewTxindexChain: Error initializing seed txn %v in txindex: %v", txn, err)
+			}
+		}
+	}
+
+	// Note that we *DONT* pass the server here because the server is already
+	// tied to the to the main blockchain.
+	txindexChain, err := lib.NewBlockchain(
+		[]string{}, 0,
+		params, chainlib.NewMedianTime(), txindexDB,
+		bitcoinManager, nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewTxindexChain: Error initializing txindex chain: %v", err)
+	}
+	return txindexChain, nil
+}
+
+// NewTxindexAPIServer returns an APIServer that can only verify and rebuild the
+// txindex in txindexDB from the blocks in blockchain. It's used to run those
+// while the node is stopped.
+func NewTxindexAPIServer(blockchain *lib.Blockchain, txindexDB *badger.DB,
+	params *lib.BitCloutParams) (*APIServer, error) {
+
+	txindexChain, err := NewTxindexChain(txindexDB, params, nil)
+	if err != nil {
+		return nil, fmt.Errorf("NewTxindexAPIServer: %v", err)
+	}
+	return &APIServer{
+		blockchain:          blockchain,
+		TxIndexChain:        txindexChain,
+		Params:              params,
+		txindexUpdateSignal: make(chan struct{}, 1),
+	}, nil
+}
+
+// txindexBitcoinManager returns the BitcoinManager views of the txindex should
+// use, which is nil if we don't have one. This makes some tests pass.
+func (fes *APIServer) txindexBitcoinManager() *lib.BitcoinManager {
+	if fes.backendServer != nil && fes.backendServer.GetBitcoinManager() != nil {
+		return fes.backendServer.GetBitcoinManager()
+	}
+	return nil
+}
+
+// computeTxindexBlockMetadata connects the txns in blockMsg to a view of
+// txindexChain, whose tip must be the block's parent, and returns the txindex
+// metadata for each of them. Nothing is written to the db.
+func (fes *APIServer) computeTxindexBlockMetadata(txindexChain *lib.Blockchain,
+	blockNode *lib.BlockNode, blockMsg *lib.MsgBitCloutBlock) ([]*lib.TransactionMetadata, error) {
+
+	// We use a view to simulate adding transactions to our chain. This allows
+	// us to extract custom metadata fields that we can show in our block explorer.
+	utxoView, err := lib.NewUtxoView(txindexChain.DB(), fes.Params, fes.txindexBitcoinManager())
+	if err != nil {
+		return nil, fmt.Errorf("computeTxindexBlockMetadata: Error initializing UtxoView: %v", err)
+	}
+
+	txnMetas := make([]*lib.TransactionMetadata, 0, len(blockMsg.Txns))
+	for txnIndexInBlock, txn := range blockMsg.Txns {
+		txnMeta, err := lib.ConnectTxnAndComputeTransactionMetadata(
+			txn, utxoView, blockNode.Hash, blockNode.Height, uint64(txnIndexInBlock))
+		if err != nil {
+			return nil, fmt.Errorf("computeTxindexBlockMetadata: Problem connecting txn %v to txindex: %v",
+				txn, err)
+		}
+		txnMetas = append(txnMetas, txnMeta)
+	}
+	return txnMetas, nil
+}
+
+// attachTxindexBlock adds the mappings for the txns in the block to the txindex
+// and attaches the block to the txindex chain. The caller must hold TxIndexLock.
+//
+// The mappings are written in the same db transaction as txindexPendingBlockKey.
+// The key is only removed once the block is attached, so if we fail or crash in
+// between, recoverTxindexPendingBlock removes the mappings again before the block
+// is retried. Either way the mappings and the txindex tip never disagree.
+func (fes *APIServer) attachTxindexBlock(blockToAttach *lib.BlockNode) error {
+	blockMsg, err := lib.GetBlock(blockToAttach.Hash, fes.blockchain.DB())
+	if err != nil {
+		return fmt.Errorf("attachTxindexBlock: Problem fetching attach block "+
+			"with hash %v: %v", blockToAttach.Hash, err)
+	}
+
+	txnMetas, err := fes.computeTxindexBlockMetadata(fes.TxIndexChain, blockToAttach, blockMsg)
+	if err != nil {
+		return fmt.Errorf("attachTxindexBlock: %v", err)
+	}
+
+	err = fes.TxIndexChain.DB().Update(func(dbTxn *badger.Txn) error {
+		for txnIndexInBlock, txn := range blockMsg.Txns {
+			err := lib.DbPutTxindexTransactionMappingsWithTxn(dbTxn, txn, fes.Params, txnMetas[txnIndexInBlock])
+			if err != nil {
+				return fmt.Errorf("Problem adding txn %v to txindex: %v", txn, err)
+			}
+		}
+		return dbTxn.Set(txindexPendingBlockKey, blockToAttach.Hash[:])
+	})
+	if err != nil {
+		return fmt.Errorf("attachTxindexBlock: Problem writing mappings for block %v: %v",
+			blockToAttach, err)
+	}
+
+	// Now that we have added all the txns to our TxIndex db, attach the block
+	// to update our chain.
+	_, _, err = fes.TxIndexChain.ProcessBlock(blockMsg, false /*verifySignatures*/)
+	if err != nil {
+		// Take the mappings back out now rather than waiting for the next update.
+		if recoverErr := fes.recoverTxindexPendingBlock(); recoverErr != nil {
+			glog.Errorf("attachTxindexBlock: %v", recoverErr)
+		}
+		return fmt.Errorf("attachTxindexBlock: Problem attaching block %v: %v",
+			blockToAttach, err)
+	}
+
+	err = fes.TxIndexChain.DB().Update(func(dbTxn *badger.Txn) error {
+		return dbTxn.Delete(txindexPendingBlockKey)
+	})
+	if err != nil {
+		return fmt.Errorf("attachTxindexBlock: Problem clearing pending block %v: %v",
+			blockToAttach, err)
+	}
+	return nil
+}
+
+// recoverTxindexPendingBlock finishes an attachTxindexBlock that was interrupted.
+// If the pending block never became the txindex tip, its mappings are removed.
+// The caller must hold TxIndexLock.
+func (fes *APIServer) recoverTxindexPendingBlock() error {
+	var pendingHash *lib.BlockHash
+	err := fes.TxIndexChain.DB().View(func(dbTxn *badger.Txn) error {
+		item, err := dbTxn.Get(txindexPendingBlockKey)
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		hashBytes, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		pendingHash = &lib.BlockHash{}
+		copy(pendingHash[:], hashBytes)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("recoverTxindexPendingBlock: Problem getting pending block: %v", err)
+	}
+	if pendingHash == nil {
+		return nil
+	}
+
+	// If the block became the tip, only clearing the key was left to do.
+	if *fes.TxIndexChain.BlockTip().Hash != *pendingHash {
+		glog.Infof("recoverTxindexPendingBlock: Removing mappings for block %v, which was "+
+			"never attached to the txindex", pendingHash)
+		blockMsg, err := lib.GetBlock(pendingHash, fes.blockchain.DB())
+		if err != nil {
+			return fmt.Errorf("recoverTxindexPendingBlock: Problem fetching pending block "+
+				"with hash %v: %v", pendingHash, err)
+		}
+		for _, txn := range blockMsg.Txns {
+			// An earlier recovery may have removed some of the mappings already.
+			if lib.DbGetTxindexTransactionRefByTxID(fes.TxIndexChain.DB(), txn.Hash()) == nil {
+				continue
+			}
+			if err := lib.DbDeleteTxindexTransactionMappings(
+				fes.TxIndexChain.DB(), txn, fes.Params); err != nil {
+
+				return fmt.Errorf("recoverTxindexPendingBlock: Problem deleting "+
+					"transaction mappings for transaction %v: %v", txn.Hash(), err)
+			}
+		}
+	}
+
+	err = fes.TxIndexChain.DB().Update(func(dbTxn *badger.Txn) error {
+		return dbTxn.Delete(txindexPendingBlockKey)
+	})
+	if err != nil {
+		return fmt.Errorf("recoverTxindexPendingBlock: Problem clearing pending block %v: %v",
+			pendingHash, err)
+	}
+	return nil
+}
+
+// detachTxindexBlocks detaches blocksToDetach from the txindex chain in order. The
+// first must be the txindex tip and each one after it the parent of the one
+// before. It stops early if the APIServer is stopping, and returns how many
+// blocks were detached either way. progress, if set, is called with the new
+// txindex tip height after each block. The caller must hold TxIndexLock.
+//
+// Each block is taken out of the db in a single db transaction, so the in-memory
+// chain is only updated once, at the end, for however many blocks made it out.
+func (fes *APIServer) detachTxindexBlocks(blocksToDetach []*lib.BlockNode,
+	progress func(tipHeight uint32)) (_numDetached int, _err error) {
+
+	numDetached := 0
+	defer func() {
+		if numDetached == 0 {
+			return
+		}
+		// Remove the blocks from our bestChain data structures.
+		newBlockIndex := fes.TxIndexChain.CopyBlockIndex()
+		newBestChain, newBestChainMap := fes.TxIndexChain.CopyBestChain()
+		newBestChain = newBestChain[:len(newBestChain)-numDetached]
+		for _, blockToDetach := range blocksToDetach[:numDetached] {
+			delete(newBestChainMap, *(blockToDetach.Hash))
+			delete(newBlockIndex, *(blockToDetach.Hash))
+		}
+		fes.TxIndexChain.SetBestChainMap(newBestChain, newBestChainMap, newBlockIndex)
+	}()
+
+	for _, blockToDetach := range blocksToDetach {
+		if fes.IsStopping() {
+			glog.Infof("detachTxindexBlocks: Stopping before detaching block %d because the "+
+				"APIServer is stopping", blockToDetach.Height)
+			break
+		}
+		if err := fes.detachTxindexBlock(blockToDetach); err != nil {
+			return numDetached, err
+		}
+		numDetached++
+		if progress != nil {
+			progress(blockToDetach.Height - 1)
+		}
+	}
+	return numDetached, nil
+}
+
+// detachTxindexBlock removes the mappings for the txns in the block, which must be
+// the txindex tip in the db, and disconnects it from the txindex chain in the db.
+// The in-memory chain is left to detachTxindexBlocks.
+//
+// The mappings, the disconnected view, the new best hash and the removal of the
+// block are all written in one db transaction, so a crash leaves the txindex
+// either at the block or at its parent, never in between.
+func (fes *APIServer) detachTxindexBlock(blockToDetach *lib.BlockNode) error {
+	glog.Debugf("detachTxindexBlock: Detaching block (height: %d, hash: %v)",
+		blockToDetach.Height, blockToDetach.Hash)
+	blockMsg, err := lib.GetBlock(blockToDetach.Hash, fes.TxIndexChain.DB())
+	if err != nil {
+		return fmt.Errorf("detachTxindexBlock: Problem fetching detach block "+
+			"with hash %v: %v", blockToDetach.Hash, err)
+	}
+
+	// Disconnect the block from a view first. Nothing is written until the view
+	// is flushed below.
+	utxoView, err := lib.NewUtxoView(
+		fes.TxIndexChain.DB(), fes.Params, fes.txindexBitcoinManager())
+	if err != nil {
+		return fmt.Errorf(
+			"detachTxindexBlock: Error initializing UtxoView: %v", err)
+	}
+	utxoOps, err := lib.GetUtxoOperationsForBlock(
+		fes.TxIndexChain.DB(), blockToDetach.Hash)
+	if err != nil {
+		return fmt.Errorf(
+			"detachTxindexBlock: Error getting UtxoOps for block %v: %v", blockToDetach, err)
+	}
+	// Compute the hashes for all the transactions.
+	txHashes, err := lib.ComputeTransactionHashes(blockMsg.Txns)
+	if err != nil {
+		return fmt.Errorf(
+			"detachTxindexBlock: Error computing tx hashes for block %v: %v",
+			blockToDetach, err)
+	}
+	if err := utxoView.DisconnectBlock(blockMsg, txHashes, utxoOps); err != nil {
+		return fmt.Errorf("detachTxindexBlock: Error detaching block "+
+			"%v from UtxoView: %v", blockToDetach, err)
+	}
+
+	err = fes.TxIndexChain.DB().Update(func(dbTxn *badger.Txn) error {
+		// Delete the mappings for each transaction in the block. Note the txindex
+		// has its own db that is distinct and isolated from our core blockchain
+		// db. Mappings that are already gone are skipped so that RebuildTxindex
+		// can get past them.
+		for _, txn := range blockMsg.Txns {
+			if lib.DbGetTxindexTransactionRefByTxIDWithTxn(dbTxn, txn.Hash()) == nil {
+				glog.Warningf("detachTxindexBlock: No mappings found for transaction %v in block %v",
+					txn.Hash(), blockToDetach.Hash)
+				continue
+			}
+			if err := lib.DbDeleteTxindexTransactionMappingsWithTxn(dbTxn, txn, fes.Params); err != nil {
+				return fmt.Errorf("Problem deleting transaction mappings for transaction %v: %v",
+					txn.Hash(), err)
+			}
+		}
+		if err := utxoView.FlushToDbWithTxn(dbTxn); err != nil {
+			return fmt.Errorf("Error flushing view to db: %v", err)
+		}
+		// We have to flush a couple of extra things that the view doesn't flush...
+		if err := lib.PutBestHashWithTxn(dbTxn, utxoView.TipHash, lib.ChainTypeBitCloutBlock); err != nil {
+			return fmt.Errorf("Error putting best hash: %v", err)
+		}
+		// Delete this block from the chain db so we don't get duplicate block errors.
+		if err := lib.DeleteUtxoOperationsForBlockWithTxn(dbTxn, blockToDetach.Hash); err != nil {
+			return fmt.Errorf("Error deleting UtxoOperations: %v", err)
+		}
+		if err := dbTxn.Delete(lib.BlockHashToBlockKey(blockToDetach.Hash)); err != nil {
+			return fmt.Errorf("Error deleting block: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("detachTxindexBlock: Problem detaching block %v: %v", blockToDetach, err)
+	}
+	return nil
+}
+
+// The kinds of problems VerifyTxindex reports.
+const (
+	// Nothing is stored for the transaction.
+	TxindexVerifyMissing = "Missing"
+	// The stored metadata isn't what the block derives.
+	TxindexVerifyDifferent = "Different"
+)
+
+// TxindexVerifyMismatch is a transaction whose stored txindex metadata doesn't
+// match the metadata VerifyTxindex derived for it.
+type TxindexVerifyMismatch struct {
+	Problem      string
+	BlockHeight  uint32
+	BlockHashHex string
+	TxnHashHex   string
+	Expected     *lib.TransactionMetadata
+	Stored       *lib.TransactionMetadata `json:",omitempty"`
+}
+
+// TxindexVerifyReport is the result of a VerifyTxindex.
+type TxindexVerifyReport struct {
+	StartHeight      uint32
+	EndHeight        uint32
+	NumBlocksChecked int
+	NumTxnsChecked   int
+	Mismatches       []*TxindexVerifyMismatch
+}
+
+// VerifyTxindex derives the txindex metadata for every txn in the main chain
+// blocks from startHeight to endHeight and compares it with the metadata stored
+// in the txindex. An endHeight of zero means the txindex tip.
+//
+// A block's metadata depends on the state before it, so every block from the
+// genesis block on is replayed into a fresh txindex chain in scratchDB, which
+// must be empty. Only the blocks in the range are compared. progress, if set, is
+// called after each block is replayed.
+func (fes *APIServer) VerifyTxindex(scratchDB *badger.DB, startHeight uint32, endHeight uint32,
+	progress func(height uint32, endHeight uint32)) (*TxindexVerifyReport, error) {
+
+	if fes.TxIndexChain == nil {
+		return nil, fmt.Errorf("VerifyTxindex: Cannot be called when TxIndexChain is nil")
+	}
+	if lib.DbGetBestHash(scratchDB, lib.ChainTypeBitCloutBlock) != nil {
+		return nil, fmt.Errorf("VerifyTxindex: The scratch db must be empty")
+	}
+
+	// Blocks past the txindex tip haven't been indexed yet, so there's nothing to
+	// compare them with.
+	fes.TxIndexLock.RLock()
+	txindexTipHeight := fes.TxIndexChain.BlockTip().Height
+	fes.TxIndexLock.RUnlock()
+	bestChain := fes.blockchain.BestChain()
+	if endHeight == 0 || endHeight > txindexTipHeight {
+		endHeight = txindexTipHeight
+	}
+	if int(endHeight) >= len(bestChain) {
+		endHeight = uint32(len(bestChain) - 1)
+	}
+	// The genesis block's mappings are seeded rather than derived.
+	if startHeight == 0 {
+		startHeight = 1
+	}
+	report := &TxindexVerifyReport{
+		StartHeight: startHeight,
+		EndHeight:   endHeight,
+		Mismatches:  []*TxindexVerifyMismatch{},
+	}
+	if startHeight > endHeight {
+		return report, nil
+	}
+
+	scratchChain, err := NewTxindexChain(scratchDB, fes.Params, fes.txindexBitcoinManager())
+	if err != nil {
+		return nil, fmt.Errorf("VerifyTxindex: %v", err)
+	}
+
+	for height := uint32(1); height <= endHeight; height++ {
+		if fes.IsStopping() {
+			return nil, fmt.Errorf("VerifyTxindex: Stopped at block %d because the "+
+				"APIServer is stopping", height)
+		}
+		blockNode := bestChain[height]
+		blockMsg, err := lib.GetBlock(blockNode.Hash, fes.blockchain.DB())
+		if err != nil {
+			return nil, fmt.Errorf("VerifyTxindex: Problem fetching block "+
+				"with hash %v: %v", blockNode.Hash, err)
+		}
+
+		if height >= startHeight {
+			txnMetas, err := fes.computeTxindexBlockMetadata(scratchChain, blockNode, blockMsg)
+			if err != nil {
+				return nil, fmt.Errorf("VerifyTxindex: %v", err)
+			}
+			fes.TxIndexLock.RLock()
+			for txnIndexInBlock, txn := range blockMsg.Txns {
+				storedMeta := lib.DbGetTxindexTransactionRefByTxID(fes.TxIndexChain.DB(), txn.Hash())
+				mismatch := &TxindexVerifyMismatch{
+					BlockHeight:  height,
+					BlockHashHex: hex.EncodeToString(blockNode.Hash[:]),
+					TxnHashHex:   hex.EncodeToString(txn.Hash()[:]),
+					Expected:     txnMetas[txnIndexInBlock],
+					Stored:       storedMeta,
+				}
+				if storedMeta == nil {
+					mismatch.Problem = TxindexVerifyMissing
+				} else if matches, err := txindexMetadataMatches(txnMetas[txnIndexInBlock], storedMeta); err != nil {
+					fes.TxIndexLock.RUnlock()
+					return nil, fmt.Errorf("VerifyTxindex: Problem comparing metadata for txn %v: %v",
+						txn.Hash(), err)
+				} else if !matches {
+					mismatch.Problem = TxindexVerifyDifferent
+				}
+				if mismatch.Problem != "" {
+					report.Mismatches = append(report.Mismatches, mismatch)
+				}
+			}
+			fes.TxIndexLock.RUnlock()
+			report.NumBlocksChecked++
+			report.NumTxnsChecked += len(blockMsg.Txns)
+		}
+
+		if _, _, err := scratchChain.ProcessBlock(blockMsg, false /*verifySignatures*/); err != nil {
+			return nil, fmt.Errorf("VerifyTxindex: Problem replaying block %v: %v", blockNode, err)
+		}
+		if progress != nil {
+			progress(height, endHeight)
+		}
+	}
+	return report, nil
+}
+
+// txindexMetadataMatches returns true if the stored metadata is the same as the
+// expected metadata. Stored metadata goes through gob, which doesn't keep empty
+// slices and maps or zero fields apart from missing ones, so both are compared as
+// JSON with those left out.
+func txindexMetadataMatches(expected *lib.TransactionMetadata, stored *lib.TransactionMetadata) (bool, error) {
+	var values [2]interface{}
+	for ii, txnMeta := range []*lib.TransactionMetadata{expected, stored} {
+		jsonBytes, err := json.Marshal(txnMeta)
+		if err != nil {
+			return false, err
+		}
+		if err := json.Unmarshal(jsonBytes, &values[ii]); err != nil {
+			return false, err
+		}
+	}
+	return reflect.DeepEqual(pruneEmptyJSONValues(values[0]), pruneEmptyJSONValues(values[1])), nil
+}
+
+// pruneEmptyJSONValues returns value, as decoded by encoding/json, without any
+// nulls, zeros, empty strings, empty arrays or empty objects. nil is returned if
+// nothing is left.
+func pruneEmptyJSONValues(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		pruned := make(map[string]interface{})
+		for key, fieldValue := range typedValue {
+			if prunedFieldValue := pruneEmptyJSONValues(fieldValue); prunedFieldValue != nil {
+				pruned[key] = prunedFieldValue
+			}
+		}
+		if len(pruned) == 0 {
+			return nil
+		}
+		return pruned
+	case []interface{}:
+		if len(typedValue) == 0 {
+			return nil
+		}
+		// Keep the elements in place since their positions matter.
+		pruned := make([]interface{}, len(typedValue))
+		for ii, element := range typedValue {
+			pruned[ii] = pruneEmptyJSONValues(element)
+		}
+		return pruned
+	case string, float64, bool:
+		if reflect.ValueOf(typedValue).IsZero() {
+			return nil
+		}
+		return typedValue
+	default:
+		return nil
+	}
+}
+
+// TxindexRebuildStatus describes the most recent txindex rebuild.
+type TxindexRebuildStatus struct {
+	IsRunning  bool
+	FromHeight uint32
+	// The height of the txindex tip. It counts down to FromHeight-1 while the
+	// txindex is rewound, then up to TargetHeight.
+	CurrentHeight uint32
+	// The height of the block tip.
+	TargetHeight     uint32
+	StartTstampNanos uint64
+	// Zero until the rebuild finishes.
+	EndTstampNanos uint64
+	// Set if the rebuild failed.
+	Error string
+}
+
+// txindexRebuild tracks the txindex rebuild that's running, if any.
+type txindexRebuild struct {
+	mtx    deadlock.Mutex
+	status TxindexRebuildStatus
+	// Called with the status each time the txindex tip moves during the rebuild.
+	progress func(status TxindexRebuildStatus)
+	// Closed when the rebuild finishes.
+	done chan struct{}
+}
+
+func (rebuild *txindexRebuild) start(fromHeight uint32, progress func(status TxindexRebuildStatus)) error {
+	rebuild.mtx.Lock()
+	defer rebuild.mtx.Unlock()
+
+	if rebuild.status.IsRunning {
+		return fmt.Errorf("A rebuild from height %d is already running", rebuild.status.FromHeight)
+	}
+	rebuild.status = TxindexRebuildStatus{
+		IsRunning:        true,
+		FromHeight:       fromHeight,
+		StartTstampNanos: uint64(time.Now().UnixNano()),
+	}
+	rebuild.progress = progress
+	rebuild.done = make(chan struct{})
+	return nil
+}
+
+// recordHeight notes that the txindex tip moved to currentHeight. It's a no-op
+// unless a rebuild is running.
+func (rebuild *txindexRebuild) recordHeight(currentHeight uint32, targetHeight uint32) {
+	rebuild.mtx.Lock()
+	if !rebuild.status.IsRunning {
+		rebuild.mtx.Unlock()
+		return
+	}
+	rebuild.status.CurrentHeight = currentHeight
+	rebuild.status.TargetHeight = targetHeight
+	status, progress := rebuild.status, rebuild.progress
+	rebuild.mtx.Unlock()
+
+	if progress != nil {
+		progress(status)
+	}
+}
+
+func (rebuild *txindexRebuild) finish(err error) {
+	rebuild.mtx.Lock()
+	defer rebuild.mtx.Unlock()
+
+	rebuild.status.IsRunning = false
+	rebuild.status.EndTstampNanos = uint64(time.Now().UnixNano())
+	if err != nil {
+		rebuild.status.Error = err.Error()
+	}
+	rebuild.progress = nil
+	close(rebuild.done)
+}
+
+func (rebuild *txindexRebuild) getStatus() TxindexRebuildStatus {
+	rebuild.mtx.Lock()
+	defer rebuild.mtx.Unlock()
+
+	return rebuild.status
+}
+
+// runningDone returns a channel that's closed when the running rebuild finishes,
+// or nil if no rebuild is running.
+func (rebuild *txindexRebuild) runningDone() <-chan struct{} {
+	rebuild.mtx.Lock()
+	defer rebuild.mtx.Unlock()
+
+	if !rebuild.status.IsRunning {
+		return nil
+	}
+	return rebuild.done
+}
+
+// RebuildTxindex removes the txindex entries for every block from fromHeight on
+// and indexes those blocks again from the current main chain. A fromHeight of zero
+// rebuilds the whole txindex. progress, if set, is called each time a block is
+// removed or indexed. Only one rebuild can run at a time.
+func (fes *APIServer) RebuildTxindex(fromHeight uint32, progress func(status TxindexRebuildStatus)) error {
+	if err := fes.startTxindexRebuild(fromHeight, progress); err != nil {
+		return err
+	}
+	return fes.runTxindexRebuild(fromHeight)
+}
+
+func (fes *APIServer) startTxindexRebuild(fromHeight uint32, progress func(status TxindexRebuildStatus)) error {
+	if fes.TxIndexChain == nil {
+		return fmt.Errorf("RebuildTxindex: Cannot be called when TxIndexChain " +
+			"is nil. This error occurs when --txindex was not passed to the program " +
+			"on startup")
+	}
+	if err := fes.txindexRebuild.start(fromHeight, progress); err != nil {
+		return fmt.Errorf("RebuildTxindex: %v", err)
+	}
+	return nil
+}
+
+// runTxindexRebuild runs the rebuild started by startTxindexRebuild.
+func (fes *APIServer) runTxindexRebuild(fromHeight uint32) (_err error) {
+	defer func() {
+		fes.txindexRebuild.finish(_err)
+	}()
+
+	// The genesis block is only ever seeded.
+	if fromHeight == 0 {
+		fromHeight = 1
+	}
+
+	// Rewind the txindex in one go. The txindex loop would attach the blocks
+	// again if it got the lock in between.
+	err := func() error {
+		fes.TxIndexLock.Lock()
+		defer fes.TxIndexLock.Unlock()
+
+		if err := fes.recoverTxindexPendingBlock(); err != nil {
+			return err
+		}
+		glog.Infof("RebuildTxindex: Removing txindex entries from height %d to %d",
+			fromHeight, fes.TxIndexChain.BlockTip().Height)
+		bestChain, _ := fes.TxIndexChain.CopyBestChain()
+		var blocksToDetach []*lib.BlockNode
+		for ii := len(bestChain) - 1; ii >= 0 && bestChain[ii].Height >= fromHeight; ii-- {
+			blocksToDetach = append(blocksToDetach, bestChain[ii])
+		}
+		numDetached, err := fes.detachTxindexBlocks(blocksToDetach, func(tipHeight uint32) {
+			fes.txindexRebuild.recordHeight(tipHeight, fes.blockchain.BlockTip().Height)
+		})
+		if err != nil {
+			return err
+		}
+		if numDetached < len(blocksToDetach) {
+			return fmt.Errorf("Stopped at height %d because the APIServer is stopping",
+				fes.TxIndexChain.BlockTip().Height)
+		}
+		return nil
+	}()
+	if err != nil {
+		return fmt.Errorf("RebuildTxindex: Problem rewinding txindex: %v", err)
+	}
+
+	glog.Infof("RebuildTxindex: Indexing blocks from height %d to %d",
+		fromHeight, fes.blockchain.BlockTip().Height)
+	for fes.isTxindexBehindBlockTip() {
+		if fes.IsStopping() {
+			return fmt.Errorf("RebuildTxindex: Stopped because the APIServer is stopping")
+		}
+		if err := fes.updateTxindex(txindexMaxBlocksPerUpdate); err != nil {
+			return fmt.Errorf("RebuildTxindex: %v", err)
+		}
+	}
+	glog.Infof("RebuildTxindex: Rebuild complete. New tip: (height: %d, hash: %v)",
+		fes.TxIndexChain.BlockTip().Height, fes.TxIndexChain.BlockTip().Hash)
+	return nil
+}
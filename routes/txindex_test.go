@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"testing"
+
+	"github.com/bitclout/core/lib"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxindexVerifyAndRebuild(t *testing.T) {
+	require := require.New(t)
+
+	apiServer, _, miner := newTestAPIServer(t, "" /*globalStateRemoteNode*/)
+	for ii := 0; ii < 3; ii++ {
+		_, err := miner.MineAndProcessSingleBlock(0 /*threadIndex*/, apiServer.mempool)
+		require.NoError(err)
+	}
+	require.NoError(apiServer.UpdateTxindex())
+	tipHeight := apiServer.blockchain.BlockTip().Height
+
+	verify := func(startHeight uint32) *TxindexVerifyReport {
+		scratchDB, _ := GetTestBadgerDb()
+		defer scratchDB.Close()
+		report, err := apiServer.VerifyTxindex(scratchDB, startHeight, 0, nil)
+		require.NoError(err)
+		return report
+	}
+
+	// A txindex built by UpdateTxindex matches the chain.
+	report := verify(0)
+	require.Equal(uint32(1), report.StartHeight)
+	require.Equal(tipHeight, report.EndHeight)
+	require.Equal(int(tipHeight), report.NumBlocksChecked)
+	require.NotZero(report.NumTxnsChecked)
+	require.Empty(report.Mismatches)
+
+	// Lose the mappings for a block. Only ranges that include it are affected.
+	blockNode := apiServer.blockchain.BestChain()[2]
+	blockMsg, err := lib.GetBlock(blockNode.Hash, apiServer.blockchain.DB())
+	require.NoError(err)
+	for _, txn := range blockMsg.Txns {
+		require.NoError(lib.DbDeleteTxindexTransactionMappings(
+			apiServer.TxIndexChain.DB(), txn, apiServer.Params))
+	}
+	require.Empty(verify(3).Mismatches)
+	report = verify(2)
+	require.Len(report.Mismatches, len(blockMsg.Txns))
+	require.Equal(TxindexVerifyMissing, report.Mismatches[0].Problem)
+	require.Equal(uint32(2), report.Mismatches[0].BlockHeight)
+	require.Nil(report.Mismatches[0].Stored)
+
+	// Rebuilding from the block puts the mappings back, and reports the txindex
+	// tip going down to just below the block and back up again. Only one rebuild
+	// runs at a time.
+	statuses := []TxindexRebuildStatus{}
+	require.NoError(apiServer.startTxindexRebuild(2, func(status TxindexRebuildStatus) {
+		statuses = append(statuses, status)
+	}))
+	require.Error(apiServer.RebuildTxindex(2, nil))
+	require.NoError(apiServer.runTxindexRebuild(2))
+	require.Len(statuses, 2*int(tipHeight-1))
+	require.Equal(tipHeight-1, statuses[0].CurrentHeight)
+	require.Equal(uint32(1), statuses[tipHeight-2].CurrentHeight)
+	require.Equal(tipHeight, statuses[len(statuses)-1].CurrentHeight)
+	for _, status := range statuses {
+		require.True(status.IsRunning)
+		require.Equal(uint32(2), status.FromHeight)
+		require.Equal(tipHeight, status.TargetHeight)
+	}
+	status := apiServer.txindexRebuild.getStatus()
+	require.False(status.IsRunning)
+	require.Empty(status.Error)
+	require.NotZero(status.EndTstampNanos)
+	require.Equal(*apiServer.blockchain.BlockTip().Hash, *apiServer.TxIndexChain.BlockTip().Hash)
+	require.Empty(verify(0).Mismatches)
+}
+
+func TestTxindexPendingBlockRecovery(t *testing.T) {
+	require := require.New(t)
+
+	apiServer, _, miner := newTestAPIServer(t, "" /*globalStateRemoteNode*/)
+	require.NoError(apiServer.UpdateTxindex())
+
+	// Write the mappings for a new block the way attachTxindexBlock does, then stop
+	// before attaching it as if the node went down.
+	_, err := miner.MineAndProcessSingleBlock(0 /*threadIndex*/, apiServer.mempool)
+	require.NoError(err)
+	blockTip := apiServer.blockchain.BlockTip()
+	blockMsg, err := lib.GetBlock(blockTip.Hash, apiServer.blockchain.DB())
+	require.NoError(err)
+	txnMetas, err := apiServer.computeTxindexBlockMetadata(apiServer.TxIndexChain, blockTip, blockMsg)
+	require.NoError(err)
+	require.NoError(apiServer.TxIndexChain.DB().Update(func(dbTxn *badger.Txn) error {
+		for txnIndexInBlock, txn := range blockMsg.Txns {
+			err := lib.DbPutTxindexTransactionMappingsWithTxn(
+				dbTxn, txn, apiServer.Params, txnMetas[txnIndexInBlock])
+			if err != nil {
+				return err
+			}
+		}
+		return dbTxn.Set(txindexPendingBlockKey, blockTip.Hash[:])
+	}))
+	minerPublicKey := lib.MustBase58CheckDecode(senderPkString)
+	numMinerTxns := len(lib.DbGetTxindexTxnsForPublicKey(apiServer.TxIndexChain.DB(), minerPublicKey))
+
+	// The next update takes the mappings back out before attaching the block, so
+	// they aren't added twice.
+	require.NoError(apiServer.UpdateTxindex())
+	require.Equal(*blockTip.Hash, *apiServer.TxIndexChain.BlockTip().Hash)
+	require.Len(lib.DbGetTxindexTxnsForPublicKey(apiServer.TxIndexChain.DB(), minerPublicKey), numMinerTxns)
+	require.NoError(apiServer.TxIndexChain.DB().View(func(dbTxn *badger.Txn) error {
+		_, err := dbTxn.Get(txindexPendingBlockKey)
+		require.Equal(badger.ErrKeyNotFound, err)
+		return nil
+	}))
+
+	scratchDB, _ := GetTestBadgerDb()
+	defer scratchDB.Close()
+	report, err := apiServer.VerifyTxindex(scratchDB, 0, 0, nil)
+	require.NoError(err)
+	require.Empty(report.Mismatches)
+}
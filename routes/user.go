@@ -34,7 +34,7 @@ func (fes *APIServer) GetUsersStateless(ww http.ResponseWriter, rr *http.Request
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	getUsersRequest := GetUsersStatelessRequest{}
 	if err := decoder.Decode(&getUsersRequest); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetUsersStateless: Error parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetUsersStateless: Error parsing request body: %v", err))
 		return
 	}
 
@@ -49,7 +49,7 @@ func (fes *APIServer) GetUsersStateless(ww http.ResponseWriter, rr *http.Request
 
 	globalParams, err := fes.updateUsersStateless(userList)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetUsersStateless: Error fetching data for user: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetUsersStateless: Error fetching data for user: %v", err))
 		return
 	}
 
@@ -72,7 +72,7 @@ func (fes *APIServer) GetUsersStateless(ww http.ResponseWriter, rr *http.Request
 	}
 
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetUsers: Problem serializing object to Error: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetUsers: Problem serializing object to Error: %v", err))
 		return
 	}
 }
@@ -83,9 +83,22 @@ func (fes *APIServer) updateUsersStateless(userList []*User) (*lib.GlobalParamsE
 		return nil, fmt.Errorf("updateUserFields: Error calling GetAugmentedUtxoViewForPublicKey: %v", err)
 	}
 	globalParams := utxoView.GlobalParamsEntry
+	// Look up everyone's admin roles at once rather than making a global state
+	// call per user.
+	var adminRolesByPublicKey map[string][]AdminRole
+	if !fes.AdminOpenAccess {
+		publicKeysBase58Check := make([]string, 0, len(userList))
+		for _, user := range userList {
+			publicKeysBase58Check = append(publicKeysBase58Check, user.PublicKeyBase58Check)
+		}
+		adminRolesByPublicKey, err = fes.BatchGetAdminRoles(publicKeysBase58Check)
+		if err != nil {
+			return nil, fmt.Errorf("updateUserFields: %v", err)
+		}
+	}
 	for _, user := range userList {
 		// If we get an error updating the user, log it but don't stop the show.
-		if err = fes.updateUserFieldsStateless(user, utxoView); err != nil {
+		if err = fes.updateUserFieldsStateless(user, utxoView, adminRolesByPublicKey); err != nil {
 			glog.Errorf(fmt.Sprintf("updateUsers: Problem updating user with pk %s: %v", user.PublicKeyBase58Check, err))
 		}
 	}
@@ -93,7 +106,11 @@ func (fes *APIServer) updateUsersStateless(userList []*User) (*lib.GlobalParamsE
 	return globalParams, nil
 }
 
-func (fes *APIServer) updateUserFieldsStateless(user *User, utxoView *lib.UtxoView) error {
+// updateUserFieldsStateless fills in the fields of user that aren't stored with
+// it. adminRolesByPublicKey holds the roles of the users being updated, from
+// BatchGetAdminRoles.
+func (fes *APIServer) updateUserFieldsStateless(user *User, utxoView *lib.UtxoView,
+	adminRolesByPublicKey map[string][]AdminRole) error {
 	// If there's no public key, then return an error. We need a public key on
 	// the user object in order to be able to update the fields.
 	if user.PublicKeyBase58Check == "" {
@@ -209,15 +226,13 @@ func (fes *APIServer) updateUserFieldsStateless(user *User, utxoView *lib.UtxoVi
 
 	// Only set User.IsAdmin in GetUsersStateless
 	// We don't want or need to set this on every endpoint that generates a ProfileEntryResponse
-	if len(fes.AdminPublicKeys) == 0 {
+	if fes.AdminOpenAccess {
 		user.IsAdmin = true
+		user.AdminRoles = []AdminRole{AdminRoleSuperadmin}
 	} else {
-		for _, k := range fes.AdminPublicKeys {
-			if k == user.PublicKeyBase58Check {
-				user.IsAdmin = true
-				break
-			}
-		}
+		adminRoles := adminRolesByPublicKey[user.PublicKeyBase58Check]
+		user.IsAdmin = len(adminRoles) > 0
+		user.AdminRoles = adminRoles
 	}
 
 	// We expect SeedInfo and LocalState are set and don't mess with them in this
@@ -405,7 +420,7 @@ func (fes *APIServer) DeleteIdentities(ww http.ResponseWriter, req *http.Request
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := DeleteIdentityRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("DeleteIdentities: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("DeleteIdentities: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -425,7 +440,7 @@ func (fes *APIServer) DeleteIdentities(ww http.ResponseWriter, req *http.Request
 
 	res := DeleteIdentityResponse{}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("DeleteIdentities: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("DeleteIdentities: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -495,7 +510,7 @@ func (fes *APIServer) GetProfiles(ww http.ResponseWriter, req *http.Request) {
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetProfilesRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetProfiles: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetProfiles: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -511,25 +526,25 @@ func (fes *APIServer) GetProfiles(ww http.ResponseWriter, req *http.Request) {
 		var err error
 		readerPubKey, _, err = lib.Base58CheckDecode(requestData.ReaderPublicKeyBase58Check)
 		if requestData.ReaderPublicKeyBase58Check != "" && err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 				"GetProfiles: Problem decoding user public key: %v : %s", err, requestData.ReaderPublicKeyBase58Check))
 			return
 		}
 	}
 
-	// Get a utxo view for lookups.
-	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
-	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
-			"GetProfiles: Error fetching profiles from mempool: %v", err))
-		return
-	}
-
 	// If this is a usernamePrefix request, we hit the DB.
 	if requestData.UsernamePrefix != "" {
 		// TODO(performance): This currently fetches all usernames that match this prefix, which
 		// could get slow. Bandaid fix would be to not search until we have a few characters.
 
+		// Get a utxo view for lookups.
+		utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf(
+				"GetProfiles: Error fetching profiles from mempool: %v", err))
+			return
+		}
+
 		// Read in verified users map from DB
 		verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
 		if err != nil {
@@ -540,7 +555,7 @@ func (fes *APIServer) GetProfiles(ww http.ResponseWriter, req *http.Request) {
 		profileEntries, err := fes.GetProfilesByUsernamePrefixAndBitCloutLocked(
 			fes.blockchain.DB(), requestData.UsernamePrefix, readerPubKey, utxoView)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddInternalServerError(ww, fmt.Sprintf(
 				"GetProfiles: Error fetching profiles from view: %v", err))
 			return
 		}
@@ -557,7 +572,7 @@ func (fes *APIServer) GetProfiles(ww http.ResponseWriter, req *http.Request) {
 
 		// If we get here, we already handled a username prefix search request and can bail.
 		if err = json.NewEncoder(ww).Encode(usernameSearchRes); err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddInternalServerError(ww, fmt.Sprintf(
 				"GetProfiles: Problem encoding response as JSON: %v", err))
 			return
 		}
@@ -570,31 +585,12 @@ func (fes *APIServer) GetProfiles(ww http.ResponseWriter, req *http.Request) {
 		var err error
 		startPubKey, _, err = lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 		if requestData.PublicKeyBase58Check != "" && err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 				"GetProfiles: Problem decoding user public key: %v : %s", err, requestData.PublicKeyBase58Check))
 			return
 		}
 	}
 
-	// If we don't have a public key, check for a username and get the public key if it exists.
-	if startPubKey == nil && requestData.Username != "" {
-		profile := utxoView.GetProfileEntryForUsername([]byte(requestData.Username))
-		if profile == nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
-				"GetProfiles: Problem getting profile for username: %v : %s", err, requestData.Username))
-			return
-		}
-		startPubKey = profile.PublicKey
-	}
-
-	getPosts := false
-	if numToFetch == 1 {
-		// The only time we need comments and posts attached to profile entries right now
-		// is on the profile detail page where we are fetching a single profile.
-		getPosts = true
-	}
-
-	// Fetch the profiles.
 	// TODO: extraNumToFetchMultiplier is a dirty hack. We fetch more than numToFetch profiles
 	// to adjust for the fact that some profiles will be filtered out from the fetch due to their
 	// being blacklisted. The real fix would be to have GetProfilesByCoinValue to keep fetching
@@ -605,77 +601,82 @@ func (fes *APIServer) GetProfiles(ww http.ResponseWriter, req *http.Request) {
 	if numToFetch == 1 {
 		totalToFetch = 1
 	}
-	profileEntriesByPublicKey,
-	postsByProfilePublicKey,
-	postEntryReaderStates,
-	err := fes.GetProfilesByCoinValue(
-		utxoView, readerPubKey, startPubKey, totalToFetch,
-		getPosts, requestData.ModerationType)
-
-	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetProfiles: Error fetching profiles from view: %v", err))
-		return
-	}
 
-	// Get the map of verified usernames.
-	verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
-	if err != nil {
-		_AddInternalServerError(ww, fmt.Sprintf("GetProfiles: Error fetching verifiedMap: %v", err))
-		return
+	// Pages of the leaderboard are the same for every reader who isn't restricted,
+	// so they're built once per chain state and shared.
+	useFeedCache := false
+	if numToFetch != 1 && (startPubKey != nil || requestData.Username == "") {
+		var err error
+		useFeedCache, err = fes.canServeProfilesFromFeedCache(readerPubKey, requestData.ModerationType)
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("GetProfiles: %v", err))
+			return
+		}
 	}
 
-	if numToFetch == 1 {
-		// If only one entry was requested, find that one.
-		profileEntry := profileEntriesByPublicKey[lib.MakePkMapKey(startPubKey)]
-		if profileEntry == nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetProfiles: Could not find profile for pub key: %v", startPubKey))
+	if useFeedCache {
+		var err error
+		profileEntryResponses, err = fes.getProfilesByCoinValueFromFeedCache(
+			startPubKey, totalToFetch, requestData.ModerationType)
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("GetProfiles: Error fetching profiles from view: %v", err))
 			return
 		}
-		profileEntryResponse := fes.augmentProfileEntry(
-			profileEntry,
-			profileEntriesByPublicKey,
-			postsByProfilePublicKey,
-			postEntryReaderStates,
-			requestData.AddGlobalFeedBool,
-			verifiedMap,
-			utxoView,
-			readerPubKey,
-		)
-
-		// Get the users that HODL this profile, if requested.
-		hodlYouList := []*BalanceEntryResponse{}
-		if requestData.FetchUsersThatHODL {
-			// Get the users that the user hodls and vice versa
-			pkid := utxoView.GetPKIDForPublicKey(startPubKey)
-			_, hodlYouMap, err := fes.GetHodlingsForPublicKey(
-				pkid, true /*fetchProfiles*/, utxoView)
-			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf(
-					"GetProfiles: Could not find HODLers for pub key: %v", startPubKey))
+	} else {
+		// Get a utxo view for lookups.
+		utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf(
+				"GetProfiles: Error fetching profiles from mempool: %v", err))
+			return
+		}
+
+		// If we don't have a public key, check for a username and get the public key if it exists.
+		if startPubKey == nil && requestData.Username != "" {
+			profile := utxoView.GetProfileEntryForUsername([]byte(requestData.Username))
+			if profile == nil {
+				_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf(
+					"GetProfiles: Problem getting profile for username: %v : %s", err, requestData.Username))
 				return
 			}
-			for _, entryRes := range hodlYouMap {
-				hodlYouList = append(hodlYouList, entryRes)
-			}
-			// Note we sort the hodlYou list by amount held, descending. If creator is hodler, creator should be first
-			sort.Slice(hodlYouList, func(ii, jj int) bool {
-				if hodlYouList[ii].CreatorPublicKeyBase58Check == hodlYouList[ii].HODLerPublicKeyBase58Check {
-					return true
-				}
-				if hodlYouList[jj].CreatorPublicKeyBase58Check == hodlYouList[jj].HODLerPublicKeyBase58Check {
-					return false
-				}
-				return hodlYouList[ii].BalanceNanos > hodlYouList[jj].BalanceNanos
-			})
+			startPubKey = profile.PublicKey
 		}
-		profileEntryResponse.UsersThatHODL = hodlYouList
 
-		// Add the completed profileEntryResponse to the list we return.
-		profileEntryResponses = append(profileEntryResponses, profileEntryResponse)
-	} else {
-		for _, profileEntry := range profileEntriesByPublicKey {
-			// Append the profile to the list
-			profileEntryResponses = append(profileEntryResponses, fes.augmentProfileEntry(
+		getPosts := false
+		if numToFetch == 1 {
+			// The only time we need comments and posts attached to profile entries right now
+			// is on the profile detail page where we are fetching a single profile.
+			getPosts = true
+		}
+
+		// Fetch the profiles.
+		profileEntriesByPublicKey,
+		postsByProfilePublicKey,
+		postEntryReaderStates,
+		err := fes.GetProfilesByCoinValue(
+			utxoView, readerPubKey, startPubKey, totalToFetch,
+			getPosts, requestData.ModerationType)
+
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("GetProfiles: Error fetching profiles from view: %v", err))
+			return
+		}
+
+		// Get the map of verified usernames.
+		verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf("GetProfiles: Error fetching verifiedMap: %v", err))
+			return
+		}
+
+		if numToFetch == 1 {
+			// If only one entry was requested, find that one.
+			profileEntry := profileEntriesByPublicKey[lib.MakePkMapKey(startPubKey)]
+			if profileEntry == nil {
+				_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("GetProfiles: Could not find profile for pub key: %v", startPubKey))
+				return
+			}
+			profileEntryResponse := fes.augmentProfileEntry(
 				profileEntry,
 				profileEntriesByPublicKey,
 				postsByProfilePublicKey,
@@ -684,7 +685,52 @@ func (fes *APIServer) GetProfiles(ww http.ResponseWriter, req *http.Request) {
 				verifiedMap,
 				utxoView,
 				readerPubKey,
-			))
+			)
+
+			// Get the users that HODL this profile, if requested.
+			hodlYouList := []*BalanceEntryResponse{}
+			if requestData.FetchUsersThatHODL {
+				// Get the users that the user hodls and vice versa
+				pkid := utxoView.GetPKIDForPublicKey(startPubKey)
+				_, hodlYouMap, err := fes.GetHodlingsForPublicKey(
+					pkid, true /*fetchProfiles*/, utxoView)
+				if err != nil {
+					_AddNotFoundError(ww, fmt.Sprintf(
+						"GetProfiles: Could not find HODLers for pub key: %v", startPubKey))
+					return
+				}
+				for _, entryRes := range hodlYouMap {
+					hodlYouList = append(hodlYouList, entryRes)
+				}
+				// Note we sort the hodlYou list by amount held, descending. If creator is hodler, creator should be first
+				sort.Slice(hodlYouList, func(ii, jj int) bool {
+					if hodlYouList[ii].CreatorPublicKeyBase58Check == hodlYouList[ii].HODLerPublicKeyBase58Check {
+						return true
+					}
+					if hodlYouList[jj].CreatorPublicKeyBase58Check == hodlYouList[jj].HODLerPublicKeyBase58Check {
+						return false
+					}
+					return hodlYouList[ii].BalanceNanos > hodlYouList[jj].BalanceNanos
+				})
+			}
+			profileEntryResponse.UsersThatHODL = hodlYouList
+
+			// Add the completed profileEntryResponse to the list we return.
+			profileEntryResponses = append(profileEntryResponses, profileEntryResponse)
+		} else {
+			for _, profileEntry := range profileEntriesByPublicKey {
+				// Append the profile to the list
+				profileEntryResponses = append(profileEntryResponses, fes.augmentProfileEntry(
+					profileEntry,
+					profileEntriesByPublicKey,
+					postsByProfilePublicKey,
+					postEntryReaderStates,
+					requestData.AddGlobalFeedBool,
+					verifiedMap,
+					utxoView,
+					readerPubKey,
+				))
+			}
 		}
 	}
 
@@ -767,7 +813,7 @@ func (fes *APIServer) GetProfiles(ww http.ResponseWriter, req *http.Request) {
 		res = &GetProfilesResponse{ProfilesFound: profileEntryResponses}
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetProfiles: Problem encoding response as JSON: %v", err))
 		return
 	}
@@ -915,13 +961,13 @@ func (fes *APIServer) GetSingleProfile(ww http.ResponseWriter, req *http.Request
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetSingleProfileRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSingleProfile: Error parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetSingleProfile: Error parsing request body: %v", err))
 		return
 	}
 	// Get a view
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSingleProfile: Error getting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetSingleProfile: Error getting utxoView: %v", err))
 		return
 	}
 
@@ -931,7 +977,7 @@ func (fes *APIServer) GetSingleProfile(ww http.ResponseWriter, req *http.Request
 		var publicKeyBytes []byte
 		publicKeyBytes, _, err = lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetSingleProfile: Problem decoding user public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetSingleProfile: Problem decoding user public key: %v", err))
 			return
 		}
 		profileEntry = utxoView.GetProfileEntryForPublicKey(publicKeyBytes)
@@ -940,12 +986,12 @@ func (fes *APIServer) GetSingleProfile(ww http.ResponseWriter, req *http.Request
 	}
 	// Return an error if we failed to find a profile entry
 	if profileEntry == nil {
-		_AddNotFoundError(ww, fmt.Sprintf("GetSingleProfile: could not find profile for username or public key: %v, %v", requestData.Username, requestData.PublicKeyBase58Check))
+		_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("GetSingleProfile: could not find profile for username or public key: %v, %v", requestData.Username, requestData.PublicKeyBase58Check))
 		return
 	}
 	filteredPubKeys, err := fes.FilterOutRestrictedPubKeysFromList([][]byte{profileEntry.PublicKey}, nil, "")
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetSingleProfile: Error filtering out blacklisted users: %v, %v, %v", err, requestData.Username, requestData.PublicKeyBase58Check))
+		_AddInternalServerError(ww, fmt.Sprintf("GetSingleProfile: Error filtering out blacklisted users: %v, %v, %v", err, requestData.Username, requestData.PublicKeyBase58Check))
 		return
 	}
 	var res GetSingleProfileResponse
@@ -958,7 +1004,7 @@ func (fes *APIServer) GetSingleProfile(ww http.ResponseWriter, req *http.Request
 		// Grab verified username map pointer
 		verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetSingleProfile: could not get verified map: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("GetSingleProfile: could not get verified map: %v", err))
 			return
 		}
 		profileEntryResponse := _profileEntryToResponse(profileEntry, fes.Params, verifiedMap, utxoView)
@@ -1010,7 +1056,7 @@ func (fes *APIServer) GetHodlersForPublicKey(ww http.ResponseWriter, req *http.R
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetHodlersForPublicKeyRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 			"GetHodlersForPublicKey: Problem parsing request body: %v", err))
 		return
 	}
@@ -1018,7 +1064,7 @@ func (fes *APIServer) GetHodlersForPublicKey(ww http.ResponseWriter, req *http.R
 	// Get a view
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetHodlersForPublicKey: Error getting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetHodlersForPublicKey: Error getting utxoView: %v", err))
 		return
 	}
 
@@ -1027,7 +1073,7 @@ func (fes *APIServer) GetHodlersForPublicKey(ww http.ResponseWriter, req *http.R
 	if requestData.PublicKeyBase58Check != "" {
 		publicKeyBytes, _, err = lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetHodlersForPublicKey: Problem decoding user public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetHodlersForPublicKey: Problem decoding user public key: %v", err))
 			return
 		}
 	} else {
@@ -1036,7 +1082,7 @@ func (fes *APIServer) GetHodlersForPublicKey(ww http.ResponseWriter, req *http.R
 
 		// Return an error if we failed to find a profile entry
 		if profileEntry == nil {
-			_AddNotFoundError(ww, fmt.Sprintf("GetHodlersForPublicKey: could not find profile for username: %v", username))
+			_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("GetHodlersForPublicKey: could not find profile for username: %v", username))
 			return
 		}
 		publicKeyBytes = profileEntry.PublicKey
@@ -1048,14 +1094,14 @@ func (fes *APIServer) GetHodlersForPublicKey(ww http.ResponseWriter, req *http.R
 	if requestData.FetchHodlings {
 		hodlMap, err = fes.GetYouHodlMap(utxoView.GetPKIDForPublicKey(publicKeyBytes), false, utxoView)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetHodlersForPublicKey: error getting youHodlMap: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("GetHodlersForPublicKey: error getting youHodlMap: %v", err))
 			return
 		}
 
 	} else {
 		hodlMap, err = fes.GetHodlYouMap(utxoView.GetPKIDForPublicKey(publicKeyBytes), false, utxoView)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetHodlersForPublicKey: error getting youHodlMap: %v", err))
+			_AddInternalServerError(ww, fmt.Sprintf("GetHodlersForPublicKey: error getting youHodlMap: %v", err))
 			return
 		}
 	}
@@ -1094,7 +1140,7 @@ func (fes *APIServer) GetHodlersForPublicKey(ww http.ResponseWriter, req *http.R
 	// Grab verified username map pointer
 	verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetHodlersForPublicKey: Error fetching verifiedMap: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetHodlersForPublicKey: Error fetching verifiedMap: %v", err))
 	}
 	for _, balanceEntryResponse := range hodlList {
 		publicKeyBase58Check := getHodlerOrHodlingPublicKey(balanceEntryResponse, requestData.FetchHodlings)
@@ -1115,7 +1161,7 @@ func (fes *APIServer) GetHodlersForPublicKey(ww http.ResponseWriter, req *http.R
 		LastPublicKeyBase58Check: resLastPublicKey,
 	}
 	if err = json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetHodlersForPublicKey: Problem encoding response as JSON: %v", err))
 		return
 	}
@@ -1151,7 +1197,7 @@ func (fes *APIServer) GetDiamondsForPublicKey(ww http.ResponseWriter, req *http.
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetDiamondsForPublicKeyRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 			"GetHodlersForPublicKey: Problem parsing request body: %v", err))
 		return
 	}
@@ -1159,21 +1205,21 @@ func (fes *APIServer) GetDiamondsForPublicKey(ww http.ResponseWriter, req *http.
 	// Get a view
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondsForPublicKey: Error getting utxoView: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetDiamondsForPublicKey: Error getting utxoView: %v", err))
 		return
 	}
 
 	// Decode the public key for which we are fetching diamonds.
 	publicKeyBytes, _, err := lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondsForPublicKey: Problem decoding user public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetDiamondsForPublicKey: Problem decoding user public key: %v", err))
 		return
 	}
 
 	// Get the DiamondEntries for this public key.
 	pkidToDiamondEntriesMap, err := utxoView.GetDiamondEntryMapForPublicKey(publicKeyBytes, requestData.FetchYouDiamonded)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetDiamondsForPublicKey: Problem getting diamond entries: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetDiamondsForPublicKey: Problem getting diamond entries: %v", err))
 		return
 	}
 
@@ -1213,7 +1259,7 @@ func (fes *APIServer) GetDiamondsForPublicKey(ww http.ResponseWriter, req *http.
 	// Grab verified username map pointer so we can verify the profiles.
 	verifiedMap, err := fes.GetVerifiedUsernameToPKIDMap()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetDiamondsForPublicKey: Error fetching verifiedMap: %v", err))
 		return
 	}
@@ -1257,7 +1303,7 @@ func (fes *APIServer) GetDiamondsForPublicKey(ww http.ResponseWriter, req *http.
 		TotalDiamonds:                 totalDiamonds,
 	}
 	if err = json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetHodlersForPublicKey: Problem encoding response as JSON: %v", err))
 		return
 	}
@@ -1420,14 +1466,14 @@ func (fes *APIServer) GetFollowsStateless(ww http.ResponseWriter, rr *http.Reque
 	decoder := json.NewDecoder(io.LimitReader(rr.Body, MaxRequestBodySizeBytes))
 	getFollowsRequest := GetFollowsStatelessRequest{}
 	if err := decoder.Decode(&getFollowsRequest); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetFollowsStateless: Error parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetFollowsStateless: Error parsing request body: %v", err))
 		return
 	}
 
 	// Get a view
 	utxoView, err := fes.backendServer.GetMempool().GetAugmentedUniversalView()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetFollowsStateless Error getting view: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("GetFollowsStateless Error getting view: %v", err))
 		return
 	}
 
@@ -1435,7 +1481,7 @@ func (fes *APIServer) GetFollowsStateless(ww http.ResponseWriter, rr *http.Reque
 	if getFollowsRequest.PublicKeyBase58Check != "" {
 		publicKeyBytes, _, err = lib.Base58CheckDecode(getFollowsRequest.PublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetFollowsStateless: Problem decoding user public key: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetFollowsStateless: Problem decoding user public key: %v", err))
 			return
 		}
 	} else {
@@ -1444,7 +1490,7 @@ func (fes *APIServer) GetFollowsStateless(ww http.ResponseWriter, rr *http.Reque
 
 		// Return an error if we failed to find a profile entry
 		if profileEntry == nil {
-			_AddNotFoundError(ww, fmt.Sprintf("GetFollowsStateless: could not find profile for username: %v", username))
+			_AddCodedError(ww, ErrorCodeProfileNotFound, fmt.Sprintf("GetFollowsStateless: could not find profile for username: %v", username))
 			return
 		}
 
@@ -1455,7 +1501,7 @@ func (fes *APIServer) GetFollowsStateless(ww http.ResponseWriter, rr *http.Reque
 	if getFollowsRequest.LastPublicKeyBase58Check != "" {
 		lastPublicKeySeenBytes, _, err = lib.Base58CheckDecode(getFollowsRequest.LastPublicKeyBase58Check)
 		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf("GetFollowsStateless: Problem decoding last public key seen: %v", err))
+			_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetFollowsStateless: Problem decoding last public key seen: %v", err))
 			return
 		}
 	}
@@ -1503,32 +1549,32 @@ func (fes *APIServer) GetUserGlobalMetadata(ww http.ResponseWriter, req *http.Re
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetUserGlobalMetadataRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GetUserGlobalMetadata: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("GetUserGlobalMetadata: Problem parsing request body: %v", err))
 		return
 	}
 
 	if requestData.UserPublicKeyBase58Check == "" {
-		_AddBadRequestError(ww, fmt.Sprintf("GetUserGlobalMetadataRequest: Must provide a valid public key."))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("GetUserGlobalMetadataRequest: Must provide a valid public key."))
 		return
 	}
 
 	// Validate their permissions
 	isValid, err := fes.ValidateJWT(requestData.UserPublicKeyBase58Check, requestData.JWT)
 	if !isValid {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Invalid token: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidJWT, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Invalid token: %v", err))
 		return
 	}
 
 	userPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.UserPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Invalid public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Invalid public key: %v", err))
 		return
 	}
 
 	// Now that we have a public key, update get the global state object.
 	userMetadata, err := fes.getUserMetadataFromGlobalState(lib.PkToString(userPublicKeyBytes, fes.Params))
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetUserGlobalMetadata: Problem getting metadata from global state: %v", err))
 		return
 	}
@@ -1539,7 +1585,7 @@ func (fes *APIServer) GetUserGlobalMetadata(ww http.ResponseWriter, req *http.Re
 		PhoneNumber: userMetadata.PhoneNumber,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminGetUserGlobalMetadata: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminGetUserGlobalMetadata: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -1568,65 +1614,59 @@ func (fes *APIServer) UpdateUserGlobalMetadata(ww http.ResponseWriter, req *http
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := UpdateUserGlobalMetadataRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateUserGlobalMetadata: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("UpdateUserGlobalMetadata: Problem parsing request body: %v", err))
 		return
 	}
 
 	if requestData.UserPublicKeyBase58Check == "" {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Must provide a valid public key."))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Must provide a valid public key."))
 		return
 	}
 
 	if requestData.Email == "" && requestData.MessageReadStateUpdatesByContact == nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Must provide something to update."))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Must provide something to update."))
 		return
 	}
 
 	// Validate their permissions
 	isValid, err := fes.ValidateJWT(requestData.UserPublicKeyBase58Check, requestData.JWT)
 	if !isValid {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Invalid token: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidJWT, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Invalid token: %v", err))
 		return
 	}
 
 	userPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.UserPublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Invalid public key: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("UpdateUserGlobalMetadataRequest: Invalid public key: %v", err))
 		return
 	}
 
-	// Now that we have a public key, update the global state object.
-	userMetadata, err := fes.getUserMetadataFromGlobalState(lib.PkToString(userPublicKeyBytes, fes.Params))
-	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem with getUserMetadataFromGlobalState: %v", err))
-		return
-	}
-
-	// Now that we have a userMetadata object, update it based on the request.
-	if requestData.Email != "" {
-		userMetadata.Email = requestData.Email
-	}
-
-	if requestData.MessageReadStateUpdatesByContact != nil {
-		if userMetadata.MessageReadStateByContact == nil {
-			userMetadata.MessageReadStateByContact = make(map[string]int)
-		}
-		for contactPubKey, readMessageCount := range requestData.MessageReadStateUpdatesByContact {
-			userMetadata.MessageReadStateByContact[contactPubKey] = readMessageCount
+	// Now that we have a public key, atomically update the global state object
+	// based on the request.
+	_, err = fes.updateUserMetadataInGlobalState(userPublicKeyBytes, func(userMetadata *UserMetadata) error {
+		if requestData.Email != "" {
+			userMetadata.Email = requestData.Email
 		}
-	}
 
-	// Stick userMetadata back into global state object.
-	err = fes.putUserMetadataInGlobalState(userMetadata)
+		if requestData.MessageReadStateUpdatesByContact != nil {
+			if userMetadata.MessageReadStateByContact == nil {
+				userMetadata.MessageReadStateByContact = make(map[string]int)
+			}
+			for contactPubKey, readMessageCount := range requestData.MessageReadStateUpdatesByContact {
+				userMetadata.MessageReadStateByContact[contactPubKey] = readMessageCount
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem putting updated user metadata: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem putting updated user metadata: %v", err))
 		return
 	}
 
 	// If we made it this far we were successful, return without error.
 	res := UpdateUserGlobalMetadataResponse{}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("AdminUpdateUserGlobalMetadata: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -1650,20 +1690,20 @@ func (fes *APIServer) GetNotifications(ww http.ResponseWriter, req *http.Request
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := GetNotificationsRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 			"GetNotifications: Problem parsing request body: %v", err))
 		return
 	}
 	finalTxnMetadataList, utxoView, err := fes._getNotifications(&requestData)
 	if err != nil {
-		_AddBadRequestError(ww, err.Error())
+		_AddCodedError(ww, errorCodeOf(err, ErrorCodeInternal), err.Error())
 		return
 	}
 
 	var userPublicKeyBytes []byte
 	userPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 	if err != nil || len(userPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"GetNotifications: Problem decoding updater public key %s: %v",
 			requestData.PublicKeyBase58Check, err))
 		return
@@ -1671,7 +1711,7 @@ func (fes *APIServer) GetNotifications(ww http.ResponseWriter, req *http.Request
 
 	blockedPubKeys, err := fes.GetBlockedPubKeysForUser(userPublicKeyBytes)
 	if err != nil {
-		_AddBadRequestError(ww, err.Error())
+		_AddInternalServerError(ww, err.Error())
 		return
 	}
 
@@ -1681,7 +1721,7 @@ func (fes *APIServer) GetNotifications(ww http.ResponseWriter, req *http.Request
 		var pkBytes []byte
 		pkBytes, _, err = lib.Base58CheckDecode(txn.Metadata.TransactorPublicKeyBase58Check)
 		if err != nil {
-			APIAddError(ww, err.Error())
+			_AddInternalServerError(ww, err.Error())
 			return
 		}
 		if _, ok := blockedPubKeys[lib.PkToString(pkBytes, fes.Params)]; !ok {
@@ -1722,13 +1762,13 @@ func (fes *APIServer) GetNotifications(ww http.ResponseWriter, req *http.Request
 	}
 	for _, txnMeta := range filteredTxnMetadataList {
 		if err := addProfileForPubKey(txnMeta.Metadata.TransactorPublicKeyBase58Check); err != nil {
-			APIAddError(ww, err.Error())
+			_AddInternalServerError(ww, err.Error())
 			return
 		}
 
 		for _, affectedPk := range txnMeta.Metadata.AffectedPublicKeys {
 			if err := addProfileForPubKey(affectedPk.PublicKeyBase58Check); err != nil {
-				APIAddError(ww, err.Error())
+				_AddInternalServerError(ww, err.Error())
 				return
 			}
 		}
@@ -1793,29 +1833,18 @@ func (fes *APIServer) GetNotifications(ww http.ResponseWriter, req *http.Request
 	// only try to update the index if we're requesting the first page of results
 	// and we have at least one notification
 	if requestData.FetchStartIndex < 0 && len(filteredTxnMetadataList) > 0 {
-		// global state does not have good support for concurrency. if someone
-		// else fetches this user's data and writes at the same time we could
-		// overwrite each other. there's a task in jira to investigate concurrency
-		// issues with global state.
-		userMetadata, err := fes.getUserMetadataFromGlobalState(lib.PkToString(userPublicKeyBytes, fes.Params))
-		if err != nil {
-			_AddBadRequestError(ww, fmt.Sprintf(
-				"GetNotifications: Problem getting metadata from global state: %v", err))
-			return
-		}
-
 		// only update the index if it's greater than the current index we have stored
 		lastSeenIndex := filteredTxnMetadataList[0].Index
-		if lastSeenIndex > userMetadata.NotificationLastSeenIndex {
-			userMetadata.NotificationLastSeenIndex = lastSeenIndex
-
-			// Place the update metadata into the global state
-			err = fes.putUserMetadataInGlobalState(userMetadata)
-			if err != nil {
-				_AddBadRequestError(ww, fmt.Sprintf(
-					"GetNotifications: Problem putting updated user metadata: %v", err))
-				return
+		_, err := fes.updateUserMetadataInGlobalState(userPublicKeyBytes, func(userMetadata *UserMetadata) error {
+			if lastSeenIndex > userMetadata.NotificationLastSeenIndex {
+				userMetadata.NotificationLastSeenIndex = lastSeenIndex
 			}
+			return nil
+		})
+		if err != nil {
+			_AddInternalServerError(ww, fmt.Sprintf(
+				"GetNotifications: Problem putting updated user metadata: %v", err))
+			return
 		}
 	}
 
@@ -1827,7 +1856,7 @@ func (fes *APIServer) GetNotifications(ww http.ResponseWriter, req *http.Request
 		PostsByHash:         postEntryResponses,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddInternalServerError(ww, fmt.Sprintf(
 			"GetNotifications: Problem encoding response as JSON: %v", err))
 		return
 	}
@@ -1837,15 +1866,16 @@ func (fes *APIServer) _getNotifications(request *GetNotificationsRequest) ([]*Tr
 	// If the TxIndex flag was not passed to this node then we can't compute
 	// notifications.
 	if fes.TxIndexChain == nil {
-		return nil, nil, errors.Errorf(
-			"GetNotifications: Cannot be called when TxIndexChain " +
-				"is nil. This error occurs when --txindex was not passed to the program " +
+		return nil, nil, NewAPIError(ErrorCodeTxindexDisabled,
+			"GetNotifications: Cannot be called when TxIndexChain "+
+				"is nil. This error occurs when --txindex was not passed to the program "+
 				"on startup")
 	}
 
 	pkBytes, _, err := lib.Base58CheckDecode(request.PublicKeyBase58Check)
 	if err != nil {
-		return nil, nil, errors.Errorf("GetNotifications: Problem parsing public key: %v", err)
+		return nil, nil, NewAPIError(ErrorCodeInvalidPublicKey, fmt.Sprintf(
+			"GetNotifications: Problem parsing public key: %v", err))
 	}
 
 	// A valid mempool object is used to compute the TransactionMetadata for the mempool
@@ -2146,7 +2176,7 @@ func (fes *APIServer) BlockPublicKey(ww http.ResponseWriter, req *http.Request)
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := BlockPublicKeyRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf(
 			"BlockPublicKey: Problem parsing request body: %v", err))
 		return
 	}
@@ -2155,7 +2185,7 @@ func (fes *APIServer) BlockPublicKey(ww http.ResponseWriter, req *http.Request)
 	var err error
 	userPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 	if err != nil || len(userPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"BlockPublicKey: Problem decoding user public key %s: %v",
 			requestData.PublicKeyBase58Check, err))
 		return
@@ -2164,7 +2194,7 @@ func (fes *APIServer) BlockPublicKey(ww http.ResponseWriter, req *http.Request)
 	// Validate their permissions
 	isValid, err := fes.ValidateJWT(requestData.PublicKeyBase58Check, requestData.JWT)
 	if !isValid {
-		_AddBadRequestError(ww, fmt.Sprintf("BlockPublicKey: Invalid token: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidJWT, fmt.Sprintf("BlockPublicKey: Invalid token: %v", err))
 		return
 	}
 
@@ -2172,47 +2202,44 @@ func (fes *APIServer) BlockPublicKey(ww http.ResponseWriter, req *http.Request)
 	var blockPublicKeyBytes []byte
 	blockPublicKeyBytes, _, err = lib.Base58CheckDecode(requestData.BlockPublicKeyBase58Check)
 	if err != nil || len(blockPublicKeyBytes) != btcec.PubKeyBytesLenCompressed {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf(
 			"BlockPublicKey: Problem decoding public key to block %s: %v",
 			requestData.BlockPublicKeyBase58Check, err))
 		return
 	}
 
-	userMetadata, err := fes.getUserMetadataFromGlobalState(requestData.PublicKeyBase58Check)
-	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BlockPublicKey: Problem with getUserMetadataFromGlobalState: %v", err))
-		return
-	}
 	blockPublicKeyString := lib.PkToString(blockPublicKeyBytes, fes.Params)
 
-	blockedPublicKeys := userMetadata.BlockedPublicKeys
-	if blockedPublicKeys == nil {
-		blockedPublicKeys = make(map[string]struct{})
-	}
-	// Check if the user is already blocked by the reader.
-	_, keyExists := blockedPublicKeys[blockPublicKeyString]
+	userMetadata, err := fes.updateUserMetadataInGlobalState(userPublicKeyBytes, func(userMetadata *UserMetadata) error {
+		blockedPublicKeys := userMetadata.BlockedPublicKeys
+		if blockedPublicKeys == nil {
+			blockedPublicKeys = make(map[string]struct{})
+		}
+		// Check if the user is already blocked by the reader.
+		_, keyExists := blockedPublicKeys[blockPublicKeyString]
 
-	// Delete the public keys from the Reader's map of blocked public keys if we are unblocking and the public key is
-	// in the map.  Add the public key to the User's map of blocked public keys if we are blocking and the public key is
-	// not currently present in the User's map of blocked public keys.
-	if keyExists && requestData.Unblock {
-		delete(blockedPublicKeys, blockPublicKeyString)
-	} else if !keyExists && !requestData.Unblock {
-		blockedPublicKeys[blockPublicKeyString] = struct{}{}
-	}
-	userMetadata.BlockedPublicKeys = blockedPublicKeys
-	err = fes.putUserMetadataInGlobalState(userMetadata)
+		// Delete the public keys from the Reader's map of blocked public keys if we are unblocking and the public key is
+		// in the map.  Add the public key to the User's map of blocked public keys if we are blocking and the public key is
+		// not currently present in the User's map of blocked public keys.
+		if keyExists && requestData.Unblock {
+			delete(blockedPublicKeys, blockPublicKeyString)
+		} else if !keyExists && !requestData.Unblock {
+			blockedPublicKeys[blockPublicKeyString] = struct{}{}
+		}
+		userMetadata.BlockedPublicKeys = blockedPublicKeys
+		return nil
+	})
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BlockPublicKey: Problem with putUserMetadataInGlobalState: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("BlockPublicKey: Problem with updateUserMetadataInGlobalState: %v", err))
 		return
 	}
 
 	// Return the posts found.
 	res := &BlockPublicKeyResponse{
-		BlockedPublicKeys: blockedPublicKeys,
+		BlockedPublicKeys: userMetadata.BlockedPublicKeys,
 	}
 	if err := json.NewEncoder(ww).Encode(res); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("BlockPublicKey: Problem encoding response as JSON: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("BlockPublicKey: Problem encoding response as JSON: %v", err))
 		return
 	}
 }
@@ -1,9 +1,7 @@
 package routes
 
 import (
-	"bytes"
 	"context"
-	"encoding/gob"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -46,12 +44,12 @@ func (fes *APIServer) SendPhoneNumberVerificationText(ww http.ResponseWriter, re
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SendPhoneNumberVerificationTextRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendPhoneNumberVerificationText: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SendPhoneNumberVerificationText: Problem parsing request body: %v", err))
 		return
 	}
 
 	if fes.Twilio == nil {
-		_AddBadRequestError(ww,
+		_AddCodedError(ww, ErrorCodeFeatureDisabled,
 			"SendPhoneNumberVerificationText: Error: You must set Twilio API keys to use this functionality")
 		return
 	}
@@ -61,7 +59,7 @@ func (fes *APIServer) SendPhoneNumberVerificationText(ww http.ResponseWriter, re
 	/**************************************************************/
 	err := fes.validatePhoneNumberNotAlreadyInUse(requestData.PhoneNumber, requestData.PublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf(
+		_AddCodedError(ww, errorCodeOf(err, ErrorCodeInternal), fmt.Sprintf(
 			"SendPhoneNumberVerificationText: Error with validatePhoneNumberNotAlreadyInUse: %v", err))
 		return
 	}
@@ -77,11 +75,11 @@ func (fes *APIServer) SendPhoneNumberVerificationText(ww http.ResponseWriter, re
 	lookup, err := fes.Twilio.Lookup.LookupPhoneNumbers.Get(ctx, phoneNumber, data)
 
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendPhoneNumberVerificationText: Problem with Lookup: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SendPhoneNumberVerificationText: Problem with Lookup: %v", err))
 		return
 	}
 	if lookup.Carrier.Type == TwilioVoipCarrierType {
-		_AddBadRequestError(ww, fmt.Sprintf("SendPhoneNumberVerificationText: VOIP number not allowed"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SendPhoneNumberVerificationText: VOIP number not allowed"))
 		return
 	}
 
@@ -94,8 +92,9 @@ func (fes *APIServer) SendPhoneNumberVerificationText(ww http.ResponseWriter, re
 	data.Add("To", phoneNumber)
 	data.Add("Channel", "sms")
 	_, err = fes.Twilio.Verify.Verifications.Create(ctx, fes.TwilioVerifyServiceId, data)
+	metricTwilioSends.WithLabelValues(metricResult(err)).Inc()
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendPhoneNumberVerificationText: Error with SendSMS: %v", err))
+		_AddCodedError(ww, ErrorCodeUpstream, fmt.Sprintf("SendPhoneNumberVerificationText: Error with SendSMS: %v", err))
 		return
 	}
 }
@@ -122,7 +121,7 @@ func (fes *APIServer) canUserCreateProfile(userMetadata *UserMetadata, utxoView
 }
 
 func (fes *APIServer) getPhoneNumberMetadataFromGlobalState(phoneNumber string) (_phoneNumberMetadata *PhoneNumberMetadata, _err error) {
-	dbKey, err := GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber)
+	dbKey, err := GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber, fes.PIIKeyring)
 	if err != nil {
 		return nil, errors.Wrap(fmt.Errorf(
 			"getPhoneNumberMetadataFromGlobalState: Problem with GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata %v", err), "")
@@ -133,35 +132,109 @@ func (fes *APIServer) getPhoneNumberMetadataFromGlobalState(phoneNumber string)
 		return nil, errors.Wrap(fmt.Errorf(
 			"getPhoneNumberMetadataFromGlobalState: Problem with GlobalStateGet: %v", err), "")
 	}
+	if phoneNumberMetadataBytes == nil {
+		phoneNumberMetadataBytes, err = fes.getLegacyPhoneNumberMetadataBytes(phoneNumber)
+		if err != nil {
+			return nil, errors.Wrap(fmt.Errorf(
+				"getPhoneNumberMetadataFromGlobalState: %v", err), "")
+		}
+	}
 
-	phoneNumberMetadata := PhoneNumberMetadata{}
+	phoneNumberMetadata := &PhoneNumberMetadata{}
 	if phoneNumberMetadataBytes != nil {
-		err = gob.NewDecoder(bytes.NewReader(phoneNumberMetadataBytes)).Decode(&phoneNumberMetadata)
+		phoneNumberMetadata, err = fes.PIIKeyring.decodePhoneNumberMetadata(phoneNumberMetadataBytes)
 		if err != nil {
 			return nil, errors.Wrap(fmt.Errorf(
 				"getPhoneNumberMetadataFromGlobalState: Problem with NewDecoder: %v", err), "")
 		}
 	}
 
-	return &phoneNumberMetadata, nil
+	return phoneNumberMetadata, nil
 }
 
-func (fes *APIServer) putPhoneNumberMetadataInGlobalState(phoneNumberMetadata *PhoneNumberMetadata) (_err error) {
-	dbKey, err := GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumberMetadata.PhoneNumber)
+// getLegacyPhoneNumberMetadataBytes returns the metadata stored under the plaintext
+// phone number key, if any. Entries stay there until the rotate-pii command moves
+// them to their HMAC key, so they have to be checked whenever blind indexing is
+// enabled and the HMAC key is empty.
+func (fes *APIServer) getLegacyPhoneNumberMetadataBytes(phoneNumber string) ([]byte, error) {
+	if fes.PIIKeyring == nil {
+		return nil, nil
+	}
+	legacyKey, err := GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber, nil)
 	if err != nil {
-		return errors.Wrap(fmt.Errorf(
-			"putPhoneNumberMetadataInGlobalState: Problem with GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata %v", err), "")
+		return nil, fmt.Errorf("getLegacyPhoneNumberMetadataBytes: %v", err)
 	}
+	phoneNumberMetadataBytes, err := fes.GlobalStateGet(legacyKey)
+	if err != nil {
+		return nil, fmt.Errorf("getLegacyPhoneNumberMetadataBytes: Problem with GlobalStateGet: %v", err)
+	}
+	return phoneNumberMetadataBytes, nil
+}
 
-	metadataDataBuf := bytes.NewBuffer([]byte{})
-	gob.NewEncoder(metadataDataBuf).Encode(phoneNumberMetadata)
-	err = fes.GlobalStatePut(dbKey, metadataDataBuf.Bytes())
+// updatePhoneNumberMetadataInGlobalState atomically applies updateFunc to the metadata
+// for a phone number. Like updateUserMetadataInGlobalState, updateFunc may be called
+// more than once if there are concurrent updates. Returns the metadata that was written.
+func (fes *APIServer) updatePhoneNumberMetadataInGlobalState(
+	phoneNumber string,
+	updateFunc func(phoneNumberMetadata *PhoneNumberMetadata) error,
+) (_phoneNumberMetadata *PhoneNumberMetadata, _err error) {
+	dbKey, err := GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber, fes.PIIKeyring)
 	if err != nil {
-		return errors.Wrap(fmt.Errorf(
-			"putPhoneNumberMetadataInGlobalState: Problem putting updated phone number metadata: %v", err), "")
+		return nil, errors.Wrap(fmt.Errorf(
+			"updatePhoneNumberMetadataInGlobalState: Problem with GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata %v", err), "")
 	}
 
-	return nil
+	var updatedPhoneNumberMetadata *PhoneNumberMetadata
+	usedLegacyEntry := false
+	err = fes.GlobalStateUpdate(dbKey, func(phoneNumberMetadataBytes []byte) ([]byte, error) {
+		// Carry over an entry that hasn't been moved to its HMAC key yet.
+		usedLegacyEntry = false
+		if phoneNumberMetadataBytes == nil {
+			legacyBytes, err := fes.getLegacyPhoneNumberMetadataBytes(phoneNumber)
+			if err != nil {
+				return nil, err
+			}
+			phoneNumberMetadataBytes = legacyBytes
+			usedLegacyEntry = legacyBytes != nil
+		}
+
+		phoneNumberMetadata := &PhoneNumberMetadata{}
+		if phoneNumberMetadataBytes != nil {
+			var err error
+			phoneNumberMetadata, err = fes.PIIKeyring.decodePhoneNumberMetadata(phoneNumberMetadataBytes)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := updateFunc(phoneNumberMetadata); err != nil {
+			return nil, err
+		}
+
+		newPhoneNumberMetadataBytes, err := fes.PIIKeyring.encodePhoneNumberMetadata(phoneNumberMetadata)
+		if err != nil {
+			return nil, err
+		}
+		updatedPhoneNumberMetadata = phoneNumberMetadata
+		return newPhoneNumberMetadataBytes, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(fmt.Errorf(
+			"updatePhoneNumberMetadataInGlobalState: Problem updating phone number metadata: %v", err), "")
+	}
+
+	// The entry now lives under its HMAC key so the plaintext key can go.
+	if usedLegacyEntry {
+		legacyKey, err := GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata(phoneNumber, nil)
+		if err == nil {
+			err = fes.GlobalStateDelete(legacyKey)
+		}
+		if err != nil {
+			glog.Errorf("updatePhoneNumberMetadataInGlobalState: Problem deleting legacy phone number key: %v", err)
+		}
+	}
+
+	return updatedPhoneNumberMetadata, nil
 }
 
 func (fes *APIServer) validatePhoneNumberNotAlreadyInUse(phoneNumber string, userPublicKeyBase58Check string) (_err error) {
@@ -175,7 +248,7 @@ func (fes *APIServer) validatePhoneNumberNotAlreadyInUse(phoneNumber string, use
 	if phoneNumberMetadata.PublicKey != nil {
 		publicKeyBase58Check := lib.PkToString(phoneNumberMetadata.PublicKey, fes.Params)
 		if publicKeyBase58Check != userPublicKeyBase58Check {
-			return errors.Wrap(fmt.Errorf("validatePhoneNumberNotAlreadyInUse: Phone number already in use"), "")
+			return NewAPIError(ErrorCodeConflict, "validatePhoneNumberNotAlreadyInUse: Phone number already in use")
 		}
 	}
 
@@ -192,7 +265,7 @@ func (fes *APIServer) SubmitPhoneNumberVerificationCode(ww http.ResponseWriter,
 	decoder := json.NewDecoder(io.LimitReader(req.Body, MaxRequestBodySizeBytes))
 	requestData := SubmitPhoneNumberVerificationCodeRequest{}
 	if err := decoder.Decode(&requestData); err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Problem parsing request body: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidRequestBody, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Problem parsing request body: %v", err))
 		return
 	}
 
@@ -201,7 +274,7 @@ func (fes *APIServer) SubmitPhoneNumberVerificationCode(ww http.ResponseWriter,
 	/**************************************************************/
 	err := fes.validatePhoneNumberNotAlreadyInUse(requestData.PhoneNumber, requestData.PublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Error with validatePhoneNumberNotAlreadyInUse: %v", err))
+		_AddCodedError(ww, errorCodeOf(err, ErrorCodeInternal), fmt.Sprintf("SubmitPhoneNumberVerificationCode: Error with validatePhoneNumberNotAlreadyInUse: %v", err))
 		return
 	}
 
@@ -216,13 +289,13 @@ func (fes *APIServer) SubmitPhoneNumberVerificationCode(ww http.ResponseWriter,
 	data.Add("To", requestData.PhoneNumber)
 	checkPhoneNumberResponse, err := fes.Twilio.Verify.Verifications.Check(ctx, fes.TwilioVerifyServiceId, data)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SendPhoneNumberVerificationText: Error with SendSMS: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SendPhoneNumberVerificationText: Error with SendSMS: %v", err))
 		return
 	}
 	if checkPhoneNumberResponse.Status != TwilioCheckPhoneNumberApproved {
 		// If the phone number has requested a code recently, and the code is well-formed (e.g. ~6 chars),
 		// but the code is incorrect, we end up here
-		_AddBadRequestError(ww, fmt.Sprintf("SendPhoneNumberVerificationText: Code is not valid"))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SendPhoneNumberVerificationText: Code is not valid"))
 		return
 	}
 
@@ -230,43 +303,42 @@ func (fes *APIServer) SubmitPhoneNumberVerificationCode(ww http.ResponseWriter,
 	// Save the phone number in global state
 	/**************************************************************/
 	// Update / save userMetadata in global state
-	userMetadata, err := fes.getUserMetadataFromGlobalState(requestData.PublicKeyBase58Check)
+	userPublicKeyBytes, _, err := lib.Base58CheckDecode(requestData.PublicKeyBase58Check)
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Problem with getUserMetadataFromGlobalState: %v", err))
+		_AddCodedError(ww, ErrorCodeInvalidPublicKey, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Problem decoding public key: %v", err))
 		return
 	}
-
-	settingPhoneNumberForFirstTime := userMetadata.PhoneNumber == ""
-	userMetadata.PhoneNumber = requestData.PhoneNumber
-	err = fes.putUserMetadataInGlobalState(userMetadata)
+	settingPhoneNumberForFirstTime := false
+	userMetadata, err := fes.updateUserMetadataInGlobalState(userPublicKeyBytes, func(userMetadata *UserMetadata) error {
+		settingPhoneNumberForFirstTime = userMetadata.PhoneNumber == ""
+		userMetadata.PhoneNumber = requestData.PhoneNumber
+		return nil
+	})
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Error putting usermetadata in Global state: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Error putting usermetadata in Global state: %v", err))
 		return
 	}
 
-	// Update / save phoneNumberMetadata in global state
-	phoneNumberMetadata, err := fes.getPhoneNumberMetadataFromGlobalState(requestData.PhoneNumber)
-	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Error with getPhoneNumberMetadataFromGlobalState: %v", err))
-		return
-	}
-
-	phoneNumberMetadata.PublicKey = userMetadata.PublicKey
-	phoneNumberMetadata.PhoneNumber = requestData.PhoneNumber
-	phoneNumberMetadata.ShouldCompProfileCreation = true
 	// Parse the raw phone number
-	parsedNumber, err := phonenumbers.Parse(phoneNumberMetadata.PhoneNumber, "")
+	parsedNumber, err := phonenumbers.Parse(requestData.PhoneNumber, "")
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata: Problem with phonenumbers.Parse: %v", err))
+		_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("GlobalStateKeyForPhoneNumberStringToPhoneNumberMetadata: Problem with phonenumbers.Parse: %v", err))
 		return
 	}
-	if parsedNumber.CountryCode != nil {
-		phoneNumberMetadata.PhoneNumberCountryCode =
-			phonenumbers.GetRegionCodeForCountryCode(int(*parsedNumber.CountryCode))
-	}
-	err = fes.putPhoneNumberMetadataInGlobalState(phoneNumberMetadata)
+
+	// Update / save phoneNumberMetadata in global state
+	_, err = fes.updatePhoneNumberMetadataInGlobalState(requestData.PhoneNumber, func(phoneNumberMetadata *PhoneNumberMetadata) error {
+		phoneNumberMetadata.PublicKey = userMetadata.PublicKey
+		phoneNumberMetadata.PhoneNumber = requestData.PhoneNumber
+		phoneNumberMetadata.ShouldCompProfileCreation = true
+		if parsedNumber.CountryCode != nil {
+			phoneNumberMetadata.PhoneNumberCountryCode =
+				phonenumbers.GetRegionCodeForCountryCode(int(*parsedNumber.CountryCode))
+		}
+		return nil
+	})
 	if err != nil {
-		_AddBadRequestError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Problem with putPhoneNumberMetadataInGlobalState: %v", err))
+		_AddInternalServerError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Problem with updatePhoneNumberMetadataInGlobalState: %v", err))
 		return
 	}
 
@@ -277,7 +349,7 @@ func (fes *APIServer) SubmitPhoneNumberVerificationCode(ww http.ResponseWriter,
 		amountToSendNanos := fes.StarterBitCloutAmountNanos
 
 		if len(requestData.PhoneNumber) == 0 || requestData.PhoneNumber[0] != '+' {
-			_AddBadRequestError(ww, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Phone number must start with a plus sign"))
+			_AddCodedError(ww, ErrorCodeBadRequest, fmt.Sprintf("SubmitPhoneNumberVerificationCode: Phone number must start with a plus sign"))
 			return
 		}
 
@@ -300,8 +372,12 @@ func (fes *APIServer) SubmitPhoneNumberVerificationCode(ww http.ResponseWriter,
 			}
 		}
 
-		if err = fes.SendSeedBitClout(userMetadata.PublicKey, amountToSendNanos); err != nil {
+		err = fes.SendSeedBitClout(userMetadata.PublicKey, amountToSendNanos)
+		metricStarterBitCloutSends.WithLabelValues(metricResult(err)).Inc()
+		if err != nil {
 			glog.Errorf("SubmitPhoneNumberVerificationCode: Error sending seed BitClout: %v", err)
+		} else {
+			metricStarterBitCloutSentNanos.Add(float64(amountToSendNanos))
 		}
 	}
 }
@@ -1,45 +1,42 @@
 package toolslib
 
 import (
-	"bytes"
+	"context"
 	"encoding/hex"
-	"encoding/json"
+	"github.com/bitclout/backend/client"
 	"github.com/bitclout/backend/routes"
 	"github.com/bitclout/core/lib"
+	"github.com/btcsuite/btcd/btcec"
 	"github.com/pkg/errors"
-	"io/ioutil"
-	"net/http"
 )
 
 // SubmitTransactionToNode...
 func SubmitTransactionToNode(txn *lib.MsgBitCloutTxn, node string) error {
-	endpoint := "http://" + node + routes.RoutePathSubmitTransaction
-
 	// Encode the signed transaction to hex
 	txnBytes, err := txn.ToBytes(false)
 	if err != nil {
-		return errors.Wrap(err, "SubmitTransactionToServer() failed to convert txn to bytes")
+		return errors.Wrap(err, "SubmitTransactionToNode() failed to convert txn to bytes")
 	}
 	txnHex := hex.EncodeToString(txnBytes)
 
-
-	// Setup request
-	payload := &routes.SubmitTransactionRequest{txnHex}
-	postBody, err := json.Marshal(payload)
+	_, err = client.NewClient(node).SubmitTransaction(context.Background(), &routes.SubmitTransactionRequest{
+		TransactionHex: txnHex,
+	})
 	if err != nil {
-		return errors.Wrap(err, "SubmitTransactionToServer() failed to marshal struct")
+		return errors.Wrap(err, "SubmitTransactionToNode() failed to submit transaction")
 	}
-	postBuffer := bytes.NewBuffer(postBody)
+	return nil
+}
 
-	// Execute request
-	resp, err := http.Post(endpoint, "application/json", postBuffer)
+// SignAndSubmitTransaction...
+func SignAndSubmitTransaction(txn *lib.MsgBitCloutTxn, privKey *btcec.PrivateKey, node string) error {
+	// Sign the transaction
+	signature, err := txn.Sign(privKey)
 	if err != nil {
-		return errors.Wrap(err, "SubmitTransactionToServer() failed to execute request")
+		return errors.Wrap(err, "SignAndSubmitTransaction() failed to sign transaction")
 	}
-	if resp.StatusCode != 200 {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return errors.Errorf("_generateUnsignedUpdateProfile(): Received non 200 response code: " +
-			"Status Code: %v Body: %v", resp.StatusCode, string(bodyBytes))
-	}
-	return nil
+	txn.Signature = signature
+
+	// Submit the transaction to the node
+	return SubmitTransactionToNode(txn, node)
 }
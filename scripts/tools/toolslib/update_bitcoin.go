@@ -1,77 +1,33 @@
 package toolslib
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"github.com/bitclout/backend/client"
 	"github.com/bitclout/backend/routes"
 	"github.com/bitclout/core/lib"
 	"github.com/btcsuite/btcd/btcec"
 	"github.com/pkg/errors"
-	"io/ioutil"
-	"net/http"
 )
 
-// _generateUnsignedBTCPriceUpdate...
-func _generateUnsignedBTCPriceUpdate(updaterPubKey *btcec.PublicKey, newUSDCentsPerBitcoin uint64,
-	params *lib.BitCloutParams, node string) (*routes.UpdateBitcoinUSDExchangeRateResponse, error) {
-	endpoint := "http://" + node + routes.RoutePathUpdateBitcoinUSDExchangeRate
-
-	// Setup request
-	payload := &routes.UpdateBitcoinUSDExchangeRateRequest {
-		UpdaterPublicKeyBase58Check: lib.PkToString(updaterPubKey.SerializeCompressed(), params),
-		USDCentsPerBitcoin: newUSDCentsPerBitcoin,
-		MinFeeRateNanosPerKB: 1000,
-	}
-	postBody, err := json.Marshal(payload)
-	if err != nil {
-		return nil, errors.Wrap(err, "_generateUnsignedBTCPriceUpdate() failed to marshal json")
-	}
-	postBuffer := bytes.NewBuffer(postBody)
-
-	// Execute request
-	resp, err := http.Post(endpoint, "application/json", postBuffer)
-	if err != nil {
-		return nil, errors.Wrap(err, "_generateUnsignedBTCPriceUpdate() failed to execute request")
-	}
-	if resp.StatusCode != 200 {
-		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return nil, errors.Errorf("_generateUnsignedBTCPriceUpdate(): Received non 200 response code: " +
-			"Status Code: %v Body: %v", resp.StatusCode, string(bodyBytes))
-	}
-
-	// Process response
-	updateBitcoinUSDExchangeRateResponse := routes.UpdateBitcoinUSDExchangeRateResponse{}
-	err = json.NewDecoder(resp.Body).Decode(&updateBitcoinUSDExchangeRateResponse)
-	if err != nil {
-		return nil, errors.Wrap(err, "_generateUnsignedBTCPriceUpdate(): failed decoding body")
-	}
-	err = resp.Body.Close()
-	if err != nil {
-		return nil, errors.Wrap(err, "_generateUnsignedBTCPriceUpdate(): failed closing body")
-	}
-	return &updateBitcoinUSDExchangeRateResponse, nil
-}
-
 // UpdateBitcoinUSDExchangeRate...
 func UpdateBitcoinUSDExchangeRate(updaterPubKey *btcec.PublicKey, updaterPrivKey *btcec.PrivateKey, newUSDCentsPerBitcoin uint64,
 	params *lib.BitCloutParams, node string) error {
 
-	// Request an unsigned transaction from the node
-	unsignedUpdateBitcoinUSDExchangeRate, err := _generateUnsignedBTCPriceUpdate(updaterPubKey, newUSDCentsPerBitcoin, params, node)
+	// Request an unsigned transaction from the node. The exchange rate is one of
+	// the global params, and values less than zero leave the others unchanged.
+	unsignedUpdateGlobalParams, err := client.NewClient(node).UpdateGlobalParams(context.Background(), &routes.UpdateGlobalParamsRequest{
+		UpdaterPublicKeyBase58Check: lib.PkToString(updaterPubKey.SerializeCompressed(), params),
+		USDCentsPerBitcoin:          int64(newUSDCentsPerBitcoin),
+		CreateProfileFeeNanos:       -1,
+		MinimumNetworkFeeNanosPerKB: -1,
+		MinFeeRateNanosPerKB:        1000,
+	})
 	if err != nil {
 		return errors.Wrap(err, "UpdateBitcoinUSDExchangeRate() failed to generate unsigned transaction")
 	}
-	txn := unsignedUpdateBitcoinUSDExchangeRate.Transaction
-
-	// Sign the transaction
-	signature, err := txn.Sign(updaterPrivKey)
-	if err != nil {
-		return errors.Wrap(err, "UpdateBitcoinUSDExchangeRate() failed to sign the transaction")
-	}
-	txn.Signature = signature
 
-	// Submit the transaction to the node
-	err = SubmitTransactionToNode(txn, node)
+	// Sign and submit the transaction to the node
+	err = SignAndSubmitTransaction(unsignedUpdateGlobalParams.Transaction, updaterPrivKey, node)
 	if err != nil {
 		return errors.Wrap(err, "UpdateBitcoinUSDExchangeRate() failed to submit transaction")
 	}